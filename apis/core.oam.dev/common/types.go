@@ -17,7 +17,11 @@ limitations under the License.
 package common
 
 import (
+	"bytes"
+	"encoding/json"
+
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
@@ -165,6 +169,10 @@ const (
 	ApplicationRendering ApplicationPhase = "rendering"
 	// ApplicationRunningWorkflow means the app is running workflow
 	ApplicationRunningWorkflow ApplicationPhase = "runningWorkflow"
+	// ApplicationWorkflowSuspending means the app's workflow is suspending
+	ApplicationWorkflowSuspending ApplicationPhase = "workflowSuspending"
+	// ApplicationWorkflowTerminated means the app's workflow is terminated
+	ApplicationWorkflowTerminated ApplicationPhase = "workflowTerminated"
 	// ApplicationRunning means the app finished rendering and applied result to the cluster
 	ApplicationRunning ApplicationPhase = "running"
 	// ApplicationHealthChecking means the app finished rendering and applied result to the cluster, but still unhealthy
@@ -205,12 +213,57 @@ type RawComponent struct {
 	Raw runtime.RawExtension `json:"raw"`
 }
 
+// WorkflowStatus record the status of workflow
+type WorkflowStatus struct {
+	// AppRevision is the app revision the workflow steps are executed for,
+	// the status of steps will be reset when a new app revision comes.
+	AppRevision string `json:"appRevision,omitempty"`
+
+	// Suspend indicates the workflow is suspended by a suspend step or by user,
+	// the following steps won't be executed until it's resumed.
+	Suspend bool `json:"suspend"`
+
+	// Terminated indicates the workflow is terminated by user,
+	// no step will be executed until the workflow is restarted or a new app revision comes.
+	Terminated bool `json:"terminated,omitempty"`
+
+	Steps []WorkflowStepStatus `json:"steps,omitempty"`
+}
+
+// UnmarshalJSON decodes the status of workflow, it also accepts the legacy status which is the list of the step
+// statuses, so that the applications stored by the previous versions can still be read and updated. The legacy
+// status has no app revision, so the workflow is executed again for the current app revision.
+func (in *WorkflowStatus) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '[' {
+		var steps []WorkflowStepStatus
+		if err := json.Unmarshal(trimmed, &steps); err != nil {
+			return err
+		}
+		*in = WorkflowStatus{Steps: steps}
+		return nil
+	}
+	// the alias type has no UnmarshalJSON method to avoid the recursion
+	type workflowStatus WorkflowStatus
+	return json.Unmarshal(data, (*workflowStatus)(in))
+}
+
 // WorkflowStepStatus record the status of a workflow step
 type WorkflowStepStatus struct {
-	Name        string                         `json:"name,omitempty"`
-	Type        string                         `json:"type,omitempty"`
-	Phase       WorkflowStepPhase              `json:"phase,omitempty"`
+	Name  string            `json:"name,omitempty"`
+	Type  string            `json:"type,omitempty"`
+	Phase WorkflowStepPhase `json:"phase,omitempty"`
+	// A human readable message indicating details about why the workflow step is in this phase.
+	Message string `json:"message,omitempty"`
+	// ResourceRef refers to the object applied by a CR based step, it's empty for built-in steps.
 	ResourceRef runtimev1alpha1.TypedReference `json:"resourceRef,omitempty"`
+	// Attempts is the number of times the step has been attempted, including retries.
+	Attempts int `json:"attempts,omitempty"`
+	// StartTime is the time when the step is executed for the first time.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Elapsed is the time elapsed from the start of the step until it's finished or last executed.
+	Elapsed *metav1.Duration `json:"elapsed,omitempty"`
+	// NextRetryTime is the time when the failed step will be retried.
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
 }
 
 // AppStatus defines the observed state of Application
@@ -232,14 +285,40 @@ type AppStatus struct {
 	// ResourceTracker record the status of the ResourceTracker
 	ResourceTracker *runtimev1alpha1.TypedReference `json:"resourceTracker,omitempty"`
 
-	// Workflow record the status of workflow steps
-	Workflow []WorkflowStepStatus `json:"workflow,omitempty"`
+	// Workflow record the status of workflow
+	Workflow *WorkflowStatus `json:"workflow,omitempty"`
+
+	// Drift record the drift of the dispatched resources from the manifests rendered by the latest revision
+	Drift *DriftStatus `json:"drift,omitempty"`
 
 	// LatestRevision of the application configuration it generates
 	// +optional
 	LatestRevision *Revision `json:"latestRevision,omitempty"`
 }
 
+// DriftStatus is the drift of the dispatched resources from the manifests rendered by the application revision.
+type DriftStatus struct {
+	// Resources are the dispatched resources drifted from their manifests
+	Resources []ResourceDrift `json:"resources,omitempty"`
+
+	// LastCorrectionTime is the last time the drifted resources were corrected
+	LastCorrectionTime *metav1.Time `json:"lastCorrectionTime,omitempty"`
+}
+
+// ResourceDrift is the drift of a dispatched resource from its manifest.
+type ResourceDrift struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+
+	// Missing indicates the resource is deleted
+	Missing bool `json:"missing,omitempty"`
+
+	// Fields are the paths of the fields whose live values differ from the manifest, e.g. spec.replicas
+	Fields []string `json:"fields,omitempty"`
+}
+
 // WorkflowStepPhase describes the phase of a workflow step.
 type WorkflowStepPhase string
 
@@ -252,6 +331,9 @@ const (
 	WorkflowStepPhaseStopped WorkflowStepPhase = "stopped"
 	// WorkflowStepPhaseRunning will make the controller continue the workflow.
 	WorkflowStepPhaseRunning WorkflowStepPhase = "running"
+	// WorkflowStepPhaseSkipped means the step is not executed as its `if` condition is false,
+	// the controller regards it as done and runs the next step.
+	WorkflowStepPhaseSkipped WorkflowStepPhase = "skipped"
 )
 
 // DefinitionType describes the type of DefinitionRevision.
@@ -283,4 +365,32 @@ type AppRolloutStatus struct {
 	// LastSourceAppRevision contains the name of the app that we need to upgrade from.
 	// We will restart the rollout if this is not the same as the spec
 	LastSourceAppRevision string `json:"LastSourceAppRevision,omitempty"`
+
+	// Components contains the rollout status of each component rolled out
+	// +optional
+	Components []ComponentRolloutStatus `json:"components,omitempty"`
+
+	// RollbackReason records why the rollout failed and is rolled back to the source app revision automatically
+	// +optional
+	RollbackReason string `json:"rollbackReason,omitempty"`
+}
+
+// ComponentRolloutStatus defines the observed rollout state of one component
+type ComponentRolloutStatus struct {
+	// Name is the name of the component
+	Name string `json:"name"`
+
+	v1alpha1.RolloutStatus `json:",inline"`
 }
+
+// MultiComponentStrategy defines how the components of a rollout move forward together
+type MultiComponentStrategy string
+
+const (
+	// LockstepMultiComponentStrategy rolls out all the components batch by batch,
+	// no component moves to the next batch until all the components finish the current one
+	LockstepMultiComponentStrategy MultiComponentStrategy = "Lockstep"
+
+	// OrderedMultiComponentStrategy rolls out the components one after another in the order of the component list
+	OrderedMultiComponentStrategy MultiComponentStrategy = "Ordered"
+)
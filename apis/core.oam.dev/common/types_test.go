@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalWorkflowStatus(t *testing.T) {
+	// the status of workflow stored by the previous versions is a list of the step statuses
+	legacy := `{"phase":"runningWorkflow","workflow":[{"name":"deploy","type":"apply-component","phase":"succeeded"},` +
+		`{"name":"notify","type":"webhook","phase":"running"}]}`
+	status := &AppStatus{}
+	require.NoError(t, json.Unmarshal([]byte(legacy), status))
+	require.NotNil(t, status.Workflow)
+	assert.Equal(t, &WorkflowStatus{Steps: []WorkflowStepStatus{
+		{Name: "deploy", Type: "apply-component", Phase: WorkflowStepPhaseSucceeded},
+		{Name: "notify", Type: "webhook", Phase: WorkflowStepPhaseRunning},
+	}}, status.Workflow)
+
+	// it's written back as an object
+	b, err := json.Marshal(status.Workflow)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"steps":[`)
+
+	current := `{"workflow":{"appRevision":"app-v2","suspend":true,"steps":[{"name":"deploy","phase":"succeeded"}]}}`
+	status = &AppStatus{}
+	require.NoError(t, json.Unmarshal([]byte(current), status))
+	assert.Equal(t, &WorkflowStatus{AppRevision: "app-v2", Suspend: true, Steps: []WorkflowStepStatus{
+		{Name: "deploy", Phase: WorkflowStepPhaseSucceeded},
+	}}, status.Workflow)
+
+	status = &AppStatus{}
+	require.NoError(t, json.Unmarshal([]byte(`{"workflow":null}`), status))
+	assert.Nil(t, status.Workflow)
+	assert.Error(t, json.Unmarshal([]byte(`{"workflow":[{"name":1}]}`), status))
+}
@@ -48,6 +48,10 @@ type ApplicationComponent struct {
 	// scopes in ApplicationComponent defines the component-level scopes
 	// the format is <scope-type:scope-instance-name> pairs, the key represents type of `ScopeDefinition` while the value represent the name of scope instance.
 	Scopes map[string]string `json:"scopes,omitempty"`
+
+	// DependsOn is the names of the components which must be healthy before the resources of this component are dispatched.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 // AppPolicy defines a global policy for all components in the app.
@@ -60,6 +64,18 @@ type AppPolicy struct {
 	Properties runtime.RawExtension `json:"properties,omitempty"`
 }
 
+// WorkflowStepFailurePolicy defines what to do with the workflow when a step is failed or stopped.
+type WorkflowStepFailurePolicy string
+
+const (
+	// WorkflowStepOnFailureAbort terminates the workflow, steps which are not started yet won't be executed.
+	WorkflowStepOnFailureAbort WorkflowStepFailurePolicy = "abort"
+	// WorkflowStepOnFailureContinue ignores the failure, the steps depending on the failed step are still executed.
+	WorkflowStepOnFailureContinue WorkflowStepFailurePolicy = "continue"
+	// WorkflowStepOnFailureRollback terminates the workflow like abort, and executes the rollback step.
+	WorkflowStepOnFailureRollback WorkflowStepFailurePolicy = "rollback"
+)
+
 // WorkflowStep defines how to execute a workflow step.
 type WorkflowStep struct {
 	// Name is the unique name of the workflow step.
@@ -69,6 +85,66 @@ type WorkflowStep struct {
 
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Properties runtime.RawExtension `json:"properties,omitempty"`
+
+	// DependsOn is the names of the steps which must be done before this step is executed.
+	// If no step in the workflow specifies dependsOn, the steps are executed one by one in array order.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// If is a CUE expression, the step is skipped when it's evaluated to false, e.g. `context.namespace == "prod"`.
+	// It can refer to the app context by `context`, the variables exported by previous steps by `outputs`,
+	// and the health status of components by `components`, e.g. `components.frontend.healthy`.
+	// +optional
+	If string `json:"if,omitempty"`
+
+	// OnFailure defines what to do when the step is failed or stopped after all retries, default to abort.
+	// +kubebuilder:validation:Enum=abort;continue;rollback
+	OnFailure WorkflowStepFailurePolicy `json:"onFailure,omitempty"`
+
+	// RollbackStep is the name of the step to execute when this step is failed and onFailure is rollback.
+	// A rollback step is only executed on failure, it cannot be depended on or have dependencies.
+	// +optional
+	RollbackStep string `json:"rollbackStep,omitempty"`
+
+	// Timeout is the duration, e.g. 30s or 10m, the step can take before it's regarded as failed.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+
+	// Retries is the number of times to retry the step once it's failed.
+	// Default to 0, except for the step types retried by default, e.g. 3 for notification steps.
+	// +optional
+	Retries *int `json:"retries,omitempty"`
+
+	// Backoff is the duration to wait before the first retry, e.g. 10s, which is doubled for each following retry.
+	// Default to 10s.
+	// +optional
+	Backoff string `json:"backoff,omitempty"`
+
+	// Inputs are the variables exported by previous steps which are consumed by this step.
+	Inputs []WorkflowStepInput `json:"inputs,omitempty"`
+
+	// Outputs are the values of this step exported as variables, which can be consumed by later steps.
+	Outputs []WorkflowStepOutput `json:"outputs,omitempty"`
+}
+
+// WorkflowStepInput injects a variable exported by a previous step into the step.
+type WorkflowStepInput struct {
+	// From is the name of the variable.
+	From string `json:"from"`
+
+	// ParameterKey is the path in the parameter of the step to set the value into, e.g. `image` or `env.url`.
+	// The value is also available as `context.inputs.<from>` in the template of WorkflowStepDefinition.
+	// +optional
+	ParameterKey string `json:"parameterKey,omitempty"`
+}
+
+// WorkflowStepOutput exports a value of the step as a variable once the step is succeeded.
+type WorkflowStepOutput struct {
+	// Name is the name of the variable.
+	Name string `json:"name"`
+
+	// ExportKey is the CUE path of the value in the object produced by the step, e.g. `status.endpoint`.
+	// The object is the applied CR for CR based steps, or the applied or watched object for built-in steps.
+	ExportKey string `json:"exportKey"`
 }
 
 // ApplicationSpec is the spec of Application
@@ -82,9 +158,12 @@ type ApplicationSpec struct {
 
 	// Workflow defines how to customize the control logic.
 	// If workflow is specified, Vela won't apply any resource, but provide rendered output in AppRevision.
-	// Workflow steps are executed in array order, and each step:
-	// - will have a context in annotation.
-	// - should mark "finish" phase in status.conditions.
+	// Workflow steps are executed in array order unless dependsOn is specified, in which case independent steps
+	// are executed in parallel. Each step is either:
+	// - a built-in step (e.g. apply-component, apply-object, suspend, wait-for-condition) executed by the
+	//   application controller itself, or
+	// - a CR based step rendered from the `output` of its WorkflowStepDefinition, which
+	//   will have a context in annotation and should mark "finish" phase in status.conditions.
 	Workflow []WorkflowStep `json:"workflow,omitempty"`
 
 	// TODO(wonderflow): we should have application level scopes supported here
@@ -34,11 +34,15 @@ type AppRolloutSpec struct {
 	SourceAppRevisionName string `json:"sourceAppRevisionName,omitempty"`
 
 	// The list of component to upgrade in the application.
-	// We only support single component application so far
-	// TODO: (RZ) Support multiple components in an application
+	// All the components are rolled out with the same rollout plan
 	// +optional
 	ComponentList []string `json:"componentList,omitempty"`
 
+	// MultiComponentStrategy decides how the components in the ComponentList move forward together.
+	// Default is Lockstep
+	// +optional
+	MultiComponentStrategy common.MultiComponentStrategy `json:"multiComponentStrategy,omitempty"`
+
 	// RolloutPlan is the details on how to rollout the resources
 	RolloutPlan v1alpha1.RolloutPlan `json:"rolloutPlan"`
 
@@ -46,6 +46,13 @@ type ComponentDefinitionSpec struct {
 	// +optional
 	Status *common.Status `json:"status,omitempty"`
 
+	// ApplyStrategy is the strategy used to apply the resources of the component,
+	// it's overridden by the app.oam.dev/apply-strategy annotation of the Application.
+	// Defaults to ThreeWayMerge.
+	// +kubebuilder:validation:Enum=ThreeWayMerge;ServerSideApply
+	// +optional
+	ApplyStrategy string `json:"applyStrategy,omitempty"`
+
 	// Schematic defines the data format and template of the encapsulation of the workload
 	// +optional
 	Schematic *common.Schematic `json:"schematic,omitempty"`
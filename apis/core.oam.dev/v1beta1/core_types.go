@@ -43,6 +43,16 @@ type WorkloadDefinitionSpec struct {
 	// +optional
 	PodSpecPath string `json:"podSpecPath,omitempty"`
 
+	// ReplicasPath indicates where this workload has the desired number of replicas, e.g. spec.replicas.
+	// A workload kind without built-in rollout support can be rolled out in batches by scaling it if it's set.
+	// +optional
+	ReplicasPath string `json:"replicasPath,omitempty"`
+
+	// ReadyReplicasPath indicates where this workload reports the number of ready replicas, e.g.
+	// status.readyReplicas. The ready pods are counted by the RevisionLabel if it's not set.
+	// +optional
+	ReadyReplicasPath string `json:"readyReplicasPath,omitempty"`
+
 	// Status defines the custom health policy and status message for workload
 	// +optional
 	Status *common.Status `json:"status,omitempty"`
@@ -250,11 +260,22 @@ type ResourceTracker struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
+	Spec   ResourceTrackerSpec   `json:"spec,omitempty"`
 	Status ResourceTrackerStatus `json:"status,omitempty"`
 }
 
+// ResourceTrackerSpec define the spec of resourceTracker
+type ResourceTrackerSpec struct {
+	// TrackedResources are the resources dispatched by the application revision.
+	// The resources beyond the capacity of one resource tracker are tracked by its shards, which are resource trackers
+	// labeled by the name of this one.
+	TrackedResources []TypedReference `json:"trackedResources,omitempty"`
+}
+
 // ResourceTrackerStatus define the status of resourceTracker
 type ResourceTrackerStatus struct {
+	// TrackedResources is deprecated, the resources are tracked in the spec.
+	// It's only read to migrate the resource trackers created by the older versions.
 	TrackedResources []TypedReference `json:"trackedResources,omitempty"`
 }
 
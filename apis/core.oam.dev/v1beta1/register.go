@@ -133,6 +133,14 @@ var (
 	AppDeploymentKindVersionKind = SchemeGroupVersion.WithKind(AppDeploymentKind)
 )
 
+// WorkflowRun type metadata.
+var (
+	WorkflowRunKind             = reflect.TypeOf(WorkflowRun{}).Name()
+	WorkflowRunGroupKind        = schema.GroupKind{Group: Group, Kind: WorkflowRunKind}.String()
+	WorkflowRunKindAPIVersion   = WorkflowRunKind + "." + SchemeGroupVersion.String()
+	WorkflowRunGroupVersionKind = SchemeGroupVersion.WithKind(WorkflowRunKind)
+)
+
 // Cluster type metadata.
 var (
 	ClusterKind            = reflect.TypeOf(Cluster{}).Name()
@@ -155,4 +163,5 @@ func init() {
 	SchemeBuilder.Register(&AppDeployment{}, &AppDeploymentList{})
 	SchemeBuilder.Register(&Cluster{}, &ClusterList{})
 	SchemeBuilder.Register(&ResourceTracker{}, &ResourceTrackerList{})
+	SchemeBuilder.Register(&WorkflowRun{}, &WorkflowRunList{})
 }
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+// WorkflowRunPhase is the phase of the workflow execution recorded by WorkflowRun
+type WorkflowRunPhase string
+
+const (
+	// WorkflowRunRunning means the workflow is being executed
+	WorkflowRunRunning WorkflowRunPhase = "running"
+	// WorkflowRunSuspending means the workflow is suspended
+	WorkflowRunSuspending WorkflowRunPhase = "suspending"
+	// WorkflowRunTerminated means the workflow is terminated by user
+	WorkflowRunTerminated WorkflowRunPhase = "terminated"
+	// WorkflowRunSucceeded means all the steps of the workflow are finished without failure
+	WorkflowRunSucceeded WorkflowRunPhase = "succeeded"
+	// WorkflowRunFailed means the workflow is aborted by a failed step
+	WorkflowRunFailed WorkflowRunPhase = "failed"
+)
+
+// WorkflowRunSpec identifies the workflow execution recorded by WorkflowRun
+type WorkflowRunSpec struct {
+	// AppName is the name of the application the workflow belongs to
+	AppName string `json:"appName"`
+	// AppRevision is the name of the application revision the workflow is executed for
+	AppRevision string `json:"appRevision"`
+}
+
+// WorkflowRunStatus records the execution of the workflow
+type WorkflowRunStatus struct {
+	Phase WorkflowRunPhase `json:"phase,omitempty"`
+	// StartTime is the time when the workflow is started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time when the workflow is finished or terminated
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+	// Steps records the execution of each step
+	Steps []WorkflowStepRecord `json:"steps,omitempty"`
+}
+
+// WorkflowStepRecord records the execution of a workflow step
+type WorkflowStepRecord struct {
+	Name     string                   `json:"name"`
+	Type     string                   `json:"type,omitempty"`
+	Phase    common.WorkflowStepPhase `json:"phase,omitempty"`
+	Message  string                   `json:"message,omitempty"`
+	Attempts int                      `json:"attempts,omitempty"`
+	// StartTime is the time when the step is started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time when the step is finished
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+	// Outputs are the variables exported by the outputs of the step
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Outputs *runtime.RawExtension `json:"outputs,omitempty"`
+	// Events are the transitions of the phase and message of the step, only the latest ones are kept
+	Events []WorkflowStepEvent `json:"events,omitempty"`
+}
+
+// WorkflowStepEvent is a transition of the phase or message of a workflow step
+type WorkflowStepEvent struct {
+	Time    metav1.Time              `json:"time"`
+	Phase   common.WorkflowStepPhase `json:"phase,omitempty"`
+	Message string                   `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkflowRun is the durable record of the workflow executed for an application revision,
+// it's pruned together with the application revision.
+// +kubebuilder:resource:scope=Namespaced,categories={oam},shortName=wfrun
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="APP",type=string,JSONPath=`.spec.appName`
+// +kubebuilder:printcolumn:name="REVISION",type=string,JSONPath=`.spec.appRevision`
+// +kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=".metadata.creationTimestamp"
+type WorkflowRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkflowRunSpec   `json:"spec,omitempty"`
+	Status WorkflowRunStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkflowRunList contains a list of WorkflowRun
+type WorkflowRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkflowRun `json:"items"`
+}
@@ -18,6 +18,8 @@ package v1alpha1
 
 import (
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -46,6 +48,43 @@ const (
 	FinalizeRolloutHook HookType = "finalize-rollout"
 )
 
+// WebhookDecisionType is the decision of a webhook on how the rollout goes on
+type WebhookDecisionType string
+
+const (
+	// ProceedWebhookDecision lets the rollout move on, it's the decision of a webhook that returns no decision
+	ProceedWebhookDecision WebhookDecisionType = "proceed"
+	// PauseWebhookDecision holds the rollout where it is, the webhook is called again until it decides otherwise
+	PauseWebhookDecision WebhookDecisionType = "pause"
+	// AbortWebhookDecision fails the rollout
+	AbortWebhookDecision WebhookDecisionType = "abort"
+)
+
+// TrafficProviderType defines the providers that route the traffic between the source and target resources
+type TrafficProviderType string
+
+const (
+	// IstioTrafficProvider routes the traffic by an Istio VirtualService
+	IstioTrafficProvider TrafficProviderType = "istio"
+	// SMITrafficProvider routes the traffic by a SMI TrafficSplit
+	SMITrafficProvider TrafficProviderType = "smi"
+	// ServiceTrafficProvider routes the traffic by switching the selector of the service, it can not split the
+	// traffic so all the traffic is switched to the target resource once its weight reaches 100
+	ServiceTrafficProvider TrafficProviderType = "service"
+)
+
+// TrafficRoutingStrategyType defines when the traffic is shifted to the target resource
+type TrafficRoutingStrategyType string
+
+const (
+	// CanaryTrafficRoutingStrategyType indicates that the traffic is shifted batch by batch
+	CanaryTrafficRoutingStrategyType TrafficRoutingStrategyType = "Canary"
+
+	// BlueGreenTrafficRoutingStrategyType indicates that all the traffic stays on the source resource until all the
+	// batches are rolled out, it's switched to the target resource at once when the rollout is finalized
+	BlueGreenTrafficRoutingStrategyType TrafficRoutingStrategyType = "BlueGreen"
+)
+
 // RollingState is the overall rollout state
 type RollingState string
 
@@ -74,7 +113,8 @@ const (
 	// we need to finalize it by cleaning up the old resources, adjust traffic and return control back to its owner
 	RolloutDeletingState RollingState = "RolloutDeletingState"
 	// RolloutFailedState indicates that rollout is failed, the target replica is not reached
-	// we can not move forward anymore, we will let the client to decide when or whether to revert.
+	// we can not move forward anymore, we will let the client to decide when or whether to revert
+	// unless the rollout plan has an automatic rollback policy.
 	RolloutFailedState RollingState = "rolloutFailed"
 )
 
@@ -140,6 +180,75 @@ type RolloutPlan struct {
 	// before complete the process
 	// +optional
 	CanaryMetric []CanaryMetric `json:"canaryMetric,omitempty"`
+
+	// RollbackPolicy defines how the rollout is rolled back when it fails
+	// +optional
+	RollbackPolicy *RollbackPolicy `json:"rollbackPolicy,omitempty"`
+
+	// TrafficRouting shifts the traffic from the source resource to the target resource along with the batches
+	// +optional
+	TrafficRouting *TrafficRouting `json:"trafficRouting,omitempty"`
+
+	// Schedule restricts when the rollout starts and when it moves on to the next batch
+	// +optional
+	Schedule *RolloutSchedule `json:"schedule,omitempty"`
+}
+
+// RollbackPolicy defines how a failed rollout is rolled back
+type RollbackPolicy struct {
+	// Automatic rolls all the batches back to the source resource once the rollout fails by a failed batch
+	// verification, failed canary metrics or a failed post-batch-rollout webhook.
+	// Otherwise, the rollout stops in the failed state for the client to decide when or whether to revert.
+	// +optional
+	Automatic bool `json:"automatic,omitempty"`
+}
+
+// TrafficRouting defines how the traffic is routed between the source and target resources during the rollout
+type TrafficRouting struct {
+	// Provider is the traffic provider that routes the traffic, it can be istio, smi or service
+	Provider TrafficProviderType `json:"provider"`
+
+	// Strategy defines when the traffic is shifted to the target resource
+	// The default is CanaryTrafficRoutingStrategyType
+	// +optional
+	Strategy TrafficRoutingStrategyType `json:"strategy,omitempty"`
+
+	// Service is the name of the service that receives the traffic of the resources, the default is the name of
+	// the component. The pods of the source and target resource have to be told apart by their labels.
+	// +optional
+	Service string `json:"service,omitempty"`
+}
+
+// RolloutSchedule defines when the rollout is allowed to roll. The rollout waits to start, or waits in the
+// batch ready state to move on to the next batch, until the schedule allows it. A batch that has started
+// is always rolled out to the end.
+type RolloutSchedule struct {
+	// StartAt is the earliest time the rollout starts
+	// +optional
+	StartAt *metav1.Time `json:"startAt,omitempty"`
+
+	// TimeZone is the IANA time zone of the windows, such as "America/New_York", default is UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows are the deployment windows, a batch only starts when one of them is open.
+	// The rollout is allowed at any time if there is no window.
+	// +optional
+	Windows []RolloutWindow `json:"windows,omitempty"`
+
+	// SoakSeconds is the minimum time, in seconds, a batch stays ready before the next batch starts, default = 0
+	// +optional
+	SoakSeconds *int32 `json:"soakSeconds,omitempty"`
+}
+
+// RolloutWindow is a recurring deployment window
+type RolloutWindow struct {
+	// Schedule is the cron expression of when the window opens, it has five fields which are the minute, hour,
+	// day of month, month and day of week. For example, "0 9 * * 1-5" opens the window at 9am on weekdays.
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open once it opens, such as "8h"
+	Duration string `json:"duration"`
 }
 
 // RolloutBatch is used to describe how the each batch rollout should be
@@ -175,6 +284,11 @@ type RolloutBatch struct {
 	// before moving to the next batch
 	// +optional
 	CanaryMetric []CanaryMetric `json:"canaryMetric,omitempty"`
+
+	// TrafficWeight is the percentage of the traffic routed to the target resource once the pods of the batch are
+	// ready. The default is the percentage of the upgraded pods. It only takes effect with a canary traffic routing.
+	// +optional
+	TrafficWeight *int32 `json:"trafficWeight,omitempty"`
 }
 
 // RolloutWebhook holds the reference to external checks used for canary analysis
@@ -197,6 +311,39 @@ type RolloutWebhook struct {
 	// Metadata (key-value pairs) for this webhook
 	// +optional
 	Metadata *map[string]string `json:"metadata,omitempty"`
+
+	// Auth authenticates the requests to this webhook
+	// +optional
+	Auth *WebhookAuth `json:"auth,omitempty"`
+
+	// TimeoutSeconds is the timeout of each request to this webhook, default is 10
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// Retries is the number of times a request is retried when it fails or the webhook returns a 5xx status code,
+	// default is 3
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// WebhookAuth defines how the requests to a webhook are authenticated, the secrets are in the same namespace
+// as the rollout
+type WebhookAuth struct {
+	// HMACSecretRef selects the key to sign the requests by HMAC-SHA256. The unix timestamp of the request is sent
+	// in the X-Vela-Timestamp header, and the signature of "<timestamp>.<request body>" is sent in the
+	// X-Vela-Signature-256 header as "sha256=<hex encoded signature>". The receivers should reject the requests
+	// with stale timestamps to prevent replay.
+	// +optional
+	HMACSecretRef *corev1.SecretKeySelector `json:"hmacSecretRef,omitempty"`
+
+	// BearerTokenSecretRef selects the token sent in the Authorization header as a bearer token
+	// +optional
+	BearerTokenSecretRef *corev1.SecretKeySelector `json:"bearerTokenSecretRef,omitempty"`
+
+	// TLSSecretRef references the secret with the client certificate (tls.crt) and key (tls.key) for mutual TLS,
+	// and optionally the CA certificate (ca.crt) to verify the webhook
+	// +optional
+	TLSSecretRef *corev1.LocalObjectReference `json:"tlsSecretRef,omitempty"`
 }
 
 // RolloutWebhookPayload holds the info and metadata sent to webhooks
@@ -214,6 +361,17 @@ type RolloutWebhookPayload struct {
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
+// RolloutWebhookResponse is the body a webhook can respond with to decide how the rollout goes on
+type RolloutWebhookResponse struct {
+	// Decision is one of proceed, pause or abort, default is proceed
+	// +optional
+	Decision WebhookDecisionType `json:"decision,omitempty"`
+
+	// Message explains the decision
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // CanaryMetric holds the reference to metrics used for canary analysis
 type CanaryMetric struct {
 	// Name of the metric
@@ -280,4 +438,16 @@ type RolloutStatus struct {
 
 	// UpgradedReadyReplicas is the number of Pods upgraded by the rollout controller that have a Ready Condition.
 	UpgradedReadyReplicas int32 `json:"upgradedReadyReplicas"`
+
+	// TrafficWeight is the percentage of the traffic routed to the target resource
+	// +optional
+	TrafficWeight int32 `json:"trafficWeight,omitempty"`
+
+	// BatchStartTime is the time the current batch started
+	// +optional
+	BatchStartTime *metav1.Time `json:"batchStartTime,omitempty"`
+
+	// BatchReadyTime is the time the current batch became ready, the next batch waits for the soak time from then
+	// +optional
+	BatchReadyTime *metav1.Time `json:"batchReadyTime,omitempty"`
 }
@@ -102,6 +102,10 @@ const (
 	BatchInitializing runtimev1alpha1.ConditionType = "BatchInitializing"
 	// BatchPaused
 	BatchPaused runtimev1alpha1.ConditionType = "BatchPaused"
+	// RolloutPaused means that a webhook paused the rollout
+	RolloutPaused runtimev1alpha1.ConditionType = "RolloutPaused"
+	// RolloutWaiting means that the rollout waits for its schedule to roll
+	RolloutWaiting runtimev1alpha1.ConditionType = "RolloutWaiting"
 	// BatchVerifying
 	BatchVerifying runtimev1alpha1.ConditionType = "BatchVerifying"
 	// BatchRolloutFailed
@@ -216,6 +220,9 @@ func (r *RolloutStatus) ResetStatus() {
 	r.CurrentBatch = 0
 	r.UpgradedReplicas = 0
 	r.UpgradedReadyReplicas = 0
+	r.TrafficWeight = 0
+	r.BatchStartTime = nil
+	r.BatchReadyTime = nil
 }
 
 // SetRolloutCondition sets the supplied condition, replacing any existing condition
@@ -318,6 +325,8 @@ func (r *RolloutStatus) StateTransition(event RolloutEvent) {
 			r.SetRolloutCondition(NewPositiveCondition(r.getRolloutConditionType()))
 			r.RollingState = RollingInBatchesState
 			r.BatchRollingState = BatchInitializingState
+			now := metav1.Now()
+			r.BatchStartTime = &now
 			return
 		}
 		r.illegalStateTransition(fmt.Errorf(invalidRollingStateTransition, rollingState, event))
@@ -403,6 +412,8 @@ func (r *RolloutStatus) batchStateTransition(event RolloutEvent) {
 		if event == FinishedOneBatchEvent {
 			r.SetRolloutCondition(NewPositiveCondition(r.getRolloutConditionType()))
 			r.BatchRollingState = BatchReadyState
+			now := metav1.Now()
+			r.BatchReadyTime = &now
 			return
 		}
 		if event == AllBatchFinishedEvent {
@@ -419,6 +430,8 @@ func (r *RolloutStatus) batchStateTransition(event RolloutEvent) {
 			r.SetRolloutCondition(NewPositiveCondition(r.getRolloutConditionType()))
 			r.BatchRollingState = BatchInitializingState
 			r.CurrentBatch++
+			now := metav1.Now()
+			r.BatchStartTime = &now
 			return
 		}
 		r.illegalStateTransition(fmt.Errorf(invalidBatchRollingStateTransition, batchRollingState, event))
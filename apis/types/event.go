@@ -24,9 +24,12 @@ const (
 	ReasonHealthCheck = "HealthChecked"
 	ReasonDeployed    = "Deployed"
 	ReasonRollout     = "Rollout"
+	ReasonDrifted     = "Drifted"
+	ReasonCorrected   = "DriftCorrected"
 
 	ReasonFailedParse       = "FailedParse"
 	ReasonFailedRender      = "FailedRender"
+	ReasonFailedPolicy      = "FailedPolicy"
 	ReasonFailedWorkflow    = "FailedWorkflow"
 	ReasonFailedApply       = "FailedApply"
 	ReasonFailedHealthCheck = "FailedHealthCheck"
@@ -42,6 +45,8 @@ const (
 	MessageHealthCheck = "Health checked healthy"
 	MessageDeployed    = "Deployed successfully"
 	MessageRollout     = "Rollout successfully"
+	MessageDrifted     = "%d resources drifted from their manifests"
+	MessageCorrected   = "Corrected %d drifted resources"
 
 	MessageFailedParse       = "fail to parse application, err: %v"
 	MessageFailedRender      = "fail to render application, err: %v"
@@ -26,4 +26,6 @@ type WorkflowContext struct {
 	AppRevision       string                      `json:"appRevision,omitempty"`
 	WorkflowIndex     int                         `json:"workflowIndex"`
 	ResourceConfigMap corev1.LocalObjectReference `json:"resourceConfigMap,omitempty"`
+	// ContextConfigMap is the ConfigMap storing the variables exported by workflow steps of the application.
+	ContextConfigMap corev1.LocalObjectReference `json:"contextConfigMap,omitempty"`
 }
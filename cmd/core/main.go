@@ -35,6 +35,7 @@ import (
 	commonconfig "github.com/oam-dev/kubevela/pkg/controller/common"
 	oamcontroller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	oamv1alpha2 "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/dispatch"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/cue/packages"
 	"github.com/oam-dev/kubevela/pkg/oam"
@@ -103,6 +104,12 @@ func main() {
 	flag.IntVar(&controllerArgs.ConcurrentReconciles, "concurrent-reconciles", 4, "concurrent-reconciles is the concurrent reconcile number of the controller. The default value is 4")
 	flag.DurationVar(&controllerArgs.DependCheckWait, "depend-check-wait", 30*time.Second, "depend-check-wait is the time to wait for ApplicationConfiguration's dependent-resource ready."+
 		"The default value is 30s, which means if dependent resources were not prepared, the ApplicationConfiguration would be reconciled after 30s.")
+	flag.BoolVar(&controllerArgs.DriftDetection, "drift-detection", false, "drift-detection enables watching the resources dispatched by applications and "+
+		"reporting their drift from the manifests in the status of the applications.")
+	flag.DurationVar(&controllerArgs.DriftCorrectionInterval, "drift-correction-interval", time.Minute, "drift-correction-interval is the minimum interval between two corrections "+
+		"of the drifted resources of an application with the app.oam.dev/auto-correct-drift annotation. The default value is 1m")
+	flag.IntVar(&dispatch.TrackedResourcesPerShard, "resource-tracker-shard-size", 1000, "resource-tracker-shard-size is the max number of resources tracked by one ResourceTracker object, "+
+		"the other resources of the application revision are tracked by the shards of the ResourceTracker. The default value is 1000")
 
 	flag.Parse()
 	// setup logging
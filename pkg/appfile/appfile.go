@@ -39,6 +39,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile/helm"
+	velacue "github.com/oam-dev/kubevela/pkg/cue"
 	"github.com/oam-dev/kubevela/pkg/cue/definition"
 	"github.com/oam-dev/kubevela/pkg/cue/process"
 	"github.com/oam-dev/kubevela/pkg/oam"
@@ -168,17 +169,18 @@ type Appfile struct {
 	WorkflowSteps []*Workload
 }
 
-// GenerateWorkflowAndPolicy generates workflow steps and policies from an appFile
-func (af *Appfile) GenerateWorkflowAndPolicy() (policies, steps []*unstructured.Unstructured, err error) {
-	policies, err = af.generateUnstructureds(af.Policies)
-	if err != nil {
-		return
-	}
-	steps, err = af.generateUnstructureds(af.WorkflowSteps)
-	if err != nil {
-		return
+// GeneratePolicies generates policies from an appFile.
+// Workflow steps are not rendered here, they're evaluated by the workflow engine when executing.
+// Policies without output, e.g. guardrails validating the rendered manifests, don't generate any resource.
+func (af *Appfile) GeneratePolicies() ([]*unstructured.Unstructured, error) {
+	var policies []*Workload
+	for _, p := range af.Policies {
+		if p.FullTemplate != nil && !velacue.HasTopLevelField(p.FullTemplate.TemplateStr, definition.OutputFieldName) {
+			continue
+		}
+		policies = append(policies, p)
 	}
-	return
+	return af.generateUnstructureds(policies)
 }
 
 func (af *Appfile) generateUnstructureds(workloads []*Workload) ([]*unstructured.Unstructured, error) {
@@ -349,9 +351,19 @@ func makeWorkloadWithContext(pCtx process.Context, wl *Workload, ns, appName str
 	}
 	commonLabels := definition.GetCommonLabels(pCtx.BaseContextLabels())
 	util.AddLabels(workload, util.MergeMapOverrideWithDst(commonLabels, map[string]string{oam.WorkloadTypeLabel: wl.Type}))
+	setApplyStrategy(workload, wl)
 	return workload, nil
 }
 
+// setApplyStrategy records the apply strategy of the workload's ComponentDefinition in the annotation of the resource
+func setApplyStrategy(obj *unstructured.Unstructured, wl *Workload) {
+	if wl.FullTemplate == nil || wl.FullTemplate.ComponentDefinition == nil ||
+		len(wl.FullTemplate.ComponentDefinition.Spec.ApplyStrategy) == 0 {
+		return
+	}
+	util.AddAnnotations(obj, map[string]string{oam.AnnotationApplyStrategy: wl.FullTemplate.ComponentDefinition.Spec.ApplyStrategy})
+}
+
 // evalWorkloadWithContext evaluate the workload's template to generate component and ACComponent
 func evalWorkloadWithContext(pCtx process.Context, wl *Workload, ns, appName, compName string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
 	componentWorkload, err := makeWorkloadWithContext(pCtx, wl, ns, appName)
@@ -376,6 +388,7 @@ func evalWorkloadWithContext(pCtx process.Context, wl *Workload, ns, appName, co
 			labels[oam.TraitResource] = assist.Name
 		}
 		util.AddLabels(tr, labels)
+		setApplyStrategy(tr, wl)
 		acComponent.Traits = append(acComponent.Traits, v1alpha2.ComponentTrait{
 			// we need to marshal the trait to byte array before sending them to the k8s
 			Trait: util.Object2RawExtension(tr),
@@ -37,6 +37,7 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	oamtypes "github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/cue/definition"
 	"github.com/oam-dev/kubevela/pkg/cue/process"
@@ -971,3 +972,15 @@ func TestGetUserConfigName(t *testing.T) {
 	wl3 := &Workload{Params: map[string]interface{}{AppfileBuiltinConfig: config}}
 	assert.Equal(t, wl3.GetUserConfigName(), config)
 }
+
+func TestSetApplyStrategy(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	setApplyStrategy(obj, &Workload{FullTemplate: &Template{}})
+	assert.Equal(t, len(obj.GetAnnotations()), 0)
+
+	wl := &Workload{FullTemplate: &Template{ComponentDefinition: &v1beta1.ComponentDefinition{
+		Spec: v1beta1.ComponentDefinitionSpec{ApplyStrategy: "ServerSideApply"},
+	}}}
+	setApplyStrategy(obj, wl)
+	assert.Equal(t, obj.GetAnnotations()[oam.AnnotationApplyStrategy], "ServerSideApply")
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/cue/model/sets"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// PolicyOverride is the built-in policy type patching the properties and traits of the components,
+// so that one application can carry the base spec plus the overrides of each environment.
+const PolicyOverride = "override"
+
+// overridePatchKey is the key to merge the lists of objects in the properties, e.g. containers or env
+const overridePatchKey = "name"
+
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// OverridePolicySpec is the properties of the override policy.
+type OverridePolicySpec struct {
+	// Namespaces select the environments the overrides are applied in by the namespace of the application,
+	// the overrides are applied in all the namespaces if it's empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Components are the overrides of the components, they're applied in order
+	Components []ComponentOverride `json:"components"`
+}
+
+// ComponentOverride patches the components selected by name or type.
+type ComponentOverride struct {
+	// Name selects the component by name, all the components are selected if both name and type are empty
+	Name string `json:"name,omitempty"`
+	// Type selects the components by type
+	Type string `json:"type,omitempty"`
+	// Properties are merged into the properties of the component by strategic merge, the scalar values override
+	// the ones of the component and the lists of objects are merged by the name of their items
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Properties *runtime.RawExtension `json:"properties,omitempty"`
+	// Traits patch the traits of the component by type, a trait is added if the component doesn't have it
+	Traits []TraitOverride `json:"traits,omitempty"`
+}
+
+// TraitOverride patches the trait of the component with the same type.
+type TraitOverride struct {
+	Type string `json:"type"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Properties *runtime.RawExtension `json:"properties,omitempty"`
+	// Disable removes the trait from the component
+	Disable bool `json:"disable,omitempty"`
+}
+
+// applyOverridePolicies returns the components patched by the override policies of the application,
+// the components of the application itself are not changed.
+func applyOverridePolicies(app *v1beta1.Application) ([]v1beta1.ApplicationComponent, error) {
+	comps := make([]v1beta1.ApplicationComponent, 0, len(app.Spec.Components))
+	for _, comp := range app.Spec.Components {
+		comps = append(comps, *comp.DeepCopy())
+	}
+	for _, policy := range app.Spec.Policies {
+		// an override policy without properties overrides nothing
+		if policy.Type != PolicyOverride || len(policy.Properties.Raw) == 0 {
+			continue
+		}
+		spec := &OverridePolicySpec{}
+		if err := json.Unmarshal(policy.Properties.Raw, spec); err != nil {
+			return nil, errors.Wrapf(err, "invalid properties of policy %s", policy.Name)
+		}
+		if len(spec.Namespaces) != 0 && !stringIn(app.Namespace, spec.Namespaces) {
+			continue
+		}
+		for _, override := range spec.Components {
+			for i := range comps {
+				if (override.Name != "" && override.Name != comps[i].Name) ||
+					(override.Type != "" && override.Type != comps[i].Type) {
+					continue
+				}
+				if err := overrideComponent(&comps[i], override); err != nil {
+					return nil, errors.WithMessagef(err, "policy %s cannot override component %s", policy.Name, comps[i].Name)
+				}
+			}
+		}
+	}
+	return comps, nil
+}
+
+func overrideComponent(comp *v1beta1.ApplicationComponent, override ComponentOverride) error {
+	if override.Properties != nil {
+		props, err := overrideProperties(&comp.Properties, override.Properties)
+		if err != nil {
+			return errors.WithMessage(err, "cannot patch properties")
+		}
+		comp.Properties = props
+	}
+	for _, to := range override.Traits {
+		idx := -1
+		for i, trait := range comp.Traits {
+			if trait.Type == to.Type {
+				idx = i
+				break
+			}
+		}
+		switch {
+		case to.Disable:
+			if idx >= 0 {
+				comp.Traits = append(comp.Traits[:idx], comp.Traits[idx+1:]...)
+			}
+		case idx < 0:
+			trait := v1beta1.ApplicationTrait{Type: to.Type}
+			if to.Properties != nil {
+				trait.Properties = *to.Properties.DeepCopy()
+			}
+			comp.Traits = append(comp.Traits, trait)
+		case to.Properties != nil:
+			props, err := overrideProperties(&comp.Traits[idx].Properties, to.Properties)
+			if err != nil {
+				return errors.WithMessagef(err, "cannot patch trait %s", to.Type)
+			}
+			comp.Traits[idx].Properties = props
+		}
+	}
+	return nil
+}
+
+// overrideProperties merges the patch into the properties by sets.StrategyUnify.
+// The values of the properties conflicting with the patch are removed first, so that they're overridden
+// instead of failing the unification.
+func overrideProperties(props, patch *runtime.RawExtension) (runtime.RawExtension, error) {
+	base, err := util.RawExtension2Map(props)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	if base == nil {
+		base = map[string]interface{}{}
+	}
+	p, err := util.RawExtension2Map(patch)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	removeOverridden(base, p)
+	baseStr, patchStr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := writeCUE(baseStr, base, false); err != nil {
+		return runtime.RawExtension{}, err
+	}
+	if err := writeCUE(patchStr, p, true); err != nil {
+		return runtime.RawExtension{}, err
+	}
+	result, err := sets.StrategyUnify(baseStr.String(), patchStr.String())
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", result)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	data, err := inst.Value().MarshalJSON()
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	return runtime.RawExtension{Raw: data}, nil
+}
+
+// removeOverridden removes the values of base which are overridden by the patch.
+func removeOverridden(base, patch map[string]interface{}) {
+	for k, pv := range patch {
+		bv, ok := base[k]
+		if !ok {
+			continue
+		}
+		switch pval := pv.(type) {
+		case map[string]interface{}:
+			if bval, ok := bv.(map[string]interface{}); ok {
+				removeOverridden(bval, pval)
+				continue
+			}
+		case []interface{}:
+			if bval, ok := bv.([]interface{}); ok && mergeableList(pval) && mergeableList(bval) {
+				for _, pi := range pval {
+					pitem := pi.(map[string]interface{})
+					for _, bi := range bval {
+						if bitem := bi.(map[string]interface{}); bitem[overridePatchKey] == pitem[overridePatchKey] {
+							removeOverridden(bitem, pitem)
+							// the items are merged by the name, which must be kept
+							bitem[overridePatchKey] = pitem[overridePatchKey]
+						}
+					}
+				}
+				continue
+			}
+		}
+		delete(base, k)
+	}
+}
+
+// mergeableList checks whether all the items of the list are objects with name, so they're merged by name.
+func mergeableList(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := obj[overridePatchKey].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// writeCUE writes the properties as CUE fields for sets.StrategyUnify, the lists merged by name are marked with
+// the patchKey tag in the patch, and are left open in the base so that new items can be appended.
+func writeCUE(buf *bytes.Buffer, props map[string]interface{}, isPatch bool) error {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if list, ok := props[k].([]interface{}); ok && isPatch && mergeableList(list) {
+			buf.WriteString("// +" + sets.TagPatchKey + "=" + overridePatchKey + "\n")
+		}
+		if identifierRegexp.MatchString(k) {
+			// sets.StrategyUnify looks up the lists to merge by the identifiers of their paths
+			buf.WriteString(k)
+		} else {
+			label, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+			buf.Write(label)
+		}
+		buf.WriteString(": ")
+		if err := writeCUEValue(buf, props[k], isPatch); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+	}
+	return nil
+}
+
+func writeCUEValue(buf *bytes.Buffer, v interface{}, isPatch bool) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		buf.WriteString("{\n")
+		if err := writeCUE(buf, val, isPatch); err != nil {
+			return err
+		}
+		buf.WriteString("}")
+	case []interface{}:
+		buf.WriteString("[")
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := writeCUEValue(buf, item, isPatch); err != nil {
+				return err
+			}
+		}
+		if !isPatch && mergeableList(val) {
+			buf.WriteString(", ...")
+		}
+		buf.WriteString("]")
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+func stringIn(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const overrideApp = `
+apiVersion: core.oam.dev/v1beta1
+kind: Application
+metadata:
+  name: website
+  namespace: prod
+spec:
+  components:
+  - name: frontend
+    type: webservice
+    properties:
+      image: nginx:1.20
+      cpu: "0.5"
+      env:
+      - name: LOG_LEVEL
+        value: debug
+      - name: PORT
+        value: "80"
+    traits:
+    - type: scaler
+      properties:
+        replicas: 1
+    - type: sidecar
+      properties:
+        name: debugger
+  - name: backend
+    type: worker
+    properties:
+      image: busybox
+  policies:
+  - name: prod-overrides
+    type: override
+    properties:
+      namespaces: ["prod"]
+      components:
+      - name: frontend
+        properties:
+          image: nginx:1.21
+          env:
+          - name: LOG_LEVEL
+            value: info
+          - name: REGION
+            value: us
+        traits:
+        - type: scaler
+          properties:
+            replicas: 3
+        - type: sidecar
+          disable: true
+        - type: ingress
+          properties:
+            domain: example.com
+      - type: worker
+        properties:
+          cmd: ["sleep", "1000"]
+  - name: staging-overrides
+    type: override
+    properties:
+      namespaces: ["staging"]
+      components:
+      - properties:
+          image: staging
+`
+
+func TestApplyOverridePolicies(t *testing.T) {
+	app := &v1beta1.Application{}
+	require.NoError(t, yaml.Unmarshal([]byte(overrideApp), app))
+	origin := app.DeepCopy()
+
+	comps, err := applyOverridePolicies(app)
+	require.NoError(t, err)
+	assert.Equal(t, origin, app, "the application should not be changed")
+	require.Len(t, comps, 2)
+
+	props, err := util.RawExtension2Map(&comps[0].Properties)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"image": "nginx:1.21",
+		"cpu":   "0.5",
+		"env": []interface{}{
+			map[string]interface{}{"name": "LOG_LEVEL", "value": "info"},
+			map[string]interface{}{"name": "PORT", "value": "80"},
+			map[string]interface{}{"name": "REGION", "value": "us"},
+		},
+	}, props)
+	require.Len(t, comps[0].Traits, 2)
+	assert.Equal(t, "scaler", comps[0].Traits[0].Type)
+	assert.JSONEq(t, `{"replicas":3}`, string(comps[0].Traits[0].Properties.Raw))
+	assert.Equal(t, "ingress", comps[0].Traits[1].Type)
+	assert.JSONEq(t, `{"domain":"example.com"}`, string(comps[0].Traits[1].Properties.Raw))
+
+	assert.JSONEq(t, `{"image":"busybox","cmd":["sleep","1000"]}`, string(comps[1].Properties.Raw))
+
+	app.Namespace = "staging"
+	comps, err = applyOverridePolicies(app)
+	require.NoError(t, err)
+	for _, comp := range comps {
+		props, err := util.RawExtension2Map(&comp.Properties)
+		require.NoError(t, err)
+		assert.Equal(t, "staging", props["image"])
+	}
+	assert.Len(t, comps[0].Traits, 2)
+
+	// the policies without properties are skipped
+	app.Spec.Policies = append(app.Spec.Policies, v1beta1.AppPolicy{Name: "empty", Type: PolicyOverride})
+	_, err = applyOverridePolicies(app)
+	require.NoError(t, err)
+
+	app.Spec.Policies[1].Properties.Raw = []byte(`{"components":"invalid"}`)
+	_, err = applyOverridePolicies(app)
+	assert.Error(t, err)
+}
@@ -91,8 +91,12 @@ func (p *Parser) GenerateAppFile(ctx context.Context, app *v1beta1.Application)
 	appfile := new(Appfile)
 	appfile.Name = appName
 	appfile.Namespace = ns
+	comps, err := applyOverridePolicies(app)
+	if err != nil {
+		return nil, err
+	}
 	var wds []*Workload
-	for _, comp := range app.Spec.Components {
+	for _, comp := range comps {
 		wd, err := p.parseWorkload(ctx, comp, appName, ns)
 		if err != nil {
 			return nil, err
@@ -101,8 +105,6 @@ func (p *Parser) GenerateAppFile(ctx context.Context, app *v1beta1.Application)
 	}
 	appfile.Workloads = wds
 
-	var err error
-
 	appfile.Policies, err = p.parsePolicies(ctx, appName, ns, app.Spec.Policies)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parsePolicies: %w", err)
@@ -116,8 +118,19 @@ func (p *Parser) GenerateAppFile(ctx context.Context, app *v1beta1.Application)
 }
 
 func (p *Parser) parsePolicies(ctx context.Context, appName, ns string, policies []v1beta1.AppPolicy) ([]*Workload, error) {
+	if _, err := ParseResourcePolicies(policies); err != nil {
+		return nil, err
+	}
 	ws := []*Workload{}
 	for _, policy := range policies {
+		if policy.Type == PolicyOverride {
+			// the built-in override policy is applied to the components before they're parsed
+			continue
+		}
+		if policy.Type == PolicyResourcePolicy {
+			// the built-in resource-policy policy is applied to the resources when they're assembled
+			continue
+		}
 		w, err := p.makeWorkload(ctx, appName, ns, policy.Name, policy.Type, types.TypePolicy, policy.Properties)
 		if err != nil {
 			return nil, err
@@ -132,7 +145,22 @@ func (p *Parser) parseWorkflow(ctx context.Context, appName, ns string, steps []
 	for _, step := range steps {
 		w, err := p.makeWorkload(ctx, appName, ns, step.Name, step.Type, types.TypeWorkflowStep, step.Properties)
 		if err != nil {
-			return nil, err
+			if !kerrors.IsNotFound(errors.Cause(err)) {
+				return nil, err
+			}
+			// a step without WorkflowStepDefinition may refer to a built-in step type,
+			// it will be resolved by the workflow engine when executing.
+			settings, err := util.RawExtension2Map(&step.Properties)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "fail to parse settings for %s", step.Name)
+			}
+			w = &Workload{
+				Traits:       []*Trait{},
+				Name:         step.Name,
+				Type:         step.Type,
+				FullTemplate: &Template{},
+				Params:       settings,
+			}
 		}
 		ws = append(ws, w)
 	}
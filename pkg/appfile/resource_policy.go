@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// PolicyResourcePolicy is the built-in policy type setting the resource policy of the rendered resources,
+// e.g. to keep the PersistentVolumeClaims of a component when the component is renamed or the application is deleted.
+const PolicyResourcePolicy = "resource-policy"
+
+// ResourcePolicySpec is the properties of the resource-policy policy.
+type ResourcePolicySpec struct {
+	// Rules set the resource policy of the resources they select, a resource selected by several rules gets the
+	// strongest policy of them, retain is stronger than orphan and orphan is stronger than delete
+	Rules []ResourcePolicyRule `json:"rules,omitempty"`
+	// Protected selects the resources never touched by the garbage collection, they're not owned by the resource
+	// trackers so that they're neither deleted nor released when the application is upgraded or deleted
+	Protected []ResourceSelector `json:"protected,omitempty"`
+}
+
+// ResourceSelector selects the resources by component, kind and name, a resource is selected if it matches all
+// the non-empty selectors.
+type ResourceSelector struct {
+	// Components select the resources rendered by the components, including the outputs of their traits
+	Components []string `json:"components,omitempty"`
+	// Kinds select the resources by kind, e.g. PersistentVolumeClaim
+	Kinds []string `json:"kinds,omitempty"`
+	// Names select the resources by name
+	Names []string `json:"names,omitempty"`
+}
+
+// ResourcePolicyRule sets the resource policy of the resources it selects.
+type ResourcePolicyRule struct {
+	// Policy is delete, orphan or retain
+	Policy           string `json:"policy"`
+	ResourceSelector `json:",inline"`
+}
+
+// ParseResourcePolicies merges the rules and protected resources of all the resource-policy policies of the application.
+func ParseResourcePolicies(policies []v1beta1.AppPolicy) (*ResourcePolicySpec, error) {
+	merged := &ResourcePolicySpec{}
+	for _, policy := range policies {
+		if policy.Type != PolicyResourcePolicy || len(policy.Properties.Raw) == 0 {
+			continue
+		}
+		spec := &ResourcePolicySpec{}
+		if err := json.Unmarshal(policy.Properties.Raw, spec); err != nil {
+			return nil, errors.Wrapf(err, "invalid properties of policy %s", policy.Name)
+		}
+		for i, rule := range spec.Rules {
+			if resourcePolicyRank(rule.Policy) < 0 {
+				return nil, errors.Errorf("invalid resource policy %q of rule %d in policy %s, it must be %s, %s or %s",
+					rule.Policy, i, policy.Name, oam.ResourcePolicyDelete, oam.ResourcePolicyOrphan, oam.ResourcePolicyRetain)
+			}
+		}
+		merged.Rules = append(merged.Rules, spec.Rules...)
+		merged.Protected = append(merged.Protected, spec.Protected...)
+	}
+	return merged, nil
+}
+
+// Matches returns whether the selector selects the resource rendered by the component.
+func (s ResourceSelector) Matches(compName string, obj *unstructured.Unstructured) bool {
+	return (len(s.Components) == 0 || stringIn(compName, s.Components)) &&
+		(len(s.Kinds) == 0 || stringIn(obj.GetKind(), s.Kinds)) &&
+		(len(s.Names) == 0 || stringIn(obj.GetName(), s.Names))
+}
+
+// SetResourcePolicy sets the resource policy annotation of the resource rendered by the component to the strongest
+// policy of the matching rules, and marks the resource protected if it's selected by the protected resources.
+// The rules never weaken the policy already set on the resource, e.g. by the template of its definition.
+func SetResourcePolicy(obj *unstructured.Unstructured, compName string, spec *ResourcePolicySpec) {
+	if spec == nil {
+		return
+	}
+	policy := obj.GetAnnotations()[oam.AnnotationResourcePolicy]
+	for _, rule := range spec.Rules {
+		if rule.Matches(compName, obj) && resourcePolicyRank(rule.Policy) > resourcePolicyRank(policy) {
+			policy = rule.Policy
+		}
+	}
+	if len(policy) != 0 {
+		util.AddAnnotations(obj, map[string]string{oam.AnnotationResourcePolicy: policy})
+	}
+	for _, selector := range spec.Protected {
+		if selector.Matches(compName, obj) {
+			util.AddAnnotations(obj, map[string]string{oam.AnnotationProtectedResource: "true"})
+			break
+		}
+	}
+}
+
+// resourcePolicyRank orders the resource policies by how much they keep, it's -1 for an invalid policy
+func resourcePolicyRank(policy string) int {
+	switch policy {
+	case "", oam.ResourcePolicyDelete:
+		return 0
+	case oam.ResourcePolicyOrphan:
+		return 1
+	case oam.ResourcePolicyRetain:
+		return 2
+	default:
+		return -1
+	}
+}
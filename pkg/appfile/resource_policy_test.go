@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestParseResourcePolicies(t *testing.T) {
+	policies := []v1beta1.AppPolicy{
+		{Name: "keep-data", Type: PolicyResourcePolicy, Properties: runtime.RawExtension{
+			Raw: []byte(`{"rules":[{"policy":"retain","kinds":["PersistentVolumeClaim"]}]}`)}},
+		{Name: "guardrail", Type: "deny-privileged", Properties: runtime.RawExtension{Raw: []byte(`{}`)}},
+		{Name: "empty", Type: PolicyResourcePolicy},
+		{Name: "keep-config", Type: PolicyResourcePolicy, Properties: runtime.RawExtension{
+			Raw: []byte(`{"rules":[{"policy":"orphan","components":["backend"],"names":["config"]}],` +
+				`"protected":[{"kinds":["PersistentVolumeClaim"],"names":["data"]}]}`)}},
+	}
+	spec, err := ParseResourcePolicies(policies)
+	require.NoError(t, err)
+	assert.Equal(t, &ResourcePolicySpec{
+		Rules: []ResourcePolicyRule{
+			{Policy: oam.ResourcePolicyRetain, ResourceSelector: ResourceSelector{Kinds: []string{"PersistentVolumeClaim"}}},
+			{Policy: oam.ResourcePolicyOrphan, ResourceSelector: ResourceSelector{Components: []string{"backend"},
+				Names: []string{"config"}}},
+		},
+		Protected: []ResourceSelector{{Kinds: []string{"PersistentVolumeClaim"}, Names: []string{"data"}}},
+	}, spec)
+
+	_, err = ParseResourcePolicies([]v1beta1.AppPolicy{{Name: "invalid", Type: PolicyResourcePolicy,
+		Properties: runtime.RawExtension{Raw: []byte(`{"rules":[{"policy":"keep"}]}`)}}})
+	assert.Error(t, err)
+	_, err = ParseResourcePolicies([]v1beta1.AppPolicy{{Name: "invalid", Type: PolicyResourcePolicy,
+		Properties: runtime.RawExtension{Raw: []byte(`{"rules":{}}`)}}})
+	assert.Error(t, err)
+}
+
+func TestSetResourcePolicy(t *testing.T) {
+	spec := &ResourcePolicySpec{
+		Rules: []ResourcePolicyRule{
+			{Policy: oam.ResourcePolicyRetain, ResourceSelector: ResourceSelector{Kinds: []string{"PersistentVolumeClaim"}}},
+			{Policy: oam.ResourcePolicyOrphan, ResourceSelector: ResourceSelector{Components: []string{"backend"}}},
+			{Policy: oam.ResourcePolicyDelete, ResourceSelector: ResourceSelector{Names: []string{"cache"}}},
+		},
+		Protected: []ResourceSelector{{Components: []string{"database"}, Kinds: []string{"PersistentVolumeClaim"}}},
+	}
+	newResource := func(kind, name, policy string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetKind(kind)
+		obj.SetName(name)
+		if policy != "" {
+			obj.SetAnnotations(map[string]string{oam.AnnotationResourcePolicy: policy})
+		}
+		return obj
+	}
+	tests := map[string]struct {
+		compName      string
+		obj           *unstructured.Unstructured
+		want          string
+		wantProtected bool
+	}{
+		"not selected": {
+			compName: "frontend",
+			obj:      newResource("Deployment", "frontend", ""),
+		},
+		"selected by kind": {
+			compName: "frontend",
+			obj:      newResource("PersistentVolumeClaim", "data", ""),
+			want:     oam.ResourcePolicyRetain,
+		},
+		"the strongest policy": {
+			compName: "backend",
+			obj:      newResource("PersistentVolumeClaim", "data", ""),
+			want:     oam.ResourcePolicyRetain,
+		},
+		"never weaken the policy of the resource": {
+			compName: "backend",
+			obj:      newResource("Deployment", "cache", oam.ResourcePolicyRetain),
+			want:     oam.ResourcePolicyRetain,
+		},
+		"strengthen the policy of the resource": {
+			compName: "backend",
+			obj:      newResource("Deployment", "backend", oam.ResourcePolicyDelete),
+			want:     oam.ResourcePolicyOrphan,
+		},
+		"protected": {
+			compName:      "database",
+			obj:           newResource("PersistentVolumeClaim", "data", ""),
+			want:          oam.ResourcePolicyRetain,
+			wantProtected: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetResourcePolicy(tt.obj, tt.compName, spec)
+			assert.Equal(t, tt.want, tt.obj.GetAnnotations()[oam.AnnotationResourcePolicy])
+			assert.Equal(t, tt.wantProtected, tt.obj.GetAnnotations()[oam.AnnotationProtectedResource] == "true")
+		})
+	}
+}
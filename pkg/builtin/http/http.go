@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"cuelang.org/go/cue"
 
@@ -64,13 +65,32 @@ func (c *HTTPCmd) Run(meta *registry.Meta) (res interface{}, err error) {
 		}
 	}
 	if header == nil {
+		header = http.Header{}
 		header.Set("Content-Type", "application/json")
 	}
 	if meta.Err != nil {
 		return nil, meta.Err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), method, u, r)
+	ctx := meta.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if v := meta.Obj.Lookup("timeout"); v.Exists() {
+		s, err := v.String()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
 	if err != nil {
 		return nil, err
 	}
@@ -86,9 +106,10 @@ func (c *HTTPCmd) Run(meta *registry.Meta) (res interface{}, err error) {
 	b, err := ioutil.ReadAll(resp.Body)
 	// parse response body and headers
 	return map[string]interface{}{
-		"body":    string(b),
-		"header":  resp.Header,
-		"trailer": resp.Trailer,
+		"body":       string(b),
+		"header":     resp.Header,
+		"trailer":    resp.Trailer,
+		"statusCode": resp.StatusCode,
 	}, err
 }
 
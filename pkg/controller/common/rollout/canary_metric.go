@@ -0,0 +1,340 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// the keys of the metric template, which is a ConfigMap referenced by the templateRef of the canary metric
+const (
+	// MetricTemplateProvider is the type of the metric provider, default to prometheus
+	MetricTemplateProvider = "provider"
+	// MetricTemplateAddress is the address of the Prometheus server, or the URL template of the http provider
+	MetricTemplateAddress = "address"
+	// MetricTemplateQuery is the query template of the Prometheus provider
+	MetricTemplateQuery = "query"
+	// MetricTemplateJSONPath is the JSONPath to extract the metric value from the response of the http provider
+	MetricTemplateJSONPath = "jsonPath"
+)
+
+const (
+	// PrometheusMetricProvider queries the metric by the Prometheus compatible HTTP API
+	PrometheusMetricProvider = "prometheus"
+	// HTTPMetricProvider gets the metric from the JSON response of a generic HTTP endpoint
+	HTTPMetricProvider = "http"
+)
+
+// the default window size of the canary metric
+const defaultMetricInterval = "1m"
+
+// the timeout of each metric query
+var metricQueryTimeout = 10 * time.Second
+
+// MetricProvider queries the value of a canary metric.
+type MetricProvider interface {
+	Query(ctx context.Context, query string) (float64, error)
+}
+
+// metricTemplate describes how to query a canary metric, the address and query are Go templates which can refer to
+// the `name` and `namespace` of the target workload, the `interval` of the metric and the current `batch`.
+type metricTemplate struct {
+	Provider string
+	Address  string
+	Query    string
+	JSONPath string
+}
+
+// NewMetricProvider creates the metric provider of the given type.
+func NewMetricProvider(provider, address, jsonPath string) (MetricProvider, error) {
+	switch provider {
+	case "", PrometheusMetricProvider:
+		if address == "" {
+			return nil, errors.New("address of the prometheus server is required")
+		}
+		return &prometheusProvider{address: strings.TrimSuffix(address, "/")}, nil
+	case HTTPMetricProvider:
+		if jsonPath == "" {
+			return nil, errors.New("jsonPath of the metric value is required")
+		}
+		jp := jsonpath.New("metric")
+		if err := jp.Parse(jsonPath); err != nil {
+			return nil, errors.Wrapf(err, "invalid jsonPath %q", jsonPath)
+		}
+		return &httpProvider{jsonPath: jp}, nil
+	default:
+		return nil, errors.Errorf("unknown metric provider %q", provider)
+	}
+}
+
+// prometheusProvider queries the instant value of the PromQL query
+type prometheusProvider struct {
+	address string
+}
+
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (p *prometheusProvider) Query(ctx context.Context, query string) (float64, error) {
+	body, err := getMetric(ctx, fmt.Sprintf("%s/api/v1/query?query=%s", p.address, url.QueryEscape(query)))
+	if err != nil {
+		return 0, err
+	}
+	resp := prometheusResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, errors.Wrap(err, "invalid prometheus response")
+	}
+	if resp.Status != "success" {
+		return 0, errors.Errorf("prometheus query failed: %s", resp.Error)
+	}
+	var sample []interface{}
+	switch resp.Data.ResultType {
+	case "vector":
+		var vector []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(resp.Data.Result, &vector); err != nil {
+			return 0, errors.Wrap(err, "invalid prometheus vector")
+		}
+		if len(vector) == 0 {
+			return 0, errors.Errorf("no values found for query %s", query)
+		}
+		if len(vector) > 1 {
+			return 0, errors.Errorf("multiple values found for query %s", query)
+		}
+		sample = vector[0].Value
+	case "scalar":
+		if err := json.Unmarshal(resp.Data.Result, &sample); err != nil {
+			return 0, errors.Wrap(err, "invalid prometheus scalar")
+		}
+	default:
+		return 0, errors.Errorf("unsupported result type %s of query %s", resp.Data.ResultType, query)
+	}
+	// a sample is a pair of the timestamp and the value in string
+	if len(sample) != 2 {
+		return 0, errors.Errorf("invalid sample of query %s", query)
+	}
+	value, ok := sample[1].(string)
+	if !ok {
+		return 0, errors.Errorf("invalid sample value of query %s", query)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// httpProvider gets the JSON document from the query URL and extracts the metric value by JSONPath
+type httpProvider struct {
+	jsonPath *jsonpath.JSONPath
+}
+
+func (p *httpProvider) Query(ctx context.Context, query string) (float64, error) {
+	body, err := getMetric(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return 0, errors.Wrap(err, "invalid JSON response")
+	}
+	results, err := p.jsonPath.FindResults(doc)
+	if err != nil {
+		return 0, errors.Wrap(err, "cannot find the metric value")
+	}
+	if len(results) != 1 || len(results[0]) != 1 {
+		return 0, errors.New("the metric value must be a single value")
+	}
+	switch v := results[0][0].Interface().(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, errors.Errorf("the metric value %v is not a number", v)
+	}
+}
+
+func getMetric(ctx context.Context, address string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, metricQueryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, errors.Errorf("metric query failed, http status = %d, body = %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// checkCanaryMetrics evaluates the canary metrics of the rollout plan and the current batch, it returns the reason
+// if any metric is out of its expected range. An error is returned if the metrics cannot be queried, so that they're
+// evaluated again later.
+func (r *Controller) checkCanaryMetrics(ctx context.Context) (string, error) {
+	metrics := r.rolloutSpec.CanaryMetric
+	currentBatch := int(r.rolloutStatus.CurrentBatch)
+	if currentBatch < len(r.rolloutSpec.RolloutBatches) {
+		metrics = append(metrics[:len(metrics):len(metrics)], r.rolloutSpec.RolloutBatches[currentBatch].CanaryMetric...)
+	}
+	for _, metric := range metrics {
+		value, err := r.queryCanaryMetric(ctx, metric)
+		if err != nil {
+			return "", errors.WithMessagef(err, "cannot query canary metric %s", metric.Name)
+		}
+		klog.InfoS("evaluated a canary metric", "metric", metric.Name, "value", value, "batch", currentBatch)
+		if inRange, err := metricInRange(value, metric.MetricsRange); err != nil {
+			return "", errors.WithMessagef(err, "invalid range of canary metric %s", metric.Name)
+		} else if !inRange {
+			return fmt.Sprintf("canary metric %s of batch %d is out of range, value = %v", metric.Name,
+				currentBatch, value), nil
+		}
+	}
+	return "", nil
+}
+
+func (r *Controller) queryCanaryMetric(ctx context.Context, metric v1alpha1.CanaryMetric) (float64, error) {
+	if metric.TemplateRef == nil {
+		return 0, errors.New("templateRef is required")
+	}
+	tmpl, err := r.getMetricTemplate(ctx, metric)
+	if err != nil {
+		return 0, err
+	}
+	interval := metric.Interval
+	if interval == "" {
+		interval = defaultMetricInterval
+	}
+	data := map[string]interface{}{
+		"name":      r.targetWorkload.GetName(),
+		"namespace": r.targetWorkload.GetNamespace(),
+		"interval":  interval,
+		"batch":     r.rolloutStatus.CurrentBatch,
+	}
+	address, err := renderMetricTemplate(tmpl.Address, data)
+	if err != nil {
+		return 0, err
+	}
+	provider, err := NewMetricProvider(tmpl.Provider, address, tmpl.JSONPath)
+	if err != nil {
+		return 0, err
+	}
+	query := address
+	if tmpl.Provider != HTTPMetricProvider {
+		if query, err = renderMetricTemplate(tmpl.Query, data); err != nil {
+			return 0, err
+		}
+	}
+	return provider.Query(ctx, query)
+}
+
+func (r *Controller) getMetricTemplate(ctx context.Context, metric v1alpha1.CanaryMetric) (*metricTemplate, error) {
+	ref := metric.TemplateRef
+	if ref.Kind != "ConfigMap" || (ref.APIVersion != "" && ref.APIVersion != "v1") {
+		return nil, errors.Errorf("unsupported metric template %s %s, only ConfigMap is supported", ref.APIVersion, ref.Kind)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: r.parentController.GetNamespace(), Name: ref.Name}, cm); err != nil {
+		return nil, errors.Wrapf(err, "cannot get metric template %s", ref.Name)
+	}
+	return &metricTemplate{
+		Provider: cm.Data[MetricTemplateProvider],
+		Address:  cm.Data[MetricTemplateAddress],
+		Query:    cm.Data[MetricTemplateQuery],
+		JSONPath: cm.Data[MetricTemplateJSONPath],
+	}, nil
+}
+
+func renderMetricTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("metric").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid metric template")
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", errors.Wrap(err, "cannot render metric template")
+	}
+	return buf.String(), nil
+}
+
+// metricInRange checks whether the value is in the expected range, the bounds are inclusive
+func metricInRange(value float64, expected *v1alpha1.MetricsExpectedRange) (bool, error) {
+	if expected == nil {
+		return true, nil
+	}
+	if expected.Min != nil {
+		min, err := metricBound(expected.Min)
+		if err != nil {
+			return false, err
+		}
+		if value < min {
+			return false, nil
+		}
+	}
+	if expected.Max != nil {
+		max, err := metricBound(expected.Max)
+		if err != nil {
+			return false, err
+		}
+		if value > max {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// metricBound parses the bound of the range, which is an integer or a decimal string, e.g. "0.99"
+func metricBound(bound *intstr.IntOrString) (float64, error) {
+	if bound.Type == intstr.Int {
+		return float64(bound.IntVal), nil
+	}
+	return strconv.ParseFloat(bound.StrVal, 64)
+}
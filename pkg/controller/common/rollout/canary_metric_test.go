@@ -0,0 +1,243 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// newFakePrometheus serves the instant queries with the values of the given queries
+func newFakePrometheus(t *testing.T, values map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query", r.URL.Path)
+		value, ok := values[r.URL.Query().Get("query")]
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1625000000.1,"%s"]}]}}`, value)
+	}))
+}
+
+func newMetricTemplate(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Data:       data,
+	}
+}
+
+func newMetric(name, template string, min, max *intstr.IntOrString) v1alpha1.CanaryMetric {
+	return v1alpha1.CanaryMetric{
+		Name:         name,
+		Interval:     "5m",
+		MetricsRange: &v1alpha1.MetricsExpectedRange{Min: min, Max: max},
+		TemplateRef:  &runtimev1alpha1.TypedReference{APIVersion: "v1", Kind: "ConfigMap", Name: template},
+	}
+}
+
+func newMetricController(plan *v1alpha1.RolloutPlan, objs ...*corev1.ConfigMap) *Controller {
+	scheme := clientgoscheme.Scheme
+	var initObjs []runtime.Object
+	for _, obj := range objs {
+		initObjs = append(initObjs, obj)
+	}
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	target := &unstructured.Unstructured{}
+	target.SetName("frontend-v2")
+	target.SetNamespace("default")
+	return NewRolloutPlanController(fake.NewFakeClientWithScheme(scheme, initObjs...), app, event.NewNopRecorder(),
+		plan, &v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: v1alpha1.BatchVerifyingState,
+			CurrentBatch:      1,
+		}, target, nil)
+}
+
+func TestVerifyOneBatchMetrics(t *testing.T) {
+	prom := newFakePrometheus(t, map[string]string{
+		`success_rate{workload="frontend-v2"}[5m]`: "0.995",
+		`latency{namespace="default"}[5m]`:         "250",
+	})
+	defer prom.Close()
+	jsonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "frontend-v2", r.URL.Query().Get("workload"))
+		_, _ = fmt.Fprint(w, `{"data":{"errors":{"count":3}}}`)
+	}))
+	defer jsonServer.Close()
+
+	templates := []*corev1.ConfigMap{
+		newMetricTemplate("success-rate", map[string]string{
+			MetricTemplateAddress: prom.URL,
+			MetricTemplateQuery:   `success_rate{workload="{{ .name }}"}[{{ .interval }}]`,
+		}),
+		newMetricTemplate("latency", map[string]string{
+			MetricTemplateProvider: PrometheusMetricProvider,
+			MetricTemplateAddress:  prom.URL,
+			MetricTemplateQuery:    `latency{namespace="{{ .namespace }}"}[{{ .interval }}]`,
+		}),
+		newMetricTemplate("errors", map[string]string{
+			MetricTemplateProvider: HTTPMetricProvider,
+			MetricTemplateAddress:  jsonServer.URL + "/errors?workload={{ .name }}",
+			MetricTemplateJSONPath: "{.data.errors.count}",
+		}),
+		newMetricTemplate("missing", map[string]string{
+			MetricTemplateAddress: prom.URL,
+			MetricTemplateQuery:   "missing",
+		}),
+	}
+	minRate := intstr.FromString("0.99")
+	maxLatency := intstr.FromInt(300)
+	maxErrors := intstr.FromInt(5)
+	lowLatency := intstr.FromInt(200)
+
+	tests := map[string]struct {
+		planMetrics  []v1alpha1.CanaryMetric
+		batchMetrics []v1alpha1.CanaryMetric
+		rollback     bool
+		wantState    v1alpha1.RollingState
+		wantBatch    v1alpha1.BatchRollingState
+	}{
+		"no metrics": {
+			wantState: v1alpha1.RollingInBatchesState,
+			wantBatch: v1alpha1.BatchFinalizingState,
+		},
+		"all metrics in range": {
+			planMetrics:  []v1alpha1.CanaryMetric{newMetric("success-rate", "success-rate", &minRate, nil)},
+			batchMetrics: []v1alpha1.CanaryMetric{newMetric("latency", "latency", nil, &maxLatency), newMetric("errors", "errors", nil, &maxErrors)},
+			wantState:    v1alpha1.RollingInBatchesState,
+			wantBatch:    v1alpha1.BatchFinalizingState,
+		},
+		"batch metric out of range": {
+			planMetrics:  []v1alpha1.CanaryMetric{newMetric("success-rate", "success-rate", &minRate, nil)},
+			batchMetrics: []v1alpha1.CanaryMetric{newMetric("latency", "latency", nil, &lowLatency)},
+			wantState:    v1alpha1.RolloutFailedState,
+			wantBatch:    v1alpha1.BatchRolloutFailedState,
+		},
+		"batch metric out of range is finalized before rolled back": {
+			batchMetrics: []v1alpha1.CanaryMetric{newMetric("latency", "latency", nil, &lowLatency)},
+			rollback:     true,
+			wantState:    v1alpha1.RolloutFailingState,
+			wantBatch:    v1alpha1.BatchInitializingState,
+		},
+		"metric without value is retried": {
+			planMetrics: []v1alpha1.CanaryMetric{newMetric("missing", "missing", &minRate, nil)},
+			wantState:   v1alpha1.RollingInBatchesState,
+			wantBatch:   v1alpha1.BatchVerifyingState,
+		},
+		"metric template not found is retried": {
+			planMetrics: []v1alpha1.CanaryMetric{newMetric("success-rate", "not-found", &minRate, nil)},
+			wantState:   v1alpha1.RollingInBatchesState,
+			wantBatch:   v1alpha1.BatchVerifyingState,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			plan := &v1alpha1.RolloutPlan{
+				CanaryMetric:   tt.planMetrics,
+				RollbackPolicy: &v1alpha1.RollbackPolicy{Automatic: tt.rollback},
+				RolloutBatches: []v1alpha1.RolloutBatch{
+					{CanaryMetric: []v1alpha1.CanaryMetric{newMetric("never-evaluated", "not-found", nil, nil)}},
+					{CanaryMetric: tt.batchMetrics},
+				},
+			}
+			r := newMetricController(plan, templates...)
+			r.verifyOneBatchMetrics(context.Background())
+			assert.Equal(t, tt.wantState, r.rolloutStatus.RollingState)
+			assert.Equal(t, tt.wantBatch, r.rolloutStatus.BatchRollingState)
+		})
+	}
+}
+
+func TestPrometheusProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "scalar":
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1625000000.1,"42"]}}`)
+		case "multiple":
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"value":[1,"1"]},{"value":[1,"2"]}]}}`)
+		case "error":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"status":"error","error":"parse error"}`)
+		}
+	}))
+	defer server.Close()
+	provider, err := NewMetricProvider(PrometheusMetricProvider, server.URL+"/", "")
+	require.NoError(t, err)
+
+	value, err := provider.Query(context.Background(), "scalar")
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), value)
+	_, err = provider.Query(context.Background(), "multiple")
+	assert.Error(t, err)
+	_, err = provider.Query(context.Background(), "error")
+	assert.Error(t, err)
+
+	_, err = NewMetricProvider(PrometheusMetricProvider, "", "")
+	assert.Error(t, err)
+	_, err = NewMetricProvider(HTTPMetricProvider, server.URL, "")
+	assert.Error(t, err)
+	_, err = NewMetricProvider("datadog", server.URL, "")
+	assert.Error(t, err)
+}
+
+func TestMetricInRange(t *testing.T) {
+	min, max, invalid := intstr.FromString("0.5"), intstr.FromInt(2), intstr.FromString("high")
+	tests := map[string]struct {
+		value    float64
+		expected *v1alpha1.MetricsExpectedRange
+		want     bool
+		wantErr  bool
+	}{
+		"no range":        {value: 100, want: true},
+		"in range":        {value: 1, expected: &v1alpha1.MetricsExpectedRange{Min: &min, Max: &max}, want: true},
+		"equal to bounds": {value: 0.5, expected: &v1alpha1.MetricsExpectedRange{Min: &min, Max: &max}, want: true},
+		"below min":       {value: 0.4, expected: &v1alpha1.MetricsExpectedRange{Min: &min}, want: false},
+		"above max":       {value: 2.1, expected: &v1alpha1.MetricsExpectedRange{Max: &max}, want: false},
+		"invalid bound":   {value: 1, expected: &v1alpha1.MetricsExpectedRange{Max: &invalid}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := metricInRange(tt.value, tt.expected)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
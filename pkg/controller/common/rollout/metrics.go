@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// the labels that identify the rollout of one component
+var rolloutLabels = []string{"kind", "namespace", "name", "component"}
+
+var rollingStates = []v1alpha1.RollingState{
+	v1alpha1.LocatingTargetAppState, v1alpha1.VerifyingSpecState, v1alpha1.InitializingState,
+	v1alpha1.RollingInBatchesState, v1alpha1.FinalisingState, v1alpha1.RolloutFailingState,
+	v1alpha1.RolloutSucceedState, v1alpha1.RolloutAbandoningState, v1alpha1.RolloutDeletingState,
+	v1alpha1.RolloutFailedState,
+}
+
+var batchRollingStates = []v1alpha1.BatchRollingState{
+	v1alpha1.BatchInitializingState, v1alpha1.BatchInRollingState, v1alpha1.BatchVerifyingState,
+	v1alpha1.BatchRolloutFailedState, v1alpha1.BatchFinalizingState, v1alpha1.BatchReadyState,
+}
+
+var (
+	rolloutStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_rollout_state",
+		Help: "The rolling state of the rollout, it's 1 for the current state and 0 for the others",
+	}, append(rolloutLabels, "state"))
+
+	rolloutBatchStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_rollout_batch_state",
+		Help: "The rolling state of the current batch, it's 1 for the current state and 0 for the others",
+	}, append(rolloutLabels, "state"))
+
+	rolloutCurrentBatchGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_rollout_current_batch",
+		Help: "The batch the rollout is working on, it starts from 0",
+	}, rolloutLabels)
+
+	rolloutTargetSizeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_rollout_target_size",
+		Help: "The number of replicas of the target workload when the rollout finishes",
+	}, rolloutLabels)
+
+	rolloutUpgradedReplicasGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_rollout_upgraded_replicas",
+		Help: "The number of pods upgraded by the rollout",
+	}, rolloutLabels)
+
+	rolloutUpgradedReadyReplicasGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_rollout_upgraded_ready_replicas",
+		Help: "The number of pods upgraded by the rollout that are ready",
+	}, rolloutLabels)
+
+	rolloutBatchDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "kubevela_rollout_batch_duration_seconds",
+		Help: "The time it takes a batch to become ready since it started",
+		// from 10 seconds to about 6 hours
+		Buckets: prometheus.ExponentialBuckets(10, 2, 12),
+	}, rolloutLabels)
+
+	rolloutWebhookDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "kubevela_rollout_webhook_duration_seconds",
+		Help: "The time it takes to call a rollout webhook, including the retries",
+	}, []string{"kind", "namespace", "name", "type"})
+
+	rolloutWebhookFailuresCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubevela_rollout_webhook_failures_total",
+		Help: "The number of the rollout webhook calls that failed",
+	}, []string{"kind", "namespace", "name", "type"})
+)
+
+// rolloutKey identifies a rollout, the components of the rollout are tracked by it so that their metrics can be
+// deleted along with the rollout
+type rolloutKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+var (
+	trackedRolloutsLock sync.Mutex
+	trackedRollouts     = make(map[rolloutKey]map[string]bool)
+)
+
+func init() {
+	metrics.Registry.MustRegister(rolloutStateGauge, rolloutBatchStateGauge, rolloutCurrentBatchGauge,
+		rolloutTargetSizeGauge, rolloutUpgradedReplicasGauge, rolloutUpgradedReadyReplicasGauge,
+		rolloutBatchDurationHistogram, rolloutWebhookDurationHistogram, rolloutWebhookFailuresCounter)
+}
+
+// recordStatusMetrics exports the status of the rollout of one component
+func recordStatusMetrics(key rolloutKey, component string, status *v1alpha1.RolloutStatus) {
+	trackedRolloutsLock.Lock()
+	if trackedRollouts[key] == nil {
+		trackedRollouts[key] = make(map[string]bool)
+	}
+	trackedRollouts[key][component] = true
+	trackedRolloutsLock.Unlock()
+
+	labels := []string{key.kind, key.namespace, key.name, component}
+	for _, state := range rollingStates {
+		rolloutStateGauge.WithLabelValues(append(labels, string(state))...).Set(boolValue(
+			status.RollingState == state))
+	}
+	for _, state := range batchRollingStates {
+		rolloutBatchStateGauge.WithLabelValues(append(labels, string(state))...).Set(boolValue(
+			status.RollingState == v1alpha1.RollingInBatchesState && status.BatchRollingState == state))
+	}
+	rolloutCurrentBatchGauge.WithLabelValues(labels...).Set(float64(status.CurrentBatch))
+	rolloutTargetSizeGauge.WithLabelValues(labels...).Set(float64(status.RolloutTargetSize))
+	rolloutUpgradedReplicasGauge.WithLabelValues(labels...).Set(float64(status.UpgradedReplicas))
+	rolloutUpgradedReadyReplicasGauge.WithLabelValues(labels...).Set(float64(status.UpgradedReadyReplicas))
+}
+
+// recordBatchDuration observes how long it took the current batch to become ready
+func recordBatchDuration(key rolloutKey, component string, status *v1alpha1.RolloutStatus) {
+	if status.BatchStartTime == nil {
+		return
+	}
+	rolloutBatchDurationHistogram.WithLabelValues(key.kind, key.namespace, key.name, component).Observe(
+		time.Since(status.BatchStartTime.Time).Seconds())
+}
+
+// recordWebhookCall observes a call to a rollout webhook
+func recordWebhookCall(key rolloutKey, hookType v1alpha1.HookType, start time.Time, err error) {
+	rolloutWebhookDurationHistogram.WithLabelValues(key.kind, key.namespace, key.name, string(hookType)).Observe(
+		time.Since(start).Seconds())
+	if err != nil {
+		rolloutWebhookFailuresCounter.WithLabelValues(key.kind, key.namespace, key.name, string(hookType)).Inc()
+	}
+}
+
+// DeleteRolloutMetrics deletes the metrics of a rollout that is gone
+func DeleteRolloutMetrics(kind, namespace, name string) {
+	key := rolloutKey{kind: kind, namespace: namespace, name: name}
+	trackedRolloutsLock.Lock()
+	components := trackedRollouts[key]
+	delete(trackedRollouts, key)
+	trackedRolloutsLock.Unlock()
+
+	for component := range components {
+		labels := prometheus.Labels{"kind": kind, "namespace": namespace, "name": name, "component": component}
+		for _, state := range rollingStates {
+			rolloutStateGauge.Delete(withState(labels, string(state)))
+		}
+		for _, state := range batchRollingStates {
+			rolloutBatchStateGauge.Delete(withState(labels, string(state)))
+		}
+		rolloutCurrentBatchGauge.Delete(labels)
+		rolloutTargetSizeGauge.Delete(labels)
+		rolloutUpgradedReplicasGauge.Delete(labels)
+		rolloutUpgradedReadyReplicasGauge.Delete(labels)
+		rolloutBatchDurationHistogram.Delete(labels)
+	}
+	for _, hookType := range []v1alpha1.HookType{v1alpha1.InitializeRolloutHook, v1alpha1.PreBatchRolloutHook,
+		v1alpha1.PostBatchRolloutHook, v1alpha1.FinalizeRolloutHook} {
+		labels := prometheus.Labels{"kind": kind, "namespace": namespace, "name": name, "type": string(hookType)}
+		rolloutWebhookDurationHistogram.Delete(labels)
+		rolloutWebhookFailuresCounter.Delete(labels)
+	}
+}
+
+// metricsKey returns the key of the rollout in the metrics, the kind is the type name of the parent controller
+// if it has no type meta
+func (r *Controller) metricsKey() rolloutKey {
+	kind := r.parentController.GetObjectKind().GroupVersionKind().Kind
+	if len(kind) == 0 {
+		kind = reflect.Indirect(reflect.ValueOf(r.parentController)).Type().Name()
+	}
+	return rolloutKey{kind: kind, namespace: r.parentController.GetNamespace(), name: r.parentController.GetName()}
+}
+
+func withState(labels prometheus.Labels, state string) prometheus.Labels {
+	l := prometheus.Labels{"state": state}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return l
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
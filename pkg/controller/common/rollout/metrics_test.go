@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestRecordStateTransition(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	target := &unstructured.Unstructured{}
+	target.SetLabels(map[string]string{oam.LabelAppComponent: "frontend"})
+	parent := &v1beta1.AppRollout{ObjectMeta: metav1.ObjectMeta{Name: "metrics-rollout", Namespace: "default"}}
+	r := NewRolloutPlanController(nil, parent, event.NewAPIRecorder(recorder), &v1alpha1.RolloutPlan{},
+		&v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: v1alpha1.BatchReadyState,
+			CurrentBatch:      1,
+			UpgradedReplicas:  4,
+			BatchStartTime:    &metav1.Time{Time: time.Now().Add(-time.Minute)},
+		}, target, nil)
+	labels := []string{v1beta1.AppRolloutKind, "default", "metrics-rollout", "frontend"}
+	// other tests may have recorded the metrics of their own rollouts
+	gauges, histograms := testutil.CollectAndCount(rolloutCurrentBatchGauge),
+		testutil.CollectAndCount(rolloutBatchDurationHistogram)
+	failures := testutil.CollectAndCount(rolloutWebhookFailuresCounter)
+
+	r.recordStateTransition(v1alpha1.RollingInBatchesState, v1alpha1.BatchFinalizingState, 1)
+	assert.Equal(t, "Normal Batch State Changed the batch 1 moved from batchFinalizing to batchReady", <-recorder.Events)
+	assert.Equal(t, histograms+1, testutil.CollectAndCount(rolloutBatchDurationHistogram))
+	assert.Equal(t, float64(1), testutil.ToFloat64(rolloutStateGauge.WithLabelValues(
+		append(labels, string(v1alpha1.RollingInBatchesState))...)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(rolloutStateGauge.WithLabelValues(
+		append(labels, string(v1alpha1.RolloutFailedState))...)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(rolloutBatchStateGauge.WithLabelValues(
+		append(labels, string(v1alpha1.BatchReadyState))...)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(rolloutCurrentBatchGauge.WithLabelValues(labels...)))
+	assert.Equal(t, float64(4), testutil.ToFloat64(rolloutUpgradedReplicasGauge.WithLabelValues(labels...)))
+
+	// nothing changes
+	r.recordStateTransition(v1alpha1.RollingInBatchesState, v1alpha1.BatchReadyState, 1)
+	assert.Empty(t, recorder.Events)
+	assert.Equal(t, uint64(1), histogramCount(t, labels))
+
+	r.rolloutStatus.RolloutFailed("the batch failed")
+	r.recordStateTransition(v1alpha1.RollingInBatchesState, v1alpha1.BatchReadyState, 1)
+	assert.Equal(t, "Warning Rolling State Changed the rollout moved from rollingInBatches to rolloutFailed",
+		<-recorder.Events)
+	assert.Equal(t, float64(1), testutil.ToFloat64(rolloutStateGauge.WithLabelValues(
+		append(labels, string(v1alpha1.RolloutFailedState))...)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(rolloutBatchStateGauge.WithLabelValues(
+		append(labels, string(v1alpha1.BatchReadyState))...)))
+
+	recordWebhookCall(r.metricsKey(), v1alpha1.PreBatchRolloutHook, time.Now(), errors.New("timeout"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(rolloutWebhookFailuresCounter.WithLabelValues(
+		v1beta1.AppRolloutKind, "default", "metrics-rollout", string(v1alpha1.PreBatchRolloutHook))))
+
+	DeleteRolloutMetrics(v1beta1.AppRolloutKind, "default", "metrics-rollout")
+	assert.Equal(t, gauges, testutil.CollectAndCount(rolloutCurrentBatchGauge))
+	assert.Equal(t, histograms, testutil.CollectAndCount(rolloutBatchDurationHistogram))
+	assert.Equal(t, failures, testutil.CollectAndCount(rolloutWebhookFailuresCounter))
+}
+
+func histogramCount(t *testing.T, labels []string) uint64 {
+	m := &dto.Metric{}
+	assert.NoError(t, rolloutBatchDurationHistogram.WithLabelValues(labels...).(prometheus.Histogram).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
@@ -19,22 +19,26 @@ package rollout
 import (
 	"context"
 	"fmt"
-	"reflect"
+	"strconv"
 	"time"
 
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
-	kruisev1 "github.com/openkruise/kruise-api/apps/v1alpha1"
-	apps "k8s.io/api/apps/v1"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/schedule"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/traffic"
 	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/workloads"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // the default time to check back if we still have work to do
@@ -91,6 +95,11 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 	}()
 	status = r.rolloutStatus
 
+	rollingState, batchRollingState, currentBatch := status.RollingState, status.BatchRollingState, status.CurrentBatch
+	defer func() {
+		r.recordStateTransition(rollingState, batchRollingState, currentBatch)
+	}()
+
 	defer func() {
 		if status.RollingState == v1alpha1.RolloutFailedState ||
 			status.RollingState == v1alpha1.RolloutSucceedState {
@@ -103,16 +112,29 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 		}
 	}()
 
-	workloadController, err := r.GetWorkloadController()
+	workloadController, err := r.GetWorkloadController(ctx)
 	if err != nil {
 		r.rolloutStatus.RolloutFailed(err.Error())
 		r.recorder.Event(r.parentController, event.Warning("Unsupported workload", err))
 		return
 	}
 
+	trafficController, err := r.GetTrafficController()
+	if err != nil {
+		r.rolloutStatus.RolloutFailed(err.Error())
+		r.recorder.Event(r.parentController, event.Warning("Unsupported traffic routing", err))
+		return
+	}
+
 	switch r.rolloutStatus.RollingState {
 	case v1alpha1.VerifyingSpecState:
 		verified, err := workloadController.VerifySpec(ctx)
+		if err == nil && verified && trafficController != nil {
+			err = trafficController.VerifySpec(ctx)
+		}
+		if err == nil && verified {
+			_, err = schedule.NewSchedule(r.rolloutSpec.Schedule)
+		}
 		if err != nil {
 			// we can fail it right away, everything after initialized need to be finalized
 			r.rolloutStatus.RolloutFailed(err.Error())
@@ -121,7 +143,9 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 		}
 
 	case v1alpha1.InitializingState:
-		if err = r.initializeRollout(ctx); err == nil {
+		// nothing is touched until the schedule allows the rollout to start,
+		// all the traffic goes to the source workload before we start to roll
+		if !r.waitForSchedule(time.Time{}) && r.initializeRollout(ctx) && r.routeTraffic(ctx, trafficController, 0) {
 			initialized, err := workloadController.Initialize(ctx)
 			if err != nil {
 				r.rolloutStatus.RolloutFailing(err.Error())
@@ -131,14 +155,22 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 		}
 
 	case v1alpha1.RollingInBatchesState:
-		r.reconcileBatchInRolling(ctx, workloadController)
+		r.reconcileBatchInRolling(ctx, workloadController, trafficController)
 
 	case v1alpha1.RolloutFailingState, v1alpha1.RolloutAbandoningState, v1alpha1.RolloutDeletingState:
+		// move all the traffic back to the source workload before we release the workloads
+		if !r.routeTraffic(ctx, trafficController, 0) {
+			return
+		}
 		if succeed := workloadController.Finalize(ctx, false); succeed {
 			r.finalizeRollout(ctx)
 		}
 
 	case v1alpha1.FinalisingState:
+		// move all the traffic to the target workload before the source workload is cleaned up
+		if !r.routeTraffic(ctx, trafficController, 100) {
+			return
+		}
 		if succeed := workloadController.Finalize(ctx, true); succeed {
 			r.finalizeRollout(ctx)
 		}
@@ -157,7 +189,8 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 }
 
 // reconcile logic when we are in the middle of rollout, we have to go through finalizing state before succeed or fail
-func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadController workloads.WorkloadController) {
+func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadController workloads.WorkloadController,
+	trafficController traffic.Controller) {
 	if r.rolloutSpec.Paused {
 		r.recorder.Event(r.parentController, event.Normal("Rollout paused", "Rollout paused"))
 		r.rolloutStatus.SetConditions(v1alpha1.NewPositiveCondition(v1alpha1.BatchPaused))
@@ -180,13 +213,13 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 	case v1alpha1.BatchVerifyingState:
 		// verifying if the application is ready to roll
 		// need to check if they meet the availability requirements in the rollout spec.
-		// TODO: evaluate any metrics/analysis
 		// TODO: We may need to go back to rollout again if the size of the resource can change behind our back
 		verified, err := workloadController.CheckOneBatchPods(ctx)
 		if err != nil {
 			r.rolloutStatus.RolloutFailing(err.Error())
-		} else if verified {
-			r.rolloutStatus.StateTransition(v1alpha1.OneBatchAvailableEvent)
+		} else if verified && r.routeTraffic(ctx, trafficController, r.batchTrafficWeight()) {
+			// the canary metrics are evaluated with the traffic of the batch
+			r.verifyOneBatchMetrics(ctx)
 		}
 
 	case v1alpha1.BatchFinalizingState:
@@ -208,47 +241,148 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 	}
 }
 
-// all the common initialize work before we rollout
+// evaluate the canary metrics once the pods of the batch are available, the rollout fails if any of them is not
+// in the expected range
+func (r *Controller) verifyOneBatchMetrics(ctx context.Context) {
+	reason, err := r.checkCanaryMetrics(ctx)
+	if err != nil {
+		klog.ErrorS(err, "failed to evaluate the canary metrics", "current batch", r.rolloutStatus.CurrentBatch)
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return
+	}
+	if reason != "" {
+		klog.InfoS("the canary analysis failed", "current batch", r.rolloutStatus.CurrentBatch, "reason", reason)
+		r.recorder.Event(r.parentController, event.Warning("Canary analysis failed", errors.New(reason)))
+		r.failOneBatch(reason)
+		return
+	}
+	r.rolloutStatus.StateTransition(v1alpha1.OneBatchAvailableEvent)
+}
+
+// routeTraffic routes the weight percentage of the traffic to the target workload, it returns if the traffic is
+// routed and the rollout can move on
+func (r *Controller) routeTraffic(ctx context.Context, trafficController traffic.Controller, weight int32) bool {
+	if trafficController == nil {
+		return true
+	}
+	if err := trafficController.RouteTraffic(ctx, weight); err != nil {
+		klog.ErrorS(err, "failed to route the traffic", "weight", weight)
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	if r.rolloutStatus.TrafficWeight != weight {
+		r.recorder.Event(r.parentController, event.Normal("Traffic Shifted",
+			fmt.Sprintf("%d%% of the traffic is routed to the target workload", weight)))
+		r.rolloutStatus.TrafficWeight = weight
+	}
+	return true
+}
+
+// batchTrafficWeight returns the percentage of the traffic routed to the target workload in the current batch.
+// The blue-green traffic routing keeps all the traffic on the source workload until the rollout is finalized.
+func (r *Controller) batchTrafficWeight() int32 {
+	routing := r.rolloutSpec.TrafficRouting
+	if routing == nil || routing.Strategy == v1alpha1.BlueGreenTrafficRoutingStrategyType {
+		return 0
+	}
+	if weight := r.rolloutSpec.RolloutBatches[r.rolloutStatus.CurrentBatch].TrafficWeight; weight != nil {
+		return *weight
+	}
+	if r.rolloutStatus.RolloutTargetSize <= 0 || r.rolloutStatus.UpgradedReplicas >= r.rolloutStatus.RolloutTargetSize {
+		return 100
+	}
+	return r.rolloutStatus.UpgradedReplicas * 100 / r.rolloutStatus.RolloutTargetSize
+}
+
+// all the common initialize work before we rollout, it returns if the rollout can move on
 // TODO: fail the rollout if the webhook call is explicitly rejected (through http status code)
-func (r *Controller) initializeRollout(ctx context.Context) error {
+func (r *Controller) initializeRollout(ctx context.Context) bool {
 	// call the pre-rollout webhooks
-	for _, rw := range r.rolloutSpec.RolloutWebhooks {
-		if rw.Type == v1alpha1.InitializeRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(v1alpha1.InitializingState), rw)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rw.Name, "webhook end point", rw.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
-				return err
-			}
-			klog.InfoS("successfully invoked a pre rollout webhook", "webhook name", rw.Name, "webhook end point",
-				rw.URL)
-		}
+	decision, reason, err := r.callWebhooks(ctx, r.rolloutSpec.RolloutWebhooks, v1alpha1.InitializeRolloutHook,
+		string(v1alpha1.InitializingState))
+	if err != nil {
+		r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
+		return false
 	}
-
-	return nil
+	switch decision {
+	case v1alpha1.PauseWebhookDecision:
+		r.pauseByWebhook(reason)
+		return false
+	case v1alpha1.AbortWebhookDecision:
+		r.rolloutStatus.RolloutFailing(reason)
+		return false
+	}
+	return true
 }
 
 // all the common initialize work before we rollout one batch of resources
 func (r *Controller) initializeOneBatch(ctx context.Context) {
-	rolloutHooks := r.gatherAllWebhooks()
 	// call all the pre-batch rollout webhooks
-	for _, rh := range rolloutHooks {
-		if rh.Type == v1alpha1.PreBatchRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(v1alpha1.BatchInitializingState), rh)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rh.Name, "webhook end point", rh.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a pre batch webhook", "webhook name", rh.Name, "webhook end point",
-				rh.URL)
-		}
+	decision, reason, err := r.callWebhooks(ctx, r.gatherAllWebhooks(), v1alpha1.PreBatchRolloutHook,
+		string(v1alpha1.BatchInitializingState))
+	if err != nil {
+		r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
+		return
+	}
+	switch decision {
+	case v1alpha1.PauseWebhookDecision:
+		r.pauseByWebhook(reason)
+		return
+	case v1alpha1.AbortWebhookDecision:
+		r.failOneBatch(reason)
+		return
 	}
 	r.rolloutStatus.StateTransition(v1alpha1.InitializedOneBatchEvent)
 }
 
+// callWebhooks calls the webhooks of the hook type in order. It stops at the first webhook that fails or decides
+// not to proceed, and returns the decision along with the reason.
+func (r *Controller) callWebhooks(ctx context.Context, hooks []v1alpha1.RolloutWebhook, hookType v1alpha1.HookType,
+	phase string) (v1alpha1.WebhookDecisionType, string, error) {
+	for _, rw := range hooks {
+		if rw.Type != hookType {
+			continue
+		}
+		start := time.Now()
+		response, err := callWebhook(ctx, r.client, r.parentController, phase, rw)
+		recordWebhookCall(r.metricsKey(), hookType, start, err)
+		if err != nil {
+			klog.ErrorS(err, "failed to invoke a webhook", "webhook type", hookType,
+				"webhook name", rw.Name, "webhook end point", rw.URL)
+			return "", "", errors.Wrapf(err, "failed to invoke the %s webhook %s", hookType, rw.Name)
+		}
+		if response.Decision != v1alpha1.ProceedWebhookDecision {
+			klog.InfoS("the webhook decides not to proceed", "webhook type", hookType, "webhook name", rw.Name,
+				"decision", response.Decision, "message", response.Message)
+			return response.Decision, fmt.Sprintf("the %s webhook %s decides to %s: %s", hookType, rw.Name,
+				response.Decision, response.Message), nil
+		}
+		klog.InfoS("successfully invoked a webhook", "webhook type", hookType, "webhook name", rw.Name,
+			"webhook end point", rw.URL)
+	}
+	r.removeCondition(v1alpha1.RolloutPaused)
+	return v1alpha1.ProceedWebhookDecision, "", nil
+}
+
+// pauseByWebhook holds the rollout where it is, the webhook is called again until it decides otherwise
+func (r *Controller) pauseByWebhook(reason string) {
+	r.recorder.Event(r.parentController, event.Normal("Rollout paused", reason))
+	cond := v1alpha1.NewPositiveCondition(v1alpha1.RolloutPaused)
+	cond.Message = reason
+	r.rolloutStatus.SetConditions(cond)
+}
+
+// removeCondition removes the condition of the type once it no longer holds
+func (r *Controller) removeCondition(condType runtimev1alpha1.ConditionType) {
+	conditions := make([]runtimev1alpha1.Condition, 0, len(r.rolloutStatus.Conditions))
+	for _, cond := range r.rolloutStatus.Conditions {
+		if cond.Type != condType {
+			conditions = append(conditions, cond)
+		}
+	}
+	r.rolloutStatus.Conditions = conditions
+}
+
 func (r *Controller) gatherAllWebhooks() []v1alpha1.RolloutWebhook {
 	// we go through the rollout level webhooks first
 	rolloutHooks := r.rolloutSpec.RolloutWebhooks
@@ -259,9 +393,68 @@ func (r *Controller) gatherAllWebhooks() []v1alpha1.RolloutWebhook {
 	return rolloutHooks
 }
 
+// failOneBatch fails the rollout as the current batch is rejected. A rollout to be rolled back automatically needs
+// to be finalized first to release its workloads, otherwise it stops right here for the client to decide.
+func (r *Controller) failOneBatch(reason string) {
+	if automaticRollback(r.rolloutSpec) {
+		r.rolloutStatus.RolloutFailing(reason)
+		return
+	}
+	r.rolloutStatus.StateTransition(v1alpha1.BatchRolloutFailedEvent)
+	r.rolloutStatus.SetConditions(v1alpha1.NewNegativeCondition(v1alpha1.BatchRolloutFailed, reason))
+}
+
+// automaticRollback returns if the rollout plan is rolled back automatically when it fails
+func automaticRollback(plan *v1alpha1.RolloutPlan) bool {
+	return plan.RollbackPolicy != nil && plan.RollbackPolicy.Automatic
+}
+
+// recordStateTransition emits a structured event for each change of the rolling state and the batch rolling state
+// since the given ones, and exports the status of the rollout as metrics
+func (r *Controller) recordStateTransition(rollingState v1alpha1.RollingState,
+	batchRollingState v1alpha1.BatchRollingState, currentBatch int32) {
+	status := r.rolloutStatus
+	component := r.targetWorkload.GetLabels()[oam.LabelAppComponent]
+	key := r.metricsKey()
+	if status.RollingState != rollingState {
+		r.recorder.Event(r.parentController, stateTransitionEvent("Rolling State Changed",
+			fmt.Sprintf("the rollout moved from %s to %s", rollingState, status.RollingState),
+			status.RollingState == v1alpha1.RolloutFailingState || status.RollingState == v1alpha1.RolloutFailedState,
+			"component", component, "previousRollingState", string(rollingState),
+			"rollingState", string(status.RollingState), "currentBatch", strconv.Itoa(int(status.CurrentBatch))))
+	}
+	if rollingState == v1alpha1.RollingInBatchesState && status.RollingState == v1alpha1.RollingInBatchesState &&
+		(status.BatchRollingState != batchRollingState || status.CurrentBatch != currentBatch) {
+		r.recorder.Event(r.parentController, stateTransitionEvent("Batch State Changed",
+			fmt.Sprintf("the batch %d moved from %s to %s", status.CurrentBatch, batchRollingState,
+				status.BatchRollingState),
+			status.BatchRollingState == v1alpha1.BatchRolloutFailedState,
+			"component", component, "previousBatchRollingState", string(batchRollingState),
+			"batchRollingState", string(status.BatchRollingState), "currentBatch",
+			strconv.Itoa(int(status.CurrentBatch))))
+	}
+	// the last batch becomes ready when the rollout moves on to finalise
+	if rollingState == v1alpha1.RollingInBatchesState && batchRollingState != v1alpha1.BatchReadyState &&
+		status.BatchRollingState == v1alpha1.BatchReadyState && status.CurrentBatch == currentBatch {
+		recordBatchDuration(key, component, status)
+	}
+	recordStatusMetrics(key, component, status)
+}
+
+// stateTransitionEvent is a warning if the rollout moves to a failure state
+func stateTransitionEvent(reason event.Reason, message string, failure bool, keysAndValues ...string) event.Event {
+	if failure {
+		return event.Warning(reason, errors.New(message), keysAndValues...)
+	}
+	return event.Normal(reason, message, keysAndValues...)
+}
+
 // check if we can move to the next batch
 func (r *Controller) tryMovingToNextBatch() {
 	if r.rolloutSpec.BatchPartition == nil || *r.rolloutSpec.BatchPartition > r.rolloutStatus.CurrentBatch {
+		if r.waitForSchedule(r.soakDeadline()) {
+			return
+		}
 		klog.InfoS("ready to rollout the next batch", "current batch", r.rolloutStatus.CurrentBatch)
 		r.rolloutStatus.StateTransition(v1alpha1.BatchRolloutApprovedEvent)
 	} else {
@@ -270,21 +463,67 @@ func (r *Controller) tryMovingToNextBatch() {
 	}
 }
 
+// soakDeadline returns the time the current batch has soaked for long enough since it became ready
+func (r *Controller) soakDeadline() time.Time {
+	if r.rolloutSpec.Schedule == nil || r.rolloutSpec.Schedule.SoakSeconds == nil ||
+		r.rolloutStatus.BatchReadyTime == nil {
+		return time.Time{}
+	}
+	return r.rolloutStatus.BatchReadyTime.Add(time.Duration(*r.rolloutSpec.Schedule.SoakSeconds) * time.Second)
+}
+
+// waitForSchedule returns if the rollout has to wait before it rolls. The rollout waits until notBefore and then
+// until its schedule allows it to roll.
+func (r *Controller) waitForSchedule(notBefore time.Time) bool {
+	rolloutSchedule, err := schedule.NewSchedule(r.rolloutSpec.Schedule)
+	if err != nil {
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return true
+	}
+	now := time.Now()
+	if notBefore.Before(now) {
+		notBefore = now
+	}
+	nextRollTime := rolloutSchedule.NextRollTime(notBefore)
+	if !nextRollTime.IsZero() && !nextRollTime.After(now) {
+		r.removeCondition(v1alpha1.RolloutWaiting)
+		return false
+	}
+	reason := "none of the deployment windows ever opens"
+	if !nextRollTime.IsZero() {
+		reason = fmt.Sprintf("the rollout waits until %s", nextRollTime.Format(time.RFC3339))
+	}
+	if r.rolloutStatus.GetCondition(v1alpha1.RolloutWaiting).Message != reason {
+		klog.InfoS("the rollout waits for its schedule", "current batch", r.rolloutStatus.CurrentBatch,
+			"reason", reason)
+		r.recorder.Event(r.parentController, event.Normal("Rollout waiting", reason))
+		cond := v1alpha1.NewPositiveCondition(v1alpha1.RolloutWaiting)
+		cond.Message = reason
+		r.rolloutStatus.SetConditions(cond)
+	}
+	return true
+}
+
 func (r *Controller) finalizeOneBatch(ctx context.Context) {
-	rolloutHooks := r.gatherAllWebhooks()
 	// call all the post-batch rollout webhooks
-	for _, rh := range rolloutHooks {
-		if rh.Type == v1alpha1.PostBatchRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(v1alpha1.BatchFinalizingState), rh)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rh.Name, "webhook end point", rh.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a post batch webhook", "webhook name", rh.Name, "webhook end point",
-				rh.URL)
+	decision, reason, err := r.callWebhooks(ctx, r.gatherAllWebhooks(), v1alpha1.PostBatchRolloutHook,
+		string(v1alpha1.BatchFinalizingState))
+	if err != nil {
+		if automaticRollback(r.rolloutSpec) {
+			// the batch is rejected, there is no point to wait since the rollout will be rolled back
+			r.failOneBatch(err.Error())
+			return
 		}
+		r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
+		return
+	}
+	switch decision {
+	case v1alpha1.PauseWebhookDecision:
+		r.pauseByWebhook(reason)
+		return
+	case v1alpha1.AbortWebhookDecision:
+		r.failOneBatch(reason)
+		return
 	}
 	// calculate the next phase
 	currentBatch := int(r.rolloutStatus.CurrentBatch)
@@ -306,57 +545,81 @@ func (r *Controller) finalizeOneBatch(ctx context.Context) {
 // all the common finalize work after we rollout
 func (r *Controller) finalizeRollout(ctx context.Context) {
 	// call the post-rollout webhooks
-	for _, rw := range r.rolloutSpec.RolloutWebhooks {
-		if rw.Type == v1alpha1.FinalizeRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(r.rolloutStatus.RollingState), rw)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rw.Name, "webhook end point", rw.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a post rollout webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a post rollout webhook", "webhook name", rw.Name, "webhook end point",
-				rw.URL)
-		}
+	decision, reason, err := r.callWebhooks(ctx, r.rolloutSpec.RolloutWebhooks, v1alpha1.FinalizeRolloutHook,
+		string(r.rolloutStatus.RollingState))
+	if err != nil {
+		r.rolloutStatus.RolloutRetry("failed to invoke a post rollout webhook")
+		return
+	}
+	switch decision {
+	case v1alpha1.PauseWebhookDecision:
+		r.pauseByWebhook(reason)
+		return
+	case v1alpha1.AbortWebhookDecision:
+		// the workloads are already finalized, it's too late to abort the rollout
+		r.recorder.Event(r.parentController, event.Warning("Rollout not aborted", errors.New(reason)))
 	}
 	r.rolloutStatus.StateTransition(v1alpha1.RollingFinalizedEvent)
 }
 
-// GetWorkloadController pick the right workload controller to work on the workload
-func (r *Controller) GetWorkloadController() (workloads.WorkloadController, error) {
-	kind := r.targetWorkload.GetObjectKind().GroupVersionKind().Kind
-	target := types.NamespacedName{
-		Namespace: r.targetWorkload.GetNamespace(),
-		Name:      r.targetWorkload.GetName(),
-	}
-	var source types.NamespacedName
-	if r.sourceWorkload != nil {
-		source.Namespace = r.sourceWorkload.GetNamespace()
-		source.Name = r.sourceWorkload.GetName()
+// GetTrafficController picks the traffic provider that routes the traffic between the workloads. There is no traffic
+// to route if the rollout plan has no traffic routing or it's only a scale event.
+func (r *Controller) GetTrafficController() (traffic.Controller, error) {
+	if r.rolloutSpec.TrafficRouting == nil || r.sourceWorkload == nil {
+		return nil, nil
 	}
+	return traffic.NewTrafficController(r.client, r.parentController, r.rolloutSpec.TrafficRouting,
+		r.sourceWorkload, r.targetWorkload)
+}
 
-	if r.targetWorkload.GroupVersionKind().Group == kruisev1.GroupVersion.Group {
-		if r.targetWorkload.GetKind() == reflect.TypeOf(kruisev1.CloneSet{}).Name() {
-			// check whether current rollout plan is for workload rolling or scaling
-			if r.sourceWorkload != nil {
-				return workloads.NewCloneSetRolloutController(r.client, r.recorder, r.parentController,
-					r.rolloutSpec, r.rolloutStatus, target), nil
-			}
-			return workloads.NewCloneSetScaleController(r.client, r.recorder, r.parentController,
-				r.rolloutSpec, r.rolloutStatus, target), nil
-		}
+// GetWorkloadController pick the right workload controller to work on the workload, the workload kinds that have
+// no registered controller are rolled out following their WorkloadDefinition
+func (r *Controller) GetWorkloadController(ctx context.Context) (workloads.WorkloadController, error) {
+	args := workloads.ControllerArgs{
+		Client:           r.client,
+		Recorder:         r.recorder,
+		ParentController: r.parentController,
+		RolloutSpec:      r.rolloutSpec,
+		RolloutStatus:    r.rolloutStatus,
+		SourceWorkload:   r.sourceWorkload,
+		TargetWorkload:   r.targetWorkload,
+	}
+	if factory, ok := workloads.GetControllerFactory(r.targetWorkload.GroupVersionKind().GroupKind()); ok {
+		return factory(args)
+	}
+	definition, err := r.getWorkloadDefinition(ctx)
+	if err != nil {
+		return nil, err
 	}
+	if definition == nil {
+		return nil, fmt.Errorf("the workload kind `%s` is not supported", r.targetWorkload.GetKind())
+	}
+	return workloads.NewDeclarativeController(args, definition.Spec)
+}
 
-	if r.targetWorkload.GroupVersionKind().Group == apps.GroupName {
-		if r.targetWorkload.GetKind() == reflect.TypeOf(apps.Deployment{}).Name() {
-			// check whether current rollout plan is for workload rolling or scaling
-			if r.sourceWorkload != nil {
-				return workloads.NewDeploymentController(r.client, r.recorder, r.parentController,
-					r.rolloutSpec, r.rolloutStatus, source, target), nil
-			}
-			return workloads.NewDeploymentScaleController(r.client, r.recorder, r.parentController,
-				r.rolloutSpec, r.rolloutStatus, target), nil
+// getWorkloadDefinition finds the WorkloadDefinition of the target workload by its workload type, which is either
+// the name of the WorkloadDefinition or a ComponentDefinition whose workload type is the WorkloadDefinition.
+// It returns nil if there is no such WorkloadDefinition.
+func (r *Controller) getWorkloadDefinition(ctx context.Context) (*v1beta1.WorkloadDefinition, error) {
+	workloadType := r.targetWorkload.GetLabels()[oam.WorkloadTypeLabel]
+	if len(workloadType) == 0 {
+		return nil, nil
+	}
+	ctx = oamutil.SetNamespaceInCtx(ctx, r.targetWorkload.GetNamespace())
+	componentDefinition := &v1beta1.ComponentDefinition{}
+	err := oamutil.GetDefinition(ctx, r.client, componentDefinition, workloadType)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get the component definition `%s`", workloadType)
+	}
+	if err == nil && len(componentDefinition.Spec.Workload.Type) != 0 {
+		workloadType = componentDefinition.Spec.Workload.Type
+	}
+	workloadDefinition := &v1beta1.WorkloadDefinition{}
+	if err := oamutil.GetDefinition(ctx, r.client, workloadDefinition, workloadType); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
 		}
+		return nil, errors.Wrapf(err, "failed to get the workload definition `%s`", workloadType)
 	}
-	return nil, fmt.Errorf("the workload kind `%s` is not supported", kind)
+	return workloadDefinition, nil
 }
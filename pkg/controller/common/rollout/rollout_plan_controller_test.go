@@ -17,11 +17,28 @@ limitations under the License.
 package rollout
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/workloads"
+	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
 func Test_TryMovingToNextBatch(t *testing.T) {
@@ -63,3 +80,237 @@ func Test_TryMovingToNextBatch(t *testing.T) {
 		})
 	}
 }
+
+func TestFinalizeOneBatchWebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	tests := map[string]struct {
+		rollbackPolicy *v1alpha1.RollbackPolicy
+		wantState      v1alpha1.RollingState
+		wantBatch      v1alpha1.BatchRollingState
+	}{
+		"retried without automatic rollback": {
+			wantState: v1alpha1.RollingInBatchesState,
+			wantBatch: v1alpha1.BatchFinalizingState,
+		},
+		"failed with automatic rollback": {
+			rollbackPolicy: &v1alpha1.RollbackPolicy{Automatic: true},
+			wantState:      v1alpha1.RolloutFailingState,
+			wantBatch:      v1alpha1.BatchInitializingState,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := newMetricController(&v1alpha1.RolloutPlan{
+				RollbackPolicy: tt.rollbackPolicy,
+				RolloutBatches: []v1alpha1.RolloutBatch{{}, {BatchRolloutWebhooks: []v1alpha1.RolloutWebhook{{
+					Type: v1alpha1.PostBatchRolloutHook,
+					Name: "approval",
+					URL:  server.URL,
+				}}}},
+			})
+			r.rolloutStatus.BatchRollingState = v1alpha1.BatchFinalizingState
+			r.finalizeOneBatch(context.Background())
+			assert.Equal(t, tt.wantState, r.rolloutStatus.RollingState)
+			assert.Equal(t, tt.wantBatch, r.rolloutStatus.BatchRollingState)
+		})
+	}
+}
+
+func TestBatchTrafficWeight(t *testing.T) {
+	tests := map[string]struct {
+		routing      *v1alpha1.TrafficRouting
+		batchWeight  *int32
+		upgraded     int32
+		targetSize   int32
+		wantedWeight int32
+	}{
+		"no traffic routing": {
+			upgraded:     2,
+			targetSize:   4,
+			wantedWeight: 0,
+		},
+		"proportional to the upgraded pods": {
+			routing:      &v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficProvider},
+			upgraded:     1,
+			targetSize:   3,
+			wantedWeight: 33,
+		},
+		"specified by the batch": {
+			routing:      &v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficProvider},
+			batchWeight:  pointer.Int32Ptr(10),
+			upgraded:     2,
+			targetSize:   4,
+			wantedWeight: 10,
+		},
+		"all the pods are upgraded": {
+			routing:      &v1alpha1.TrafficRouting{Provider: v1alpha1.SMITrafficProvider},
+			upgraded:     4,
+			targetSize:   4,
+			wantedWeight: 100,
+		},
+		"blue green": {
+			routing: &v1alpha1.TrafficRouting{Provider: v1alpha1.ServiceTrafficProvider,
+				Strategy: v1alpha1.BlueGreenTrafficRoutingStrategyType},
+			batchWeight:  pointer.Int32Ptr(100),
+			upgraded:     4,
+			targetSize:   4,
+			wantedWeight: 0,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := newMetricController(&v1alpha1.RolloutPlan{
+				TrafficRouting: tt.routing,
+				RolloutBatches: []v1alpha1.RolloutBatch{{}, {TrafficWeight: tt.batchWeight}},
+			})
+			r.rolloutStatus.UpgradedReplicas = tt.upgraded
+			r.rolloutStatus.RolloutTargetSize = tt.targetSize
+			assert.Equal(t, tt.wantedWeight, r.batchTrafficWeight())
+		})
+	}
+}
+
+func TestInitializeOneBatchWebhookDecision(t *testing.T) {
+	decision := `{"decision": "pause", "message": "out of business hours"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(decision))
+	}))
+	defer server.Close()
+
+	r := newMetricController(&v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{}, {BatchRolloutWebhooks: []v1alpha1.RolloutWebhook{{
+			Type: v1alpha1.PreBatchRolloutHook,
+			Name: "verification",
+			URL:  server.URL,
+		}}}},
+	})
+	r.rolloutStatus.BatchRollingState = v1alpha1.BatchInitializingState
+	r.initializeOneBatch(context.Background())
+	assert.Equal(t, v1alpha1.BatchInitializingState, r.rolloutStatus.BatchRollingState)
+	paused := r.rolloutStatus.GetCondition(v1alpha1.RolloutPaused)
+	assert.Equal(t, corev1.ConditionTrue, paused.Status)
+	assert.Contains(t, paused.Message, "out of business hours")
+
+	decision = `{"decision": "proceed"}`
+	r.initializeOneBatch(context.Background())
+	assert.Equal(t, v1alpha1.BatchInRollingState, r.rolloutStatus.BatchRollingState)
+	assert.Equal(t, corev1.ConditionUnknown, r.rolloutStatus.GetCondition(v1alpha1.RolloutPaused).Status)
+
+	decision = `{"decision": "abort", "message": "error budget exhausted"}`
+	r.rolloutStatus.BatchRollingState = v1alpha1.BatchInitializingState
+	r.initializeOneBatch(context.Background())
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+	assert.Equal(t, v1alpha1.BatchRolloutFailedState, r.rolloutStatus.BatchRollingState)
+}
+
+func TestTryMovingToNextBatchWithSchedule(t *testing.T) {
+	r := newMetricController(&v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{}, {}, {}},
+		Schedule:       &v1alpha1.RolloutSchedule{SoakSeconds: pointer.Int32Ptr(600)},
+	})
+	r.rolloutStatus.BatchRollingState = v1alpha1.BatchReadyState
+	r.rolloutStatus.BatchReadyTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	r.tryMovingToNextBatch()
+	assert.Equal(t, int32(1), r.rolloutStatus.CurrentBatch)
+	assert.Equal(t, v1alpha1.BatchReadyState, r.rolloutStatus.BatchRollingState)
+	waiting := r.rolloutStatus.GetCondition(v1alpha1.RolloutWaiting)
+	assert.Equal(t, corev1.ConditionTrue, waiting.Status)
+	assert.Contains(t, waiting.Message, "the rollout waits until")
+
+	// the batch has soaked for long enough
+	r.rolloutStatus.BatchReadyTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	r.tryMovingToNextBatch()
+	assert.Equal(t, int32(2), r.rolloutStatus.CurrentBatch)
+	assert.Equal(t, v1alpha1.BatchInitializingState, r.rolloutStatus.BatchRollingState)
+	assert.Equal(t, corev1.ConditionUnknown, r.rolloutStatus.GetCondition(v1alpha1.RolloutWaiting).Status)
+
+	// the deployment window is closed
+	r.rolloutSpec.Schedule.Windows = []v1alpha1.RolloutWindow{{
+		Schedule: fmt.Sprintf("0 0 %d * *", time.Now().UTC().Add(72*time.Hour).Day()),
+		Duration: "1h",
+	}}
+	r.rolloutStatus.BatchRollingState = v1alpha1.BatchReadyState
+	r.tryMovingToNextBatch()
+	assert.Equal(t, int32(2), r.rolloutStatus.CurrentBatch)
+	assert.Equal(t, corev1.ConditionTrue, r.rolloutStatus.GetCondition(v1alpha1.RolloutWaiting).Status)
+}
+
+func TestGetWorkloadController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1beta1.SchemeBuilder.AddToScheme(scheme))
+	webAppDef := &v1beta1.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "webapp", Namespace: oam.SystemDefinitonNamespace},
+		Spec: v1beta1.WorkloadDefinitionSpec{
+			Reference:         common.DefinitionReference{Name: "webapps.example.com"},
+			ReplicasPath:      "spec.replicas",
+			ReadyReplicasPath: "status.readyReplicas",
+		},
+	}
+	webServiceDef := &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "webservice", Namespace: "default"},
+		Spec:       v1beta1.ComponentDefinitionSpec{Workload: common.WorkloadTypeDescriptor{Type: "webapp"}},
+	}
+	newWorkload := func(apiVersion, kind, workloadType string) *unstructured.Unstructured {
+		workload := &unstructured.Unstructured{}
+		workload.SetAPIVersion(apiVersion)
+		workload.SetKind(kind)
+		workload.SetNamespace("default")
+		workload.SetName("frontend-v2")
+		if len(workloadType) != 0 {
+			workload.SetLabels(map[string]string{oam.WorkloadTypeLabel: workloadType})
+		}
+		return workload
+	}
+	tests := map[string]struct {
+		target  *unstructured.Unstructured
+		source  *unstructured.Unstructured
+		want    interface{}
+		wantErr bool
+	}{
+		"registered kind": {
+			target: newWorkload("apps/v1", "Deployment", ""),
+			source: newWorkload("apps/v1", "Deployment", ""),
+			want:   &workloads.DeploymentRolloutController{},
+		},
+		"registered kind to scale": {
+			target: newWorkload("apps/v1", "Deployment", ""),
+			want:   &workloads.DeploymentScaleController{},
+		},
+		"workload definition": {
+			target: newWorkload("example.com/v1", "WebApp", "webapp"),
+			source: newWorkload("example.com/v1", "WebApp", "webapp"),
+			want:   &workloads.DeclarativeRolloutController{},
+		},
+		"component definition": {
+			target: newWorkload("example.com/v1", "WebApp", "webservice"),
+			want:   &workloads.DeclarativeScaleController{},
+		},
+		"no definition": {
+			target:  newWorkload("example.com/v1", "WebApp", "unknown"),
+			wantErr: true,
+		},
+		"no workload type": {
+			target:  newWorkload("example.com/v1", "WebApp", ""),
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := NewRolloutPlanController(fake.NewFakeClientWithScheme(scheme, webAppDef, webServiceDef),
+				&v1beta1.AppRollout{}, event.NewNopRecorder(), &v1alpha1.RolloutPlan{}, &v1alpha1.RolloutStatus{},
+				tt.target, tt.source)
+			controller, err := r.GetWorkloadController(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.IsType(t, tt.want, controller)
+		})
+	}
+}
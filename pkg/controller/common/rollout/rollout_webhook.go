@@ -19,46 +19,71 @@ package rollout
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 )
 
-// issue an http call to the an end ponit
-func makeHTTPRequest(ctx context.Context, webhookEndPoint, method string, payload interface{}) ([]byte, int, error) {
-	payloadBin, err := json.Marshal(payload)
-	if err != nil {
-		return nil, http.StatusInternalServerError, err
-	}
+const (
+	// the default timeout of each webhook request
+	defaultWebhookTimeout = 10 * time.Second
 
-	hook, err := url.Parse(webhookEndPoint)
-	if err != nil {
-		return nil, http.StatusInternalServerError, err
-	}
+	// SignatureHeader is the header of the HMAC-SHA256 signature of the timestamp and body of the webhook request
+	SignatureHeader = "X-Vela-Signature-256"
+	// TimestampHeader is the header of the unix timestamp when the webhook request is signed, the receivers
+	// should reject the requests with stale timestamps to prevent replay
+	TimestampHeader = "X-Vela-Timestamp"
+
+	// the key of the CA certificate in the secret for mutual TLS
+	caCertKey = "ca.crt"
+)
 
-	req, err := http.NewRequestWithContext(context.Background(), method, hook.String(), bytes.NewBuffer(payloadBin))
+// issue an http call to the an end ponit
+func makeHTTPRequest(ctx context.Context, httpClient *http.Client, backoff wait.Backoff, webhookEndPoint,
+	method string, header http.Header, payloadBin []byte) ([]byte, int, error) {
+	hook, err := url.Parse(webhookEndPoint)
 	if err != nil {
 		return nil, http.StatusInternalServerError, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
 	// issue request with retry
 	var r *http.Response
 	var body []byte
-	err = retry.OnError(retry.DefaultBackoff,
+	err = retry.OnError(backoff,
 		func(error) bool {
 			// not sure what not to retry on
 			return true
 		}, func() error {
-			var requestErr error
-			r, requestErr = http.DefaultClient.Do(req.WithContext(ctx))
+			// each attempt needs a new request since the body is consumed
+			req, requestErr := http.NewRequestWithContext(ctx, method, hook.String(), bytes.NewReader(payloadBin))
+			if requestErr != nil {
+				return requestErr
+			}
+			for key, values := range header {
+				req.Header[key] = values
+			}
+			req.Header.Set("Content-Type", "application/json")
+			r, requestErr = httpClient.Do(req)
 			defer func() {
 				if r != nil {
 					_ = r.Body.Close()
@@ -87,9 +112,10 @@ func makeHTTPRequest(ctx context.Context, webhookEndPoint, method string, payloa
 	return body, r.StatusCode, nil
 }
 
-// callWebhook does a HTTP POST to an external service and
-// returns an error if the response status code is non-2xx
-func callWebhook(ctx context.Context, resource klog.KMetadata, phase string, rw v1alpha1.RolloutWebhook) error {
+// callWebhook does a HTTP POST to an external service and returns an error if the response status code is non-2xx.
+// Otherwise it returns the decision of the webhook in the response body, the rollout proceeds if there is none.
+func callWebhook(ctx context.Context, c client.Reader, resource klog.KMetadata, phase string,
+	rw v1alpha1.RolloutWebhook) (*v1alpha1.RolloutWebhookResponse, error) {
 	payload := v1alpha1.RolloutWebhookPayload{
 		Name:      resource.GetName(),
 		Namespace: resource.GetNamespace(),
@@ -99,20 +125,32 @@ func callWebhook(ctx context.Context, resource klog.KMetadata, phase string, rw
 	if rw.Metadata != nil {
 		payload.Metadata = *rw.Metadata
 	}
+	payloadBin, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
 	// make the http request
 	if len(rw.Method) == 0 {
 		rw.Method = http.MethodPost
 	}
-	_, status, err := makeHTTPRequest(ctx, rw.URL, rw.Method, payload)
+	httpClient, header, err := webhookClient(ctx, c, resource.GetNamespace(), rw, payloadBin)
+	if err != nil {
+		return nil, err
+	}
+	backoff := retry.DefaultBackoff
+	if rw.Retries != nil {
+		backoff.Steps = int(*rw.Retries) + 1
+	}
+	body, status, err := makeHTTPRequest(ctx, httpClient, backoff, rw.URL, rw.Method, header, payloadBin)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(rw.ExpectedStatus) == 0 {
 		if status > http.StatusAccepted {
 			err := fmt.Errorf("we fail the webhook request based on status, http status = %d", status)
-			return err
+			return nil, err
 		}
-		return nil
+		return webhookDecision(body)
 	}
 	// check if the returned status is expected
 	accepted := false
@@ -125,7 +163,141 @@ func callWebhook(ctx context.Context, resource klog.KMetadata, phase string, rw
 	if !accepted {
 		err := fmt.Errorf("http request to the webhook not accepeted, http status = %d", status)
 		klog.ErrorS(err, "The status is not expected", "expected status", rw.ExpectedStatus)
-		return err
+		return nil, err
+	}
+	return webhookDecision(body)
+}
+
+// webhookDecision parses the decision in the response body of a webhook. The rollout proceeds if the webhook doesn't
+// respond with a decision, but it fails with a decision not known.
+func webhookDecision(body []byte) (*v1alpha1.RolloutWebhookResponse, error) {
+	response := &v1alpha1.RolloutWebhookResponse{}
+	if err := json.Unmarshal(body, response); err != nil || len(response.Decision) == 0 {
+		return &v1alpha1.RolloutWebhookResponse{Decision: v1alpha1.ProceedWebhookDecision}, nil
+	}
+	switch response.Decision {
+	case v1alpha1.ProceedWebhookDecision, v1alpha1.PauseWebhookDecision, v1alpha1.AbortWebhookDecision:
+		return response, nil
+	}
+	return nil, fmt.Errorf("the webhook responds with an unknown decision `%s`", response.Decision)
+}
+
+// webhookClient returns the http client and headers to request the webhook according to its timeout and auth
+func webhookClient(ctx context.Context, c client.Reader, namespace string, rw v1alpha1.RolloutWebhook,
+	payloadBin []byte) (*http.Client, http.Header, error) {
+	httpClient := &http.Client{Timeout: defaultWebhookTimeout}
+	if rw.TimeoutSeconds != nil {
+		httpClient.Timeout = time.Duration(*rw.TimeoutSeconds) * time.Second
+	}
+	header := http.Header{}
+	auth := rw.Auth
+	if auth == nil {
+		return httpClient, header, nil
+	}
+	if auth.HMACSecretRef != nil {
+		key, err := secretValue(ctx, c, namespace, auth.HMACSecretRef)
+		if err != nil {
+			return nil, nil, err
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		header.Set(TimestampHeader, timestamp)
+		header.Set(SignatureHeader, "sha256="+signPayload(key, timestamp, payloadBin))
+	}
+	if auth.BearerTokenSecretRef != nil {
+		token, err := secretValue(ctx, c, namespace, auth.BearerTokenSecretRef)
+		if err != nil {
+			return nil, nil, err
+		}
+		header.Set("Authorization", "Bearer "+string(token))
+	}
+	if auth.TLSSecretRef != nil {
+		transport, err := tlsTransport(ctx, c, namespace, auth.TLSSecretRef.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		httpClient.Transport = transport
+	}
+	return httpClient, header, nil
+}
+
+// signPayload returns the hex encoded HMAC-SHA256 signature of "<timestamp>.<payload>", the timestamp is signed
+// together so that a captured request cannot be replayed later
+func signPayload(key []byte, timestamp string, payloadBin []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(payloadBin)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func secretValue(ctx context.Context, c client.Reader, namespace string,
+	selector *corev1.SecretKeySelector) ([]byte, error) {
+	var secret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: selector.Name}, &secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get the secret %s", selector.Name)
+	}
+	value, ok := secret.Data[selector.Key]
+	if !ok {
+		return nil, fmt.Errorf("the secret %s has no key %s", selector.Name, selector.Key)
+	}
+	return value, nil
+}
+
+// cachedTransport is the transport built from a version of the TLS secret
+type cachedTransport struct {
+	resourceVersion string
+	transport       *http.Transport
+}
+
+// tlsTransports caches the transports by the TLS secrets, so that the connections to the webhooks are reused
+// rather than leaked by a new transport for each request
+var tlsTransports = struct {
+	sync.Mutex
+	cache map[types.NamespacedName]cachedTransport
+}{cache: make(map[types.NamespacedName]cachedTransport)}
+
+// tlsTransport returns the transport for mutual TLS with the client certificate in the secret,
+// a new transport is only built once the secret is changed
+func tlsTransport(ctx context.Context, c client.Reader, namespace, secretName string) (*http.Transport, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: secretName}
+	var secret corev1.Secret
+	if err := c.Get(ctx, key, &secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get the secret %s", secretName)
+	}
+	tlsTransports.Lock()
+	defer tlsTransports.Unlock()
+	cached, ok := tlsTransports.cache[key]
+	if ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached.transport, nil
+	}
+	tlsConfig, err := clientTLSConfig(&secret)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cached.transport.CloseIdleConnections()
+	}
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	tlsTransports.cache[key] = cachedTransport{resourceVersion: secret.ResourceVersion, transport: transport}
+	return transport, nil
+}
+
+// clientTLSConfig loads the client certificate and the optional CA certificate for mutual TLS
+func clientTLSConfig(secret *corev1.Secret) (*tls.Config, error) {
+	secretName := secret.Name
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the client certificate in the secret %s", secretName)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if ca, ok := secret.Data[caCertKey]; ok {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to load the CA certificate in the secret %s", secretName)
+		}
+		tlsConfig.RootCAs = pool
 	}
-	return nil
+	return tlsConfig, nil
 }
@@ -18,15 +18,36 @@ package rollout
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
-	"math/rand"
+	"io/ioutil"
+	"math/big"
+	mathrand "math/rand"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/oam"
@@ -113,14 +134,16 @@ func TestMakeHTTPRequest(t *testing.T) {
 	}
 	for testName, tt := range tests {
 		func(testName string) {
-			mockUrl := mockUrlBase + strconv.FormatInt(rand.Int63n(128)+1000, 10)
+			mockUrl := mockUrlBase + strconv.FormatInt(mathrand.Int63n(128)+1000, 10)
 			// generate a test server so we can capture and inspect the request
 			testServer := NewMock(tt.httpParameter.method, mockUrl, tt.httpParameter.statusCode, tt.httpParameter.body)
 			defer testServer.Close()
 			if len(tt.url) == 0 {
 				tt.url = mockUrl
 			}
-			gotReply, gotCode, gotErr := makeHTTPRequest(ctx, "http://"+tt.url, tt.method, tt.payload)
+			payloadBin, _ := json.Marshal(tt.payload)
+			gotReply, gotCode, gotErr := makeHTTPRequest(ctx, http.DefaultClient, retry.DefaultBackoff, "http://"+tt.url,
+				tt.method, nil, payloadBin)
 			if gotCode != tt.want.statusCode {
 				t.Errorf("\n%s\nr.Reconcile(...): want code `%d`, got code:`%d` got err: %v \n", testName, tt.want.statusCode,
 					gotCode, gotErr)
@@ -207,13 +230,13 @@ func TestCallWebhook(t *testing.T) {
 	}
 	for name, tt := range tests {
 		func(name string) {
-			url := mockUrlBase + strconv.FormatInt(rand.Int63n(4848)+1000, 10)
+			url := mockUrlBase + strconv.FormatInt(mathrand.Int63n(4848)+1000, 10)
 			tt.args.rw.URL = "http://" + url
 			// generate a test server so we can capture and inspect the request
 			testServer := NewMock(http.MethodPost, url, tt.returnedStatusCode, body)
 			defer testServer.Close()
 
-			gotErr := callWebhook(ctx, tt.args.resource, tt.args.phase, tt.args.rw)
+			_, gotErr := callWebhook(ctx, nil, tt.args.resource, tt.args.phase, tt.args.rw)
 			if (tt.wantErr == nil && gotErr != nil) || (tt.wantErr != nil && gotErr == nil) {
 				t.Errorf("\n%s\nr.Reconcile(...): want error `%s`, got error:`%s`\n", name, tt.wantErr, gotErr)
 			}
@@ -242,3 +265,158 @@ func NewMock(method, mockUrl string, statusCode int, body string) *httptest.Serv
 	ts.Start()
 	return ts
 }
+
+func TestCallWebhookWithAuth(t *testing.T) {
+	ctx := context.TODO()
+	res := v1alpha1.PodSpecWorkload{ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-auth", Namespace: "namespace"},
+		Data:       map[string][]byte{"hmac": []byte("hmac-key"), "token": []byte("bearer-token")},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, secret)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		// the timestamp is signed together with the body, and a stale one is rejected
+		timestamp := req.Header.Get(TimestampHeader)
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		mac := hmac.New(sha256.New, []byte("hmac-key"))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		if err != nil || time.Since(time.Unix(unix, 0)) > time.Minute ||
+			req.Header.Get(SignatureHeader) != "sha256="+hex.EncodeToString(mac.Sum(nil)) ||
+			req.Header.Get("Authorization") != "Bearer bearer-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"decision": "pause", "message": "out of business hours"}`))
+	}))
+	defer server.Close()
+
+	rw := v1alpha1.RolloutWebhook{
+		Name: "verification",
+		URL:  server.URL,
+		Auth: &v1alpha1.WebhookAuth{
+			HMACSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "webhook-auth"}, Key: "hmac"},
+			BearerTokenSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "webhook-auth"}, Key: "token"},
+		},
+		Retries: pointer.Int32Ptr(0),
+	}
+	response, err := callWebhook(ctx, k8sClient, &res, string(v1alpha1.BatchInitializingState), rw)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.PauseWebhookDecision, response.Decision)
+	assert.Equal(t, "out of business hours", response.Message)
+
+	rw.Auth.BearerTokenSecretRef.Key = "hmac"
+	_, err = callWebhook(ctx, k8sClient, &res, string(v1alpha1.BatchInitializingState), rw)
+	assert.Error(t, err)
+
+	rw.Auth.BearerTokenSecretRef.Key = "missing"
+	_, err = callWebhook(ctx, k8sClient, &res, string(v1alpha1.BatchInitializingState), rw)
+	assert.Error(t, err)
+}
+
+func TestSignPayload(t *testing.T) {
+	key, payload := []byte("hmac-key"), []byte(`{"name":"name"}`)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(`1600000000.{"name":"name"}`))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), signPayload(key, "1600000000", payload))
+	// the signature of a replayed body doesn't match a new timestamp
+	assert.NotEqual(t, signPayload(key, "1600000000", payload), signPayload(key, "1600000060", payload))
+}
+
+func TestCallWebhookWithMutualTLS(t *testing.T) {
+	ctx := context.TODO()
+	res := v1alpha1.PodSpecWorkload{ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace"}}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "vela-core"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-tls", Namespace: "namespace"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+			caCertKey:               pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+		},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, secret)
+
+	rw := v1alpha1.RolloutWebhook{
+		Name:    "verification",
+		URL:     server.URL,
+		Retries: pointer.Int32Ptr(0),
+	}
+	_, err = callWebhook(ctx, k8sClient, &res, string(v1alpha1.BatchInitializingState), rw)
+	assert.Error(t, err, "the server can not be verified without the CA")
+
+	rw.Auth = &v1alpha1.WebhookAuth{TLSSecretRef: &corev1.LocalObjectReference{Name: "webhook-tls"}}
+	response, err := callWebhook(ctx, k8sClient, &res, string(v1alpha1.BatchInitializingState), rw)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.ProceedWebhookDecision, response.Decision)
+
+	// the transport is reused until the secret is changed
+	transport, err := tlsTransport(ctx, k8sClient, "namespace", "webhook-tls")
+	require.NoError(t, err)
+	again, err := tlsTransport(ctx, k8sClient, "namespace", "webhook-tls")
+	require.NoError(t, err)
+	assert.Same(t, transport, again)
+	secret.Labels = map[string]string{"rotated": "true"}
+	require.NoError(t, k8sClient.Update(ctx, secret))
+	again, err = tlsTransport(ctx, k8sClient, "namespace", "webhook-tls")
+	require.NoError(t, err)
+	assert.NotSame(t, transport, again)
+}
+
+func TestWebhookDecision(t *testing.T) {
+	tests := map[string]struct {
+		body         string
+		wantDecision v1alpha1.WebhookDecisionType
+		wantErr      bool
+	}{
+		"no body": {
+			wantDecision: v1alpha1.ProceedWebhookDecision,
+		},
+		"not a decision": {
+			body:         "all good",
+			wantDecision: v1alpha1.ProceedWebhookDecision,
+		},
+		"abort": {
+			body:         `{"decision": "abort", "message": "error budget exhausted"}`,
+			wantDecision: v1alpha1.AbortWebhookDecision,
+		},
+		"unknown decision": {
+			body:    `{"decision": "retry"}`,
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			response, err := webhookDecision([]byte(tt.body))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDecision, response.Decision)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"fmt"
+	"time"
+	// the controller image doesn't ship the time zone database
+	_ "time/tzdata"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// Schedule tells when a rollout is allowed to roll
+type Schedule struct {
+	startAt  time.Time
+	location *time.Location
+	windows  []window
+}
+
+// window opens when its cron expression fires and stays open for its duration
+type window struct {
+	cron     *Cron
+	duration time.Duration
+}
+
+// NewSchedule parses the schedule of a rollout plan, a nil schedule allows the rollout to roll at any time
+func NewSchedule(spec *v1alpha1.RolloutSchedule) (*Schedule, error) {
+	s := &Schedule{location: time.UTC}
+	if spec == nil {
+		return s, nil
+	}
+	if spec.StartAt != nil {
+		s.startAt = spec.StartAt.Time
+	}
+	if len(spec.TimeZone) != 0 {
+		location, err := time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "the time zone `%s` is invalid", spec.TimeZone)
+		}
+		s.location = location
+	}
+	for _, w := range spec.Windows {
+		cron, err := ParseCron(w.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := time.ParseDuration(w.Duration)
+		if err != nil {
+			return nil, errors.Wrapf(err, "the duration `%s` of the window is invalid", w.Duration)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("the duration `%s` of the window is not positive", w.Duration)
+		}
+		s.windows = append(s.windows, window{cron: cron, duration: duration})
+	}
+	return s, nil
+}
+
+// NextRollTime returns the earliest time, not before t, that the rollout is allowed to roll. It's t itself if the
+// rollout can roll at t, and it's the zero time if none of the windows ever opens.
+func (s *Schedule) NextRollTime(t time.Time) time.Time {
+	if t.Before(s.startAt) {
+		t = s.startAt
+	}
+	if len(s.windows) == 0 {
+		return t
+	}
+	t = t.In(s.location)
+	var next time.Time
+	for _, w := range s.windows {
+		// the window is open if it opened within its duration before t
+		opensAt := w.cron.Next(t.Add(-w.duration))
+		if opensAt.IsZero() {
+			continue
+		}
+		if !opensAt.After(t) {
+			return t
+		}
+		if next.IsZero() || opensAt.Before(next) {
+			next = opensAt
+		}
+	}
+	return next
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{"* * * * *", "0 9 * * 1-5", "*/15 9-17 1,15 jan-mar MON-fri", "30 2 * * 7",
+		"5/10 * * * *"} {
+		_, err := ParseCron(expr)
+		assert.NoError(t, err, expr)
+	}
+	for _, expr := range []string{"", "0 9 * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2021-06-02 is a Wednesday
+	now := time.Date(2021, 6, 2, 10, 30, 15, 0, time.UTC)
+	tests := map[string]struct {
+		expr string
+		want time.Time
+	}{
+		"every minute": {
+			expr: "* * * * *",
+			want: time.Date(2021, 6, 2, 10, 31, 0, 0, time.UTC),
+		},
+		"later today": {
+			expr: "0 17 * * *",
+			want: time.Date(2021, 6, 2, 17, 0, 0, 0, time.UTC),
+		},
+		"tomorrow": {
+			expr: "0 9 * * *",
+			want: time.Date(2021, 6, 3, 9, 0, 0, 0, time.UTC),
+		},
+		"step": {
+			expr: "*/20 * * * *",
+			want: time.Date(2021, 6, 2, 10, 40, 0, 0, time.UTC),
+		},
+		"next weekend": {
+			expr: "0 0 * * sat,sun",
+			want: time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC),
+		},
+		"sunday as 7": {
+			expr: "0 0 * * 7",
+			want: time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC),
+		},
+		"either day of month or day of week": {
+			expr: "0 0 4 * 1",
+			want: time.Date(2021, 6, 4, 0, 0, 0, 0, time.UTC),
+		},
+		"next year": {
+			expr: "0 0 1 1 *",
+			want: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"never": {
+			expr: "0 0 30 2 *",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cron.Next(now))
+		})
+	}
+}
+
+func TestNextRollTime(t *testing.T) {
+	// 2021-06-02 is a Wednesday
+	now := time.Date(2021, 6, 2, 10, 30, 0, 0, time.UTC)
+	businessHours := []v1alpha1.RolloutWindow{{Schedule: "0 9 * * mon-fri", Duration: "8h"}}
+	tests := map[string]struct {
+		spec *v1alpha1.RolloutSchedule
+		now  time.Time
+		want time.Time
+	}{
+		"no schedule": {
+			now:  now,
+			want: now,
+		},
+		"not started yet": {
+			spec: &v1alpha1.RolloutSchedule{StartAt: &metav1.Time{Time: now.Add(time.Hour)}},
+			now:  now,
+			want: now.Add(time.Hour),
+		},
+		"started": {
+			spec: &v1alpha1.RolloutSchedule{StartAt: &metav1.Time{Time: now.Add(-time.Hour)}},
+			now:  now,
+			want: now,
+		},
+		"in business hours": {
+			spec: &v1alpha1.RolloutSchedule{Windows: businessHours},
+			now:  now,
+			want: now,
+		},
+		"after business hours": {
+			spec: &v1alpha1.RolloutSchedule{Windows: businessHours},
+			now:  time.Date(2021, 6, 2, 17, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 3, 9, 0, 0, 0, time.UTC),
+		},
+		"on the weekend": {
+			spec: &v1alpha1.RolloutSchedule{Windows: businessHours},
+			now:  time.Date(2021, 6, 5, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 7, 9, 0, 0, 0, time.UTC),
+		},
+		"business hours in another time zone": {
+			spec: &v1alpha1.RolloutSchedule{Windows: businessHours, TimeZone: "Asia/Shanghai"},
+			now:  now,
+			want: time.Date(2021, 6, 3, 1, 0, 0, 0, time.UTC),
+		},
+		"the earliest window": {
+			spec: &v1alpha1.RolloutSchedule{Windows: []v1alpha1.RolloutWindow{
+				{Schedule: "0 14 * * *", Duration: "1h"},
+				{Schedule: "0 12 * * *", Duration: "1h"},
+			}},
+			now:  now,
+			want: time.Date(2021, 6, 2, 12, 0, 0, 0, time.UTC),
+		},
+		"start in a window": {
+			spec: &v1alpha1.RolloutSchedule{Windows: businessHours,
+				StartAt: &metav1.Time{Time: time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)}},
+			now:  now,
+			want: time.Date(2021, 6, 7, 9, 0, 0, 0, time.UTC),
+		},
+		"never": {
+			spec: &v1alpha1.RolloutSchedule{Windows: []v1alpha1.RolloutWindow{{Schedule: "0 0 30 2 *", Duration: "1h"}}},
+			now:  now,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSchedule(tt.spec)
+			require.NoError(t, err)
+			got := s.NextRollTime(tt.now)
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+		})
+	}
+
+	for _, spec := range []*v1alpha1.RolloutSchedule{
+		{TimeZone: "Mars/Olympus_Mons"},
+		{Windows: []v1alpha1.RolloutWindow{{Schedule: "0 9 * *", Duration: "8h"}}},
+		{Windows: []v1alpha1.RolloutWindow{{Schedule: "0 9 * * *", Duration: "8 hours"}}},
+		{Windows: []v1alpha1.RolloutWindow{{Schedule: "0 9 * * *", Duration: "0s"}}},
+	} {
+		_, err := NewSchedule(spec)
+		assert.Error(t, err)
+	}
+}
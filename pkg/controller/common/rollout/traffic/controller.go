@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+)
+
+// Controller is the interface that all the traffic providers implement
+type Controller interface {
+	// VerifySpec makes sure that the traffic can be routed between the source and target workloads
+	VerifySpec(ctx context.Context) error
+
+	// RouteTraffic routes the weight percentage of the traffic to the target workload and the rest to the source
+	// workload, the weight is between 0 and 100
+	RouteTraffic(ctx context.Context, weight int32) error
+}
+
+// trafficController holds the fields shared by all the traffic providers
+type trafficController struct {
+	client           client.Client
+	parentController oam.Object
+
+	// service is the service that receives the traffic of the workloads
+	service types.NamespacedName
+	source  *unstructured.Unstructured
+	target  *unstructured.Unstructured
+}
+
+// NewTrafficController picks the traffic provider that routes the traffic between the source and target workloads
+func NewTrafficController(client client.Client, parentController oam.Object, routing *v1alpha1.TrafficRouting,
+	sourceWorkload, targetWorkload *unstructured.Unstructured) (Controller, error) {
+	serviceName := routing.Service
+	if len(serviceName) == 0 {
+		serviceName = targetWorkload.GetLabels()[oam.LabelAppComponent]
+	}
+	if len(serviceName) == 0 {
+		return nil, fmt.Errorf("the service of the workload `%s` to route the traffic is not specified",
+			targetWorkload.GetName())
+	}
+	c := trafficController{
+		client:           client,
+		parentController: parentController,
+		service:          types.NamespacedName{Namespace: targetWorkload.GetNamespace(), Name: serviceName},
+		source:           sourceWorkload,
+		target:           targetWorkload,
+	}
+	switch routing.Provider {
+	case v1alpha1.IstioTrafficProvider:
+		return &istioController{trafficController: c}, nil
+	case v1alpha1.SMITrafficProvider:
+		return &smiController{trafficController: c}, nil
+	case v1alpha1.ServiceTrafficProvider:
+		return &serviceController{trafficController: c}, nil
+	}
+	return nil, fmt.Errorf("the traffic provider `%s` is not supported", routing.Provider)
+}
+
+// VerifySpec checks that the service exists and it's possible to select the pods of the source and target
+// workloads separately
+func (c *trafficController) VerifySpec(ctx context.Context) error {
+	if _, err := c.fetchService(ctx); err != nil {
+		return err
+	}
+	sourceLabels, targetLabels := podLabels(c.source), podLabels(c.target)
+	if len(sourceLabels) == 0 || len(targetLabels) == 0 ||
+		labels.SelectorFromSet(sourceLabels).Matches(labels.Set(targetLabels)) ||
+		labels.SelectorFromSet(targetLabels).Matches(labels.Set(sourceLabels)) {
+		return fmt.Errorf("the pods of the source workload `%s` and the target workload `%s` can not be told "+
+			"apart by their labels", c.source.GetName(), c.target.GetName())
+	}
+	return nil
+}
+
+func (c *trafficController) fetchService(ctx context.Context) (*corev1.Service, error) {
+	var svc corev1.Service
+	if err := c.client.Get(ctx, c.service, &svc); err != nil {
+		return nil, errors.Wrapf(err, "failed to get the service %s", c.service.Name)
+	}
+	return &svc, nil
+}
+
+// applyWorkloadServices applies a service for each of the source and target workloads so that the traffic can be
+// split between them, the services have the same ports as the service of the workloads
+func (c *trafficController) applyWorkloadServices(ctx context.Context) error {
+	svc, err := c.fetchService(ctx)
+	if err != nil {
+		return err
+	}
+	applicator := apply.NewAPIApplicator(c.client)
+	for _, workload := range []*unstructured.Unstructured{c.source, c.target} {
+		if err := applicator.Apply(ctx, c.makeWorkloadService(svc, workload)); err != nil {
+			return errors.Wrapf(err, "failed to apply the service of the workload %s", workload.GetName())
+		}
+	}
+	return nil
+}
+
+func (c *trafficController) makeWorkloadService(svc *corev1.Service, workload *unstructured.Unstructured) *corev1.Service {
+	workloadSvc := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workloadServiceName(workload),
+			Namespace: c.service.Namespace,
+			Labels: map[string]string{
+				oam.LabelAppComponent: c.target.GetLabels()[oam.LabelAppComponent],
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: podLabels(workload),
+		},
+	}
+	for _, port := range svc.Spec.Ports {
+		port.NodePort = 0
+		workloadSvc.Spec.Ports = append(workloadSvc.Spec.Ports, port)
+	}
+	c.setOwner(workloadSvc)
+	return workloadSvc
+}
+
+// setOwner makes the rollout own the objects it generates to route the traffic
+func (c *trafficController) setOwner(obj metav1.Object) {
+	obj.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(c.parentController, v1beta1.AppRolloutKindVersionKind)})
+}
+
+// workloadServiceName is the name of the service that only selects the pods of the workload
+func workloadServiceName(workload *unstructured.Unstructured) string {
+	return workload.GetName()
+}
+
+// podLabels returns the labels of the pod template of the workload
+func podLabels(workload *unstructured.Unstructured) map[string]string {
+	podLabels, _, _ := unstructured.NestedStringMap(workload.Object, "spec", "template", "metadata", "labels")
+	return podLabels
+}
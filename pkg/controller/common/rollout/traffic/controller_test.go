@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	istioclientv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+var testAppRollout = &v1beta1.AppRollout{
+	ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: "default", UID: "rollout-uid"},
+}
+
+func newTestWorkload(name, revision string) *unstructured.Unstructured {
+	workload := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"app": "frontend", "revision": revision},
+				},
+			},
+		},
+	}}
+	workload.SetName(name)
+	workload.SetNamespace("default")
+	workload.SetLabels(map[string]string{oam.LabelAppComponent: "frontend"})
+	return workload
+}
+
+func newTestClient(t *testing.T) client.Client {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, istioclientv1beta1.AddToScheme(scheme))
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "frontend"},
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+		},
+	}
+	return fake.NewFakeClientWithScheme(scheme, svc)
+}
+
+func TestVerifySpec(t *testing.T) {
+	ctx := context.Background()
+	routing := &v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficProvider}
+	c, err := NewTrafficController(newTestClient(t), testAppRollout, routing,
+		newTestWorkload("frontend-v1", "v1"), newTestWorkload("frontend-v2", "v2"))
+	require.NoError(t, err)
+	assert.NoError(t, c.VerifySpec(ctx))
+
+	// the pods of an in-place upgraded workload can not be told apart
+	c, err = NewTrafficController(newTestClient(t), testAppRollout, routing,
+		newTestWorkload("frontend", "v1"), newTestWorkload("frontend", "v1"))
+	require.NoError(t, err)
+	assert.Error(t, c.VerifySpec(ctx))
+
+	routing.Service = "backend"
+	c, err = NewTrafficController(newTestClient(t), testAppRollout, routing,
+		newTestWorkload("frontend-v1", "v1"), newTestWorkload("frontend-v2", "v2"))
+	require.NoError(t, err)
+	assert.Error(t, c.VerifySpec(ctx))
+
+	_, err = NewTrafficController(newTestClient(t), testAppRollout, &v1alpha1.TrafficRouting{Provider: "nginx"},
+		newTestWorkload("frontend-v1", "v1"), newTestWorkload("frontend-v2", "v2"))
+	assert.Error(t, err)
+}
+
+func TestIstioRouteTraffic(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newTestClient(t)
+	c, err := NewTrafficController(k8sClient, testAppRollout,
+		&v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficProvider},
+		newTestWorkload("frontend-v1", "v1"), newTestWorkload("frontend-v2", "v2"))
+	require.NoError(t, err)
+	require.NoError(t, c.RouteTraffic(ctx, 20))
+	require.NoError(t, c.RouteTraffic(ctx, 40))
+
+	var svc corev1.Service
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "frontend-v2"}, &svc))
+	assert.Equal(t, map[string]string{"app": "frontend", "revision": "v2"}, svc.Spec.Selector)
+	assert.Equal(t, int32(80), svc.Spec.Ports[0].Port)
+	assert.Equal(t, int32(0), svc.Spec.Ports[0].NodePort)
+	assert.Equal(t, testAppRollout.UID, svc.GetOwnerReferences()[0].UID)
+
+	var vsvc istioclientv1beta1.VirtualService
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "frontend"}, &vsvc))
+	assert.Equal(t, []string{"frontend"}, vsvc.Spec.Hosts)
+	routes := vsvc.Spec.Http[0].Route
+	assert.Equal(t, "frontend-v1", routes[0].Destination.Host)
+	assert.Equal(t, int32(60), routes[0].Weight)
+	assert.Equal(t, "frontend-v2", routes[1].Destination.Host)
+	assert.Equal(t, int32(40), routes[1].Weight)
+}
+
+func TestSMIRouteTraffic(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newTestClient(t)
+	c, err := NewTrafficController(k8sClient, testAppRollout,
+		&v1alpha1.TrafficRouting{Provider: v1alpha1.SMITrafficProvider},
+		newTestWorkload("frontend-v1", "v1"), newTestWorkload("frontend-v2", "v2"))
+	require.NoError(t, err)
+	require.NoError(t, c.RouteTraffic(ctx, 30))
+
+	trafficSplit := &unstructured.Unstructured{}
+	trafficSplit.SetGroupVersionKind(TrafficSplitGroupVersionKind)
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "frontend"}, trafficSplit))
+	service, _, _ := unstructured.NestedString(trafficSplit.Object, "spec", "service")
+	assert.Equal(t, "frontend", service)
+	backends, _, _ := unstructured.NestedSlice(trafficSplit.Object, "spec", "backends")
+	require.Equal(t, 2, len(backends))
+	assert.Equal(t, "frontend-v1", backends[0].(map[string]interface{})["service"])
+	assert.EqualValues(t, 70, backends[0].(map[string]interface{})["weight"])
+	assert.Equal(t, "frontend-v2", backends[1].(map[string]interface{})["service"])
+	assert.EqualValues(t, 30, backends[1].(map[string]interface{})["weight"])
+}
+
+func TestServiceRouteTraffic(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newTestClient(t)
+	c, err := NewTrafficController(k8sClient, testAppRollout,
+		&v1alpha1.TrafficRouting{Provider: v1alpha1.ServiceTrafficProvider},
+		newTestWorkload("frontend-v1", "v1"), newTestWorkload("frontend-v2", "v2"))
+	require.NoError(t, err)
+
+	var svc corev1.Service
+	key := types.NamespacedName{Namespace: "default", Name: "frontend"}
+	// the service can not split the traffic, it stays on the source until all of it goes to the target
+	require.NoError(t, c.RouteTraffic(ctx, 50))
+	require.NoError(t, k8sClient.Get(ctx, key, &svc))
+	assert.Equal(t, map[string]string{"app": "frontend", "revision": "v1"}, svc.Spec.Selector)
+
+	require.NoError(t, c.RouteTraffic(ctx, 100))
+	require.NoError(t, k8sClient.Get(ctx, key, &svc))
+	assert.Equal(t, map[string]string{"app": "frontend", "revision": "v2"}, svc.Spec.Selector)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	istioapiv1beta1 "istio.io/api/networking/v1beta1"
+	istioclientv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+)
+
+// istioController routes the traffic by a VirtualService with the same name as the service, the VirtualService
+// splits the traffic of the service between the services of the source and target workloads
+type istioController struct {
+	trafficController
+}
+
+// RouteTraffic applies the VirtualService with the weights of the source and target workloads
+func (c *istioController) RouteTraffic(ctx context.Context, weight int32) error {
+	if err := c.applyWorkloadServices(ctx); err != nil {
+		return err
+	}
+	vsvc := &istioclientv1beta1.VirtualService{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: istioclientv1beta1.SchemeGroupVersion.String(),
+			Kind:       "VirtualService",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.service.Name,
+			Namespace: c.service.Namespace,
+		},
+		Spec: istioapiv1beta1.VirtualService{
+			Hosts: []string{c.service.Name},
+			Http: []*istioapiv1beta1.HTTPRoute{{
+				Route: []*istioapiv1beta1.HTTPRouteDestination{
+					{
+						Destination: &istioapiv1beta1.Destination{Host: workloadServiceName(c.source)},
+						Weight:      100 - weight,
+					},
+					{
+						Destination: &istioapiv1beta1.Destination{Host: workloadServiceName(c.target)},
+						Weight:      weight,
+					},
+				},
+			}},
+		},
+	}
+	c.setOwner(vsvc)
+	if err := apply.NewAPIApplicator(c.client).Apply(ctx, vsvc); err != nil {
+		return errors.Wrapf(err, "failed to apply the virtual service %s", vsvc.Name)
+	}
+	klog.InfoS("routed the traffic by the virtual service", "virtual service", klog.KObj(vsvc),
+		"target workload", c.target.GetName(), "weight", weight)
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// serviceController routes the traffic by switching the selector of the service between the pods of the source
+// and target workloads. It can not split the traffic, so the traffic stays on the source workload until the weight
+// of the target workload reaches 100.
+type serviceController struct {
+	trafficController
+}
+
+// RouteTraffic selects the pods of the target workload if the weight is 100, otherwise the source workload
+func (c *serviceController) RouteTraffic(ctx context.Context, weight int32) error {
+	svc, err := c.fetchService(ctx)
+	if err != nil {
+		return err
+	}
+	workload := c.source
+	if weight >= 100 {
+		workload = c.target
+	}
+	selector := podLabels(workload)
+	if reflect.DeepEqual(svc.Spec.Selector, selector) {
+		return nil
+	}
+	svcPatch := client.MergeFrom(svc.DeepCopy())
+	svc.Spec.Selector = selector
+	if err := c.client.Patch(ctx, svc, svcPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+		return errors.Wrapf(err, "failed to switch the selector of the service %s", svc.Name)
+	}
+	klog.InfoS("switched the traffic of the service", "service", klog.KObj(svc), "workload", workload.GetName())
+	return nil
+}
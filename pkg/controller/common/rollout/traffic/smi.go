@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+)
+
+// TrafficSplitGroupVersionKind is the SMI TrafficSplit applied by the smi traffic provider
+var TrafficSplitGroupVersionKind = schema.GroupVersionKind{
+	Group:   "split.smi-spec.io",
+	Version: "v1alpha2",
+	Kind:    "TrafficSplit",
+}
+
+// smiController routes the traffic by a TrafficSplit with the same name as the service, the TrafficSplit splits
+// the traffic of the service between the services of the source and target workloads
+type smiController struct {
+	trafficController
+}
+
+// RouteTraffic applies the TrafficSplit with the weights of the source and target workloads
+func (c *smiController) RouteTraffic(ctx context.Context, weight int32) error {
+	if err := c.applyWorkloadServices(ctx); err != nil {
+		return err
+	}
+	trafficSplit := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"service": c.service.Name,
+			"backends": []interface{}{
+				map[string]interface{}{
+					"service": workloadServiceName(c.source),
+					"weight":  int64(100 - weight),
+				},
+				map[string]interface{}{
+					"service": workloadServiceName(c.target),
+					"weight":  int64(weight),
+				},
+			},
+		},
+	}}
+	trafficSplit.SetGroupVersionKind(TrafficSplitGroupVersionKind)
+	trafficSplit.SetName(c.service.Name)
+	trafficSplit.SetNamespace(c.service.Namespace)
+	c.setOwner(trafficSplit)
+	if err := apply.NewAPIApplicator(c.client).Apply(ctx, trafficSplit); err != nil {
+		return errors.Wrapf(err, "failed to apply the traffic split %s", trafficSplit.GetName())
+	}
+	klog.InfoS("routed the traffic by the traffic split", "traffic split", klog.KObj(trafficSplit),
+		"target workload", c.target.GetName(), "weight", weight)
+	return nil
+}
@@ -17,11 +17,15 @@ limitations under the License.
 package workloads
 
 import (
+	"context"
 	"fmt"
 
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 )
@@ -141,3 +145,45 @@ func getDeployReplicaSize(deploy *apps.Deployment) int32 {
 	}
 	return 1
 }
+
+// listControlledPods lists the pods selected by the workload selector and controlled by the workload
+func listControlledPods(ctx context.Context, c client.Client, workload metav1.Object,
+	selector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	podList := &corev1.PodList{}
+	if err := c.List(ctx, podList, client.InNamespace(workload.GetNamespace()),
+		client.MatchingLabelsSelector{Selector: podSelector}); err != nil {
+		return nil, err
+	}
+	var pods []corev1.Pod
+	for _, pod := range podList.Items {
+		if controller := metav1.GetControllerOf(&pod); controller != nil && controller.UID == workload.GetUID() {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// countReadyPodsOfRevision counts the ready pods labeled with the revision hash
+func countReadyPodsOfRevision(pods []corev1.Pod, revision string) int {
+	count := 0
+	for i := range pods {
+		if pods[i].GetLabels()[apps.ControllerRevisionHashLabelKey] == revision && pods[i].DeletionTimestamp == nil &&
+			isPodReady(&pods[i]) {
+			count++
+		}
+	}
+	return count
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -18,10 +18,13 @@ package workloads
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	kruise "github.com/openkruise/kruise-api/apps/v1alpha1"
+	apps "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -104,3 +107,119 @@ func (c *cloneSetController) fetchCloneSet(ctx context.Context) error {
 	c.cloneSet = &workload
 	return nil
 }
+
+// statefulSetController is the place to hold fields needed for handle StatefulSet type of workloads
+type statefulSetController struct {
+	workloadController
+	targetNamespacedName types.NamespacedName
+	statefulSet          *apps.StatefulSet
+}
+
+// size fetches the StatefulSet and returns the replicas (not the actual number of pods)
+func (s *statefulSetController) size(ctx context.Context) (int32, error) {
+	if s.statefulSet == nil {
+		err := s.fetchStatefulSet(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// default is 1
+	if s.statefulSet.Spec.Replicas == nil {
+		return 1, nil
+	}
+	return *s.statefulSet.Spec.Replicas, nil
+}
+
+func (s *statefulSetController) fetchStatefulSet(ctx context.Context) error {
+	// get the statefulSet
+	workload := apps.StatefulSet{}
+	err := s.client.Get(ctx, s.targetNamespacedName, &workload)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			s.recorder.Event(s.parentController, event.Warning("Failed to get the StatefulSet", err))
+		}
+		return err
+	}
+	s.statefulSet = &workload
+	return nil
+}
+
+// partition returns the ordinal from which the pods of the StatefulSet are updated, all the pods are updated
+// if the StatefulSet is not held by a partition
+func (s *statefulSetController) partition() int32 {
+	strategy := s.statefulSet.Spec.UpdateStrategy
+	if strategy.Type != apps.RollingUpdateStatefulSetStrategyType || strategy.RollingUpdate == nil ||
+		strategy.RollingUpdate.Partition == nil {
+		return 0
+	}
+	return *strategy.RollingUpdate.Partition
+}
+
+// setPartition makes the StatefulSet update the pods whose ordinal is no less than the partition
+func (s *statefulSetController) setPartition(partition int32) {
+	s.statefulSet.Spec.UpdateStrategy.Type = apps.RollingUpdateStatefulSetStrategyType
+	if s.statefulSet.Spec.UpdateStrategy.RollingUpdate == nil {
+		s.statefulSet.Spec.UpdateStrategy.RollingUpdate = &apps.RollingUpdateStatefulSetStrategy{}
+	}
+	s.statefulSet.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+}
+
+// daemonSetController is the place to hold fields needed for handle DaemonSet type of workloads
+type daemonSetController struct {
+	workloadController
+	targetNamespacedName types.NamespacedName
+	daemonSet            *apps.DaemonSet
+}
+
+// size fetches the DaemonSet and returns the number of nodes that should run the daemon pod
+func (d *daemonSetController) size(ctx context.Context) (int32, error) {
+	if d.daemonSet == nil {
+		err := d.fetchDaemonSet(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return d.daemonSet.Status.DesiredNumberScheduled, nil
+}
+
+func (d *daemonSetController) fetchDaemonSet(ctx context.Context) error {
+	// get the daemonSet
+	workload := apps.DaemonSet{}
+	err := d.client.Get(ctx, d.targetNamespacedName, &workload)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			d.recorder.Event(d.parentController, event.Warning("Failed to get the DaemonSet", err))
+		}
+		return err
+	}
+	d.daemonSet = &workload
+	return nil
+}
+
+// updateRevision returns the hash of the latest controller revision of the DaemonSet,
+// the daemon pods created from the latest template are labeled with it
+func (d *daemonSetController) updateRevision(ctx context.Context) (string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.daemonSet.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+	revisions := &apps.ControllerRevisionList{}
+	if err := d.client.List(ctx, revisions, client.InNamespace(d.daemonSet.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return "", err
+	}
+	var latest *apps.ControllerRevision
+	for i := range revisions.Items {
+		revision := &revisions.Items[i]
+		if controller := metav1.GetControllerOf(revision); controller == nil || controller.UID != d.daemonSet.UID {
+			continue
+		}
+		if latest == nil || revision.Revision > latest.Revision {
+			latest = revision
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("cannot find the controller revision of the daemonset %s", d.daemonSet.Name)
+	}
+	return latest.Labels[apps.DefaultDaemonSetUniqueLabelKey], nil
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// DaemonSetRolloutController is responsible for handle rollout DaemonSet type of workloads.
+// The DaemonSet is upgraded in place with the OnDelete update strategy, the controller deletes the daemon pods
+// of the old revision in each batch so that they are recreated from the new template.
+// A DaemonSet runs one pod on each of its nodes, so its size is not decided by the rollout plan and
+// it's never scaled.
+type DaemonSetRolloutController struct {
+	daemonSetController
+}
+
+// NewDaemonSetRolloutController creates a new DaemonSet rollout controller
+func NewDaemonSetRolloutController(client client.Client, recorder event.Recorder, parentController oam.Object,
+	rolloutSpec *v1alpha1.RolloutPlan, rolloutStatus *v1alpha1.RolloutStatus, workloadName types.NamespacedName) *DaemonSetRolloutController {
+	return &DaemonSetRolloutController{
+		daemonSetController: daemonSetController{
+			workloadController: workloadController{
+				client:           client,
+				recorder:         recorder,
+				parentController: parentController,
+				rolloutSpec:      rolloutSpec,
+				rolloutStatus:    rolloutStatus,
+			},
+			targetNamespacedName: workloadName,
+		},
+	}
+}
+
+// VerifySpec verifies that the target rollout resource is consistent with the rollout spec
+func (d *DaemonSetRolloutController) VerifySpec(ctx context.Context) (bool, error) {
+	var verifyErr error
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			d.recorder.Event(d.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	// fetch the daemonset and get its current size
+	currentSize, verifyErr := d.size(ctx)
+	if verifyErr != nil {
+		// do not fail the rollout because we can't get the resource
+		d.rolloutStatus.RolloutRetry(verifyErr.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+
+	// the status is only valid after the daemonset controller observes the latest spec
+	if d.daemonSet.Status.ObservedGeneration < d.daemonSet.Generation {
+		verifyErr = fmt.Errorf("the daemonset %s is not observed yet, generation = %d, observed generation = %d",
+			d.daemonSet.GetName(), d.daemonSet.Generation, d.daemonSet.Status.ObservedGeneration)
+		d.rolloutStatus.RolloutRetry(verifyErr.Error())
+		return false, nil
+	}
+
+	// make sure that the update revision is different from what we have already done
+	targetHash, verifyErr := d.updateRevision(ctx)
+	if verifyErr != nil {
+		// the controller revision may not be created yet
+		d.rolloutStatus.RolloutRetry(verifyErr.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	if targetHash == d.rolloutStatus.LastAppliedPodTemplateIdentifier {
+		return false, fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+	}
+
+	// check if the rollout batch replicas added up to the number of the daemon pods
+	if verifyErr = d.verifyRolloutBatchReplicaValue(currentSize); verifyErr != nil {
+		return false, verifyErr
+	}
+
+	// record the size
+	klog.InfoS("record the target size", "total replicas", currentSize)
+	d.rolloutStatus.RolloutTargetSize = currentSize
+	d.rolloutStatus.RolloutOriginalSize = currentSize
+
+	// check if the daemonset only updates the pods when they are deleted
+	if d.daemonSet.Spec.UpdateStrategy.Type != apps.OnDeleteDaemonSetStrategyType {
+		return false, fmt.Errorf("the daemonset %s is updated by %s, need to be updated on delete first",
+			d.daemonSet.GetName(), d.daemonSet.Spec.UpdateStrategy.Type)
+	}
+
+	// check if the daemonset has any controller
+	if controller := metav1.GetControllerOf(d.daemonSet); controller != nil {
+		return false, fmt.Errorf("the daemonset %s has a controller owner %s",
+			d.daemonSet.GetName(), controller.String())
+	}
+
+	// mark the rollout verified
+	d.recorder.Event(d.parentController, event.Normal("Rollout Verified",
+		"Rollout spec and the DaemonSet resource are verified"))
+	// record the new pod template hash only if it succeeds
+	d.rolloutStatus.NewPodTemplateIdentifier = targetHash
+	return true, nil
+}
+
+// Initialize makes sure that the daemonset is under our control
+func (d *DaemonSetRolloutController) Initialize(ctx context.Context) (bool, error) {
+	if err := d.fetchDaemonSet(ctx); err != nil {
+		d.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+
+	if controller := metav1.GetControllerOf(d.daemonSet); controller != nil {
+		if controller.Kind == v1beta1.AppRolloutKind && controller.APIVersion == v1beta1.SchemeGroupVersion.String() {
+			// it's already there
+			return true, nil
+		}
+	}
+	// add the parent controller to the owner of the daemonset
+	// the pods are not updated until we delete them, so every pod stays in the old version
+	dsPatch := client.MergeFrom(d.daemonSet.DeepCopyObject())
+	ref := metav1.NewControllerRef(d.parentController, v1beta1.AppRolloutKindVersionKind)
+	d.daemonSet.SetOwnerReferences(append(d.daemonSet.GetOwnerReferences(), *ref))
+
+	// patch the DaemonSet
+	if err := d.client.Patch(ctx, d.daemonSet, dsPatch, client.FieldOwner(d.parentController.GetUID())); err != nil {
+		d.recorder.Event(d.parentController, event.Warning("Failed to the start the daemonset update", err))
+		d.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	// mark the rollout initialized
+	d.recorder.Event(d.parentController, event.Normal("Rollout Initialized", "Rollout resource are initialized"))
+	return true, nil
+}
+
+// RolloutOneBatchPods calculates the number of pods we can upgrade once according to the rollout spec
+// and then deletes the pods of the old revision accordingly, return if we are done
+func (d *DaemonSetRolloutController) RolloutOneBatchPods(ctx context.Context) (bool, error) {
+	if err := d.fetchDaemonSet(ctx); err != nil {
+		d.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	newPodTarget := calculateNewBatchTarget(d.rolloutSpec, 0, int(d.rolloutStatus.RolloutTargetSize),
+		int(d.rolloutStatus.CurrentBatch))
+	pods, err := listControlledPods(ctx, d.client, d.daemonSet, d.daemonSet.Spec.Selector)
+	if err != nil {
+		d.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	// the pods being deleted are recreated in the new revision
+	upgraded := 0
+	var oldPods []*corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil ||
+			pod.Labels[apps.DefaultDaemonSetUniqueLabelKey] == d.rolloutStatus.NewPodTemplateIdentifier {
+			upgraded++
+			continue
+		}
+		oldPods = append(oldPods, pod)
+	}
+	// upgrade the pods which are not ready first since they are not serving anyway
+	sort.SliceStable(oldPods, func(i, j int) bool {
+		if isPodReady(oldPods[i]) != isPodReady(oldPods[j]) {
+			return !isPodReady(oldPods[i])
+		}
+		return oldPods[i].Name < oldPods[j].Name
+	})
+	for i := 0; i < newPodTarget-upgraded && i < len(oldPods); i++ {
+		if err := d.client.Delete(ctx, oldPods[i]); err != nil && !apierrors.IsNotFound(err) {
+			d.recorder.Event(d.parentController, event.Warning("Failed to delete the daemon pod to upgrade", err))
+			d.rolloutStatus.RolloutRetry(err.Error())
+			return false, nil
+		}
+		klog.InfoS("deleted a daemon pod of the old revision", "pod", oldPods[i].Name)
+	}
+	// record the upgrade
+	klog.InfoS("upgraded one batch", "current batch", d.rolloutStatus.CurrentBatch)
+	d.recorder.Event(d.parentController, event.Normal("Batch Rollout",
+		fmt.Sprintf("Submitted upgrade quest for batch %d", d.rolloutStatus.CurrentBatch)))
+	d.rolloutStatus.UpgradedReplicas = int32(newPodTarget)
+	return true, nil
+}
+
+// CheckOneBatchPods checks to see if enough pods are upgraded according to the rollout plan
+func (d *DaemonSetRolloutController) CheckOneBatchPods(ctx context.Context) (bool, error) {
+	if err := d.fetchDaemonSet(ctx); err != nil {
+		d.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	dsSize := int(d.rolloutStatus.RolloutTargetSize)
+	newPodTarget := calculateNewBatchTarget(d.rolloutSpec, 0, dsSize, int(d.rolloutStatus.CurrentBatch))
+	pods, err := listControlledPods(ctx, d.client, d.daemonSet, d.daemonSet.Spec.Selector)
+	if err != nil {
+		d.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	readyPodCount := countReadyPodsOfRevision(pods, d.rolloutStatus.NewPodTemplateIdentifier)
+	if len(d.rolloutSpec.RolloutBatches) <= int(d.rolloutStatus.CurrentBatch) {
+		err = errors.New("somehow, currentBatch number exceeded the rolloutBatches spec")
+		klog.ErrorS(err, "total batch", len(d.rolloutSpec.RolloutBatches), "current batch",
+			d.rolloutStatus.CurrentBatch)
+		return false, err
+	}
+	currentBatch := d.rolloutSpec.RolloutBatches[d.rolloutStatus.CurrentBatch]
+	unavail := 0
+	if currentBatch.MaxUnavailable != nil {
+		unavail, _ = intstr.GetValueFromIntOrPercent(currentBatch.MaxUnavailable, dsSize, true)
+	}
+	klog.InfoS("checking the rolling out progress", "current batch", d.rolloutStatus.CurrentBatch,
+		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
+		"max unavailable pod allowed", unavail)
+	d.rolloutStatus.UpgradedReadyReplicas = int32(readyPodCount)
+	if unavail+readyPodCount >= newPodTarget {
+		// record the successful upgrade
+		klog.InfoS("all pods in current batch are ready", "current batch", d.rolloutStatus.CurrentBatch)
+		d.recorder.Event(d.parentController, event.Normal("Batch Available",
+			fmt.Sprintf("Batch %d is available", d.rolloutStatus.CurrentBatch)))
+		return true, nil
+	}
+	// continue to verify
+	klog.InfoS("the batch is not ready yet", "current batch", d.rolloutStatus.CurrentBatch)
+	d.rolloutStatus.RolloutRetry("the batch is not ready yet")
+	return false, nil
+}
+
+// FinalizeOneBatch makes sure that the upgradedReplicas and current batch in the status are valid according to the spec
+func (d *DaemonSetRolloutController) FinalizeOneBatch(ctx context.Context) (bool, error) {
+	status := d.rolloutStatus
+	spec := d.rolloutSpec
+	if spec.BatchPartition != nil && *spec.BatchPartition < status.CurrentBatch {
+		err := fmt.Errorf("the current batch value in the status is greater than the batch partition")
+		klog.ErrorS(err, "we have moved past the user defined partition", "user specified batch partition",
+			*spec.BatchPartition, "current batch we are working on", status.CurrentBatch)
+		return false, err
+	}
+	upgradedReplicas := int(status.UpgradedReplicas)
+	currentBatch := int(status.CurrentBatch)
+	// calculate the lower bound of the possible pod count just before the current batch
+	podCount := calculateNewBatchTarget(d.rolloutSpec, 0, int(d.rolloutStatus.RolloutTargetSize), currentBatch-1)
+	// the recorded number should be at least as much as the all the pods before the current batch
+	if podCount > upgradedReplicas {
+		err := fmt.Errorf("the upgraded replica in the status is less than all the pods in the previous batch")
+		klog.ErrorS(err, "rollout status inconsistent", "upgraded num status", upgradedReplicas,
+			"pods in all the previous batches", podCount)
+		return false, err
+	}
+	// calculate the upper bound with the current batch
+	podCount = calculateNewBatchTarget(d.rolloutSpec, 0, int(d.rolloutStatus.RolloutTargetSize), currentBatch)
+	// the recorded number should be not as much as the all the pods including the active batch
+	if podCount < upgradedReplicas {
+		err := fmt.Errorf("the upgraded replica in the status is greater than all the pods in the current batch")
+		klog.ErrorS(err, "rollout status inconsistent", "total target size", d.rolloutStatus.RolloutTargetSize,
+			"upgraded num status", upgradedReplicas, "pods in the batches including the current batch", podCount)
+		return false, err
+	}
+	return true, nil
+}
+
+// Finalize makes sure the DaemonSet is released, the pods left in the old revision stay there
+// until the next rollout since they're only updated on delete
+func (d *DaemonSetRolloutController) Finalize(ctx context.Context, succeed bool) bool {
+	if err := d.fetchDaemonSet(ctx); err != nil {
+		d.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	dsPatch := client.MergeFrom(d.daemonSet.DeepCopyObject())
+	// remove the parent controller from the resources' owner list
+	var newOwnerList []metav1.OwnerReference
+	isOwner := false
+	for _, owner := range d.daemonSet.GetOwnerReferences() {
+		if owner.Kind == v1beta1.AppRolloutKind && owner.APIVersion == v1beta1.SchemeGroupVersion.String() {
+			isOwner = true
+			continue
+		}
+		newOwnerList = append(newOwnerList, owner)
+	}
+	if !isOwner {
+		// nothing to do if we are already not the owner
+		klog.InfoS("the daemonset is already released and not controlled by rollout", "daemonSet", d.daemonSet.Name)
+		return true
+	}
+	d.daemonSet.SetOwnerReferences(newOwnerList)
+	// patch the DaemonSet
+	if err := d.client.Patch(ctx, d.daemonSet, dsPatch, client.FieldOwner(d.parentController.GetUID())); err != nil {
+		d.recorder.Event(d.parentController, event.Warning("Failed to the finalize the daemonset", err))
+		d.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	// mark the resource finalized
+	d.recorder.Event(d.parentController, event.Normal("Rollout Finalized",
+		fmt.Sprintf("Rollout resource are finalized, succeed := %t", succeed)))
+	d.rolloutStatus.LastAppliedPodTemplateIdentifier = d.rolloutStatus.NewPodTemplateIdentifier
+	return true
+}
+
+// ---------------------------------------------
+// The functions below are helper functions
+// ---------------------------------------------
+
+// check if the replicas in all the rollout batches add up to the right number
+func (d *DaemonSetRolloutController) verifyRolloutBatchReplicaValue(currentSize int32) error {
+	// the target size has to be the same as the number of the daemon pods
+	if d.rolloutSpec.TargetSize != nil && *d.rolloutSpec.TargetSize != currentSize {
+		return fmt.Errorf("the rollout plan is attempting to scale the daemonset, target = %d, daemonset size = %d",
+			*d.rolloutSpec.TargetSize, currentSize)
+	}
+	// use a common function to check if the sum of all the batches can match the daemonset size
+	return verifyBatchesWithRollout(d.rolloutSpec, currentSize)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func newTestControllerRevision(ds *apps.DaemonSet, hash string, revision int64) *apps.ControllerRevision {
+	return &apps.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ds.Name + "-" + hash,
+			Namespace: ds.Namespace,
+			Labels:    map[string]string{"app": "test", apps.DefaultDaemonSetUniqueLabelKey: hash},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: apps.SchemeGroupVersion.String(),
+				Kind:       "DaemonSet",
+				Name:       ds.Name,
+				UID:        ds.UID,
+				Controller: pointer.BoolPtr(true),
+			}},
+		},
+		Revision: revision,
+	}
+}
+
+func TestDaemonSetRolloutController(t *testing.T) {
+	ctx := context.Background()
+	ds := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ds", Namespace: "default", UID: "ds-uid"},
+		Spec: apps.DaemonSetSpec{
+			Selector:       testSelector,
+			UpdateStrategy: apps.DaemonSetUpdateStrategy{Type: apps.OnDeleteDaemonSetStrategyType},
+		},
+		Status: apps.DaemonSetStatus{DesiredNumberScheduled: 4},
+	}
+	objs := []runtime.Object{ds, newTestControllerRevision(ds, "v1", 1), newTestControllerRevision(ds, "v2", 2)}
+	for i := 0; i < 4; i++ {
+		// the not ready pod is upgraded first
+		objs = append(objs, newTestPod(fmt.Sprintf("ds-%d", i), "v1", i != 2, ds, "DaemonSet"))
+	}
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, objs...)
+	spec := &v1alpha1.RolloutPlan{RolloutBatches: []v1alpha1.RolloutBatch{
+		{Replicas: intstr.FromInt(1)}, {Replicas: intstr.FromInt(3), MaxUnavailable: &intstr.IntOrString{IntVal: 1}},
+	}}
+	status := &v1alpha1.RolloutStatus{}
+	controller := NewDaemonSetRolloutController(c, event.NewNopRecorder(), testAppRollout, spec, status,
+		types.NamespacedName{Namespace: "default", Name: "ds"})
+
+	verified, err := controller.VerifySpec(ctx)
+	require.NoError(t, err)
+	assert.True(t, verified)
+	assert.Equal(t, int32(4), status.RolloutTargetSize)
+	assert.Equal(t, "v2", status.NewPodTemplateIdentifier)
+
+	initialized, err := controller.Initialize(ctx)
+	require.NoError(t, err)
+	assert.True(t, initialized)
+	got := &apps.DaemonSet{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "ds"}, got))
+	assert.Equal(t, v1beta1.AppRolloutKind, metav1.GetControllerOf(got).Kind)
+
+	listPods := func() []string {
+		pods := &corev1.PodList{}
+		require.NoError(t, c.List(ctx, pods))
+		var names []string
+		for _, pod := range pods.Items {
+			names = append(names, pod.Name)
+		}
+		return names
+	}
+	done, err := controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []string{"ds-0", "ds-1", "ds-3"}, listPods())
+	available, err := controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.False(t, available)
+
+	// the daemon pod is recreated in the new revision
+	require.NoError(t, c.Create(ctx, newTestPod("ds-4", "v2", true, ds, "DaemonSet")))
+	available, err = controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, available)
+
+	status.CurrentBatch = 1
+	done, err = controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []string{"ds-4"}, listPods())
+	for i := 5; i < 7; i++ {
+		require.NoError(t, c.Create(ctx, newTestPod(fmt.Sprintf("ds-%d", i), "v2", true, ds, "DaemonSet")))
+	}
+	available, err = controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, available, "one unavailable pod is allowed")
+
+	assert.True(t, controller.Finalize(ctx, true))
+	assert.Equal(t, "v2", status.LastAppliedPodTemplateIdentifier)
+
+	// the rollout can't start again without a new revision
+	status.RolloutTargetSize = 0
+	verified, err = NewDaemonSetRolloutController(c, event.NewNopRecorder(), testAppRollout, spec, status,
+		types.NamespacedName{Namespace: "default", Name: "ds"}).VerifySpec(ctx)
+	assert.Error(t, err)
+	assert.False(t, verified)
+}
+
+func TestDaemonSetRolloutVerifyUpdateStrategy(t *testing.T) {
+	ds := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ds", Namespace: "default", UID: "ds-uid"},
+		Spec: apps.DaemonSetSpec{
+			Selector:       testSelector,
+			UpdateStrategy: apps.DaemonSetUpdateStrategy{Type: apps.RollingUpdateDaemonSetStrategyType},
+		},
+		Status: apps.DaemonSetStatus{DesiredNumberScheduled: 1},
+	}
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, ds, newTestControllerRevision(ds, "v1", 1))
+	spec := &v1alpha1.RolloutPlan{RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(1)}}}
+	controller := NewDaemonSetRolloutController(c, event.NewNopRecorder(), testAppRollout, spec,
+		&v1alpha1.RolloutStatus{}, types.NamespacedName{Namespace: "default", Name: "ds"})
+	verified, err := controller.VerifySpec(context.Background())
+	assert.Error(t, err)
+	assert.False(t, verified)
+}
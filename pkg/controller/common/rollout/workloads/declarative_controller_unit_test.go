@@ -0,0 +1,233 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+var testDefinition = v1beta1.WorkloadDefinitionSpec{
+	PodSpecPath:       "spec.deployment.template.spec",
+	ReplicasPath:      "spec.deployment.replicas",
+	ReadyReplicasPath: "status.readyReplicas",
+}
+
+func newTestWorkload(name, image string, replicas, ready int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "WebApp",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec": map[string]interface{}{"deployment": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{"spec": map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "app", "image": image}},
+			}},
+		}},
+		"status": map[string]interface{}{"readyReplicas": ready},
+	}}
+}
+
+func TestRegisterWorkloadController(t *testing.T) {
+	for _, kind := range []string{"Deployment", "StatefulSet", "DaemonSet"} {
+		_, ok := GetControllerFactory(schema.GroupKind{Group: apps.GroupName, Kind: kind})
+		assert.True(t, ok, kind)
+	}
+	gk := schema.GroupKind{Group: "example.com", Kind: "WebApp"}
+	_, ok := GetControllerFactory(gk)
+	assert.False(t, ok)
+
+	RegisterWorkloadController(gk, func(args ControllerArgs) (WorkloadController, error) {
+		return NewDeclarativeController(args, testDefinition)
+	})
+	defer func() {
+		registryLock.Lock()
+		delete(registry, gk)
+		registryLock.Unlock()
+	}()
+	factory, ok := GetControllerFactory(gk)
+	require.True(t, ok)
+	controller, err := factory(ControllerArgs{TargetWorkload: newTestWorkload("app-v1", "nginx:1", 3, 3)})
+	require.NoError(t, err)
+	assert.IsType(t, &DeclarativeScaleController{}, controller)
+}
+
+func TestNewDeclarativeController(t *testing.T) {
+	args := ControllerArgs{TargetWorkload: newTestWorkload("app-v1", "nginx:1", 3, 3)}
+	_, err := NewDeclarativeController(args, v1beta1.WorkloadDefinitionSpec{})
+	assert.Error(t, err, "the replicas path is required")
+	_, err = NewDeclarativeController(args, v1beta1.WorkloadDefinitionSpec{ReplicasPath: "spec.replicas"})
+	assert.Error(t, err, "either the ready replicas path or the revision label is required")
+	_, err = NewDeclarativeController(args, v1beta1.WorkloadDefinitionSpec{ReplicasPath: "spec.replicas",
+		RevisionLabel: "app.oam.dev/revision"})
+	assert.NoError(t, err)
+}
+
+func TestDeclarativeRolloutController(t *testing.T) {
+	ctx := context.Background()
+	source, target := newTestWorkload("app-v1", "nginx:1", 4, 4), newTestWorkload("app-v2", "nginx:2", 4, 0)
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, source.DeepCopy(), target.DeepCopy())
+	spec := &v1alpha1.RolloutPlan{RolloutBatches: []v1alpha1.RolloutBatch{
+		{Replicas: intstr.FromInt(1)}, {Replicas: intstr.FromInt(3), MaxUnavailable: &intstr.IntOrString{IntVal: 1}},
+	}}
+	status := &v1alpha1.RolloutStatus{}
+	newController := func() WorkloadController {
+		controller, err := NewDeclarativeController(ControllerArgs{Client: c, Recorder: event.NewNopRecorder(),
+			ParentController: testAppRollout, RolloutSpec: spec, RolloutStatus: status,
+			SourceWorkload: source, TargetWorkload: target}, testDefinition)
+		require.NoError(t, err)
+		return controller
+	}
+	get := func(name string) *unstructured.Unstructured {
+		workload := &unstructured.Unstructured{}
+		workload.SetAPIVersion("example.com/v1")
+		workload.SetKind("WebApp")
+		require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, workload))
+		return workload
+	}
+	replicas := func(name string) int64 {
+		replicas, _, err := unstructured.NestedInt64(get(name).Object, "spec", "deployment", "replicas")
+		require.NoError(t, err)
+		return replicas
+	}
+	setReady := func(name string, ready int64) {
+		workload := get(name)
+		require.NoError(t, unstructured.SetNestedField(workload.Object, ready, "status", "readyReplicas"))
+		require.NoError(t, c.Update(ctx, workload))
+	}
+
+	controller := newController()
+	verified, err := controller.VerifySpec(ctx)
+	require.NoError(t, err)
+	assert.True(t, verified)
+	assert.Equal(t, int32(4), status.RolloutTargetSize)
+	assert.NotEmpty(t, status.NewPodTemplateIdentifier)
+
+	initialized, err := controller.Initialize(ctx)
+	require.NoError(t, err)
+	assert.True(t, initialized)
+	assert.Equal(t, v1beta1.AppRolloutKind, metav1.GetControllerOf(get("app-v1")).Kind)
+	assert.Equal(t, v1beta1.AppRolloutKind, metav1.GetControllerOf(get("app-v2")).Kind)
+	assert.Equal(t, int64(0), replicas("app-v2"))
+
+	// the target is scaled up first and the source is scaled down once the target is ready
+	done, err := controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, int64(1), replicas("app-v2"))
+	done, err = controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, int64(4), replicas("app-v1"))
+	setReady("app-v2", 1)
+	done, err = controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, int64(3), replicas("app-v1"))
+	available, err := controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, available)
+	finalized, err := controller.FinalizeOneBatch(ctx)
+	require.NoError(t, err)
+	assert.True(t, finalized)
+
+	status.CurrentBatch = 1
+	done, err = controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.False(t, done)
+	setReady("app-v2", 3)
+	done, err = controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, done, "one unavailable pod is allowed")
+	assert.Equal(t, int64(0), replicas("app-v1"))
+	assert.Equal(t, int64(4), replicas("app-v2"))
+
+	assert.True(t, controller.Finalize(ctx, true))
+	assert.Nil(t, metav1.GetControllerOf(get("app-v1")))
+	assert.Nil(t, metav1.GetControllerOf(get("app-v2")))
+	assert.Equal(t, status.NewPodTemplateIdentifier, status.LastAppliedPodTemplateIdentifier)
+
+	// the rollout can't start again without a new pod spec
+	verified, err = newController().VerifySpec(ctx)
+	assert.Error(t, err)
+	assert.False(t, verified)
+}
+
+func TestDeclarativeScaleController(t *testing.T) {
+	ctx := context.Background()
+	workload := newTestWorkload("app-v1", "nginx:1", 2, 2)
+	workload.SetLabels(map[string]string{oam.LabelAppComponentRevision: "app-v1"})
+	newPod := func(name string, ready bool) *corev1.Pod {
+		pod := newTestPod(name, "v1", ready, workload, "WebApp")
+		pod.Labels["app.oam.dev/revision"] = "app-v1"
+		return pod
+	}
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, workload.DeepCopy(), newPod("pod-0", true),
+		newPod("pod-1", true), newPod("pod-2", false))
+	spec := &v1alpha1.RolloutPlan{TargetSize: pointer.Int32Ptr(4), RolloutBatches: []v1alpha1.RolloutBatch{
+		{Replicas: intstr.FromInt(1)}, {Replicas: intstr.FromInt(1)},
+	}}
+	status := &v1alpha1.RolloutStatus{}
+	controller, err := NewDeclarativeController(ControllerArgs{Client: c, Recorder: event.NewNopRecorder(),
+		ParentController: testAppRollout, RolloutSpec: spec, RolloutStatus: status, TargetWorkload: workload},
+		v1beta1.WorkloadDefinitionSpec{ReplicasPath: "spec.deployment.replicas", RevisionLabel: "app.oam.dev/revision"})
+	require.NoError(t, err)
+
+	verified, err := controller.VerifySpec(ctx)
+	require.NoError(t, err)
+	assert.True(t, verified)
+	assert.Equal(t, int32(2), status.RolloutOriginalSize)
+	initialized, err := controller.Initialize(ctx)
+	require.NoError(t, err)
+	assert.True(t, initialized)
+
+	done, err := controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, int32(3), status.UpgradedReplicas)
+	// the ready pods are counted by the revision label
+	available, err := controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.False(t, available)
+	pod := newPod("pod-2", true)
+	require.NoError(t, c.Update(ctx, pod))
+	available, err = controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, available)
+	finalized, err := controller.FinalizeOneBatch(ctx)
+	require.NoError(t, err)
+	assert.True(t, finalized)
+	assert.True(t, controller.Finalize(ctx, true))
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// DeclarativeRolloutController is responsible for rolling out the workloads whose kind is declared rollable by
+// their WorkloadDefinition, the source and the target workload run side by side and are scaled in turn
+type DeclarativeRolloutController struct {
+	declarativeWorkload
+	sourceWorkload *unstructured.Unstructured
+	targetWorkload *unstructured.Unstructured
+}
+
+// VerifySpec verifies that the rollout resource is consistent with the rollout spec
+func (c *DeclarativeRolloutController) VerifySpec(ctx context.Context) (bool, error) {
+	var verifyErr error
+
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			c.recorder.Event(c.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	if err := c.fetchWorkloads(ctx); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// do not fail the rollout just because we can't get the resource
+		// nolint:nilerr
+		return false, nil
+	}
+	if c.sourceWorkload.GetName() == c.targetWorkload.GetName() {
+		verifyErr = fmt.Errorf("the %s %s can't be upgraded in place by scaling it", c.targetWorkload.GetKind(),
+			c.targetWorkload.GetName())
+		return false, verifyErr
+	}
+
+	// check if the rollout spec is compatible with the current state
+	sourceSize, verifyErr := c.replicas(c.sourceWorkload)
+	if verifyErr != nil {
+		return false, verifyErr
+	}
+	targetTotalReplicas := sourceSize
+	// the spec target size is the truth if it's set
+	if c.rolloutSpec.TargetSize != nil {
+		targetTotalReplicas = *c.rolloutSpec.TargetSize
+		if targetTotalReplicas < sourceSize {
+			verifyErr = fmt.Errorf("target size `%d` less than source size `%d`", targetTotalReplicas, sourceSize)
+			return false, verifyErr
+		}
+	}
+	c.rolloutStatus.RolloutTargetSize = targetTotalReplicas
+
+	// make sure that the target is different from what we have already done
+	targetHash, err := c.podTemplateHash(c.targetWorkload)
+	if err != nil {
+		// do not fail the rollout because we can't compute the hash value for some reason
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	if targetHash == c.rolloutStatus.LastAppliedPodTemplateIdentifier {
+		verifyErr = fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+		return false, verifyErr
+	}
+
+	// check if the rollout batch replicas added up to the target size
+	if verifyErr = verifyBatchesWithRollout(c.rolloutSpec, targetTotalReplicas); verifyErr != nil {
+		return false, verifyErr
+	}
+
+	if verifyErr = c.verifyOwner(c.sourceWorkload); verifyErr != nil {
+		return false, verifyErr
+	}
+	if verifyErr = c.verifyOwner(c.targetWorkload); verifyErr != nil {
+		return false, verifyErr
+	}
+
+	// mark the rollout verified
+	c.recorder.Event(c.parentController, event.Normal("Rollout Verified",
+		fmt.Sprintf("Rollout spec and the %s resource are verified", c.targetWorkload.GetKind())))
+	// record the new pod template hash on success
+	c.rolloutStatus.NewPodTemplateIdentifier = targetHash
+	return true, nil
+}
+
+// Initialize makes sure that the source and target workload is under our control
+func (c *DeclarativeRolloutController) Initialize(ctx context.Context) (bool, error) {
+	if err := c.fetchWorkloads(ctx); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	if err := c.claim(ctx, c.sourceWorkload); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	if err := c.claim(ctx, c.targetWorkload); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	// make sure we start with the matching replicas and target
+	sourceSize, err := c.replicas(c.sourceWorkload)
+	if err != nil {
+		return false, err
+	}
+	if err := c.scale(ctx, c.targetWorkload, c.rolloutStatus.RolloutTargetSize-sourceSize); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	// mark the rollout initialized
+	c.recorder.Event(c.parentController, event.Normal("Rollout Initialized", "Rollout resource are initialized"))
+	return true, nil
+}
+
+// RolloutOneBatchPods scales the target and the source workload to the sizes of the current batch, it waits for
+// the pods of the part scaled first to be ready before scaling the other part if the rollout strategy asks to
+func (c *DeclarativeRolloutController) RolloutOneBatchPods(ctx context.Context) (bool, error) {
+	if err := c.fetchWorkloads(ctx); err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	sourceReplicas, err := c.replicas(c.sourceWorkload)
+	if err != nil {
+		return false, err
+	}
+	targetReplicas, err := c.replicas(c.targetWorkload)
+	if err != nil {
+		return false, err
+	}
+	targetSize := c.calculateCurrentTarget()
+	sourceSize := c.rolloutStatus.RolloutTargetSize - targetSize
+
+	if c.rolloutSpec.RolloutStrategy == v1alpha1.DecreaseFirstRolloutStrategyType {
+		if sourceSize < sourceReplicas {
+			c.scaleOneSide(ctx, c.sourceWorkload, sourceSize, "decrease")
+			return false, nil
+		}
+		if targetSize > targetReplicas {
+			if !c.scaleOneSide(ctx, c.targetWorkload, targetSize, "increase") {
+				return false, nil
+			}
+		}
+	} else {
+		if targetSize > targetReplicas {
+			c.scaleOneSide(ctx, c.targetWorkload, targetSize, "increase")
+			return false, nil
+		}
+		if sourceSize < sourceReplicas {
+			// make sure that the target workload has enough ready pods before reducing the source
+			ready, err := c.readyReplicas(ctx, c.targetWorkload)
+			if err != nil {
+				return false, err
+			}
+			if ready+c.maxUnavailable() < targetSize {
+				klog.InfoS("the batch is not ready yet", "current batch", c.rolloutStatus.CurrentBatch,
+					"target ready pod", ready)
+				c.rolloutStatus.RolloutRetry(fmt.Sprintf("the batch %d is not ready yet with %d target pods ready",
+					c.rolloutStatus.CurrentBatch, ready))
+				return false, nil
+			}
+			if !c.scaleOneSide(ctx, c.sourceWorkload, sourceSize, "decrease") {
+				return false, nil
+			}
+		}
+	}
+	// record the finished upgrade action
+	klog.InfoS("upgraded one batch", "current batch", c.rolloutStatus.CurrentBatch,
+		"target workload size", targetSize)
+	c.recorder.Event(c.parentController, event.Normal("Batch Rollout",
+		fmt.Sprintf("Finished submiting all upgrade quests for batch %d", c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.UpgradedReplicas = targetSize
+	return true, nil
+}
+
+// CheckOneBatchPods checks to see if the pods are all available according to the rollout plan
+func (c *DeclarativeRolloutController) CheckOneBatchPods(ctx context.Context) (bool, error) {
+	if err := c.fetchWorkloads(ctx); err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		// nolint:nilerr
+		return false, nil
+	}
+	ready, err := c.readyReplicas(ctx, c.targetWorkload)
+	if err != nil {
+		return false, err
+	}
+	targetGoal := c.calculateCurrentTarget()
+	maxUnavail := c.maxUnavailable()
+	klog.InfoS("checking the rolling out progress", "current batch", c.rolloutStatus.CurrentBatch,
+		"target pod ready count", ready, "max unavailable pod allowed", maxUnavail, "target goal", targetGoal)
+	if ready+maxUnavail < targetGoal {
+		// we haven't met the end goal of this batch, continue to verify
+		klog.InfoS("the batch is not ready yet", "current batch", c.rolloutStatus.CurrentBatch)
+		c.rolloutStatus.RolloutRetry(fmt.Sprintf(
+			"the batch %d is not ready yet with %d target pods ready and %d unavailable allowed",
+			c.rolloutStatus.CurrentBatch, ready, maxUnavail))
+		return false, nil
+	}
+	// record the successful upgrade
+	c.rolloutStatus.UpgradedReadyReplicas = ready
+	klog.InfoS("all pods in current batch are ready", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Batch Available",
+		fmt.Sprintf("Batch %d is available", c.rolloutStatus.CurrentBatch)))
+	return true, nil
+}
+
+// FinalizeOneBatch makes sure that the rollout status are updated correctly
+func (c *DeclarativeRolloutController) FinalizeOneBatch(ctx context.Context) (bool, error) {
+	if err := c.fetchWorkloads(ctx); err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		// nolint:nilerr
+		return false, nil
+	}
+	sourceTarget, err := c.replicas(c.sourceWorkload)
+	if err != nil {
+		return false, err
+	}
+	targetTarget, err := c.replicas(c.targetWorkload)
+	if err != nil {
+		return false, err
+	}
+	if sourceTarget+targetTarget != c.rolloutStatus.RolloutTargetSize {
+		err = fmt.Errorf("workload targets don't match total rollout, sourceTarget = %d, targetTarget = %d, "+
+			"rolloutTargetSize = %d", sourceTarget, targetTarget, c.rolloutStatus.RolloutTargetSize)
+		klog.ErrorS(err, "the batch is not valid", "current batch", c.rolloutStatus.CurrentBatch)
+		return false, err
+	}
+	return true, nil
+}
+
+// Finalize releases the source and the target workload
+func (c *DeclarativeRolloutController) Finalize(ctx context.Context, succeed bool) bool {
+	if err := c.fetchWorkloads(ctx); err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		return false
+	}
+	if err := c.release(ctx, c.sourceWorkload); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	if err := c.release(ctx, c.targetWorkload); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	// mark the resource finalized
+	c.rolloutStatus.LastAppliedPodTemplateIdentifier = c.rolloutStatus.NewPodTemplateIdentifier
+	c.recorder.Event(c.parentController, event.Normal("Rollout Finalized",
+		fmt.Sprintf("Rollout resource are finalized, succeed := %t", succeed)))
+	return true
+}
+
+/*
+	----------------------------------
+
+The functions below are helper functions
+-------------------------------------
+*/
+func (c *DeclarativeRolloutController) fetchWorkloads(ctx context.Context) error {
+	if err := c.fetch(ctx, c.sourceWorkload); err != nil {
+		return err
+	}
+	return c.fetch(ctx, c.targetWorkload)
+}
+
+// scaleOneSide scales one of the workloads as one part of the current batch, returns if succeeded
+func (c *DeclarativeRolloutController) scaleOneSide(ctx context.Context, workload *unstructured.Unstructured,
+	size int32, part string) bool {
+	klog.InfoS("set workload replicas", "workload", workload.GetName(), "size", size)
+	if err := c.scale(ctx, workload, size); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	c.recorder.Event(c.parentController, event.Normal("Batch Rollout",
+		fmt.Sprintf("Submitted the %s part of upgrade quests for batch %d, size = %d",
+			part, c.rolloutStatus.CurrentBatch, size)))
+	return true
+}
+
+// the target workload size for the current batch
+func (c *DeclarativeRolloutController) calculateCurrentTarget() int32 {
+	return int32(calculateNewBatchTarget(c.rolloutSpec, 0, int(c.rolloutStatus.RolloutTargetSize),
+		int(c.rolloutStatus.CurrentBatch)))
+}
+
+func (c *DeclarativeRolloutController) maxUnavailable() int32 {
+	currentBatch := c.rolloutSpec.RolloutBatches[c.rolloutStatus.CurrentBatch]
+	if currentBatch.MaxUnavailable == nil {
+		return 0
+	}
+	maxUnavail, _ := intstr.GetValueFromIntOrPercent(currentBatch.MaxUnavailable,
+		int(c.rolloutStatus.RolloutTargetSize), true)
+	return int32(maxUnavail)
+}
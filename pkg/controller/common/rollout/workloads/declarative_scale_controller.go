@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DeclarativeScaleController is responsible for scaling the workloads whose kind is declared rollable by their
+// WorkloadDefinition
+type DeclarativeScaleController struct {
+	declarativeWorkload
+	workload *unstructured.Unstructured
+}
+
+// VerifySpec verifies that the workload can be scaled
+func (s *DeclarativeScaleController) VerifySpec(ctx context.Context) (bool, error) {
+	var verifyErr error
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			s.recorder.Event(s.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	// the rollout has to have a target size in the scale case
+	if s.rolloutSpec.TargetSize == nil {
+		verifyErr = fmt.Errorf("the rollout plan is attempting to scale the %s %s without a target",
+			s.workload.GetKind(), s.workload.GetName())
+		return false, verifyErr
+	}
+	// record the target size
+	s.rolloutStatus.RolloutTargetSize = *s.rolloutSpec.TargetSize
+	klog.InfoS("record the target size", "target size", *s.rolloutSpec.TargetSize)
+
+	if err := s.fetch(ctx, s.workload); err != nil {
+		// do not fail the rollout because we can't get the resource
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	originalSize, verifyErr := s.replicas(s.workload)
+	if verifyErr != nil {
+		return false, verifyErr
+	}
+	s.rolloutStatus.RolloutOriginalSize = originalSize
+	klog.InfoS("record the original size", "original size", originalSize)
+
+	// check if the rollout batch replicas scale up/down to the replicas target
+	if verifyErr = verifyBatchesWithScale(s.rolloutSpec, int(originalSize),
+		int(s.rolloutStatus.RolloutTargetSize)); verifyErr != nil {
+		return false, verifyErr
+	}
+
+	if verifyErr = s.verifyOwner(s.workload); verifyErr != nil {
+		return false, verifyErr
+	}
+
+	// mark the scale verified
+	s.recorder.Event(s.parentController, event.Normal("Scale Verified",
+		fmt.Sprintf("Rollout spec and the %s resource are verified", s.workload.GetKind())))
+	return true, nil
+}
+
+// Initialize makes sure that the workload is under our control
+func (s *DeclarativeScaleController) Initialize(ctx context.Context) (bool, error) {
+	if err := s.fetch(ctx, s.workload); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	if err := s.claim(ctx, s.workload); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	// mark the rollout initialized
+	s.recorder.Event(s.parentController, event.Normal("Scale Initialized",
+		fmt.Sprintf("%s is initialized", s.workload.GetKind())))
+	return true, nil
+}
+
+// RolloutOneBatchPods calculates the number of pods we can scale to according to the rollout spec
+func (s *DeclarativeScaleController) RolloutOneBatchPods(ctx context.Context) (bool, error) {
+	if err := s.fetch(ctx, s.workload); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	newPodTarget := s.calculateCurrentTarget()
+	if err := s.scale(ctx, s.workload, newPodTarget); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	// record the scale
+	klog.InfoS("scale one batch", "current batch", s.rolloutStatus.CurrentBatch)
+	s.recorder.Event(s.parentController, event.Normal("Batch Rollout",
+		fmt.Sprintf("Submitted scale quest for batch %d", s.rolloutStatus.CurrentBatch)))
+	s.rolloutStatus.UpgradedReplicas = newPodTarget
+	return true, nil
+}
+
+// CheckOneBatchPods checks to see if the pods are scaled according to the rollout plan
+func (s *DeclarativeScaleController) CheckOneBatchPods(ctx context.Context) (bool, error) {
+	if err := s.fetch(ctx, s.workload); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+	readyPodCount, err := s.readyReplicas(ctx, s.workload)
+	if err != nil {
+		return false, err
+	}
+	newPodTarget := s.calculateCurrentTarget()
+	currentBatch := s.rolloutSpec.RolloutBatches[s.rolloutStatus.CurrentBatch]
+	unavail := 0
+	if currentBatch.MaxUnavailable != nil {
+		unavail, _ = intstr.GetValueFromIntOrPercent(currentBatch.MaxUnavailable,
+			util.Abs(int(s.rolloutStatus.RolloutTargetSize-s.rolloutStatus.RolloutOriginalSize)), true)
+	}
+	klog.InfoS("checking the scaling progress", "current batch", s.rolloutStatus.CurrentBatch,
+		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
+		"max unavailable pod allowed", unavail)
+	s.rolloutStatus.UpgradedReadyReplicas = readyPodCount
+	scaleUp := s.rolloutStatus.RolloutOriginalSize <= s.rolloutStatus.RolloutTargetSize
+	if (scaleUp && int32(unavail)+readyPodCount >= newPodTarget) || (!scaleUp && readyPodCount <= newPodTarget) {
+		klog.InfoS("the current batch is ready", "current batch", s.rolloutStatus.CurrentBatch)
+		s.recorder.Event(s.parentController, event.Normal("Batch Available",
+			fmt.Sprintf("Batch %d is available", s.rolloutStatus.CurrentBatch)))
+		return true, nil
+	}
+	// continue to verify
+	klog.InfoS("the batch is not ready yet", "current batch", s.rolloutStatus.CurrentBatch)
+	s.rolloutStatus.RolloutRetry("the batch is not ready yet")
+	return false, nil
+}
+
+// FinalizeOneBatch makes sure that the workload is scaled to the size of the current batch
+func (s *DeclarativeScaleController) FinalizeOneBatch(ctx context.Context) (bool, error) {
+	if err := s.fetch(ctx, s.workload); err != nil {
+		// nolint: nilerr
+		return false, nil
+	}
+	replicas, err := s.replicas(s.workload)
+	if err != nil {
+		return false, err
+	}
+	if newPodTarget := s.calculateCurrentTarget(); replicas != newPodTarget {
+		err = fmt.Errorf("the %s %s has %d replicas instead of %d", s.workload.GetKind(), s.workload.GetName(),
+			replicas, newPodTarget)
+		klog.ErrorS(err, "the batch is not valid", "current batch", s.rolloutStatus.CurrentBatch)
+		return false, err
+	}
+	return true, nil
+}
+
+// Finalize releases the workload
+func (s *DeclarativeScaleController) Finalize(ctx context.Context, succeed bool) bool {
+	if err := s.fetch(ctx, s.workload); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	if err := s.release(ctx, s.workload); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	// mark the resource finalized
+	s.recorder.Event(s.parentController, event.Normal("Scale Finalized",
+		fmt.Sprintf("Scale resource are finalized, succeed := %t", succeed)))
+	return true
+}
+
+// the workload size for the current batch
+func (s *DeclarativeScaleController) calculateCurrentTarget() int32 {
+	return int32(calculateNewBatchTarget(s.rolloutSpec, int(s.rolloutStatus.RolloutOriginalSize),
+		int(s.rolloutStatus.RolloutTargetSize), int(s.rolloutStatus.CurrentBatch)))
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// NewDeclarativeController creates a controller that rolls out the workload following the paths declared in its
+// WorkloadDefinition. It scales the target workload up and the source workload down batch by batch, or only
+// scales the target workload if there is no source workload.
+func NewDeclarativeController(args ControllerArgs, definition v1beta1.WorkloadDefinitionSpec) (WorkloadController, error) {
+	if len(definition.ReplicasPath) == 0 {
+		return nil, fmt.Errorf("the workload kind `%s` is not supported since its definition has no replicasPath",
+			args.TargetWorkload.GetKind())
+	}
+	if len(definition.ReadyReplicasPath) == 0 && len(definition.RevisionLabel) == 0 {
+		return nil, fmt.Errorf("the workload kind `%s` is not supported since its definition has neither "+
+			"readyReplicasPath nor revisionLabel", args.TargetWorkload.GetKind())
+	}
+	workload := declarativeWorkload{
+		workloadController: workloadController{
+			client:           args.Client,
+			recorder:         args.Recorder,
+			parentController: args.ParentController,
+			rolloutSpec:      args.RolloutSpec,
+			rolloutStatus:    args.RolloutStatus,
+		},
+		definition: definition,
+	}
+	if args.SourceWorkload != nil {
+		return &DeclarativeRolloutController{
+			declarativeWorkload: workload,
+			sourceWorkload:      args.SourceWorkload.DeepCopy(),
+			targetWorkload:      args.TargetWorkload.DeepCopy(),
+		}, nil
+	}
+	return &DeclarativeScaleController{
+		declarativeWorkload: workload,
+		workload:            args.TargetWorkload.DeepCopy(),
+	}, nil
+}
+
+// declarativeWorkload reads and scales workloads following the paths declared in their WorkloadDefinition
+type declarativeWorkload struct {
+	workloadController
+	definition v1beta1.WorkloadDefinitionSpec
+}
+
+// fetch refreshes the workload, it only needs the apiVersion, kind, namespace and name of the workload
+func (d *declarativeWorkload) fetch(ctx context.Context, workload *unstructured.Unstructured) error {
+	latest := &unstructured.Unstructured{}
+	latest.SetGroupVersionKind(workload.GroupVersionKind())
+	err := d.client.Get(ctx, types.NamespacedName{Namespace: workload.GetNamespace(), Name: workload.GetName()}, latest)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			d.recorder.Event(d.parentController, event.Warning(event.Reason(
+				fmt.Sprintf("Failed to get the %s", workload.GetKind())), err))
+		}
+		return err
+	}
+	latest.DeepCopyInto(workload)
+	return nil
+}
+
+// replicas returns the desired number of replicas of the workload, the default is 1
+func (d *declarativeWorkload) replicas(workload *unstructured.Unstructured) (int32, error) {
+	replicas, err := fieldpath.Pave(workload.UnstructuredContent()).GetInteger(d.definition.ReplicasPath)
+	if err != nil {
+		if fieldpath.IsNotFound(err) {
+			return 1, nil
+		}
+		return 0, err
+	}
+	return int32(replicas), nil
+}
+
+// readyReplicas returns the number of ready replicas of the workload, the pods labeled with the revision label are
+// counted if the workload doesn't report it. The value of the revision label is the component revision of the
+// workload.
+func (d *declarativeWorkload) readyReplicas(ctx context.Context, workload *unstructured.Unstructured) (int32, error) {
+	if len(d.definition.ReadyReplicasPath) != 0 {
+		ready, err := fieldpath.Pave(workload.UnstructuredContent()).GetInteger(d.definition.ReadyReplicasPath)
+		if err != nil {
+			if fieldpath.IsNotFound(err) {
+				return 0, nil
+			}
+			return 0, err
+		}
+		return int32(ready), nil
+	}
+	revision := workload.GetLabels()[oam.LabelAppComponentRevision]
+	if len(revision) == 0 {
+		revision = workload.GetName()
+	}
+	podList := &corev1.PodList{}
+	if err := d.client.List(ctx, podList, client.InNamespace(workload.GetNamespace()),
+		client.MatchingLabels{d.definition.RevisionLabel: revision}); err != nil {
+		return 0, err
+	}
+	var ready int32
+	for i := range podList.Items {
+		if podList.Items[i].DeletionTimestamp == nil && isPodReady(&podList.Items[i]) {
+			ready++
+		}
+	}
+	return ready, nil
+}
+
+// podTemplateHash returns the hash of the pod spec of the workload, or the hash of its whole spec if the
+// definition has no podSpecPath
+func (d *declarativeWorkload) podTemplateHash(workload *unstructured.Unstructured) (string, error) {
+	path := d.definition.PodSpecPath
+	if len(path) == 0 {
+		path = "spec"
+	}
+	podSpec, err := fieldpath.Pave(workload.UnstructuredContent()).GetValue(path)
+	if err != nil {
+		return "", err
+	}
+	return utils.ComputeSpecHash(podSpec)
+}
+
+// scale patches the desired number of replicas of the workload
+func (d *declarativeWorkload) scale(ctx context.Context, workload *unstructured.Unstructured, replicas int32) error {
+	patch := client.MergeFrom(workload.DeepCopy())
+	if err := fieldpath.Pave(workload.UnstructuredContent()).SetValue(d.definition.ReplicasPath,
+		int64(replicas)); err != nil {
+		return err
+	}
+	if err := d.client.Patch(ctx, workload, patch, client.FieldOwner(d.parentController.GetUID())); err != nil {
+		d.recorder.Event(d.parentController, event.Warning(event.Reason(fmt.Sprintf(
+			"Failed to update the %s %s to the correct target %d", workload.GetKind(), workload.GetName(),
+			replicas)), err))
+		return err
+	}
+	return nil
+}
+
+// verifyOwner makes sure that the workload isn't controlled by another controller
+func (d *declarativeWorkload) verifyOwner(workload *unstructured.Unstructured) error {
+	if controller := metav1.GetControllerOf(workload); controller != nil &&
+		(controller.Kind != v1beta1.AppRolloutKind || controller.APIVersion != v1beta1.SchemeGroupVersion.String()) {
+		return fmt.Errorf("the %s %s has a controller owner %s", workload.GetKind(), workload.GetName(),
+			controller.String())
+	}
+	return nil
+}
+
+// claim adds the parent controller to the owner of the workload
+func (d *declarativeWorkload) claim(ctx context.Context, workload *unstructured.Unstructured) error {
+	if controller := metav1.GetControllerOf(workload); controller != nil {
+		return nil
+	}
+	patch := client.MergeFrom(workload.DeepCopy())
+	ref := metav1.NewControllerRef(d.parentController, v1beta1.AppRolloutKindVersionKind)
+	workload.SetOwnerReferences(append(workload.GetOwnerReferences(), *ref))
+	if err := d.client.Patch(ctx, workload, patch, client.FieldOwner(d.parentController.GetUID())); err != nil {
+		d.recorder.Event(d.parentController, event.Warning(event.Reason(
+			fmt.Sprintf("Failed to the start the %s update", workload.GetKind())), err))
+		return err
+	}
+	return nil
+}
+
+// release removes the parent controller from the owner of the workload
+func (d *declarativeWorkload) release(ctx context.Context, workload *unstructured.Unstructured) error {
+	var newOwnerList []metav1.OwnerReference
+	found := false
+	for _, owner := range workload.GetOwnerReferences() {
+		if owner.Kind == v1beta1.AppRolloutKind && owner.APIVersion == v1beta1.SchemeGroupVersion.String() {
+			found = true
+			continue
+		}
+		newOwnerList = append(newOwnerList, owner)
+	}
+	if !found {
+		return nil
+	}
+	patch := client.MergeFrom(workload.DeepCopy())
+	workload.SetOwnerReferences(newOwnerList)
+	if err := d.client.Patch(ctx, workload, patch, client.FieldOwner(d.parentController.GetUID())); err != nil {
+		d.recorder.Event(d.parentController, event.Warning(event.Reason(
+			fmt.Sprintf("Failed to the finalize the %s", workload.GetKind())), err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	kruise "github.com/openkruise/kruise-api/apps/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// ControllerArgs are what a workload controller needs to roll out a workload
+type ControllerArgs struct {
+	Client           client.Client
+	Recorder         event.Recorder
+	ParentController oam.Object
+
+	RolloutSpec   *v1alpha1.RolloutPlan
+	RolloutStatus *v1alpha1.RolloutStatus
+
+	// SourceWorkload is nil if the rollout scales the target workload
+	SourceWorkload *unstructured.Unstructured
+	TargetWorkload *unstructured.Unstructured
+}
+
+// ControllerFactory creates the workload controller of a rollout
+type ControllerFactory func(args ControllerArgs) (WorkloadController, error)
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[schema.GroupKind]ControllerFactory)
+)
+
+func init() {
+	RegisterWorkloadController(schema.GroupKind{Group: kruise.GroupVersion.Group, Kind: "CloneSet"},
+		newCloneSetController)
+	RegisterWorkloadController(schema.GroupKind{Group: apps.GroupName, Kind: "Deployment"},
+		newDeploymentController)
+	RegisterWorkloadController(schema.GroupKind{Group: apps.GroupName, Kind: "StatefulSet"},
+		newStatefulSetController)
+	RegisterWorkloadController(schema.GroupKind{Group: apps.GroupName, Kind: "DaemonSet"},
+		newDaemonSetController)
+}
+
+// RegisterWorkloadController registers the factory of the workload controller that rolls out the workloads of
+// the group kind, it replaces the factory registered for the same group kind before
+func RegisterWorkloadController(gk schema.GroupKind, factory ControllerFactory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[gk] = factory
+}
+
+// GetControllerFactory returns the factory of the workload controller registered for the group kind
+func GetControllerFactory(gk schema.GroupKind) (ControllerFactory, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	factory, ok := registry[gk]
+	return factory, ok
+}
+
+func namespacedName(workload *unstructured.Unstructured) types.NamespacedName {
+	return types.NamespacedName{Namespace: workload.GetNamespace(), Name: workload.GetName()}
+}
+
+func newCloneSetController(args ControllerArgs) (WorkloadController, error) {
+	// check whether current rollout plan is for workload rolling or scaling
+	if args.SourceWorkload != nil {
+		return NewCloneSetRolloutController(args.Client, args.Recorder, args.ParentController, args.RolloutSpec,
+			args.RolloutStatus, namespacedName(args.TargetWorkload)), nil
+	}
+	return NewCloneSetScaleController(args.Client, args.Recorder, args.ParentController, args.RolloutSpec,
+		args.RolloutStatus, namespacedName(args.TargetWorkload)), nil
+}
+
+func newDeploymentController(args ControllerArgs) (WorkloadController, error) {
+	// check whether current rollout plan is for workload rolling or scaling
+	if args.SourceWorkload != nil {
+		return NewDeploymentController(args.Client, args.Recorder, args.ParentController, args.RolloutSpec,
+			args.RolloutStatus, namespacedName(args.SourceWorkload), namespacedName(args.TargetWorkload)), nil
+	}
+	return NewDeploymentScaleController(args.Client, args.Recorder, args.ParentController, args.RolloutSpec,
+		args.RolloutStatus, namespacedName(args.TargetWorkload)), nil
+}
+
+func newStatefulSetController(args ControllerArgs) (WorkloadController, error) {
+	// check whether current rollout plan is for workload rolling or scaling
+	if args.SourceWorkload != nil {
+		return NewStatefulSetRolloutController(args.Client, args.Recorder, args.ParentController, args.RolloutSpec,
+			args.RolloutStatus, namespacedName(args.TargetWorkload)), nil
+	}
+	return NewStatefulSetScaleController(args.Client, args.Recorder, args.ParentController, args.RolloutSpec,
+		args.RolloutStatus, namespacedName(args.TargetWorkload)), nil
+}
+
+func newDaemonSetController(args ControllerArgs) (WorkloadController, error) {
+	// the size of a daemonset is decided by its nodes, so it's always rolled out in place
+	return NewDaemonSetRolloutController(args.Client, args.Recorder, args.ParentController, args.RolloutSpec,
+		args.RolloutStatus, namespacedName(args.TargetWorkload)), nil
+}
@@ -0,0 +1,326 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// StatefulSetRolloutController is responsible for handle rollout StatefulSet type of workloads.
+// The StatefulSet is upgraded in place, the partition of its rolling update strategy decides how many pods
+// are upgraded in each batch.
+type StatefulSetRolloutController struct {
+	statefulSetController
+}
+
+// NewStatefulSetRolloutController creates a new StatefulSet rollout controller
+func NewStatefulSetRolloutController(client client.Client, recorder event.Recorder, parentController oam.Object,
+	rolloutSpec *v1alpha1.RolloutPlan, rolloutStatus *v1alpha1.RolloutStatus, workloadName types.NamespacedName) *StatefulSetRolloutController {
+	return &StatefulSetRolloutController{
+		statefulSetController: statefulSetController{
+			workloadController: workloadController{
+				client:           client,
+				recorder:         recorder,
+				parentController: parentController,
+				rolloutSpec:      rolloutSpec,
+				rolloutStatus:    rolloutStatus,
+			},
+			targetNamespacedName: workloadName,
+		},
+	}
+}
+
+// VerifySpec verifies that the target rollout resource is consistent with the rollout spec
+func (s *StatefulSetRolloutController) VerifySpec(ctx context.Context) (bool, error) {
+	var verifyErr error
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			s.recorder.Event(s.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	// fetch the statefulset and get its current size
+	currentReplicas, verifyErr := s.size(ctx)
+	if verifyErr != nil {
+		// do not fail the rollout because we can't get the resource
+		s.rolloutStatus.RolloutRetry(verifyErr.Error())
+		// nolint: nilerr
+		return false, nil
+	}
+
+	// the update revision is only valid after the statefulset controller observes the latest spec
+	if s.statefulSet.Status.ObservedGeneration < s.statefulSet.Generation {
+		verifyErr = fmt.Errorf("the statefulset %s is not observed yet, generation = %d, observed generation = %d",
+			s.statefulSet.GetName(), s.statefulSet.Generation, s.statefulSet.Status.ObservedGeneration)
+		s.rolloutStatus.RolloutRetry(verifyErr.Error())
+		return false, nil
+	}
+
+	// the statefulset size has to be the same as the current size
+	if currentReplicas != s.statefulSet.Status.Replicas {
+		verifyErr = fmt.Errorf("the statefulset is still scaling, target = %d, statefulset size = %d",
+			currentReplicas, s.statefulSet.Status.Replicas)
+		// we can wait for the statefulset scale operation to finish
+		s.rolloutStatus.RolloutRetry(verifyErr.Error())
+		return false, nil
+	}
+
+	// make sure that the updateRevision is different from what we have already done
+	targetHash := s.statefulSet.Status.UpdateRevision
+	if targetHash == s.rolloutStatus.LastAppliedPodTemplateIdentifier {
+		return false, fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+	}
+
+	// check if the rollout batch replicas added up to the StatefulSet replicas
+	if verifyErr = s.verifyRolloutBatchReplicaValue(currentReplicas); verifyErr != nil {
+		return false, verifyErr
+	}
+
+	// record the size
+	klog.InfoS("record the target size", "total replicas", currentReplicas)
+	s.rolloutStatus.RolloutTargetSize = currentReplicas
+	s.rolloutStatus.RolloutOriginalSize = currentReplicas
+
+	// check if the statefulset is held by the partition, so no pod is updated before the rollout starts
+	if s.partition() < currentReplicas {
+		return false, fmt.Errorf("the statefulset %s is in the middle of updating, need to be held by partition first",
+			s.statefulSet.GetName())
+	}
+
+	// check if the statefulset has any controller
+	if controller := metav1.GetControllerOf(s.statefulSet); controller != nil {
+		return false, fmt.Errorf("the statefulset %s has a controller owner %s",
+			s.statefulSet.GetName(), controller.String())
+	}
+
+	// mark the rollout verified
+	s.recorder.Event(s.parentController, event.Normal("Rollout Verified",
+		"Rollout spec and the StatefulSet resource are verified"))
+	// record the new pod template hash only if it succeeds
+	s.rolloutStatus.NewPodTemplateIdentifier = targetHash
+	return true, nil
+}
+
+// Initialize makes sure that the statefulset is under our control
+func (s *StatefulSetRolloutController) Initialize(ctx context.Context) (bool, error) {
+	totalReplicas, err := s.size(ctx)
+	if err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+
+	if controller := metav1.GetControllerOf(s.statefulSet); controller != nil {
+		if controller.Kind == v1beta1.AppRolloutKind && controller.APIVersion == v1beta1.SchemeGroupVersion.String() {
+			// it's already there
+			return true, nil
+		}
+	}
+	// add the parent controller to the owner of the statefulset
+	// before kicking start the update and start from every pod in the old version
+	stsPatch := client.MergeFrom(s.statefulSet.DeepCopyObject())
+	ref := metav1.NewControllerRef(s.parentController, v1beta1.AppRolloutKindVersionKind)
+	s.statefulSet.SetOwnerReferences(append(s.statefulSet.GetOwnerReferences(), *ref))
+	s.setPartition(totalReplicas)
+
+	// patch the StatefulSet
+	if err := s.client.Patch(ctx, s.statefulSet, stsPatch, client.FieldOwner(s.parentController.GetUID())); err != nil {
+		s.recorder.Event(s.parentController, event.Warning("Failed to the start the statefulset update", err))
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	// mark the rollout initialized
+	s.recorder.Event(s.parentController, event.Normal("Rollout Initialized", "Rollout resource are initialized"))
+	return true, nil
+}
+
+// RolloutOneBatchPods calculates the number of pods we can upgrade once according to the rollout spec
+// and then set the partition accordingly, return if we are done
+func (s *StatefulSetRolloutController) RolloutOneBatchPods(ctx context.Context) (bool, error) {
+	// calculate what's the total pods that should be upgraded given the currentBatch in the status
+	stsSize, err := s.size(ctx)
+	if err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+
+	newPodTarget := calculateNewBatchTarget(s.rolloutSpec, 0, int(stsSize), int(s.rolloutStatus.CurrentBatch))
+	// set the partition as the desired number of pods in old revisions, the pods with the higher ordinals
+	// are upgraded first
+	stsPatch := client.MergeFrom(s.statefulSet.DeepCopyObject())
+	s.setPartition(stsSize - int32(newPodTarget))
+	// patch the StatefulSet
+	if err = s.client.Patch(ctx, s.statefulSet, stsPatch, client.FieldOwner(s.parentController.GetUID())); err != nil {
+		s.recorder.Event(s.parentController, event.Warning("Failed to update the statefulset to upgrade", err))
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	// record the upgrade
+	klog.InfoS("upgraded one batch", "current batch", s.rolloutStatus.CurrentBatch)
+	s.recorder.Event(s.parentController, event.Normal("Batch Rollout",
+		fmt.Sprintf("Submitted upgrade quest for batch %d", s.rolloutStatus.CurrentBatch)))
+	s.rolloutStatus.UpgradedReplicas = int32(newPodTarget)
+	return true, nil
+}
+
+// CheckOneBatchPods checks to see if enough pods are upgraded according to the rollout plan
+func (s *StatefulSetRolloutController) CheckOneBatchPods(ctx context.Context) (bool, error) {
+	if err := s.fetchStatefulSet(ctx); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	stsSize, _ := s.size(ctx)
+	newPodTarget := calculateNewBatchTarget(s.rolloutSpec, 0, int(stsSize), int(s.rolloutStatus.CurrentBatch))
+	// the statefulset status doesn't tell how many updated pods are ready, so we count them
+	pods, err := listControlledPods(ctx, s.client, s.statefulSet, s.statefulSet.Spec.Selector)
+	if err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		// nolint:nilerr
+		return false, nil
+	}
+	readyPodCount := countReadyPodsOfRevision(pods, s.statefulSet.Status.UpdateRevision)
+	if len(s.rolloutSpec.RolloutBatches) <= int(s.rolloutStatus.CurrentBatch) {
+		err = errors.New("somehow, currentBatch number exceeded the rolloutBatches spec")
+		klog.ErrorS(err, "total batch", len(s.rolloutSpec.RolloutBatches), "current batch",
+			s.rolloutStatus.CurrentBatch)
+		return false, err
+	}
+	currentBatch := s.rolloutSpec.RolloutBatches[s.rolloutStatus.CurrentBatch]
+	unavail := 0
+	if currentBatch.MaxUnavailable != nil {
+		unavail, _ = intstr.GetValueFromIntOrPercent(currentBatch.MaxUnavailable, int(stsSize), true)
+	}
+	klog.InfoS("checking the rolling out progress", "current batch", s.rolloutStatus.CurrentBatch,
+		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
+		"max unavailable pod allowed", unavail)
+	s.rolloutStatus.UpgradedReadyReplicas = int32(readyPodCount)
+	// we could overshoot in the revert case when many pods are already upgraded
+	if unavail+readyPodCount >= newPodTarget {
+		// record the successful upgrade
+		klog.InfoS("all pods in current batch are ready", "current batch", s.rolloutStatus.CurrentBatch)
+		s.recorder.Event(s.parentController, event.Normal("Batch Available",
+			fmt.Sprintf("Batch %d is available", s.rolloutStatus.CurrentBatch)))
+		return true, nil
+	}
+	// continue to verify
+	klog.InfoS("the batch is not ready yet", "current batch", s.rolloutStatus.CurrentBatch)
+	s.rolloutStatus.RolloutRetry("the batch is not ready yet")
+	return false, nil
+}
+
+// FinalizeOneBatch makes sure that the upgradedReplicas and current batch in the status are valid according to the spec
+func (s *StatefulSetRolloutController) FinalizeOneBatch(ctx context.Context) (bool, error) {
+	status := s.rolloutStatus
+	spec := s.rolloutSpec
+	if spec.BatchPartition != nil && *spec.BatchPartition < status.CurrentBatch {
+		err := fmt.Errorf("the current batch value in the status is greater than the batch partition")
+		klog.ErrorS(err, "we have moved past the user defined partition", "user specified batch partition",
+			*spec.BatchPartition, "current batch we are working on", status.CurrentBatch)
+		return false, err
+	}
+	upgradedReplicas := int(status.UpgradedReplicas)
+	currentBatch := int(status.CurrentBatch)
+	// calculate the lower bound of the possible pod count just before the current batch
+	podCount := calculateNewBatchTarget(s.rolloutSpec, 0, int(s.rolloutStatus.RolloutTargetSize), currentBatch-1)
+	// the recorded number should be at least as much as the all the pods before the current batch
+	if podCount > upgradedReplicas {
+		err := fmt.Errorf("the upgraded replica in the status is less than all the pods in the previous batch")
+		klog.ErrorS(err, "rollout status inconsistent", "upgraded num status", upgradedReplicas,
+			"pods in all the previous batches", podCount)
+		return false, err
+	}
+	// calculate the upper bound with the current batch
+	podCount = calculateNewBatchTarget(s.rolloutSpec, 0, int(s.rolloutStatus.RolloutTargetSize), currentBatch)
+	// the recorded number should be not as much as the all the pods including the active batch
+	if podCount < upgradedReplicas {
+		err := fmt.Errorf("the upgraded replica in the status is greater than all the pods in the current batch")
+		klog.ErrorS(err, "rollout status inconsistent", "total target size", s.rolloutStatus.RolloutTargetSize,
+			"upgraded num status", upgradedReplicas, "pods in the batches including the current batch", podCount)
+		return false, err
+	}
+	return true, nil
+}
+
+// Finalize makes sure the StatefulSet is all upgraded
+func (s *StatefulSetRolloutController) Finalize(ctx context.Context, succeed bool) bool {
+	if err := s.fetchStatefulSet(ctx); err != nil {
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	stsPatch := client.MergeFrom(s.statefulSet.DeepCopyObject())
+	// remove the parent controller from the resources' owner list
+	var newOwnerList []metav1.OwnerReference
+	isOwner := false
+	for _, owner := range s.statefulSet.GetOwnerReferences() {
+		if owner.Kind == v1beta1.AppRolloutKind && owner.APIVersion == v1beta1.SchemeGroupVersion.String() {
+			isOwner = true
+			continue
+		}
+		newOwnerList = append(newOwnerList, owner)
+	}
+	if !isOwner {
+		// nothing to do if we are already not the owner
+		klog.InfoS("the statefulset is already released and not controlled by rollout", "statefulSet", s.statefulSet.Name)
+		return true
+	}
+	s.statefulSet.SetOwnerReferences(newOwnerList)
+	// hold the rest of the pods when the rollout failed so we can try again next time
+	if !succeed {
+		size, _ := s.size(ctx)
+		s.setPartition(size)
+	}
+	// patch the StatefulSet
+	if err := s.client.Patch(ctx, s.statefulSet, stsPatch, client.FieldOwner(s.parentController.GetUID())); err != nil {
+		s.recorder.Event(s.parentController, event.Warning("Failed to the finalize the statefulset", err))
+		s.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	// mark the resource finalized
+	s.recorder.Event(s.parentController, event.Normal("Rollout Finalized",
+		fmt.Sprintf("Rollout resource are finalized, succeed := %t", succeed)))
+	s.rolloutStatus.LastAppliedPodTemplateIdentifier = s.rolloutStatus.NewPodTemplateIdentifier
+	return true
+}
+
+// ---------------------------------------------
+// The functions below are helper functions
+// ---------------------------------------------
+
+// check if the replicas in all the rollout batches add up to the right number
+func (s *StatefulSetRolloutController) verifyRolloutBatchReplicaValue(currentReplicas int32) error {
+	// the target size has to be the same as the statefulset size
+	if s.rolloutSpec.TargetSize != nil && *s.rolloutSpec.TargetSize != currentReplicas {
+		return fmt.Errorf("the rollout plan is attempting to scale the statefulset, target = %d, statefulset size = %d",
+			*s.rolloutSpec.TargetSize, currentReplicas)
+	}
+	// use a common function to check if the sum of all the batches can match the statefulset size
+	return verifyBatchesWithRollout(s.rolloutSpec, currentReplicas)
+}
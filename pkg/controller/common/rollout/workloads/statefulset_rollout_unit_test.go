@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+var testAppRollout = &v1beta1.AppRollout{
+	ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: "default", UID: "rollout-uid"},
+}
+
+var testSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+func newTestPod(name, revision string, ready bool, owner metav1.Object, kind string) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app": "test", apps.ControllerRevisionHashLabelKey: revision},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: apps.SchemeGroupVersion.String(),
+				Kind:       kind,
+				Name:       owner.GetName(),
+				UID:        owner.GetUID(),
+				Controller: pointer.BoolPtr(true),
+			}},
+		},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func TestStatefulSetRolloutController(t *testing.T) {
+	ctx := context.Background()
+	sts := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "sts", Namespace: "default", UID: "sts-uid"},
+		Spec: apps.StatefulSetSpec{
+			Replicas: pointer.Int32Ptr(4),
+			Selector: testSelector,
+			UpdateStrategy: apps.StatefulSetUpdateStrategy{
+				Type:          apps.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{Partition: pointer.Int32Ptr(1 << 30)},
+			},
+		},
+		Status: apps.StatefulSetStatus{Replicas: 4, UpdateRevision: "sts-v2", CurrentRevision: "sts-v1"},
+	}
+	objs := []runtime.Object{sts}
+	for i := 0; i < 4; i++ {
+		revision := "sts-v1"
+		if i == 3 {
+			revision = "sts-v2"
+		}
+		objs = append(objs, newTestPod(fmt.Sprintf("sts-%d", i), revision, true, sts, "StatefulSet"))
+	}
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, objs...)
+	spec := &v1alpha1.RolloutPlan{RolloutBatches: []v1alpha1.RolloutBatch{
+		{Replicas: intstr.FromInt(1)}, {Replicas: intstr.FromString("50%")}, {Replicas: intstr.FromInt(1)},
+	}}
+	status := &v1alpha1.RolloutStatus{}
+	controller := NewStatefulSetRolloutController(c, event.NewNopRecorder(), testAppRollout, spec, status,
+		types.NamespacedName{Namespace: "default", Name: "sts"})
+
+	verified, err := controller.VerifySpec(ctx)
+	require.NoError(t, err)
+	assert.True(t, verified)
+	assert.Equal(t, int32(4), status.RolloutTargetSize)
+	assert.Equal(t, "sts-v2", status.NewPodTemplateIdentifier)
+
+	initialized, err := controller.Initialize(ctx)
+	require.NoError(t, err)
+	assert.True(t, initialized)
+	got := &apps.StatefulSet{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "sts"}, got))
+	assert.Equal(t, int32(4), *got.Spec.UpdateStrategy.RollingUpdate.Partition)
+	assert.Equal(t, v1beta1.AppRolloutKind, metav1.GetControllerOf(got).Kind)
+
+	// the first batch upgrades the pod with the highest ordinal
+	done, err := controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, done)
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "sts"}, got))
+	assert.Equal(t, int32(3), *got.Spec.UpdateStrategy.RollingUpdate.Partition)
+	available, err := controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, available)
+	assert.Equal(t, int32(1), status.UpgradedReadyReplicas)
+
+	// the second batch is not ready since no more pod is upgraded
+	status.CurrentBatch = 1
+	done, err = controller.RolloutOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.True(t, done)
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "sts"}, got))
+	assert.Equal(t, int32(1), *got.Spec.UpdateStrategy.RollingUpdate.Partition)
+	available, err = controller.CheckOneBatchPods(ctx)
+	require.NoError(t, err)
+	assert.False(t, available)
+	finalized, err := controller.FinalizeOneBatch(ctx)
+	require.NoError(t, err)
+	assert.True(t, finalized)
+
+	// the rest of the pods are held when the rollout fails
+	assert.True(t, controller.Finalize(ctx, false))
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "sts"}, got))
+	assert.Equal(t, int32(4), *got.Spec.UpdateStrategy.RollingUpdate.Partition)
+	assert.Equal(t, "sts-v2", status.LastAppliedPodTemplateIdentifier)
+}
+
+func TestStatefulSetRolloutVerifySpec(t *testing.T) {
+	newStatefulSet := func(partition *int32, owner *metav1.OwnerReference) *apps.StatefulSet {
+		sts := &apps.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "sts", Namespace: "default"},
+			Spec:       apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(2)},
+			Status:     apps.StatefulSetStatus{Replicas: 2, UpdateRevision: "sts-v2"},
+		}
+		if partition != nil {
+			sts.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
+				Type:          apps.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{Partition: partition},
+			}
+		}
+		if owner != nil {
+			sts.SetOwnerReferences([]metav1.OwnerReference{*owner})
+		}
+		return sts
+	}
+	cases := map[string]struct {
+		sts         *apps.StatefulSet
+		lastApplied string
+		wantErr     bool
+	}{
+		"held by partition": {
+			sts: newStatefulSet(pointer.Int32Ptr(2), nil),
+		},
+		"not held by partition": {
+			sts:     newStatefulSet(nil, nil),
+			wantErr: true,
+		},
+		"no difference from the last rollout": {
+			sts:         newStatefulSet(pointer.Int32Ptr(2), nil),
+			lastApplied: "sts-v2",
+			wantErr:     true,
+		},
+		"controlled by others": {
+			sts: newStatefulSet(pointer.Int32Ptr(2), &metav1.OwnerReference{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "owner", Controller: pointer.BoolPtr(true)}),
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, tc.sts)
+			spec := &v1alpha1.RolloutPlan{RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(2)}}}
+			status := &v1alpha1.RolloutStatus{LastAppliedPodTemplateIdentifier: tc.lastApplied}
+			controller := NewStatefulSetRolloutController(c, event.NewNopRecorder(), testAppRollout, spec, status,
+				types.NamespacedName{Namespace: "default", Name: "sts"})
+			verified, err := controller.VerifySpec(context.Background())
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.False(t, verified)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, verified)
+		})
+	}
+}
@@ -79,4 +79,11 @@ type Args struct {
 
 	// AutoGenWorkloadDefinition indicates whether automatic generated workloadDefinition which componentDefinition refers to
 	AutoGenWorkloadDefinition bool
+
+	// DriftDetection indicates whether the application controller watches the dispatched resources to detect the drift
+	// from their manifests
+	DriftDetection bool
+
+	// DriftCorrectionInterval is the minimum interval between two corrections of the drifted resources of an application
+	DriftCorrectionInterval time.Duration
 }
@@ -49,8 +49,10 @@ import (
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/policy"
 	"github.com/oam-dev/kubevela/pkg/utils/apply"
 	"github.com/oam-dev/kubevela/pkg/workflow"
+	"github.com/oam-dev/kubevela/pkg/workflow/tasks"
 	"github.com/oam-dev/kubevela/version"
 )
 
@@ -61,7 +63,9 @@ const (
 
 const (
 	// WorkflowReconcileWaitTime is the time to wait before reconcile again workflow running
-	WorkflowReconcileWaitTime      = time.Second * 3
+	WorkflowReconcileWaitTime = time.Second * 3
+	// DependencyReconcileWaitTime is the time to wait before reconcile again components waiting for their dependencies
+	DependencyReconcileWaitTime    = time.Second * 5
 	legacyResourceTrackerFinalizer = "resourceTracker.finalizer.core.oam.dev"
 	// resourceTrackerFinalizer is to delete the resource tracker of the latest app revision.
 	resourceTrackerFinalizer = "app.oam.dev/resource-tracker-finalizer"
@@ -80,6 +84,10 @@ type Reconciler struct {
 	applicator           apply.Applicator
 	appRevisionLimit     int
 	concurrentReconciles int
+
+	driftDetection          bool
+	driftCorrectionInterval time.Duration
+	driftWatcher            *driftWatcher
 }
 
 // +kubebuilder:rbac:groups=core.oam.dev,resources=applications,verbs=get;list;watch;create;update;patch;delete
@@ -110,6 +118,9 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	if endReconcile, err := r.handleFinalizers(ctx, app); endReconcile {
 		return ctrl.Result{}, err
 	}
+	if endReconcile, err := r.handleWorkflowControl(ctx, app); endReconcile {
+		return ctrl.Result{}, err
+	}
 
 	handler := &appHandler{
 		r:   r,
@@ -153,14 +164,22 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedRender, err))
 		return handler.handleErr(err)
 	}
-	policies, wfSteps, err := generatedAppfile.GenerateWorkflowAndPolicy()
+	policies, err := generatedAppfile.GeneratePolicies()
 	if err != nil {
-		klog.Error(err, "[Handle GenerateWorkflowAndPolicy]")
+		klog.ErrorS(err, "Failed to generate policies", "application", klog.KObj(app))
 		app.Status.SetConditions(errorCondition("Built", err))
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedRender, err))
 		return handler.handleErr(err)
 	}
 
+	// validate and mutate the rendered manifests by guardrail policies before dispatching them
+	if err := policy.Enforce(generatedAppfile, r.pd, ac, comps); err != nil {
+		klog.ErrorS(err, "Failed to enforce policies", "application", klog.KObj(app))
+		app.Status.SetConditions(errorCondition("Policy", err))
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedPolicy, err))
+		return handler.handleErr(err)
+	}
+
 	app.Status.SetConditions(readyCondition("Built"))
 	r.Recorder.Event(app, event.Normal(velatypes.ReasonRendered, velatypes.MessageRendered))
 	klog.Info("Successfully render application resources", "application", klog.KObj(app))
@@ -169,6 +188,11 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	oamutil.PassLabelAndAnnotation(app, ac)
 	// apply application resources' manifests to the cluster
 	if err := handler.apply(ctx, appRev, ac, comps, policies); err != nil {
+		if dispatch.IsPendingDependencies(err) {
+			klog.InfoS("Wait for the dependencies of components to be healthy", "application", klog.KObj(app), "reason", err.Error())
+			app.Status.SetConditions(errorCondition("Applied", err))
+			return ctrl.Result{RequeueAfter: DependencyReconcileWaitTime}, r.UpdateStatus(ctx, app)
+		}
 		klog.ErrorS(err, "Failed to apply application resources' manifests",
 			"application", klog.KObj(app))
 		app.Status.SetConditions(errorCondition("Applied", err))
@@ -177,17 +201,44 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	}
 	klog.Info("Successfully apply application resources' manifests", "application", klog.KObj(app))
 
-	done, err := workflow.NewWorkflow(app, handler.r.applicator).ExecuteSteps(ctx, appRev.Name, wfSteps)
+	taskRunners, err := tasks.NewTaskDiscover(app, r.Client, r.applicator, r.pd,
+		handler.applyComponentFunc(appRev, ac, comps)).GenerateTaskRunners(generatedAppfile.WorkflowSteps)
 	if err != nil {
-		klog.Error(err, "[handle workflow]")
+		klog.ErrorS(err, "Failed to generate workflow steps", "application", klog.KObj(app))
+		app.Status.SetConditions(errorCondition("Workflow", err))
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+		return handler.handleErr(err)
+	}
+	done, err := workflow.NewWorkflow(app, r.Client).ExecuteSteps(ctx, appRev.Name, taskRunners)
+	if err != nil {
+		klog.ErrorS(err, "Failed to execute workflow", "application", klog.KObj(app))
 		app.Status.SetConditions(errorCondition("Workflow", err))
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
 		return handler.handleErr(err)
 	}
 	if !done {
+		if app.Status.Workflow != nil && (app.Status.Workflow.Suspend || app.Status.Workflow.Terminated) {
+			// a suspended or terminated workflow will be continued once it's resumed or restarted,
+			// which updates the application
+			return reconcile.Result{}, r.UpdateStatus(ctx, app)
+		}
 		return reconcile.Result{RequeueAfter: WorkflowReconcileWaitTime}, r.UpdateStatus(ctx, app)
 	}
 
+	if r.driftDetection && app.Spec.Workflow != nil {
+		if err := handler.handleWorkflowDrift(ctx, appRev, ac, comps); err != nil {
+			if dispatch.IsPendingDependencies(err) {
+				klog.InfoS("Wait for the dependencies of components to be healthy", "application", klog.KObj(app), "reason", err.Error())
+				app.Status.SetConditions(errorCondition("Applied", err))
+				return ctrl.Result{RequeueAfter: DependencyReconcileWaitTime}, r.UpdateStatus(ctx, app)
+			}
+			klog.ErrorS(err, "Failed to handle the drift of the resources applied by workflow", "application", klog.KObj(app))
+			app.Status.SetConditions(errorCondition("Applied", err))
+			r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedApply, err))
+			return handler.handleErr(err)
+		}
+	}
+
 	// if inplace is false and rolloutPlan is nil, it means the user will use an outer AppRollout object to rollout the application
 	if handler.app.Spec.RolloutPlan != nil {
 		res, err := handler.handleRollout(ctx)
@@ -254,7 +305,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	}
 	app.Status.Components = refComps
 	r.Recorder.Event(app, event.Normal(velatypes.ReasonDeployed, velatypes.MessageDeployed))
-	return ctrl.Result{}, r.UpdateStatus(ctx, app)
+	return ctrl.Result{RequeueAfter: handler.driftRequeueAfter}, r.UpdateStatus(ctx, app)
 }
 
 // NOTE Because resource tracker is cluster-scoped resources, we cannot garbage collect them
@@ -293,6 +344,11 @@ func (r *Reconciler) handleFinalizers(ctx context.Context, app *v1beta1.Applicat
 			if app.Status.LatestRevision != nil && len(app.Status.LatestRevision.Name) != 0 {
 				latestTracker := &v1beta1.ResourceTracker{}
 				latestTracker.SetName(dispatch.ConstructResourceTrackerName(app.Status.LatestRevision.Name, app.Namespace))
+				if err := r.releaseRetainedResources(ctx, latestTracker); err != nil {
+					klog.ErrorS(err, "Failed to release retained resources", "resourceTracker", latestTracker.Name)
+					app.Status.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, "error to  remove finalizer")))
+					return true, errors.Wrap(r.UpdateStatus(ctx, app), errUpdateApplicationStatus)
+				}
 				if err := r.Client.Delete(ctx, latestTracker); err != nil && !kerrors.IsNotFound(err) {
 					klog.ErrorS(err, "Failed to delete latest resource tracker", "name", latestTracker.Name)
 					app.Status.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, "error to  remove finalizer")))
@@ -315,6 +371,11 @@ func (r *Reconciler) handleFinalizers(ctx context.Context, app *v1beta1.Applicat
 				return true, errors.Wrap(r.UpdateStatus(ctx, app), errUpdateApplicationStatus)
 			}
 			for _, rt := range rtList.Items {
+				if err := dispatch.ReleaseRetainedResources(ctx, r.Client, rt.DeepCopy()); err != nil {
+					klog.ErrorS(err, "Failed to release retained resources", "resourceTracker", rt.Name)
+					app.Status.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, "error to  remove finalizer")))
+					return true, errors.Wrap(r.UpdateStatus(ctx, app), errUpdateApplicationStatus)
+				}
 				if err := r.Client.Delete(ctx, rt.DeepCopy()); err != nil && !kerrors.IsNotFound(err) {
 					klog.ErrorS(err, "Failed to delete resource tracker", "name", rt.Name)
 					app.Status.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, "error to  remove finalizer")))
@@ -328,6 +389,15 @@ func (r *Reconciler) handleFinalizers(ctx context.Context, app *v1beta1.Applicat
 	return false, nil
 }
 
+// releaseRetainedResources releases the resources with the retain policy from the resource tracker before the
+// resource tracker is deleted along with the application
+func (r *Reconciler) releaseRetainedResources(ctx context.Context, rt *v1beta1.ResourceTracker) error {
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: rt.Name}, rt); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return dispatch.ReleaseRetainedResources(ctx, r.Client, rt)
+}
+
 // appWillReleaseByRollout judge whether the application will be released by rollout.
 // If it's true, application controller will only create or update application revision but not emit any other K8s
 // resources into the cluster. Rollout controller will do real release works.
@@ -335,16 +405,45 @@ func appWillReleaseByRollout(app *v1beta1.Application) bool {
 	return len(app.GetAnnotations()[oam.AnnotationAppRollout]) != 0 || app.Spec.RolloutPlan != nil
 }
 
+// handleWorkflowControl applies the workflow control operation requested by the annotation of the application,
+// the annotation is removed afterwards which triggers another reconciliation.
+func (r *Reconciler) handleWorkflowControl(ctx context.Context, app *v1beta1.Application) (bool, error) {
+	operation := app.GetAnnotations()[oam.AnnotationWorkflowControl]
+	if operation == "" {
+		return false, nil
+	}
+	step := app.GetAnnotations()[oam.AnnotationWorkflowRestartStep]
+	if err := workflow.Control(app, operation, step); err != nil {
+		klog.ErrorS(err, "Failed to control workflow", "application", klog.KObj(app), "operation", operation)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+	} else {
+		klog.InfoS("Control workflow", "application", klog.KObj(app), "operation", operation, "step", step)
+		if err := r.UpdateStatus(ctx, app); err != nil {
+			return true, errors.Wrap(err, errUpdateApplicationStatus)
+		}
+	}
+	oamutil.RemoveAnnotations(app, []string{oam.AnnotationWorkflowControl, oam.AnnotationWorkflowRestartStep})
+	return true, errors.Wrap(r.Client.Update(ctx, app), "cannot remove workflow control annotation")
+}
+
 // SetupWithManager install to manager
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, compHandler *ac.ComponentHandler) error {
 	// If Application Own these two child objects, AC status change will notify application controller and recursively update AC again, and trigger application event again...
-	return ctrl.NewControllerManagedBy(mgr).
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.concurrentReconciles,
 		}).
 		For(&v1beta1.Application{}).
 		Watches(&source.Kind{Type: &v1alpha2.Component{}}, compHandler).
-		Complete(r)
+		Build(r)
+	if err != nil {
+		return err
+	}
+	if r.driftDetection {
+		// the kinds of the dispatched resources are only known after rendering, so they're watched on demand
+		r.driftWatcher = newDriftWatcher(c, mgr.GetCache())
+	}
+	return nil
 }
 
 // UpdateStatus updates v1beta1.Application's Status with retry.RetryOnConflict
@@ -370,6 +469,9 @@ func Setup(mgr ctrl.Manager, args core.Args) error {
 		applicator:           apply.NewAPIApplicator(mgr.GetClient()),
 		appRevisionLimit:     args.AppRevisionLimit,
 		concurrentReconciles: args.ConcurrentReconciles,
+
+		driftDetection:          args.DriftDetection,
+		driftCorrectionInterval: args.DriftCorrectionInterval,
 	}
 	compHandler := &ac.ComponentHandler{
 		Client:                mgr.GetClient(),
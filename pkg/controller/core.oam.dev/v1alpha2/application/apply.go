@@ -19,6 +19,7 @@ package application
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
@@ -49,6 +50,7 @@ import (
 	"github.com/oam-dev/kubevela/pkg/cue/process"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow/tasks"
 )
 
 func errorCondition(tpy string, err error) runtimev1alpha1.Condition {
@@ -78,6 +80,10 @@ type appHandler struct {
 	isNewRevision        bool
 	revisionHash         string
 	autodetect           bool
+	// driftRequeueAfter is the time to wait before correcting the drifted resources
+	driftRequeueAfter time.Duration
+	// appliedComponents is the set of the components applied by the workflow steps in this reconciliation
+	appliedComponents sync.Map
 }
 
 func (h *appHandler) handleErr(err error) (ctrl.Result, error) {
@@ -162,12 +168,17 @@ func (h *appHandler) apply(ctx context.Context, appRev *v1beta1.ApplicationRevis
 		if err != nil {
 			return errors.WithMessage(err, "cannot assemble resources' manifests")
 		}
-		d := dispatch.NewAppManifestsDispatcher(h.r.Client, appRev)
+		d := dispatch.NewAppManifestsDispatcher(h.r.Client, appRev).WithHealthChecker(h.componentHealthChecker(h.appfile))
 		if len(h.previousRevisionName) != 0 {
 			latestTracker := &v1beta1.ResourceTracker{}
 			latestTracker.SetName(dispatch.ConstructResourceTrackerName(h.previousRevisionName, h.app.Namespace))
 			d = d.EnableUpgradeAndGC(latestTracker)
 		}
+		if h.r.driftDetection {
+			if manifests, err = h.handleDrift(ctx, manifests); err != nil {
+				return err
+			}
+		}
 		if _, err := d.Dispatch(ctx, manifests); err != nil {
 			return errors.WithMessage(err, "cannot dispatch resources' manifests")
 		}
@@ -175,6 +186,90 @@ func (h *appHandler) apply(ctx context.Context, appRev *v1beta1.ApplicationRevis
 	return nil
 }
 
+// applyComponentFunc returns the function used by the `apply-component` workflow step to apply the workload and
+// traits of one component. The applied resources are recorded in the resource tracker of the app revision.
+func (h *appHandler) applyComponentFunc(appRev *v1beta1.ApplicationRevision, ac *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) tasks.ComponentApplier {
+	return func(ctx context.Context, compName string) error {
+		manifests, err := h.componentManifests(ctx, appRev, ac, comps, compName)
+		if err != nil {
+			return err
+		}
+		if h.r.driftDetection {
+			// the step isn't executed again once it succeeded, the drift of its resources is handled
+			// after the workflow is done
+			if err := h.r.driftWatcher.watch(manifests); err != nil {
+				return err
+			}
+		}
+		if err := h.dispatchComponentManifests(ctx, appRev, manifests); err != nil {
+			return err
+		}
+		h.appliedComponents.Store(compName, true)
+		return nil
+	}
+}
+
+// componentManifests renders the manifests of the workload and traits of one component
+func (h *appHandler) componentManifests(ctx context.Context, appRev *v1beta1.ApplicationRevision,
+	ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component, compName string) ([]*unstructured.Unstructured, error) {
+	owners := []metav1.OwnerReference{*metav1.NewControllerRef(h.app, v1beta1.ApplicationKindVersionKind)}
+	var revisionName string
+	for _, comp := range comps {
+		if comp.Name != compName {
+			continue
+		}
+		newComp := comp.DeepCopy()
+		newComp.SetOwnerReferences(owners)
+		// the component revision only advances when the component changes
+		var err error
+		if revisionName, err = h.createOrUpdateComponent(ctx, newComp); err != nil {
+			return nil, err
+		}
+	}
+	if len(revisionName) == 0 {
+		return nil, errors.Errorf("component %s not found in application %s", compName, h.app.Name)
+	}
+	compAC := ac.DeepCopy()
+	compAC.SetOwnerReferences(owners)
+	compAC.Spec.Components = nil
+	for _, acc := range ac.Spec.Components {
+		if acc.ComponentName == compName {
+			acc.RevisionName = revisionName
+			acc.ComponentName = ""
+			compAC.Spec.Components = append(compAC.Spec.Components, acc)
+		}
+	}
+	if len(compAC.Spec.Components) == 0 {
+		return nil, errors.Errorf("component %s not found in application %s", compName, h.app.Name)
+	}
+	compRev := appRev.DeepCopy()
+	h.setRevisionWithRenderedResult(compRev, compAC, comps)
+
+	a := assemble.NewAppManifests(compRev).WithWorkloadOption(assemble.DiscoveryHelmBasedWorkload(ctx, h.r.Client))
+	manifests, err := a.AssembledManifests()
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot assemble resources' manifests")
+	}
+	return manifests, nil
+}
+
+// dispatchComponentManifests dispatches the manifests applied by the workflow, the resources of the previous
+// revision are left to the garbage collection after the workflow is done
+func (h *appHandler) dispatchComponentManifests(ctx context.Context, appRev *v1beta1.ApplicationRevision,
+	manifests []*unstructured.Unstructured) error {
+	d := dispatch.NewAppManifestsDispatcher(h.r.Client, appRev).WithHealthChecker(h.componentHealthChecker(h.appfile))
+	if len(h.previousRevisionName) != 0 && h.previousRevisionName != appRev.Name {
+		latestTracker := &v1beta1.ResourceTracker{}
+		latestTracker.SetName(dispatch.ConstructResourceTrackerName(h.previousRevisionName, h.app.Namespace))
+		d = d.EnableUpgradeAndSkipGC(latestTracker)
+	}
+	if _, err := d.Dispatch(ctx, manifests); err != nil {
+		return errors.WithMessage(err, "cannot dispatch resources' manifests")
+	}
+	return nil
+}
+
 func (h *appHandler) createOrUpdateAppRevision(ctx context.Context, appRev *v1beta1.ApplicationRevision) error {
 	if appRev.Labels == nil {
 		appRev.Labels = make(map[string]string)
@@ -198,93 +293,120 @@ func (h *appHandler) statusAggregate(appFile *appfile.Appfile) ([]common.Applica
 	var appStatus []common.ApplicationComponentStatus
 	var healthy = true
 	for _, wl := range appFile.Workloads {
-		var status = common.ApplicationComponentStatus{
-			Name:               wl.Name,
-			WorkloadDefinition: wl.FullTemplate.Reference.Definition,
-			Healthy:            true,
-		}
-
-		var (
-			outputSecretName string
-			err              error
-			pCtx             process.Context
-		)
-
-		if wl.IsCloudResourceProducer() {
-			outputSecretName, err = appfile.GetOutputSecretNames(wl)
-			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, setting outputSecretName error", appFile.Name, wl.Name)
-			}
-			pCtx.InsertSecrets(outputSecretName, wl.RequiredSecrets)
+		status, compHealthy, err := h.collectHealthStatus(appFile, wl)
+		if err != nil {
+			return nil, false, err
+		}
+		if !compHealthy {
+			healthy = false
 		}
+		appStatus = append(appStatus, status)
+	}
+	return appStatus, healthy, nil
+}
 
-		switch wl.CapabilityCategory {
-		case types.TerraformCategory:
-			pCtx = appfile.NewBasicContext(wl, appFile.Name, appFile.RevisionName, appFile.Namespace)
-			ctx := context.Background()
-			var configuration terraformapi.Configuration
-			if err := h.r.Client.Get(ctx, client.ObjectKey{Name: wl.Name, Namespace: h.app.Namespace}, &configuration); err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, check health error", appFile.Name, wl.Name)
-			}
-			if configuration.Status.State != terraformtypes.Available {
-				healthy = false
-				status.Healthy = false
-			} else {
-				status.Healthy = true
-			}
-			status.Message = configuration.Status.Message
-		default:
-			pCtx = process.NewContext(h.app.Namespace, wl.Name, appFile.Name, appFile.RevisionName)
-			if err := wl.EvalContext(pCtx); err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate context error", appFile.Name, wl.Name)
-			}
-			workloadHealth, err := wl.EvalHealth(pCtx, h.r, h.app.Namespace)
-			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, check health error", appFile.Name, wl.Name)
-			}
-			if !workloadHealth {
-				// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
-				status.Healthy = false
-				healthy = false
+// componentHealthChecker checks the health of a component by the health policies of its workload and traits
+func (h *appHandler) componentHealthChecker(appFile *appfile.Appfile) dispatch.HealthChecker {
+	return func(_ context.Context, compName string) (bool, error) {
+		for _, wl := range appFile.Workloads {
+			if wl.Name == compName {
+				_, healthy, err := h.collectHealthStatus(appFile, wl)
+				return healthy, err
 			}
+		}
+		return false, errors.Errorf("component %s not found in application %s", compName, appFile.Name)
+	}
+}
 
-			status.Message, err = wl.EvalStatus(pCtx, h.r, h.app.Namespace)
-			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate workload status message error", appFile.Name, wl.Name)
-			}
+// collectHealthStatus evaluates the health and status message of the workload and traits of a component,
+// the component is healthy only if its workload and traits are all healthy.
+func (h *appHandler) collectHealthStatus(appFile *appfile.Appfile, wl *appfile.Workload) (common.ApplicationComponentStatus, bool, error) {
+	var status = common.ApplicationComponentStatus{
+		Name:               wl.Name,
+		WorkloadDefinition: wl.FullTemplate.Reference.Definition,
+		Healthy:            true,
+	}
+	var healthy = true
+
+	var (
+		outputSecretName string
+		err              error
+		pCtx             process.Context
+	)
+
+	if wl.IsCloudResourceProducer() {
+		outputSecretName, err = appfile.GetOutputSecretNames(wl)
+		if err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, setting outputSecretName error", appFile.Name, wl.Name)
 		}
+		pCtx.InsertSecrets(outputSecretName, wl.RequiredSecrets)
+	}
 
-		var traitStatusList []common.ApplicationTraitStatus
-		for _, tr := range wl.Traits {
-			if err := tr.EvalContext(pCtx); err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate context error", appFile.Name, wl.Name, tr.Name)
-			}
+	switch wl.CapabilityCategory {
+	case types.TerraformCategory:
+		pCtx = appfile.NewBasicContext(wl, appFile.Name, appFile.RevisionName, appFile.Namespace)
+		ctx := context.Background()
+		var configuration terraformapi.Configuration
+		if err := h.r.Client.Get(ctx, client.ObjectKey{Name: wl.Name, Namespace: h.app.Namespace}, &configuration); err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, check health error", appFile.Name, wl.Name)
+		}
+		if configuration.Status.State != terraformtypes.Available {
+			healthy = false
+			status.Healthy = false
+		} else {
+			status.Healthy = true
+		}
+		status.Message = configuration.Status.Message
+	default:
+		pCtx = process.NewContext(h.app.Namespace, wl.Name, appFile.Name, appFile.RevisionName)
+		if err := wl.EvalContext(pCtx); err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate context error", appFile.Name, wl.Name)
+		}
+		workloadHealth, err := wl.EvalHealth(pCtx, h.r, h.app.Namespace)
+		if err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, check health error", appFile.Name, wl.Name)
+		}
+		if !workloadHealth {
+			// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
+			status.Healthy = false
+			healthy = false
+		}
 
-			var traitStatus = common.ApplicationTraitStatus{
-				Type:    tr.Name,
-				Healthy: true,
-			}
-			traitHealth, err := tr.EvalHealth(pCtx, h.r, h.app.Namespace)
-			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, check health error", appFile.Name, wl.Name, tr.Name)
-			}
-			if !traitHealth {
-				// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
-				traitStatus.Healthy = false
-				healthy = false
-			}
-			traitStatus.Message, err = tr.EvalStatus(pCtx, h.r, h.app.Namespace)
-			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate status message error", appFile.Name, wl.Name, tr.Name)
-			}
-			traitStatusList = append(traitStatusList, traitStatus)
+		status.Message, err = wl.EvalStatus(pCtx, h.r, h.app.Namespace)
+		if err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate workload status message error", appFile.Name, wl.Name)
 		}
+	}
 
-		status.Traits = traitStatusList
-		status.Scopes = generateScopeReference(wl.Scopes)
-		appStatus = append(appStatus, status)
+	var traitStatusList []common.ApplicationTraitStatus
+	for _, tr := range wl.Traits {
+		if err := tr.EvalContext(pCtx); err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate context error", appFile.Name, wl.Name, tr.Name)
+		}
+
+		var traitStatus = common.ApplicationTraitStatus{
+			Type:    tr.Name,
+			Healthy: true,
+		}
+		traitHealth, err := tr.EvalHealth(pCtx, h.r, h.app.Namespace)
+		if err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, check health error", appFile.Name, wl.Name, tr.Name)
+		}
+		if !traitHealth {
+			// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
+			traitStatus.Healthy = false
+			healthy = false
+		}
+		traitStatus.Message, err = tr.EvalStatus(pCtx, h.r, h.app.Namespace)
+		if err != nil {
+			return status, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate status message error", appFile.Name, wl.Name, tr.Name)
+		}
+		traitStatusList = append(traitStatusList, traitStatus)
 	}
-	return appStatus, healthy, nil
+
+	status.Traits = traitStatusList
+	status.Scopes = generateScopeReference(wl.Scopes)
+	return status, healthy, nil
 }
 
 // createOrUpdateComponent creates a component if not exist and update if exists.
@@ -26,6 +26,7 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
 	ctrlutil "github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
@@ -52,6 +53,8 @@ type AppManifests struct {
 	appAnnotations map[string]string
 	appOwnerRef    *metav1.OwnerReference
 
+	resourcePolicies *appfile.ResourcePolicySpec
+
 	assembledWorkloads map[string]*unstructured.Unstructured
 	assembledTraits    map[string][]*unstructured.Unstructured
 	// key is workload reference, values are the references of scopes the workload belongs to
@@ -169,6 +172,12 @@ func (am *AppManifests) assemble() {
 		am.finalizeAssemble(err)
 		return
 	}
+	resourcePolicies, err := appfile.ParseResourcePolicies(am.AppRevision.Spec.Application.Spec.Policies)
+	if err != nil {
+		am.finalizeAssemble(err)
+		return
+	}
+	am.resourcePolicies = resourcePolicies
 	for _, ac := range am.appComponents {
 		compRevisionName := ac.revisionName
 		compName := ctrlutil.ExtractComponentName(compRevisionName)
@@ -296,6 +305,9 @@ func (am *AppManifests) setAnnotations(obj *unstructured.Unstructured) {
 		oam.AnnotationRollingComponent,
 		oam.AnnotationInplaceUpgrade,
 	})
+	// the resource-policy policies of the application decide what GC does to the resource, the labels of
+	// the workloads and traits are set before their annotations
+	appfile.SetResourcePolicy(obj, obj.GetLabels()[oam.LabelAppComponent], am.resourcePolicies)
 }
 
 func (am *AppManifests) setNamespace(obj *unstructured.Unstructured) {
@@ -19,6 +19,7 @@ package assemble
 import (
 	"context"
 	"fmt"
+	"math"
 	"reflect"
 	"strings"
 
@@ -123,6 +124,9 @@ func PrepareWorkloadForRollout() WorkloadOption {
 			cloneSetDisablePath            = "spec.updateStrategy.paused"
 			advancedStatefulSetDisablePath = "spec.updateStrategy.rollingUpdate.paused"
 			deploymentDisablePath          = "spec.paused"
+			statefulSetStrategyTypePath    = "spec.updateStrategy.type"
+			statefulSetDisablePath         = "spec.updateStrategy.rollingUpdate.partition"
+			daemonSetDisablePath           = "spec.updateStrategy.type"
 		)
 		pv := fieldpath.Pave(assembledWorkload.UnstructuredContent())
 		// TODO: we can get the workloadDefinition name from workload.GetLabels()["oam.WorkloadTypeLabel"]
@@ -148,15 +152,37 @@ func PrepareWorkloadForRollout() WorkloadOption {
 					"kind", assembledWorkload.GetKind(), "instance name", assembledWorkload.GetName())
 				return nil
 			}
-		} else if assembledWorkload.GroupVersionKind().Group == appsv1.GroupName &&
-			assembledWorkload.GetKind() == reflect.TypeOf(appsv1.Deployment{}).Name() {
-			err := pv.SetBool(deploymentDisablePath, true)
-			if err != nil {
-				return err
+		} else if assembledWorkload.GroupVersionKind().Group == appsv1.GroupName {
+			switch assembledWorkload.GetKind() {
+			case reflect.TypeOf(appsv1.Deployment{}).Name():
+				err := pv.SetBool(deploymentDisablePath, true)
+				if err != nil {
+					return err
+				}
+				klog.InfoS("we render a deployment assembledWorkload.paused on the first time",
+					"kind", assembledWorkload.GetKind(), "instance name", assembledWorkload.GetName())
+				return nil
+			case reflect.TypeOf(appsv1.StatefulSet{}).Name():
+				// no pod is updated until the rollout moves the partition down
+				if err := pv.SetString(statefulSetStrategyTypePath, string(appsv1.RollingUpdateStatefulSetStrategyType)); err != nil {
+					return err
+				}
+				if err := pv.SetValue(statefulSetDisablePath, int64(math.MaxInt32)); err != nil {
+					return err
+				}
+				klog.InfoS("we render a statefulset assembledWorkload held by partition on the first time",
+					"kind", assembledWorkload.GetKind(), "instance name", assembledWorkload.GetName())
+				return nil
+			case reflect.TypeOf(appsv1.DaemonSet{}).Name():
+				// no pod is updated until the rollout deletes it
+				if err := pv.SetString(daemonSetDisablePath, string(appsv1.OnDeleteDaemonSetStrategyType)); err != nil {
+					return err
+				}
+				unstructured.RemoveNestedField(assembledWorkload.Object, "spec", "updateStrategy", "rollingUpdate")
+				klog.InfoS("we render a daemonset assembledWorkload updated on delete on the first time",
+					"kind", assembledWorkload.GetKind(), "instance name", assembledWorkload.GetName())
+				return nil
 			}
-			klog.InfoS("we render a deployment assembledWorkload.paused on the first time",
-				"kind", assembledWorkload.GetKind(), "instance name", assembledWorkload.GetName())
-			return nil
 		}
 
 		klog.InfoS("we encountered an unknown resource, we don't know how to prepare it",
@@ -142,6 +142,54 @@ var _ = Describe("Test WorkloadOption", func() {
 			Expect(assembledDeploy.Spec.Paused).Should(BeTrue())
 		})
 
+		It("test rollout StatefulSet", func() {
+			By("Use StatefulSet as workload")
+			sts := appsv1.StatefulSet{}
+			sts.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind(reflect.TypeOf(appsv1.StatefulSet{}).Name()))
+			comp := v1alpha2.Component{}
+			comp.SetName(compName)
+			comp.Spec.Workload = util.Object2RawExtension(sts)
+			appRev.Spec.Components[0] = common.RawComponent{
+				Raw: util.Object2RawExtension(comp),
+			}
+
+			By("Add PrepareWorkloadForRollout WorkloadOption")
+			ao := NewAppManifests(appRev).WithWorkloadOption(PrepareWorkloadForRollout())
+			workloads, _, _, err := ao.GroupAssembledManifests()
+			Expect(err).Should(BeNil())
+
+			By("Verify workload is held by partition")
+			assembledSts := &appsv1.StatefulSet{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(workloads[compName].Object, assembledSts)
+			Expect(assembledSts.Spec.UpdateStrategy.Type).Should(Equal(appsv1.RollingUpdateStatefulSetStrategyType))
+			Expect(*assembledSts.Spec.UpdateStrategy.RollingUpdate.Partition).Should(BeNumerically(">", 1<<30))
+		})
+
+		It("test rollout DaemonSet", func() {
+			By("Use DaemonSet as workload")
+			ds := appsv1.DaemonSet{}
+			ds.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind(reflect.TypeOf(appsv1.DaemonSet{}).Name()))
+			ds.Spec.UpdateStrategy.Type = appsv1.RollingUpdateDaemonSetStrategyType
+			ds.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateDaemonSet{}
+			comp := v1alpha2.Component{}
+			comp.SetName(compName)
+			comp.Spec.Workload = util.Object2RawExtension(ds)
+			appRev.Spec.Components[0] = common.RawComponent{
+				Raw: util.Object2RawExtension(comp),
+			}
+
+			By("Add PrepareWorkloadForRollout WorkloadOption")
+			ao := NewAppManifests(appRev).WithWorkloadOption(PrepareWorkloadForRollout())
+			workloads, _, _, err := ao.GroupAssembledManifests()
+			Expect(err).Should(BeNil())
+
+			By("Verify workload is updated on delete")
+			assembledDs := &appsv1.DaemonSet{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(workloads[compName].Object, assembledDs)
+			Expect(assembledDs.Spec.UpdateStrategy.Type).Should(Equal(appsv1.OnDeleteDaemonSetStrategyType))
+			Expect(assembledDs.Spec.UpdateStrategy.RollingUpdate).Should(BeNil())
+		})
+
 	})
 
 	Describe("test DiscoveryHelmBasedWorkload", func() {
@@ -25,7 +25,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -49,9 +48,10 @@ func NewAppManifestsDispatcher(c client.Client, appRev *v1beta1.ApplicationRevis
 // resource tracker which is named by a particular rule: name = appRevision's Name + appRevision's namespace.
 // A bundle of manifests to be dispatched MUST come from the given application revision.
 type AppManifestsDispatcher struct {
-	c          client.Client
-	applicator apply.Applicator
-	gcHandler  GarbageCollector
+	c             client.Client
+	applicator    apply.Applicator
+	gcHandler     GarbageCollector
+	healthChecker HealthChecker
 
 	appRev     *v1beta1.ApplicationRevision
 	previousRT *v1beta1.ResourceTracker
@@ -85,7 +85,17 @@ func (a *AppManifestsDispatcher) EnableUpgradeAndSkipGC(rt *v1beta1.ResourceTrac
 	return a
 }
 
+// WithHealthChecker return an AppManifestsDispatcher that holds back the components until the components
+// they depend on are healthy. Without a health checker, the dependencies only decide the apply order.
+func (a *AppManifestsDispatcher) WithHealthChecker(checker HealthChecker) *AppManifestsDispatcher {
+	a.healthChecker = checker
+	return a
+}
+
 // Dispatch apply manifests into k8s and return a resource tracker recording applied manifests' references.
+// Manifests are applied in order: CRDs and Namespaces first, then ConfigMaps and Secrets, then workloads, then traits,
+// and the components are applied after the components they depend on.
+// If some components are held back by their dependencies, it returns a PendingDependenciesError and skips GC.
 // If GC is enabled, it will do GC after applying.
 // If 'UpgradeAndSkipGC' is enabled, it will:
 // - create new resources if not exist before
@@ -98,9 +108,14 @@ func (a *AppManifestsDispatcher) Dispatch(ctx context.Context, manifests []*unst
 	if err := a.createOrGetResourceTracker(ctx); err != nil {
 		return nil, err
 	}
-	if err := a.applyAndRecordManifests(ctx, manifests); err != nil {
+	pending, err := a.applyAndRecordManifests(ctx, manifests)
+	if err != nil {
 		return nil, err
 	}
+	if len(pending) != 0 {
+		// the resources of the pending components are not applied yet, GC would delete their old versions
+		return a.currentRT.DeepCopy(), &PendingDependenciesError{Components: pending}
+	}
 	if !a.skipGC && a.previousRT != nil && a.previousRT.Name != a.currentRTName {
 		if err := a.gcHandler.GarbageCollect(ctx, a.previousRT, a.currentRT); err != nil {
 			return nil, errors.WithMessagef(err, "cannot do GC based on resource trackers %q and %q", a.previousRT.Name, a.currentRTName)
@@ -173,7 +188,7 @@ func (a *AppManifestsDispatcher) createOrGetResourceTracker(ctx context.Context)
 	return nil
 }
 
-func (a *AppManifestsDispatcher) applyAndRecordManifests(ctx context.Context, manifests []*unstructured.Unstructured) error {
+func (a *AppManifestsDispatcher) applyAndRecordManifests(ctx context.Context, manifests []*unstructured.Unstructured) (map[string][]string, error) {
 	ctrlUIDs := []types.UID{a.currentRT.UID}
 	if a.previousRT != nil && a.previousRT.Name != a.currentRTName {
 		klog.InfoS("Going to apply or upgrade resources", "from", a.previousRT.Name, "to", a.currentRTName)
@@ -192,61 +207,60 @@ func (a *AppManifestsDispatcher) applyAndRecordManifests(ctx context.Context, ma
 		Controller:         pointer.BoolPtr(true),
 		BlockOwnerDeletion: pointer.BoolPtr(true),
 	}
-	for _, rsc := range manifests {
-		// each resource applied by dispatcher MUST be controlled by resource tracker
-		setOrOverrideControllerOwner(rsc, ownerRef)
-		if err := a.applicator.Apply(ctx, rsc, applyOpts...); err != nil {
-			klog.ErrorS(err, "Failed to apply a resource", "object",
+	var applied []*unstructured.Unstructured
+	pending, err := a.dispatchWaves(ctx, manifests, func(wave []*unstructured.Unstructured) error {
+		for _, rsc := range wave {
+			// each resource applied by dispatcher MUST be controlled by resource tracker, except the protected ones
+			// which must survive the resource trackers
+			if !isProtected(rsc) {
+				setOrOverrideControllerOwner(rsc, ownerRef)
+			}
+			if err := a.applicator.Apply(ctx, rsc, applyOpts...); err != nil {
+				klog.ErrorS(err, "Failed to apply a resource", "object",
+					klog.KObj(rsc), "apiVersion", rsc.GetAPIVersion(), "kind", rsc.GetKind())
+				return errors.Wrapf(err, "cannot apply manifest, name: %q apiVersion: %q kind: %q",
+					rsc.GetName(), rsc.GetAPIVersion(), rsc.GetKind())
+			}
+			klog.InfoS("Successfully apply a resource", "object",
 				klog.KObj(rsc), "apiVersion", rsc.GetAPIVersion(), "kind", rsc.GetKind())
-			return errors.Wrapf(err, "cannot apply manifest, name: %q apiVersion: %q kind: %q",
-				rsc.GetName(), rsc.GetAPIVersion(), rsc.GetKind())
+			applied = append(applied, rsc)
 		}
-		klog.InfoS("Successfully apply a resource", "object",
-			klog.KObj(rsc), "apiVersion", rsc.GetAPIVersion(), "kind", rsc.GetKind())
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return a.updateResourceTrackerStatus(ctx, manifests)
+	for comp, deps := range pending {
+		klog.InfoS("Hold back a component until its dependencies are healthy", "component", comp, "dependencies", deps)
+	}
+	return pending, a.updateResourceTracker(ctx, applied)
 }
 
-func (a *AppManifestsDispatcher) updateResourceTrackerStatus(ctx context.Context, appliedManifests []*unstructured.Unstructured) error {
+func (a *AppManifestsDispatcher) updateResourceTracker(ctx context.Context, appliedManifests []*unstructured.Unstructured) error {
 	// merge applied resources and already tracked ones
-	if a.currentRT.Status.TrackedResources == nil {
-		a.currentRT.Status.TrackedResources = make([]v1beta1.TypedReference, 0)
+	idx, shards, err := loadResourceIndex(ctx, a.c, a.currentRT)
+	if err != nil {
+		return err
 	}
+	changed := false
 	for _, rsc := range appliedManifests {
-		appliedRef := v1beta1.TypedReference{
+		if idx.add(v1beta1.TypedReference{
 			APIVersion: rsc.GetAPIVersion(),
 			Kind:       rsc.GetKind(),
 			Name:       rsc.GetName(),
 			Namespace:  rsc.GetNamespace(),
-		}
-		alreadyTracked := false
-		for _, tracked := range a.currentRT.Status.TrackedResources {
-			if tracked.APIVersion == appliedRef.APIVersion && tracked.Kind == appliedRef.Kind &&
-				tracked.Name == appliedRef.Name && tracked.Namespace == appliedRef.Namespace {
-				alreadyTracked = true
-				break
-			}
-		}
-		if !alreadyTracked {
-			a.currentRT.Status.TrackedResources = append(a.currentRT.Status.TrackedResources, appliedRef)
+		}) {
+			changed = true
 		}
 	}
-
-	// TODO move TrackedResources from status to spec
-	// update status with retry
-	copyRT := a.currentRT.DeepCopy()
-	sts := copyRT.Status
-	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
-		if err = a.c.Get(ctx, client.ObjectKey{Name: a.currentRTName}, copyRT); err != nil {
-			return
-		}
-		copyRT.Status = sts
-		return a.c.Status().Update(ctx, copyRT)
-	}); err != nil {
-		klog.ErrorS(err, "Failed to update resource tracker status", "resourceTracker", a.currentRTName)
-		return errors.Wrap(err, "cannot update resource tracker status")
+	if !changed && len(a.currentRT.Status.TrackedResources) == 0 {
+		return nil
+	}
+	if err := recordTrackedResources(ctx, a.c, a.currentRT, idx.refs, shards); err != nil {
+		klog.ErrorS(err, "Failed to update resource tracker", "resourceTracker", a.currentRTName)
+		return errors.Wrap(err, "cannot update resource tracker")
 	}
-	klog.InfoS("Successfully update resource tracker status", "resourceTracker", a.currentRTName)
+	klog.InfoS("Successfully update resource tracker", "resourceTracker", a.currentRTName)
 	return nil
 }
 
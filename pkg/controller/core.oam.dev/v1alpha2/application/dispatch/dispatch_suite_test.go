@@ -209,7 +209,7 @@ var _ = Describe("Test AppManifestsDispatcher", func() {
 
 			By("Verify resource tracker records all applied resources")
 			recordedNames := []string{}
-			for _, r := range rt.Status.TrackedResources {
+			for _, r := range rt.Spec.TrackedResources {
 				recordedNames = append(recordedNames, r.Name)
 			}
 			Expect(recordedNames).Should(ContainElements(deployName1, svcName1, pvName1))
@@ -0,0 +1,238 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+// DetectDrift compares the live state of the dispatched resources with their manifests, it returns the resources
+// drifted from their manifests, e.g. edited by kubectl.
+// Only the fields set in the manifests are compared, the fields defaulted by the API server or added by the other
+// controllers are not drift.
+func DetectDrift(ctx context.Context, c client.Reader, manifests []*unstructured.Unstructured) ([]common.ResourceDrift, error) {
+	var drifts []common.ResourceDrift
+	for _, manifest := range manifests {
+		drift := common.ResourceDrift{
+			APIVersion: manifest.GetAPIVersion(),
+			Kind:       manifest.GetKind(),
+			Namespace:  manifest.GetNamespace(),
+			Name:       manifest.GetName(),
+		}
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(manifest.GroupVersionKind())
+		if err := c.Get(ctx, client.ObjectKey{Namespace: manifest.GetNamespace(), Name: manifest.GetName()}, live); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return nil, errors.Wrapf(err, "cannot get resource %q", manifest.GetName())
+			}
+			drift.Missing = true
+			drifts = append(drifts, drift)
+			continue
+		}
+		if drift.Fields = DriftedFields(manifest, live); len(drift.Fields) != 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts, nil
+}
+
+// DriftedFields returns the paths of the fields set in the manifest whose live values are different.
+// The labels and annotations are the only metadata compared, the status is never compared.
+func DriftedFields(manifest, live *unstructured.Unstructured) []string {
+	var fields []string
+	for _, key := range sortedKeys(manifest.Object) {
+		switch key {
+		case "apiVersion", "kind", "status":
+		case "metadata":
+			for _, meta := range []string{"labels", "annotations"} {
+				desired, _, _ := unstructured.NestedFieldNoCopy(manifest.Object, "metadata", meta)
+				actual, _, _ := unstructured.NestedFieldNoCopy(live.Object, "metadata", meta)
+				collectDriftedFields("metadata."+meta, desired, actual, &fields)
+			}
+		default:
+			actual, found := live.Object[key]
+			if !found {
+				if !isEmptyValue(manifest.Object[key]) {
+					fields = append(fields, key)
+				}
+				continue
+			}
+			collectDriftedFields(key, manifest.Object[key], actual, &fields)
+		}
+	}
+	return fields
+}
+
+func collectDriftedFields(path string, desired, actual interface{}, fields *[]string) {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			if !isEmptyValue(d) {
+				*fields = append(*fields, path)
+			}
+			return
+		}
+		for _, key := range sortedKeys(d) {
+			child := fieldPath(path, key)
+			value, found := a[key]
+			if !found {
+				// the API server drops the empty values
+				if !isEmptyValue(d[key]) {
+					*fields = append(*fields, child)
+				}
+				continue
+			}
+			collectDriftedFields(child, d[key], value, fields)
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			if !isEmptyValue(d) {
+				*fields = append(*fields, path)
+			}
+			return
+		}
+		if !isObjectList(d) {
+			if !listEqual(d, a) {
+				*fields = append(*fields, path)
+			}
+			return
+		}
+		// the items are compared by index, the items appended by the other controllers, e.g. sidecars, are not drift
+		for i, item := range d {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(a) {
+				*fields = append(*fields, child)
+				continue
+			}
+			collectDriftedFields(child, item, a[i], fields)
+		}
+	default:
+		if !valueEqual(d, actual) && !(strings.Contains(path, ".resources.") && quantityEqual(d, actual)) {
+			*fields = append(*fields, path)
+		}
+	}
+}
+
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, "./[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func isObjectList(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return len(list) != 0
+}
+
+func listEqual(desired, actual []interface{}) bool {
+	if len(desired) != len(actual) {
+		return false
+	}
+	for i := range desired {
+		if !valueEqual(desired[i], actual[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// valueEqual compares the scalar values, the numbers are compared by value
+func valueEqual(desired, actual interface{}) bool {
+	if reflect.DeepEqual(desired, actual) {
+		return true
+	}
+	if d, ok := toFloat(desired); ok {
+		if a, ok := toFloat(actual); ok {
+			return d == a
+		}
+	}
+	return false
+}
+
+// quantityEqual compares the quantities of the resources normalized by the API server, e.g. cpu 0.5 equals to 500m
+func quantityEqual(desired, actual interface{}) bool {
+	s, ok := actual.(string)
+	if !ok {
+		return false
+	}
+	a, err := resource.ParseQuantity(s)
+	if err != nil {
+		return false
+	}
+	var d resource.Quantity
+	switch desired := desired.(type) {
+	case string:
+		d, err = resource.ParseQuantity(desired)
+	case int, int64, float64:
+		d, err = resource.ParseQuantity(fmt.Sprint(desired))
+	default:
+		return false
+	}
+	return err == nil && d.Cmp(a) == 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newDriftManifest() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app.oam.dev/component": "web"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"selector": map[string]interface{}{},
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name":      "web",
+						"image":     "nginx:1.20",
+						"resources": map[string]interface{}{"limits": map[string]interface{}{"memory": "1Gi"}},
+					}},
+				},
+			},
+		},
+	}}
+}
+
+func TestDriftedFields(t *testing.T) {
+	tests := map[string]struct {
+		mutate func(live *unstructured.Unstructured)
+		want   []string
+	}{
+		"defaulted fields and status": {
+			mutate: func(live *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(live.Object, "RollingUpdate", "spec", "strategy", "type")
+				_ = unstructured.SetNestedField(live.Object, int64(2), "status", "replicas")
+				live.SetResourceVersion("10")
+				live.SetLabels(map[string]string{"app.oam.dev/component": "web", "other": "label"})
+			},
+		},
+		"equal numbers and quantities": {
+			mutate: func(live *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(live.Object, float64(2), "spec", "replicas")
+				containers, _, _ := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+				containers[0].(map[string]interface{})["resources"] = map[string]interface{}{
+					"limits": map[string]interface{}{"memory": "1024Mi"}}
+				_ = unstructured.SetNestedSlice(live.Object, containers, "spec", "template", "spec", "containers")
+			},
+		},
+		"changed fields": {
+			mutate: func(live *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(live.Object, int64(5), "spec", "replicas")
+				containers, _, _ := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+				containers[0].(map[string]interface{})["image"] = "nginx:latest"
+				_ = unstructured.SetNestedSlice(live.Object, containers, "spec", "template", "spec", "containers")
+				live.SetLabels(map[string]string{"app.oam.dev/component": "api"})
+			},
+			want: []string{
+				`metadata.labels["app.oam.dev/component"]`,
+				"spec.replicas",
+				"spec.template.spec.containers[0].image",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			live := newDriftManifest()
+			tt.mutate(live)
+			assert.Equal(t, tt.want, DriftedFields(newDriftManifest(), live))
+		})
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	ctx := context.Background()
+	live := newDriftManifest()
+	require.NoError(t, unstructured.SetNestedField(live.Object, int64(5), "spec", "replicas"))
+	c := fake.NewFakeClientWithScheme(velacommon.Scheme, live)
+
+	missing := newDriftManifest()
+	missing.SetName("missing")
+	drifts, err := DetectDrift(ctx, c, []*unstructured.Unstructured{newDriftManifest(), missing})
+	require.NoError(t, err)
+	assert.Equal(t, []common.ResourceDrift{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "web", Fields: []string{"spec.replicas"}},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "missing", Missing: true},
+	}, drifts)
+
+	require.NoError(t, unstructured.SetNestedField(live.Object, int64(2), "spec", "replicas"))
+	require.NoError(t, c.Update(ctx, live))
+	drifts, err = DetectDrift(ctx, c, []*unstructured.Unstructured{newDriftManifest()})
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
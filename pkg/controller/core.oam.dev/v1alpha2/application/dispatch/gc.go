@@ -22,11 +22,13 @@ import (
 
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
 // GarbageCollector do GC according two resource trackers
@@ -56,29 +58,23 @@ func (h *GCHandler) GarbageCollect(ctx context.Context, oldRT, newRT *v1beta1.Re
 		return err
 	}
 	klog.InfoS("Garbage collect for application", "old", h.oldRT.Name, "new", h.newRT.Name)
-	for _, oldRsc := range h.oldRT.Status.TrackedResources {
-		isRemoved := true
-		for _, newRsc := range h.newRT.Status.TrackedResources {
-			if oldRsc.APIVersion == newRsc.APIVersion && oldRsc.Kind == newRsc.Kind &&
-				oldRsc.Namespace == newRsc.Namespace && oldRsc.Name == newRsc.Name {
-				isRemoved = false
-				break
-			}
-		}
-		if isRemoved {
-			toBeDeleted := &unstructured.Unstructured{}
-			toBeDeleted.SetAPIVersion(oldRsc.APIVersion)
-			toBeDeleted.SetKind(oldRsc.Kind)
-			toBeDeleted.SetNamespace(oldRsc.Namespace)
-			toBeDeleted.SetName(oldRsc.Name)
-			if err := h.c.Delete(ctx, toBeDeleted); err != nil && !kerrors.IsNotFound(err) {
-				klog.ErrorS(err, "Failed to delete a resource", "name", oldRsc.Name, "apiVersion", oldRsc.APIVersion, "kind", oldRsc.Kind)
-				return errors.Wrapf(err, "cannot delete resource %q", oldRsc)
+	oldResources, err := LoadTrackedResources(ctx, h.c, h.oldRT)
+	if err != nil {
+		return err
+	}
+	newResources, err := LoadTrackedResources(ctx, h.c, h.newRT)
+	if err != nil {
+		return err
+	}
+	newIndex := newResourceIndex(newResources...)
+	for _, oldRsc := range oldResources {
+		if !newIndex.has(oldRsc) {
+			if err := h.collectResource(ctx, oldRsc); err != nil {
+				return err
 			}
-			klog.InfoS("Successfully GC a resource", "name", oldRsc.Name, "apiVersion", oldRsc.APIVersion, "kind", oldRsc.Kind)
 		}
 	}
-	// delete the old resource tracker
+	// delete the old resource tracker, its shards are deleted along with it
 	if err := h.c.Delete(ctx, h.oldRT); err != nil && !kerrors.IsNotFound(err) {
 		klog.ErrorS(err, "Failed to delete resource tracker", "name", h.oldRT.Name)
 		return errors.Wrapf(err, "cannot delete resource tracker %q", h.oldRT.Name)
@@ -88,6 +84,111 @@ func (h *GCHandler) GarbageCollect(ctx context.Context, oldRT, newRT *v1beta1.Re
 	return nil
 }
 
+// collectResource deletes the resource no longer rendered by the application, unless its resource policy is orphan
+// or retain, then the resource is released from the resource trackers instead to survive the old resource tracker.
+// A protected resource is never touched.
+func (h *GCHandler) collectResource(ctx context.Context, ref v1beta1.TypedReference) error {
+	rsc := &unstructured.Unstructured{}
+	rsc.SetAPIVersion(ref.APIVersion)
+	rsc.SetKind(ref.Kind)
+	if err := h.c.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, rsc); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		klog.ErrorS(err, "Failed to get a resource", "name", ref.Name, "apiVersion", ref.APIVersion, "kind", ref.Kind)
+		return errors.Wrapf(err, "cannot get resource %q", ref)
+	}
+	if isProtected(rsc) {
+		klog.InfoS("Skip a protected resource", "name", ref.Name, "apiVersion", ref.APIVersion, "kind", ref.Kind)
+		return nil
+	}
+	if policy := getResourcePolicy(rsc); policy != oam.ResourcePolicyDelete {
+		if err := releaseResource(ctx, h.c, rsc); err != nil {
+			return err
+		}
+		klog.InfoS("Keep a resource by its resource policy", "name", ref.Name, "apiVersion", ref.APIVersion,
+			"kind", ref.Kind, "policy", policy)
+		return nil
+	}
+	if err := h.c.Delete(ctx, rsc); err != nil && !kerrors.IsNotFound(err) {
+		klog.ErrorS(err, "Failed to delete a resource", "name", ref.Name, "apiVersion", ref.APIVersion, "kind", ref.Kind)
+		return errors.Wrapf(err, "cannot delete resource %q", ref)
+	}
+	klog.InfoS("Successfully GC a resource", "name", ref.Name, "apiVersion", ref.APIVersion, "kind", ref.Kind)
+	return nil
+}
+
+// ReleaseRetainedResources releases the resources tracked by the resource tracker whose resource policy is retain,
+// so that they're not deleted along with the resource tracker when the application is deleted.
+func ReleaseRetainedResources(ctx context.Context, c client.Client, rt *v1beta1.ResourceTracker) error {
+	refs, err := LoadTrackedResources(ctx, c, rt)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		rsc := &unstructured.Unstructured{}
+		rsc.SetAPIVersion(ref.APIVersion)
+		rsc.SetKind(ref.Kind)
+		if err := c.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, rsc); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "cannot get resource %q", ref)
+		}
+		if isProtected(rsc) || getResourcePolicy(rsc) != oam.ResourcePolicyRetain {
+			continue
+		}
+		if err := releaseResource(ctx, c, rsc); err != nil {
+			return err
+		}
+		klog.InfoS("Retain a resource of the deleted application", "name", ref.Name, "apiVersion", ref.APIVersion,
+			"kind", ref.Kind, "resourceTracker", rt.Name)
+	}
+	return nil
+}
+
+// isProtected checks whether the resource is protected from the garbage collection, a protected resource is not owned
+// by the resource trackers, so it's neither deleted nor released along with them.
+func isProtected(rsc *unstructured.Unstructured) bool {
+	return rsc.GetAnnotations()[oam.AnnotationProtectedResource] == "true"
+}
+
+// getResourcePolicy returns the resource policy of the resource, an invalid policy is taken as retain
+// because a resource deleted by mistake can't be restored.
+func getResourcePolicy(rsc *unstructured.Unstructured) string {
+	switch policy := rsc.GetAnnotations()[oam.AnnotationResourcePolicy]; policy {
+	case "", oam.ResourcePolicyDelete:
+		return oam.ResourcePolicyDelete
+	case oam.ResourcePolicyOrphan:
+		return oam.ResourcePolicyOrphan
+	default:
+		return oam.ResourcePolicyRetain
+	}
+}
+
+// releaseResource removes the owner references to the resource trackers from the resource, so that it's not deleted
+// by the Kubernetes garbage collector when the resource trackers are deleted.
+func releaseResource(ctx context.Context, c client.Client, rsc *unstructured.Unstructured) error {
+	var owners []metav1.OwnerReference
+	for _, owner := range rsc.GetOwnerReferences() {
+		if owner.Kind == v1beta1.ResourceTrackerKind && strings.HasPrefix(owner.APIVersion, v1beta1.Group+"/") {
+			continue
+		}
+		owners = append(owners, owner)
+	}
+	if len(owners) == len(rsc.GetOwnerReferences()) {
+		return nil
+	}
+	patch := client.MergeFrom(rsc.DeepCopy())
+	rsc.SetOwnerReferences(owners)
+	if err := c.Patch(ctx, rsc, patch); err != nil {
+		klog.ErrorS(err, "Failed to release a resource", "object", klog.KObj(rsc),
+			"apiVersion", rsc.GetAPIVersion(), "kind", rsc.GetKind())
+		return errors.Wrapf(err, "cannot release resource %q from resource trackers", rsc.GetName())
+	}
+	return nil
+}
+
 // validate two resource trackers come from the same application
 func (h *GCHandler) validate() error {
 	oldRTName := h.oldRT.Name
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newTrackedConfigMap(name, policy string, rt *v1beta1.ResourceTracker) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ResourceTrackerKind, Name: rt.Name,
+				UID: rt.UID, Controller: pointer.BoolPtr(true)},
+			{APIVersion: "v1", Kind: "Secret", Name: "owner", UID: "secret-uid"},
+		}},
+	}
+	if policy != "" {
+		cm.SetAnnotations(map[string]string{oam.AnnotationResourcePolicy: policy})
+	}
+	return cm
+}
+
+func newProtectedConfigMap(name string, rt *v1beta1.ResourceTracker) *corev1.ConfigMap {
+	cm := newTrackedConfigMap(name, oam.ResourcePolicyRetain, rt)
+	cm.Annotations[oam.AnnotationProtectedResource] = "true"
+	return cm
+}
+
+func newResourceTracker(name, uid string, tracked ...string) *v1beta1.ResourceTracker {
+	rt := &v1beta1.ResourceTracker{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(uid)}}
+	for _, rsc := range tracked {
+		rt.Spec.TrackedResources = append(rt.Spec.TrackedResources, v1beta1.TypedReference{
+			APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: rsc})
+	}
+	return rt
+}
+
+func TestGarbageCollectByResourcePolicy(t *testing.T) {
+	ctx := context.Background()
+	oldRT := newResourceTracker("app-v1-default", "v1-uid", "kept", "deleted", "orphaned", "retained", "invalid",
+		"protected", "gone")
+	newRT := newResourceTracker("app-v2-default", "v2-uid", "kept")
+	c := fake.NewFakeClientWithScheme(common.Scheme, oldRT, newRT,
+		newTrackedConfigMap("kept", "", oldRT),
+		newTrackedConfigMap("deleted", oam.ResourcePolicyDelete, oldRT),
+		newTrackedConfigMap("orphaned", oam.ResourcePolicyOrphan, oldRT),
+		newTrackedConfigMap("retained", oam.ResourcePolicyRetain, oldRT),
+		newTrackedConfigMap("invalid", "keep", oldRT),
+		newProtectedConfigMap("protected", oldRT))
+
+	require.NoError(t, NewGCHandler(c, "default").GarbageCollect(ctx, oldRT, newRT))
+	get := func(name string) (*corev1.ConfigMap, error) {
+		cm := &corev1.ConfigMap{}
+		return cm, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, cm)
+	}
+	_, err := get("deleted")
+	assert.True(t, kerrors.IsNotFound(err))
+	kept, err := get("kept")
+	require.NoError(t, err)
+	assert.Len(t, kept.OwnerReferences, 2)
+	for _, name := range []string{"orphaned", "retained", "invalid"} {
+		cm, err := get(name)
+		require.NoError(t, err, name)
+		// only the owner references to the resource trackers are removed
+		assert.Equal(t, []metav1.OwnerReference{{APIVersion: "v1", Kind: "Secret", Name: "owner", UID: "secret-uid"}},
+			cm.OwnerReferences, name)
+	}
+	// the protected resource is never touched
+	protected, err := get("protected")
+	require.NoError(t, err)
+	assert.Equal(t, newProtectedConfigMap("protected", oldRT).OwnerReferences, protected.OwnerReferences)
+	err = c.Get(ctx, client.ObjectKey{Name: oldRT.Name}, &v1beta1.ResourceTracker{})
+	assert.True(t, kerrors.IsNotFound(err))
+}
+
+func TestReleaseRetainedResources(t *testing.T) {
+	ctx := context.Background()
+	rt := newResourceTracker("app-v1-default", "v1-uid", "deleted", "orphaned", "retained", "protected", "gone")
+	c := fake.NewFakeClientWithScheme(common.Scheme, rt,
+		newTrackedConfigMap("deleted", "", rt),
+		newTrackedConfigMap("orphaned", oam.ResourcePolicyOrphan, rt),
+		newTrackedConfigMap("retained", oam.ResourcePolicyRetain, rt),
+		newProtectedConfigMap("protected", rt))
+
+	require.NoError(t, ReleaseRetainedResources(ctx, c, rt))
+	for name, owners := range map[string]int{"deleted": 2, "orphaned": 2, "retained": 1, "protected": 2} {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, cm))
+		assert.Len(t, cm.OwnerReferences, owners, name)
+	}
+}
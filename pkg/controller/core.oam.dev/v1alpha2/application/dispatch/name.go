@@ -28,6 +28,12 @@ func ConstructResourceTrackerName(appRevName, ns string) string {
 	return fmt.Sprintf("%s-%s", appRevName, ns)
 }
 
+// ConstructResourceTrackerShardName generates the name of the i-th shard of the resource tracker, the resource tracker
+// itself is the 0-th shard.
+func ConstructResourceTrackerShardName(resourceTrackerName string, i int) string {
+	return fmt.Sprintf("%s-shard-%d", resourceTrackerName, i)
+}
+
 // ExtractAppName get application name from resource tracker name
 func ExtractAppName(resourceTrackerName, ns string) string {
 	splits := strings.Split(strings.TrimSuffix(resourceTrackerName, "-"+ns), "-")
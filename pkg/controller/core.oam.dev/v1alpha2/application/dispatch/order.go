@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// HealthChecker checks whether a component of the application is healthy, it's used to
+// hold back the components depending on the component until it's healthy.
+type HealthChecker func(ctx context.Context, compName string) (bool, error)
+
+// PendingDependenciesError is returned by Dispatch if some components are not dispatched
+// because the components they depend on are not healthy yet.
+type PendingDependenciesError struct {
+	// Components maps the pending components to the dependencies they're waiting for
+	Components map[string][]string
+}
+
+// Error implements error
+func (e *PendingDependenciesError) Error() string {
+	comps := make([]string, 0, len(e.Components))
+	for comp, deps := range e.Components {
+		comps = append(comps, fmt.Sprintf("%s (waiting for %s)", comp, strings.Join(deps, ", ")))
+	}
+	sort.Strings(comps)
+	return "components are waiting for their dependencies to be healthy: " + strings.Join(comps, ", ")
+}
+
+// IsPendingDependencies returns true if the error is, or wraps, a PendingDependenciesError
+func IsPendingDependencies(err error) bool {
+	var pending *PendingDependenciesError
+	return errors.As(err, &pending)
+}
+
+// ValidateComponentDependencies checks the components depended on exist and the dependencies don't form a cycle.
+func ValidateComponentDependencies(comps []v1beta1.ApplicationComponent) error {
+	deps := make(map[string][]string, len(comps))
+	for _, comp := range comps {
+		deps[comp.Name] = comp.DependsOn
+	}
+	for _, comp := range comps {
+		for _, dep := range comp.DependsOn {
+			if dep == comp.Name {
+				return errors.Errorf("component %s cannot depend on itself", comp.Name)
+			}
+			if _, ok := deps[dep]; !ok {
+				return errors.Errorf("component %s depends on non-existent component %s", comp.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(comps))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return errors.Errorf("components have circular dependencies: %v", append(path, name))
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, comp := range comps {
+		if err := visit(comp.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyOrder returns the rank of the manifest in the apply order:
+// CRDs and Namespaces come first, then ConfigMaps and Secrets, then workloads, then traits.
+func applyOrder(u *unstructured.Unstructured) int {
+	gvk := u.GroupVersionKind()
+	switch {
+	case gvk.Group == "apiextensions.k8s.io" && gvk.Kind == "CustomResourceDefinition",
+		gvk.Group == "" && gvk.Kind == "Namespace":
+		return 0
+	case gvk.Group == "" && (gvk.Kind == "ConfigMap" || gvk.Kind == "Secret"):
+		return 1
+	case u.GetLabels()[oam.LabelOAMResourceType] == oam.ResourceTypeTrait:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// sortManifests sorts the manifests by the apply order, the manifests of the same rank keep their order
+func sortManifests(manifests []*unstructured.Unstructured) {
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return applyOrder(manifests[i]) < applyOrder(manifests[j])
+	})
+}
+
+// dispatchWaves groups the manifests by component and dispatches them wave by wave, the components of a wave
+// only depend on components dispatched in previous waves. A component is held back if any component it depends
+// on is held back or not healthy, it returns the held back components and the dependencies they're waiting for.
+func (a *AppManifestsDispatcher) dispatchWaves(ctx context.Context, manifests []*unstructured.Unstructured,
+	apply func([]*unstructured.Unstructured) error) (map[string][]string, error) {
+	appComps := a.appRev.Spec.Application.Spec.Components
+	if err := ValidateComponentDependencies(appComps); err != nil {
+		return nil, err
+	}
+	deps := make(map[string][]string, len(appComps))
+	for _, comp := range appComps {
+		deps[comp.Name] = comp.DependsOn
+	}
+
+	var comps []string
+	groups := map[string][]*unstructured.Unstructured{}
+	for _, rsc := range manifests {
+		comp := rsc.GetLabels()[oam.LabelAppComponent]
+		if _, ok := groups[comp]; !ok {
+			comps = append(comps, comp)
+		}
+		groups[comp] = append(groups[comp], rsc)
+	}
+
+	health := map[string]bool{}
+	healthy := func(comp string) (bool, error) {
+		if a.healthChecker == nil {
+			return true, nil
+		}
+		if h, ok := health[comp]; ok {
+			return h, nil
+		}
+		h, err := a.healthChecker(ctx, comp)
+		if err != nil {
+			if !kerrors.IsNotFound(errors.Cause(err)) {
+				return false, errors.WithMessagef(err, "cannot check the health of component %s", comp)
+			}
+			// the resources of the component are not created yet, e.g. by a later workflow step
+			h = false
+		}
+		health[comp] = h
+		return h, nil
+	}
+
+	dispatched := map[string]bool{}
+	pending := map[string][]string{}
+	for {
+		var wave []*unstructured.Unstructured
+		var waveComps []string
+		held := false
+		for _, comp := range comps {
+			if dispatched[comp] || pending[comp] != nil {
+				continue
+			}
+			ready := true
+			var waiting []string
+			for _, dep := range deps[comp] {
+				_, inDispatch := groups[dep]
+				switch {
+				case pending[dep] != nil:
+					waiting = append(waiting, dep)
+				case inDispatch && !dispatched[dep]:
+					// the dependency is dispatched in a later wave
+					ready = false
+				default:
+					h, err := healthy(dep)
+					if err != nil {
+						return nil, err
+					}
+					if !h {
+						waiting = append(waiting, dep)
+					}
+				}
+			}
+			if len(waiting) != 0 {
+				pending[comp] = waiting
+				held = true
+				continue
+			}
+			if ready {
+				waveComps = append(waveComps, comp)
+				wave = append(wave, groups[comp]...)
+			}
+		}
+		if len(wave) == 0 {
+			if held {
+				// the components depending on the newly held back ones are held back in the next round
+				continue
+			}
+			break
+		}
+		sortManifests(wave)
+		if err := apply(wave); err != nil {
+			return nil, err
+		}
+		for _, comp := range waveComps {
+			dispatched[comp] = true
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newManifest(apiVersion, kind, name, comp, resourceType string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	labels := map[string]string{}
+	if comp != "" {
+		labels[oam.LabelAppComponent] = comp
+	}
+	if resourceType != "" {
+		labels[oam.LabelOAMResourceType] = resourceType
+	}
+	u.SetLabels(labels)
+	return u
+}
+
+func manifestNames(manifests []*unstructured.Unstructured) []string {
+	names := make([]string, len(manifests))
+	for i, m := range manifests {
+		names[i] = m.GetName()
+	}
+	return names
+}
+
+func TestValidateComponentDependencies(t *testing.T) {
+	assert.NoError(t, ValidateComponentDependencies([]v1beta1.ApplicationComponent{
+		{Name: "frontend", DependsOn: []string{"backend", "db"}},
+		{Name: "backend", DependsOn: []string{"db"}},
+		{Name: "db"},
+	}))
+	for _, comps := range [][]v1beta1.ApplicationComponent{
+		{{Name: "frontend", DependsOn: []string{"frontend"}}},
+		{{Name: "frontend", DependsOn: []string{"backend"}}},
+		{{Name: "frontend", DependsOn: []string{"backend"}}, {Name: "backend", DependsOn: []string{"frontend"}}},
+	} {
+		assert.Error(t, ValidateComponentDependencies(comps))
+	}
+}
+
+func TestSortManifests(t *testing.T) {
+	manifests := []*unstructured.Unstructured{
+		newManifest("v1", "Service", "svc", "web", oam.ResourceTypeTrait),
+		newManifest("apps/v1", "Deployment", "web", "web", oam.ResourceTypeWorkload),
+		newManifest("v1", "Secret", "secret", "web", oam.ResourceTypeTrait),
+		newManifest("v1", "ConfigMap", "config", "web", oam.ResourceTypeWorkload),
+		newManifest("apiextensions.k8s.io/v1", "CustomResourceDefinition", "crd", "web", oam.ResourceTypeWorkload),
+		newManifest("v1", "Namespace", "ns", "web", oam.ResourceTypeTrait),
+		newManifest("networking.k8s.io/v1", "Ingress", "ingress", "web", oam.ResourceTypeTrait),
+	}
+	sortManifests(manifests)
+	assert.Equal(t, []string{"crd", "ns", "secret", "config", "web", "svc", "ingress"}, manifestNames(manifests))
+}
+
+func TestDispatchWaves(t *testing.T) {
+	appRev := &v1beta1.ApplicationRevision{}
+	appRev.Spec.Application.Spec.Components = []v1beta1.ApplicationComponent{
+		{Name: "frontend", DependsOn: []string{"backend"}},
+		{Name: "backend", DependsOn: []string{"db", "config"}},
+		{Name: "db"},
+		{Name: "config"},
+		{Name: "worker", DependsOn: []string{"db"}},
+	}
+	manifests := []*unstructured.Unstructured{
+		newManifest("apps/v1", "Deployment", "frontend", "frontend", oam.ResourceTypeWorkload),
+		newManifest("v1", "Service", "backend-svc", "backend", oam.ResourceTypeTrait),
+		newManifest("apps/v1", "Deployment", "backend", "backend", oam.ResourceTypeWorkload),
+		newManifest("apps/v1", "StatefulSet", "db", "db", oam.ResourceTypeWorkload),
+		newManifest("apps/v1", "Deployment", "worker", "worker", oam.ResourceTypeWorkload),
+	}
+
+	var waves [][]string
+	record := func(wave []*unstructured.Unstructured) error {
+		waves = append(waves, manifestNames(wave))
+		return nil
+	}
+
+	// without a health checker, the dependencies only decide the order
+	d := NewAppManifestsDispatcher(nil, appRev)
+	pending, err := d.dispatchWaves(context.Background(), manifests, record)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.Equal(t, [][]string{{"db"}, {"backend", "worker", "backend-svc"}, {"frontend"}}, waves)
+
+	// the components depending on an unhealthy component are held back
+	waves = nil
+	healthy := map[string]bool{"db": true, "config": false}
+	d = NewAppManifestsDispatcher(nil, appRev).WithHealthChecker(func(_ context.Context, comp string) (bool, error) {
+		return healthy[comp], nil
+	})
+	pending, err = d.dispatchWaves(context.Background(), manifests, record)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"backend": {"config"}, "frontend": {"backend"}}, pending)
+	assert.Equal(t, [][]string{{"db"}, {"worker"}}, waves)
+	pendingErr := &PendingDependenciesError{Components: pending}
+	assert.True(t, IsPendingDependencies(errors.WithMessage(pendingErr, "cannot dispatch")))
+	assert.Equal(t, "components are waiting for their dependencies to be healthy: "+
+		"backend (waiting for config), frontend (waiting for backend)", pendingErr.Error())
+
+	// the dependencies not created yet are not healthy
+	waves = nil
+	d = NewAppManifestsDispatcher(nil, appRev).WithHealthChecker(func(_ context.Context, comp string) (bool, error) {
+		if comp == "config" {
+			return false, errors.WithMessage(kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, comp),
+				"check health error")
+		}
+		return true, nil
+	})
+	pending, err = d.dispatchWaves(context.Background(), manifests, record)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"backend": {"config"}, "frontend": {"backend"}}, pending)
+	assert.Equal(t, [][]string{{"db"}, {"worker"}}, waves)
+
+	// errors of checking health are returned
+	d = NewAppManifestsDispatcher(nil, appRev).WithHealthChecker(func(_ context.Context, comp string) (bool, error) {
+		return false, errors.New("boom")
+	})
+	_, err = d.dispatchWaves(context.Background(), manifests, record)
+	assert.Error(t, err)
+
+	// invalid dependencies are rejected
+	appRev.Spec.Application.Spec.Components[2].DependsOn = []string{"frontend"}
+	_, err = NewAppManifestsDispatcher(nil, appRev).dispatchWaves(context.Background(), manifests, record)
+	assert.Error(t, err)
+}
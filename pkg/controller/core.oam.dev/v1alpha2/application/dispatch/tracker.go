@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// TrackedResourcesPerShard is the max number of resources tracked by one resource tracker object, the other resources
+// are tracked by the shards of the resource tracker, so that the resource trackers are far below the size limit of
+// the objects in etcd.
+var TrackedResourcesPerShard = 1000
+
+// resourceKey identifies a tracked resource, the UID is ignored
+type resourceKey struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+func keyOf(ref v1beta1.TypedReference) resourceKey {
+	return resourceKey{APIVersion: ref.APIVersion, Kind: ref.Kind, Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// resourceIndex is the set of the tracked resources in the order they're tracked
+type resourceIndex struct {
+	refs []v1beta1.TypedReference
+	keys map[resourceKey]struct{}
+}
+
+func newResourceIndex(refs ...v1beta1.TypedReference) *resourceIndex {
+	idx := &resourceIndex{keys: make(map[resourceKey]struct{}, len(refs))}
+	idx.add(refs...)
+	return idx
+}
+
+// add adds the resources not tracked yet, it returns whether any resource is added
+func (idx *resourceIndex) add(refs ...v1beta1.TypedReference) bool {
+	added := false
+	for _, ref := range refs {
+		key := keyOf(ref)
+		if _, ok := idx.keys[key]; ok {
+			continue
+		}
+		idx.keys[key] = struct{}{}
+		idx.refs = append(idx.refs, ref)
+		added = true
+	}
+	return added
+}
+
+func (idx *resourceIndex) has(ref v1beta1.TypedReference) bool {
+	_, ok := idx.keys[keyOf(ref)]
+	return ok
+}
+
+// LoadTrackedResources returns the resources tracked by the resource tracker and its shards.
+// The resources recorded in the status by the older versions are included too, they're moved to the spec the next
+// time the resource tracker records resources.
+func LoadTrackedResources(ctx context.Context, c client.Reader, rt *v1beta1.ResourceTracker) ([]v1beta1.TypedReference, error) {
+	idx, _, err := loadResourceIndex(ctx, c, rt)
+	if err != nil {
+		return nil, err
+	}
+	return idx.refs, nil
+}
+
+// loadResourceIndex returns the index of the resources tracked by the resource tracker and its shards,
+// and the shards by name
+func loadResourceIndex(ctx context.Context, c client.Reader, rt *v1beta1.ResourceTracker) (*resourceIndex, map[string]*v1beta1.ResourceTracker, error) {
+	shardList := &v1beta1.ResourceTrackerList{}
+	if err := c.List(ctx, shardList, client.MatchingLabels{oam.LabelResourceTracker: rt.Name}); err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot list the shards of resource tracker %q", rt.Name)
+	}
+	shards := make(map[string]*v1beta1.ResourceTracker, len(shardList.Items))
+	names := make([]string, 0, len(shardList.Items))
+	for i := range shardList.Items {
+		shards[shardList.Items[i].Name] = &shardList.Items[i]
+		names = append(names, shardList.Items[i].Name)
+	}
+	idx := newResourceIndex(rt.Spec.TrackedResources...)
+	idx.add(rt.Status.TrackedResources...)
+	// the shards are loaded in order so that the resources keep their shards when they're recorded again
+	loaded := make(map[string]bool, len(shards))
+	for i := 1; i <= len(shards); i++ {
+		name := ConstructResourceTrackerShardName(rt.Name, i)
+		if shard, ok := shards[name]; ok {
+			idx.add(shard.Spec.TrackedResources...)
+			loaded[name] = true
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !loaded[name] {
+			idx.add(shards[name].Spec.TrackedResources...)
+		}
+	}
+	return idx, shards, nil
+}
+
+// recordTrackedResources records the resources in the spec of the resource tracker and its shards, each of them
+// tracks TrackedResourcesPerShard resources at most. Only the shards whose resources change are updated.
+// The resources tracked in the status by the older versions are removed from the status.
+func recordTrackedResources(ctx context.Context, c client.Client, rt *v1beta1.ResourceTracker, refs []v1beta1.TypedReference,
+	shards map[string]*v1beta1.ResourceTracker) error {
+	size := TrackedResourcesPerShard
+	if size <= 0 {
+		size = len(refs)
+	}
+	var chunks [][]v1beta1.TypedReference
+	for start := 0; start < len(refs); start += size {
+		end := start + size
+		if end > len(refs) {
+			end = len(refs)
+		}
+		chunks = append(chunks, refs[start:end])
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, nil)
+	}
+
+	// record the shards before the resource tracker, so that the resource tracker is never updated if the shards fail
+	owner := metav1.OwnerReference{
+		APIVersion:         v1beta1.SchemeGroupVersion.String(),
+		Kind:               v1beta1.ResourceTrackerKind,
+		Name:               rt.Name,
+		UID:                rt.UID,
+		Controller:         pointer.BoolPtr(true),
+		BlockOwnerDeletion: pointer.BoolPtr(true),
+	}
+	inUse := make(map[string]bool, len(chunks))
+	for i := 1; i < len(chunks); i++ {
+		name := ConstructResourceTrackerShardName(rt.Name, i)
+		inUse[name] = true
+		shard, ok := shards[name]
+		if ok && reflect.DeepEqual(shard.Spec.TrackedResources, chunks[i]) {
+			continue
+		}
+		if !ok {
+			shard = &v1beta1.ResourceTracker{}
+			shard.SetName(name)
+			// the shards have no labels of the application, so that they're not taken as the resource trackers of
+			// the application revisions, they're deleted along with the resource tracker by the owner reference
+			shard.SetLabels(map[string]string{oam.LabelResourceTracker: rt.Name})
+			shard.SetOwnerReferences([]metav1.OwnerReference{owner})
+			shard.Spec.TrackedResources = chunks[i]
+			if err := c.Create(ctx, shard); err != nil {
+				return errors.Wrapf(err, "cannot create shard %q of resource tracker", name)
+			}
+			continue
+		}
+		shard.Spec.TrackedResources = chunks[i]
+		if err := c.Update(ctx, shard); err != nil {
+			return errors.Wrapf(err, "cannot update shard %q of resource tracker", name)
+		}
+	}
+
+	copyRT := rt.DeepCopy()
+	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
+		if err = c.Get(ctx, client.ObjectKey{Name: rt.Name}, copyRT); err != nil {
+			return
+		}
+		copyRT.Spec.TrackedResources = chunks[0]
+		if err = c.Update(ctx, copyRT); err != nil {
+			return
+		}
+		if len(copyRT.Status.TrackedResources) != 0 {
+			// migrate the resource tracker created by the older versions
+			copyRT.Status.TrackedResources = nil
+			return c.Status().Update(ctx, copyRT)
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "cannot update resource tracker %q", rt.Name)
+	}
+	copyRT.DeepCopyInto(rt)
+
+	for name, shard := range shards {
+		if inUse[name] {
+			continue
+		}
+		if err := c.Delete(ctx, shard); client.IgnoreNotFound(err) != nil {
+			return errors.Wrapf(err, "cannot delete shard %q of resource tracker", name)
+		}
+	}
+	klog.InfoS("Successfully record tracked resources", "resourceTracker", rt.Name, "resources", len(refs),
+		"shards", len(chunks))
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newRefs(from, to int) []v1beta1.TypedReference {
+	var refs []v1beta1.TypedReference
+	for i := from; i < to; i++ {
+		refs = append(refs, v1beta1.TypedReference{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default",
+			Name: fmt.Sprintf("cm-%d", i)})
+	}
+	return refs
+}
+
+func TestResourceIndex(t *testing.T) {
+	idx := newResourceIndex(newRefs(0, 2)...)
+	assert.False(t, idx.add(newRefs(0, 1)...))
+	// the UID is ignored
+	ref := newRefs(1, 2)[0]
+	ref.UID = "uid"
+	assert.False(t, idx.add(ref))
+	assert.True(t, idx.add(newRefs(1, 3)...))
+	assert.Equal(t, newRefs(0, 3), idx.refs)
+	assert.True(t, idx.has(newRefs(2, 3)[0]))
+	assert.False(t, idx.has(newRefs(3, 4)[0]))
+}
+
+func TestRecordTrackedResources(t *testing.T) {
+	defer func(size int) { TrackedResourcesPerShard = size }(TrackedResourcesPerShard)
+	TrackedResourcesPerShard = 2
+	ctx := context.Background()
+	rt := &v1beta1.ResourceTracker{ObjectMeta: metav1.ObjectMeta{Name: "app-v1-default", UID: "rt-uid"}}
+	c := fake.NewFakeClientWithScheme(common.Scheme, rt)
+	record := func(refs []v1beta1.TypedReference) {
+		idx, shards, err := loadResourceIndex(ctx, c, rt)
+		require.NoError(t, err)
+		idx.add(refs...)
+		require.NoError(t, recordTrackedResources(ctx, c, rt, idx.refs, shards))
+	}
+	getShard := func(i int) (*v1beta1.ResourceTracker, error) {
+		shard := &v1beta1.ResourceTracker{}
+		return shard, c.Get(ctx, client.ObjectKey{Name: ConstructResourceTrackerShardName(rt.Name, i)}, shard)
+	}
+
+	record(newRefs(0, 5))
+	assert.Equal(t, newRefs(0, 2), rt.Spec.TrackedResources)
+	for i, want := range [][]v1beta1.TypedReference{newRefs(2, 4), newRefs(4, 5)} {
+		shard, err := getShard(i + 1)
+		require.NoError(t, err)
+		assert.Equal(t, want, shard.Spec.TrackedResources)
+		assert.Equal(t, rt.Name, shard.Labels[oam.LabelResourceTracker])
+		assert.Empty(t, shard.Labels[oam.LabelAppName])
+		assert.Equal(t, rt.UID, shard.OwnerReferences[0].UID)
+	}
+
+	// the tracked resources keep their shards
+	shard1, _ := getShard(1)
+	record(newRefs(3, 7))
+	refs, err := LoadTrackedResources(ctx, c, rt)
+	require.NoError(t, err)
+	assert.Equal(t, newRefs(0, 7), refs)
+	got, _ := getShard(1)
+	assert.Equal(t, shard1.ResourceVersion, got.ResourceVersion)
+	shard3, err := getShard(3)
+	require.NoError(t, err)
+	assert.Equal(t, newRefs(6, 7), shard3.Spec.TrackedResources)
+
+	// the shards no longer needed are deleted
+	TrackedResourcesPerShard = 4
+	record(nil)
+	assert.Equal(t, newRefs(0, 4), rt.Spec.TrackedResources)
+	_, err = getShard(2)
+	assert.True(t, kerrors.IsNotFound(err))
+	_, err = getShard(3)
+	assert.True(t, kerrors.IsNotFound(err))
+	refs, err = LoadTrackedResources(ctx, c, rt)
+	require.NoError(t, err)
+	assert.Equal(t, newRefs(0, 7), refs)
+}
+
+func TestMigrateTrackedResources(t *testing.T) {
+	ctx := context.Background()
+	rt := &v1beta1.ResourceTracker{ObjectMeta: metav1.ObjectMeta{Name: "app-v1-default"},
+		Status: v1beta1.ResourceTrackerStatus{TrackedResources: newRefs(0, 2)}}
+	c := fake.NewFakeClientWithScheme(common.Scheme, rt)
+
+	// the resources tracked in the status by the older versions are still loaded
+	refs, err := LoadTrackedResources(ctx, c, rt)
+	require.NoError(t, err)
+	assert.Equal(t, newRefs(0, 2), refs)
+
+	idx, shards, err := loadResourceIndex(ctx, c, rt)
+	require.NoError(t, err)
+	idx.add(newRefs(1, 3)...)
+	require.NoError(t, recordTrackedResources(ctx, c, rt, idx.refs, shards))
+	got := &v1beta1.ResourceTracker{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Name: rt.Name}, got))
+	assert.Equal(t, newRefs(0, 3), got.Spec.TrackedResources)
+	assert.Empty(t, got.Status.TrackedResources)
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/dispatch"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/workflow/tasks"
+)
+
+// driftWatcher watches the kinds of the dispatched resources, so that the application is reconciled once its
+// resources are changed rather than at the next resync. The live state of the resources is read from the cache
+// of the informers started by the watches, rather than from the API server for each reconciliation.
+type driftWatcher struct {
+	mu      sync.Mutex
+	ctrl    controller.Controller
+	cache   client.Reader
+	watched map[schema.GroupVersionKind]bool
+}
+
+func newDriftWatcher(ctrl controller.Controller, cache client.Reader) *driftWatcher {
+	return &driftWatcher{ctrl: ctrl, cache: cache, watched: make(map[schema.GroupVersionKind]bool)}
+}
+
+// watch starts watching the kinds of the manifests not watched yet
+func (w *driftWatcher) watch(manifests []*unstructured.Unstructured) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, manifest := range manifests {
+		gvk := manifest.GroupVersionKind()
+		if w.watched[gvk] {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		if err := w.ctrl.Watch(&source.Kind{Type: obj},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(w.mapToApplication)},
+			driftPredicate); err != nil {
+			return errors.Wrapf(err, "cannot watch %s", gvk)
+		}
+		w.watched[gvk] = true
+		klog.InfoS("Watch dispatched resources for drift", "apiVersion", gvk.GroupVersion().String(), "kind", gvk.Kind)
+	}
+	return nil
+}
+
+// mapToApplication maps a dispatched resource to its application by the resource tracker controlling it
+func (w *driftWatcher) mapToApplication(obj handler.MapObject) []reconcile.Request {
+	owner := metav1.GetControllerOf(obj.Meta)
+	if owner == nil || owner.Kind != v1beta1.ResourceTrackerKind {
+		return nil
+	}
+	rt := &v1beta1.ResourceTracker{}
+	if err := w.cache.Get(context.Background(), client.ObjectKey{Name: owner.Name}, rt); err != nil {
+		return nil
+	}
+	if len(rt.Labels[oam.LabelAppName]) == 0 {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: rt.Labels[oam.LabelAppNamespace],
+		Name:      rt.Labels[oam.LabelAppName],
+	}}}
+}
+
+// driftPredicate filters the changes that may drift the resources from their manifests,
+// e.g. the changes of the status are ignored
+var driftPredicate = predicate.Funcs{
+	CreateFunc: func(ctrlevent.CreateEvent) bool { return false },
+	UpdateFunc: func(e ctrlevent.UpdateEvent) bool {
+		if e.MetaNew.GetGeneration() != 0 && e.MetaNew.GetGeneration() == e.MetaOld.GetGeneration() {
+			return !reflect.DeepEqual(e.MetaNew.GetLabels(), e.MetaOld.GetLabels()) ||
+				!reflect.DeepEqual(e.MetaNew.GetAnnotations(), e.MetaOld.GetAnnotations())
+		}
+		return e.MetaNew.GetResourceVersion() != e.MetaOld.GetResourceVersion()
+	},
+	DeleteFunc:  func(ctrlevent.DeleteEvent) bool { return true },
+	GenericFunc: func(ctrlevent.GenericEvent) bool { return false },
+}
+
+// handleDrift reports the drift of the dispatched resources from the manifests of an unchanged revision, and returns
+// the manifests to dispatch. The drifted resources are applied again only if the application enables auto correction,
+// at most once per correction interval, otherwise they're held back for the users to check the drift. The missing
+// resources are always created again.
+func (h *appHandler) handleDrift(ctx context.Context, manifests []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if err := h.r.driftWatcher.watch(manifests); err != nil {
+		return nil, err
+	}
+	status := h.app.Status.Drift
+	if h.isNewRevision {
+		// the resources are expected to differ from the manifests of a new revision
+		if status != nil {
+			status.Resources = nil
+		}
+		return manifests, nil
+	}
+	drifts, err := dispatch.DetectDrift(ctx, h.r.driftWatcher.cache, manifests)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot detect drift")
+	}
+	if len(drifts) == 0 {
+		if status != nil {
+			status.Resources = nil
+		}
+		return manifests, nil
+	}
+	if status == nil {
+		status = &common.DriftStatus{}
+		h.app.Status.Drift = status
+	}
+
+	if h.app.Annotations[oam.AnnotationAutoCorrectDrift] == "true" {
+		now := time.Now()
+		if status.LastCorrectionTime == nil || !now.Before(status.LastCorrectionTime.Add(h.r.driftCorrectionInterval)) {
+			klog.InfoS("Correct drifted resources", "application", klog.KObj(h.app), "resources", len(drifts))
+			h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonCorrected, fmt.Sprintf(velatypes.MessageCorrected, len(drifts))))
+			status.Resources = nil
+			status.LastCorrectionTime = &metav1.Time{Time: now}
+			return manifests, nil
+		}
+		// the drifted resources are corrected once the correction interval passes
+		h.driftRequeueAfter = status.LastCorrectionTime.Add(h.r.driftCorrectionInterval).Sub(now)
+	}
+	if !reflect.DeepEqual(status.Resources, drifts) {
+		klog.InfoS("Detect drifted resources", "application", klog.KObj(h.app), "resources", len(drifts))
+		h.r.Recorder.Event(h.app, event.Warning(velatypes.ReasonDrifted, errors.Errorf(velatypes.MessageDrifted, len(drifts))))
+	}
+	status.Resources = drifts
+
+	drifted := make(map[corev1.ObjectReference]bool, len(drifts))
+	for _, drift := range drifts {
+		if drift.Missing {
+			continue
+		}
+		drifted[corev1.ObjectReference{APIVersion: drift.APIVersion, Kind: drift.Kind, Namespace: drift.Namespace,
+			Name: drift.Name}] = true
+	}
+	var rest []*unstructured.Unstructured
+	for _, manifest := range manifests {
+		if !drifted[corev1.ObjectReference{APIVersion: manifest.GetAPIVersion(), Kind: manifest.GetKind(),
+			Namespace: manifest.GetNamespace(), Name: manifest.GetName()}] {
+			rest = append(rest, manifest)
+		}
+	}
+	return rest, nil
+}
+
+// handleWorkflowDrift handles the drift of the resources applied by the succeeded `apply-component` steps, which
+// aren't executed again once the workflow is done. The components applied by the steps in this reconciliation
+// are skipped, since their resources have just been dispatched.
+func (h *appHandler) handleWorkflowDrift(ctx context.Context, appRev *v1beta1.ApplicationRevision,
+	ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
+	var manifests []*unstructured.Unstructured
+	for _, compName := range h.workflowAppliedComponents() {
+		if _, ok := h.appliedComponents.Load(compName); ok {
+			continue
+		}
+		compManifests, err := h.componentManifests(ctx, appRev, ac, comps, compName)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, compManifests...)
+	}
+	manifests, err := h.handleDrift(ctx, manifests)
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return nil
+	}
+	return h.dispatchComponentManifests(ctx, appRev, manifests)
+}
+
+// workflowAppliedComponents returns the components applied by the succeeded `apply-component` steps
+func (h *appHandler) workflowAppliedComponents() []string {
+	if h.app.Status.Workflow == nil {
+		return nil
+	}
+	succeeded := make(map[string]bool, len(h.app.Status.Workflow.Steps))
+	for _, ss := range h.app.Status.Workflow.Steps {
+		if ss.Phase == common.WorkflowStepPhaseSucceeded {
+			succeeded[ss.Name] = true
+		}
+	}
+	var compNames []string
+	for _, step := range h.app.Spec.Workflow {
+		if step.Type != tasks.StepApplyComponent || !succeeded[step.Name] {
+			continue
+		}
+		params := struct {
+			Component string `json:"component"`
+		}{}
+		if err := json.Unmarshal(step.Properties.Raw, &params); err != nil || params.Component == "" {
+			continue
+		}
+		compNames = append(compNames, params.Component)
+	}
+	return compNames
+}
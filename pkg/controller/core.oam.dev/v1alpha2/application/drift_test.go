@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// fakeController records the watches started by the drift watcher
+type fakeController struct {
+	watches int
+}
+
+func (c *fakeController) Reconcile(reconcile.Request) (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (c *fakeController) Watch(source.Source, handler.EventHandler, ...predicate.Predicate) error {
+	c.watches++
+	return nil
+}
+
+func (c *fakeController) Start(<-chan struct{}) error {
+	return nil
+}
+
+func newDriftDeployment(name string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec":       map[string]interface{}{"replicas": replicas},
+	}}
+}
+
+func TestHandleDrift(t *testing.T) {
+	interval := time.Minute
+	tests := map[string]struct {
+		autoCorrect        bool
+		isNewRevision      bool
+		lastCorrection     time.Duration
+		wantDispatched     []string
+		wantDrifted        []string
+		wantCorrected      bool
+		wantRequeueAtLeast time.Duration
+	}{
+		"hold back the drifted resources but create the missing ones": {
+			wantDispatched: []string{"unchanged", "missing"},
+			wantDrifted:    []string{"drifted", "missing"},
+		},
+		"correct the drift": {
+			autoCorrect:    true,
+			wantDispatched: []string{"drifted", "unchanged", "missing"},
+			wantCorrected:  true,
+		},
+		"correct the drift once the interval passed": {
+			autoCorrect:    true,
+			lastCorrection: 2 * interval,
+			wantDispatched: []string{"drifted", "unchanged", "missing"},
+			wantCorrected:  true,
+		},
+		"wait for the interval to correct the drift": {
+			autoCorrect:        true,
+			lastCorrection:     interval / 4,
+			wantDispatched:     []string{"unchanged", "missing"},
+			wantDrifted:        []string{"drifted", "missing"},
+			wantRequeueAtLeast: interval / 2,
+		},
+		"no drift for a new revision": {
+			isNewRevision:  true,
+			wantDispatched: []string{"drifted", "unchanged", "missing"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := fake.NewFakeClientWithScheme(velacommon.Scheme,
+				newDriftDeployment("drifted", 5), newDriftDeployment("unchanged", 2))
+			ctrl := &fakeController{}
+			app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+			if tt.autoCorrect {
+				app.Annotations = map[string]string{oam.AnnotationAutoCorrectDrift: "true"}
+			}
+			var lastCorrection *metav1.Time
+			if tt.lastCorrection != 0 {
+				lastCorrection = &metav1.Time{Time: time.Now().Add(-tt.lastCorrection)}
+				app.Status.Drift = &common.DriftStatus{LastCorrectionTime: lastCorrection.DeepCopy()}
+			}
+			h := &appHandler{
+				r: &Reconciler{
+					Client:                  c,
+					Recorder:                event.NewNopRecorder(),
+					driftCorrectionInterval: interval,
+					driftWatcher:            newDriftWatcher(ctrl, c),
+				},
+				app:           app,
+				isNewRevision: tt.isNewRevision,
+			}
+
+			manifests := []*unstructured.Unstructured{newDriftDeployment("drifted", 2),
+				newDriftDeployment("unchanged", 2), newDriftDeployment("missing", 1)}
+			dispatched, err := h.handleDrift(context.Background(), manifests)
+			require.NoError(t, err)
+			var names []string
+			for _, m := range dispatched {
+				names = append(names, m.GetName())
+			}
+			assert.Equal(t, tt.wantDispatched, names)
+			// the kind is only watched once
+			assert.Equal(t, 1, ctrl.watches)
+
+			var drifted []string
+			if app.Status.Drift != nil {
+				for _, drift := range app.Status.Drift.Resources {
+					drifted = append(drifted, drift.Name)
+				}
+			}
+			assert.Equal(t, tt.wantDrifted, drifted)
+			if tt.wantCorrected {
+				require.NotNil(t, app.Status.Drift.LastCorrectionTime)
+				assert.True(t, lastCorrection == nil || app.Status.Drift.LastCorrectionTime.After(lastCorrection.Time))
+			} else if app.Status.Drift != nil {
+				assert.Equal(t, lastCorrection, app.Status.Drift.LastCorrectionTime)
+			}
+			assert.True(t, h.driftRequeueAfter >= tt.wantRequeueAtLeast && h.driftRequeueAfter <= interval,
+				"requeue after %s", h.driftRequeueAfter)
+			if tt.wantRequeueAtLeast == 0 {
+				assert.Zero(t, h.driftRequeueAfter)
+			}
+		})
+	}
+}
+
+func TestWorkflowAppliedComponents(t *testing.T) {
+	app := &v1beta1.Application{}
+	app.Spec.Workflow = []v1beta1.WorkflowStep{
+		{Name: "apply-frontend", Type: "apply-component", Properties: runtime.RawExtension{Raw: []byte(`{"component":"frontend"}`)}},
+		{Name: "apply-backend", Type: "apply-component", Properties: runtime.RawExtension{Raw: []byte(`{"component":"backend"}`)}},
+		{Name: "apply-config", Type: "apply-object", Properties: runtime.RawExtension{Raw: []byte(`{"value":{"kind":"ConfigMap"}}`)}},
+		{Name: "apply-database", Type: "apply-component", Properties: runtime.RawExtension{Raw: []byte(`{"component":"database"}`)}},
+	}
+	h := &appHandler{app: app}
+	assert.Empty(t, h.workflowAppliedComponents())
+
+	app.Status.Workflow = &common.WorkflowStatus{Steps: []common.WorkflowStepStatus{
+		{Name: "apply-frontend", Phase: common.WorkflowStepPhaseSucceeded},
+		{Name: "apply-backend", Phase: common.WorkflowStepPhaseRunning},
+		{Name: "apply-config", Phase: common.WorkflowStepPhaseSucceeded},
+		{Name: "apply-database", Phase: common.WorkflowStepPhaseSucceeded},
+	}}
+	// only the components applied by the succeeded apply-component steps
+	assert.Equal(t, []string{"frontend", "database"}, h.workflowAppliedComponents())
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
 )
 
 // AppRevisionHash is used to compute the hash value of the AppRevision
@@ -396,6 +397,11 @@ func cleanUpApplicationRevision(ctx context.Context, h *appHandler) error {
 		if err := h.r.Delete(ctx, rev.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
 			return err
 		}
+		// the workflow run is the history of the revision, prune it together
+		run := &v1beta1.WorkflowRun{ObjectMeta: metav1.ObjectMeta{Namespace: rev.Namespace, Name: workflow.WorkflowRunName(rev.Name)}}
+		if err := h.r.Delete(ctx, run); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
 		needKill--
 	}
 	return nil
@@ -95,6 +95,13 @@ var _ = Describe("Test application controller clean up ", func() {
 			return nil
 		}, time.Second*30, time.Microsecond*300).Should(BeNil())
 
+		By("create workflow run of appRevision1")
+		run := &v1beta1.WorkflowRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: appName + "-v1"},
+			Spec:       v1beta1.WorkflowRunSpec{AppName: appName, AppRevision: appName + "-v1"},
+		}
+		Expect(k8sClient.Create(ctx, run)).Should(BeNil())
+
 		By("create new appRevision will remove appRevison1")
 		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
 		property := fmt.Sprintf(`{"cmd":["sleep","1000"],"image":"busybox:%d"}`, 6)
@@ -119,6 +126,10 @@ var _ = Describe("Test application controller clean up ", func() {
 			if err == nil || !apierrors.IsNotFound(err) {
 				return fmt.Errorf("haven't clean up the oldest revision")
 			}
+			err = k8sClient.Get(ctx, revKey, new(v1beta1.WorkflowRun))
+			if err == nil || !apierrors.IsNotFound(err) {
+				return fmt.Errorf("haven't clean up the workflow run of the oldest revision")
+			}
 			return nil
 		}, time.Second*30, time.Microsecond*300).Should(BeNil())
 
@@ -26,6 +26,7 @@ import (
 	"github.com/ghodss/yaml"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,7 +36,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/workflow"
 )
@@ -153,6 +157,91 @@ var _ = Describe("Test Workflow", func() {
 			Namespace: appWithWorkflow.Namespace,
 		}, step2obj)).Should(BeNil())
 	})
+
+	It("should execute built-in workflow steps", func() {
+		appWithBuiltinSteps := appWithWorkflow.DeepCopy()
+		appWithBuiltinSteps.Name = "test-wf-builtin"
+		appWithBuiltinSteps.Spec.Workflow = []oamcore.WorkflowStep{{
+			Name:       "apply",
+			Type:       "apply-component",
+			Properties: runtime.RawExtension{Raw: []byte(`{"component":"test-component"}`)},
+		}, {
+			Name: "approve",
+			Type: "suspend",
+		}}
+		Expect(k8sClient.Create(ctx, appWithBuiltinSteps)).Should(BeNil())
+
+		// first try to add finalizer
+		tryReconcile(reconciler, appWithBuiltinSteps.Name, appWithBuiltinSteps.Namespace)
+		tryReconcile(reconciler, appWithBuiltinSteps.Name, appWithBuiltinSteps.Namespace)
+
+		deploy := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{
+			Name:      "test-component",
+			Namespace: appWithBuiltinSteps.Namespace,
+		}, deploy)).Should(BeNil())
+		// the workload is labeled with the real revision of the component
+		comp := &v1alpha2.Component{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{
+			Name:      "test-component",
+			Namespace: appWithBuiltinSteps.Namespace,
+		}, comp)).Should(BeNil())
+		Expect(comp.Status.LatestRevision).ShouldNot(BeNil())
+		Expect(deploy.Labels[oam.LabelAppComponentRevision]).Should(Equal(comp.Status.LatestRevision.Name))
+
+		app := &oamcore.Application{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{
+			Name:      appWithBuiltinSteps.Name,
+			Namespace: appWithBuiltinSteps.Namespace,
+		}, app)).Should(BeNil())
+		Expect(app.Status.Phase).Should(Equal(common.ApplicationWorkflowSuspending))
+		Expect(app.Status.Workflow.Suspend).Should(BeTrue())
+		Expect(len(app.Status.Workflow.Steps)).Should(Equal(2))
+		Expect(app.Status.Workflow.Steps[0].Phase).Should(Equal(common.WorkflowStepPhaseSucceeded))
+		Expect(app.Status.Workflow.Steps[1].Phase).Should(Equal(common.WorkflowStepPhaseRunning))
+	})
+
+	It("should correct the drift of the resources applied by workflow steps", func() {
+		driftReconciler := *reconciler
+		driftReconciler.driftDetection = true
+		driftReconciler.driftCorrectionInterval = time.Minute
+		driftReconciler.driftWatcher = newDriftWatcher(&fakeController{}, k8sClient)
+
+		appWithDrift := appWithWorkflow.DeepCopy()
+		appWithDrift.Name = "test-wf-drift"
+		appWithDrift.Annotations = map[string]string{oam.AnnotationAutoCorrectDrift: "true"}
+		appWithDrift.Spec.Workflow = []oamcore.WorkflowStep{{
+			Name:       "apply",
+			Type:       "apply-component",
+			Properties: runtime.RawExtension{Raw: []byte(`{"component":"test-component"}`)},
+		}}
+		Expect(k8sClient.Create(ctx, appWithDrift)).Should(BeNil())
+
+		// first try to add finalizer
+		tryReconcile(&driftReconciler, appWithDrift.Name, appWithDrift.Namespace)
+		tryReconcile(&driftReconciler, appWithDrift.Name, appWithDrift.Namespace)
+
+		deployKey := client.ObjectKey{Name: "test-component", Namespace: appWithDrift.Namespace}
+		deploy := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, deployKey, deploy)).Should(BeNil())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Image).Should(Equal("busybox"))
+		app := &oamcore.Application{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: appWithDrift.Name, Namespace: appWithDrift.Namespace}, app)).Should(BeNil())
+		Expect(app.Status.Workflow.Steps[0].Phase).Should(Equal(common.WorkflowStepPhaseSucceeded))
+
+		By("drift the deployment applied by the succeeded step")
+		deploy.Spec.Template.Spec.Containers[0].Image = "nginx"
+		Expect(k8sClient.Update(ctx, deploy)).Should(BeNil())
+
+		// the succeeded step isn't executed again, the drift is corrected after the workflow is done
+		tryReconcile(&driftReconciler, appWithDrift.Name, appWithDrift.Namespace)
+		Expect(k8sClient.Get(ctx, deployKey, deploy)).Should(BeNil())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Image).Should(Equal("busybox"))
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: appWithDrift.Name, Namespace: appWithDrift.Namespace}, app)).Should(BeNil())
+		Expect(app.Status.Drift).ShouldNot(BeNil())
+		Expect(app.Status.Drift.LastCorrectionTime).ShouldNot(BeNil())
+		Expect(app.Status.Drift.Resources).Should(BeEmpty())
+	})
 })
 
 func markWorkflowSucceeded(obj *unstructured.Unstructured) {
@@ -24,7 +24,6 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
@@ -88,6 +87,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 	if err := r.Get(ctx, req.NamespacedName, &appRollout); err != nil {
 		if apierrors.IsNotFound(err) {
 			klog.InfoS("appRollout does not exist", "appRollout", klog.KRef(req.Namespace, req.Name))
+			rollout.DeleteRolloutMetrics(v1beta1.AppRolloutKind, req.Namespace, req.Name)
 		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
@@ -108,11 +108,11 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 
 // DoReconcile is real reconcile logic for appRollout.
 // 1.prepare rollout info: use assemble module in application pkg to generate manifest with appRevision
-// 2.determine which component is the common component between source and target AppRevision
+// 2.determine which components are the common components to rollout between source and target AppRevision
 // 3.if target workload isn't exist yet, template the targetAppRevision to apply target manifest
-// 4.extract target workload and source workload(if sourceAppRevision not empty)
-// 5.generate a rolloutPlan controller with source and target workload and call rolloutPlan's reconcile func
-// 6.handle output status
+// 4.extract target workload and source workload(if sourceAppRevision not empty) of each component
+// 5.generate a rolloutPlan controller with source and target workload and call rolloutPlan's reconcile func for each component
+// 6.handle output status of each component and summarize them
 // !!! Note the AppRollout object should not be updated in this function as it could be logically used in Application reconcile loop which does not have real AppRollout object.
 func (r *Reconciler) DoReconcile(ctx context.Context, appRollout *v1beta1.AppRollout) (reconcile.Result, error) {
 	if len(appRollout.Status.RollingState) == 0 {
@@ -134,6 +134,10 @@ func (r *Reconciler) DoReconcile(ctx context.Context, appRollout *v1beta1.AppRol
 		// except modified in middle of one rollout, in most cases use real source/target in appRollout and revision as this round reconcile
 		h.sourceRevName = appRollout.Spec.SourceAppRevisionName
 		h.targetRevName = appRollout.Spec.TargetAppRevisionName
+		if isRollingBack(appRollout) {
+			// roll back from the target to the source
+			h.sourceRevName, h.targetRevName = h.targetRevName, h.sourceRevName
+		}
 	}
 
 	// call assemble func generate source and target manifest
@@ -141,13 +145,11 @@ func (r *Reconciler) DoReconcile(ctx context.Context, appRollout *v1beta1.AppRol
 		return reconcile.Result{}, err
 	}
 
-	// we only support one workload rollout now, so here is determine witch component is need to rollout
-	if err = h.determineRolloutComponent(); err != nil {
+	// determine which components need to rollout
+	if err = h.determineRolloutComponents(); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	var sourceWorkload, targetWorkload *unstructured.Unstructured
-
 	// we should handle two special cases before call rolloutPlan Reconcile
 	switch h.appRollout.Status.RollingState {
 	case v1alpha1.RolloutDeletingState:
@@ -172,29 +174,28 @@ func (r *Reconciler) DoReconcile(ctx context.Context, appRollout *v1beta1.AppRol
 		}
 		// this ensures that we template workload only once
 		h.appRollout.Status.StateTransition(v1alpha1.AppLocatedEvent)
+		// the components start to rollout from the located target app
+		h.appRollout.Status.Components = nil
 		return reconcile.Result{RequeueAfter: 3 * time.Second}, nil
 	default:
 		// in other cases there is no need do anything
 	}
 
-	sourceWorkload, targetWorkload, err = h.fetchSourceAndTargetWorkload(ctx)
+	// a rollout being deleted is finalized as failed, it's not rolled back
+	deleting := appRollout.Status.RollingState == v1alpha1.RolloutDeletingState
+	// reconcile the rollout plan of each component and summarize their status
+	result, err := h.reconcileComponents(ctx)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
-
-	klog.InfoS("get the target workload we need to work on", "targetWorkload", klog.KObj(targetWorkload))
-	if sourceWorkload != nil {
-		klog.InfoS("get the source workload we need to work on", "sourceWorkload", klog.KObj(sourceWorkload))
-	}
-
-	// reconcile the rollout part of the spec given the target and source workload
-	rolloutPlanController := rollout.NewRolloutPlanController(r, appRollout, r.record,
-		&appRollout.Spec.RolloutPlan, &appRollout.Status.RolloutStatus, targetWorkload, sourceWorkload)
-	result, rolloutStatus := rolloutPlanController.Reconcile(ctx)
-	// make sure that the new status is copied back
-	appRollout.Status.RolloutStatus = *rolloutStatus
+	rolloutStatus := &appRollout.Status.RolloutStatus
 	// do not update the last with new revision if we are still trying to abandon the previous rollout
 	if rolloutStatus.RollingState != v1alpha1.RolloutAbandoningState {
+		if appRollout.Status.LastUpgradedTargetAppRevision != appRollout.Spec.TargetAppRevisionName ||
+			appRollout.Status.LastSourceAppRevision != appRollout.Spec.SourceAppRevisionName {
+			// the previous rollout is abandoned, so is its rollback
+			appRollout.Status.RollbackReason = ""
+		}
 		appRollout.Status.LastUpgradedTargetAppRevision = appRollout.Spec.TargetAppRevisionName
 		appRollout.Status.LastSourceAppRevision = appRollout.Spec.SourceAppRevisionName
 	}
@@ -204,9 +205,17 @@ func (r *Reconciler) DoReconcile(ctx context.Context, appRollout *v1beta1.AppRol
 		if err != nil {
 			return reconcile.Result{}, err
 		}
+		if isRollingBack(appRollout) {
+			h.finalizeRollingBack()
+			return result, nil
+		}
 		klog.InfoS("rollout succeeded, record the source and target app revision", "source", appRollout.Spec.SourceAppRevisionName,
 			"target", appRollout.Spec.TargetAppRevisionName)
 	} else if rolloutStatus.RollingState == v1alpha1.RolloutFailedState {
+		if reason, rollback := needRollback(appRollout); rollback && !deleting {
+			h.rollbackRollout(reason)
+			return reconcile.Result{RequeueAfter: 3 * time.Second}, nil
+		}
 		klog.InfoS("rollout failed, record the source and target app revision", "source", appRollout.Spec.SourceAppRevisionName,
 			"target", appRollout.Spec.TargetAppRevisionName, "revert on deletion", appRollout.Spec.RevertOnDelete)
 
@@ -0,0 +1,347 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationrollout
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// allBatchesCompleted is the completed batch index of a component that has finished all its batches
+const allBatchesCompleted = math.MaxInt32 - 1
+
+// reconcileComponents reconciles the rollout plan of each component and summarizes their status into the
+// rollout status. The rollout as a whole goes through the abandoning and deleting states, and each component
+// follows it to finalize its own workloads.
+func (h *rolloutHandler) reconcileComponents(ctx context.Context) (reconcile.Result, error) {
+	status := &h.appRollout.Status
+	strategy := h.appRollout.Spec.MultiComponentStrategy
+	initComponentStatus(status, h.needRollComponents, strategy)
+
+	switch status.RollingState {
+	case v1alpha1.RolloutAbandoningState, v1alpha1.RolloutDeletingState:
+		result, err := h.finalizeComponents(ctx)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		return result, nil
+	default:
+	}
+
+	if abortComponents(h.appRollout) {
+		klog.InfoS("the rollout is aborted", "appRollout", klog.KObj(h.appRollout),
+			"target", h.appRollout.Spec.TargetAppRevisionName)
+		h.record.Event(h.appRollout, event.Normal("Rollout aborted",
+			fmt.Sprintf("the rollout to %s is aborted", h.appRollout.Spec.TargetAppRevisionName)))
+	}
+
+	var result reconcile.Result
+	for _, comp := range activeComponents(status, h.needRollComponents, strategy) {
+		compStatus := componentRolloutStatus(status, comp)
+		plan := componentRolloutPlan(h.rolloutPlan(), status, strategy)
+		res, err := h.reconcileComponent(ctx, comp, plan, &compStatus.RolloutStatus)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		result = mergeResult(result, res)
+	}
+	failComponents(status)
+	// come back for the next component if the ordered rollout of the previous one just succeeded
+	if result.RequeueAfter == 0 && len(activeComponents(status, h.needRollComponents, strategy)) != 0 {
+		result = mergeResult(result, reconcile.Result{RequeueAfter: 3 * time.Second})
+	}
+	aggregateComponentStatus(status)
+	return result, nil
+}
+
+// finalizeComponents passes the abandoning or deleting state to each component and finalizes them, the rollout is
+// finalized once all of its components are
+func (h *rolloutHandler) finalizeComponents(ctx context.Context) (reconcile.Result, error) {
+	status := &h.appRollout.Status
+	var result reconcile.Result
+	finalized := true
+	for i := range status.Components {
+		compStatus := &status.Components[i].RolloutStatus
+		switch status.RollingState {
+		case v1alpha1.RolloutAbandoningState:
+			if compStatus.RollingState != v1alpha1.RolloutAbandoningState &&
+				compStatus.RollingState != v1alpha1.LocatingTargetAppState {
+				compStatus.StateTransition(v1alpha1.RollingModifiedEvent)
+			}
+		case v1alpha1.RolloutDeletingState:
+			if compStatus.RollingState != v1alpha1.RolloutDeletingState && !isTerminated(compStatus) {
+				compStatus.StateTransition(v1alpha1.RollingDeletedEvent)
+			}
+		default:
+		}
+		if compStatus.RollingState == v1alpha1.LocatingTargetAppState || isTerminated(compStatus) {
+			continue
+		}
+		res, err := h.reconcileComponent(ctx, status.Components[i].Name, h.rolloutPlan(), compStatus)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		result = mergeResult(result, res)
+		if compStatus.RollingState != v1alpha1.LocatingTargetAppState && !isTerminated(compStatus) {
+			finalized = false
+		}
+	}
+	if !finalized {
+		return result, nil
+	}
+	klog.InfoS("all the components are finalized", "appRollout", klog.KObj(h.appRollout),
+		"rolling state", status.RollingState)
+	status.StateTransition(v1alpha1.RollingFinalizedEvent)
+	if status.RollingState == v1alpha1.LocatingTargetAppState {
+		// the components start over with the new target app
+		status.Components = nil
+		return reconcile.Result{RequeueAfter: 3 * time.Second}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// reconcileComponent reconciles the rollout plan of one component given its source and target workload
+func (h *rolloutHandler) reconcileComponent(ctx context.Context, comp string, plan *v1alpha1.RolloutPlan,
+	compStatus *v1alpha1.RolloutStatus) (reconcile.Result, error) {
+	sourceWorkload, targetWorkload, err := h.fetchSourceAndTargetWorkload(ctx, comp)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	klog.InfoS("get the target workload we need to work on", "component", comp, "targetWorkload", klog.KObj(targetWorkload))
+	if sourceWorkload != nil {
+		klog.InfoS("get the source workload we need to work on", "component", comp, "sourceWorkload", klog.KObj(sourceWorkload))
+	}
+
+	// reconcile the rollout part of the spec given the target and source workload
+	rolloutPlanController := rollout.NewRolloutPlanController(h, h.appRollout, h.record, plan, compStatus,
+		targetWorkload, sourceWorkload)
+	result, rolloutStatus := rolloutPlanController.Reconcile(ctx)
+	// make sure that the new status is copied back
+	*compStatus = *rolloutStatus
+	return result, nil
+}
+
+// initComponentStatus starts the rollout of the first components. They start from the rollout status which is
+// reset when the target app is located, this also carries on the rollout not yet tracked per component.
+func initComponentStatus(status *common.AppRolloutStatus, comps []string, strategy common.MultiComponentStrategy) {
+	if len(status.Components) != 0 || status.RollingState == v1alpha1.LocatingTargetAppState {
+		return
+	}
+	if strategy == common.OrderedMultiComponentStrategy {
+		comps = comps[:1]
+	}
+	for _, comp := range comps {
+		compStatus := common.ComponentRolloutStatus{Name: comp}
+		status.RolloutStatus.DeepCopyInto(&compStatus.RolloutStatus)
+		status.Components = append(status.Components, compStatus)
+	}
+}
+
+// componentRolloutStatus returns the rollout status of a component, it's nil if the component is not started yet
+func componentRolloutStatus(status *common.AppRolloutStatus, comp string) *common.ComponentRolloutStatus {
+	for i := range status.Components {
+		if status.Components[i].Name == comp {
+			return &status.Components[i]
+		}
+	}
+	return nil
+}
+
+// activeComponents returns the components to move forward in this round. All the components in lockstep move
+// forward together, while in order the next component starts only after the previous one succeeds.
+func activeComponents(status *common.AppRolloutStatus, comps []string,
+	strategy common.MultiComponentStrategy) []string {
+	var active []string
+	for _, comp := range comps {
+		compStatus := componentRolloutStatus(status, comp)
+		if compStatus == nil {
+			if strategy != common.OrderedMultiComponentStrategy {
+				continue
+			}
+			// the target app is already located for all the components
+			compStatus = &common.ComponentRolloutStatus{Name: comp}
+			compStatus.ResetStatus()
+			compStatus.StateTransition(v1alpha1.AppLocatedEvent)
+			status.Components = append(status.Components, *compStatus)
+		}
+		if !isTerminated(&compStatus.RolloutStatus) {
+			active = append(active, comp)
+		}
+		if strategy == common.OrderedMultiComponentStrategy &&
+			compStatus.RollingState != v1alpha1.RolloutSucceedState {
+			break
+		}
+	}
+	return active
+}
+
+// componentRolloutPlan returns the rollout plan of the components. Components in lockstep are held by the batch
+// partition so that none of them moves to the next batch until all of them finish the current one.
+func componentRolloutPlan(plan *v1alpha1.RolloutPlan, status *common.AppRolloutStatus,
+	strategy common.MultiComponentStrategy) *v1alpha1.RolloutPlan {
+	if strategy == common.OrderedMultiComponentStrategy || len(status.Components) < 2 {
+		return plan
+	}
+	gate := int32(allBatchesCompleted)
+	for i := range status.Components {
+		if completed := completedBatch(&status.Components[i].RolloutStatus); completed < gate {
+			gate = completed
+		}
+	}
+	if gate == allBatchesCompleted || (plan.BatchPartition != nil && *plan.BatchPartition <= gate+1) {
+		return plan
+	}
+	lockstepPlan := plan.DeepCopy()
+	partition := gate + 1
+	lockstepPlan.BatchPartition = &partition
+	return lockstepPlan
+}
+
+// completedBatch returns the index of the last batch a component has finished, it's -1 if none is finished
+func completedBatch(status *v1alpha1.RolloutStatus) int32 {
+	switch status.RollingState {
+	case v1alpha1.FinalisingState, v1alpha1.RolloutSucceedState:
+		return allBatchesCompleted
+	case v1alpha1.RollingInBatchesState:
+		if status.BatchRollingState == v1alpha1.BatchReadyState {
+			return status.CurrentBatch
+		}
+		return status.CurrentBatch - 1
+	default:
+		return -1
+	}
+}
+
+// failComponents fails the rollout of all the other components once one of them fails, the components that already
+// succeeded are left as they are
+func failComponents(status *common.AppRolloutStatus) {
+	var failedComp string
+	for i := range status.Components {
+		state := status.Components[i].RollingState
+		if state == v1alpha1.RolloutFailingState || state == v1alpha1.RolloutFailedState {
+			failedComp = status.Components[i].Name
+			break
+		}
+	}
+	if len(failedComp) == 0 {
+		return
+	}
+	reason := fmt.Sprintf("the rollout of component %s failed", failedComp)
+	for i := range status.Components {
+		failComponent(&status.Components[i].RolloutStatus, reason)
+	}
+}
+
+// abortComponents fails the rollout of all the components once the user aborts the rollout of the target app
+// revision, it returns if any of the components is aborted in this round
+func abortComponents(appRollout *v1beta1.AppRollout) bool {
+	if appRollout.GetAnnotations()[oam.AnnotationRolloutAbort] != appRollout.Spec.TargetAppRevisionName {
+		return false
+	}
+	aborted := false
+	for i := range appRollout.Status.Components {
+		if failComponent(&appRollout.Status.Components[i].RolloutStatus, "the rollout is aborted") {
+			aborted = true
+		}
+	}
+	return aborted
+}
+
+// failComponent fails the rollout of a component, it returns false if the component already succeeded or failed
+func failComponent(compStatus *v1alpha1.RolloutStatus, reason string) bool {
+	switch compStatus.RollingState {
+	case v1alpha1.RolloutSucceedState, v1alpha1.RolloutFailedState, v1alpha1.RolloutFailingState:
+		return false
+	case v1alpha1.VerifyingSpecState:
+		// nothing is changed yet, no need to finalize
+		compStatus.RolloutFailed(reason)
+	default:
+		compStatus.RolloutFailing(reason)
+	}
+	return true
+}
+
+// aggregateComponentStatus summarizes the status of all the components into the rollout status. The rollout
+// follows the first failed component or otherwise the least progressed one, with the replicas of all components.
+func aggregateComponentStatus(status *common.AppRolloutStatus) {
+	if len(status.Components) == 0 {
+		return
+	}
+	representative := -1
+	terminated, failed := 0, 0
+	for i := range status.Components {
+		compStatus := &status.Components[i].RolloutStatus
+		if isTerminated(compStatus) {
+			terminated++
+		}
+		if compStatus.RollingState == v1alpha1.RolloutFailingState || compStatus.RollingState == v1alpha1.RolloutFailedState {
+			if failed == 0 {
+				representative = i
+			}
+			failed++
+		}
+		if failed == 0 && (representative == -1 ||
+			completedBatch(compStatus) < completedBatch(&status.Components[representative].RolloutStatus)) {
+			representative = i
+		}
+	}
+	aggregated := status.Components[representative].RolloutStatus.DeepCopy()
+	aggregated.RolloutOriginalSize, aggregated.UpgradedReplicas, aggregated.UpgradedReadyReplicas = 0, 0, 0
+	for i := range status.Components {
+		compStatus := &status.Components[i].RolloutStatus
+		aggregated.RolloutOriginalSize += compStatus.RolloutOriginalSize
+		aggregated.UpgradedReplicas += compStatus.UpgradedReplicas
+		aggregated.UpgradedReadyReplicas += compStatus.UpgradedReadyReplicas
+		if compStatus.RolloutTargetSize >= 0 && i != representative {
+			if aggregated.RolloutTargetSize < 0 {
+				aggregated.RolloutTargetSize = 0
+			}
+			aggregated.RolloutTargetSize += compStatus.RolloutTargetSize
+		}
+	}
+	if failed != 0 {
+		aggregated.RollingState = v1alpha1.RolloutFailingState
+		if terminated == len(status.Components) {
+			aggregated.RollingState = v1alpha1.RolloutFailedState
+		}
+	}
+	status.RolloutStatus = *aggregated
+}
+
+// mergeResult returns the result that requeues the soonest
+func mergeResult(result, res reconcile.Result) reconcile.Result {
+	if res.RequeueAfter > 0 && (result.RequeueAfter == 0 || res.RequeueAfter < result.RequeueAfter) {
+		return res
+	}
+	return result
+}
+
+func isTerminated(status *v1alpha1.RolloutStatus) bool {
+	return status.RollingState == v1alpha1.RolloutSucceedState || status.RollingState == v1alpha1.RolloutFailedState
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationrollout
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/utils/pointer"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newComponentStatus(name string, state v1alpha1.RollingState, batchState v1alpha1.BatchRollingState,
+	batch int32) common.ComponentRolloutStatus {
+	return common.ComponentRolloutStatus{Name: name, RolloutStatus: v1alpha1.RolloutStatus{
+		RollingState:      state,
+		BatchRollingState: batchState,
+		CurrentBatch:      batch,
+		RolloutTargetSize: 4,
+	}}
+}
+
+func TestInitComponentStatus(t *testing.T) {
+	status := &common.AppRolloutStatus{}
+	status.ResetStatus()
+	initComponentStatus(status, []string{"frontend", "backend"}, "")
+	assert.Equal(t, 0, len(status.Components))
+
+	status.StateTransition(v1alpha1.AppLocatedEvent)
+	initComponentStatus(status, []string{"frontend", "backend"}, common.OrderedMultiComponentStrategy)
+	assert.Equal(t, 1, len(status.Components))
+	assert.Equal(t, "frontend", status.Components[0].Name)
+	assert.Equal(t, v1alpha1.VerifyingSpecState, status.Components[0].RollingState)
+
+	status.Components = nil
+	initComponentStatus(status, []string{"frontend", "backend"}, common.LockstepMultiComponentStrategy)
+	assert.Equal(t, 2, len(status.Components))
+}
+
+func TestActiveComponents(t *testing.T) {
+	comps := []string{"frontend", "backend"}
+	status := &common.AppRolloutStatus{Components: []common.ComponentRolloutStatus{
+		newComponentStatus("frontend", v1alpha1.RollingInBatchesState, v1alpha1.BatchInRollingState, 0),
+	}}
+	assert.DeepEqual(t, []string{"frontend"}, activeComponents(status, comps, common.OrderedMultiComponentStrategy))
+
+	// the next component starts once the previous one succeeds
+	status.Components[0].RollingState = v1alpha1.RolloutSucceedState
+	assert.DeepEqual(t, []string{"backend"}, activeComponents(status, comps, common.OrderedMultiComponentStrategy))
+	assert.Equal(t, 2, len(status.Components))
+	assert.Equal(t, v1alpha1.VerifyingSpecState, status.Components[1].RollingState)
+
+	status.Components[1].RollingState = v1alpha1.RolloutSucceedState
+	assert.Equal(t, 0, len(activeComponents(status, comps, common.OrderedMultiComponentStrategy)))
+
+	status.Components[0].RollingState = v1alpha1.RollingInBatchesState
+	assert.DeepEqual(t, []string{"frontend"}, activeComponents(status, comps, common.LockstepMultiComponentStrategy))
+}
+
+func TestComponentRolloutPlan(t *testing.T) {
+	plan := &v1alpha1.RolloutPlan{RolloutBatches: make([]v1alpha1.RolloutBatch, 3)}
+	status := &common.AppRolloutStatus{Components: []common.ComponentRolloutStatus{
+		newComponentStatus("frontend", v1alpha1.RollingInBatchesState, v1alpha1.BatchReadyState, 1),
+		newComponentStatus("backend", v1alpha1.RollingInBatchesState, v1alpha1.BatchVerifyingState, 1),
+	}}
+	// the frontend waits for the backend to finish the batch
+	got := componentRolloutPlan(plan, status, common.LockstepMultiComponentStrategy)
+	assert.Equal(t, int32(1), *got.BatchPartition)
+	assert.Assert(t, plan.BatchPartition == nil)
+
+	status.Components[1].BatchRollingState = v1alpha1.BatchReadyState
+	got = componentRolloutPlan(plan, status, common.LockstepMultiComponentStrategy)
+	assert.Equal(t, int32(2), *got.BatchPartition)
+
+	// the partition of the plan still holds all the components
+	plan.BatchPartition = pointer.Int32Ptr(1)
+	got = componentRolloutPlan(plan, status, common.LockstepMultiComponentStrategy)
+	assert.Equal(t, int32(1), *got.BatchPartition)
+
+	plan.BatchPartition = nil
+	status.Components[0].RollingState = v1alpha1.FinalisingState
+	status.Components[1].RollingState = v1alpha1.RolloutSucceedState
+	got = componentRolloutPlan(plan, status, common.LockstepMultiComponentStrategy)
+	assert.Assert(t, got.BatchPartition == nil)
+
+	status.Components[0].RollingState = v1alpha1.VerifyingSpecState
+	got = componentRolloutPlan(plan, status, common.OrderedMultiComponentStrategy)
+	assert.Assert(t, got.BatchPartition == nil)
+}
+
+func TestFailComponents(t *testing.T) {
+	status := &common.AppRolloutStatus{Components: []common.ComponentRolloutStatus{
+		newComponentStatus("frontend", v1alpha1.RolloutSucceedState, v1alpha1.BatchReadyState, 1),
+		newComponentStatus("backend", v1alpha1.RollingInBatchesState, v1alpha1.BatchVerifyingState, 1),
+		newComponentStatus("worker", v1alpha1.VerifyingSpecState, v1alpha1.BatchInitializingState, 0),
+		newComponentStatus("cache", v1alpha1.RolloutFailedState, v1alpha1.BatchRolloutFailedState, 0),
+	}}
+	failComponents(status)
+	assert.Equal(t, v1alpha1.RolloutSucceedState, status.Components[0].RollingState)
+	assert.Equal(t, v1alpha1.RolloutFailingState, status.Components[1].RollingState)
+	assert.Equal(t, v1alpha1.RolloutFailedState, status.Components[2].RollingState)
+}
+
+func TestAbortComponents(t *testing.T) {
+	appRollout := &v1beta1.AppRollout{
+		Spec: v1beta1.AppRolloutSpec{TargetAppRevisionName: "app-v2"},
+		Status: common.AppRolloutStatus{Components: []common.ComponentRolloutStatus{
+			newComponentStatus("frontend", v1alpha1.RollingInBatchesState, v1alpha1.BatchReadyState, 1),
+			newComponentStatus("backend", v1alpha1.RolloutSucceedState, v1alpha1.BatchReadyState, 1),
+		}},
+	}
+	assert.Assert(t, !abortComponents(appRollout))
+
+	// the abort of a previous target app revision doesn't affect this rollout
+	appRollout.SetAnnotations(map[string]string{oam.AnnotationRolloutAbort: "app-v1"})
+	assert.Assert(t, !abortComponents(appRollout))
+	assert.Equal(t, v1alpha1.RollingInBatchesState, appRollout.Status.Components[0].RollingState)
+
+	appRollout.SetAnnotations(map[string]string{oam.AnnotationRolloutAbort: "app-v2"})
+	assert.Assert(t, abortComponents(appRollout))
+	assert.Equal(t, v1alpha1.RolloutFailingState, appRollout.Status.Components[0].RollingState)
+	assert.Equal(t, v1alpha1.RolloutSucceedState, appRollout.Status.Components[1].RollingState)
+	// the rollout is only aborted once
+	assert.Assert(t, !abortComponents(appRollout))
+}
+
+func TestAggregateComponentStatus(t *testing.T) {
+	status := &common.AppRolloutStatus{Components: []common.ComponentRolloutStatus{
+		newComponentStatus("frontend", v1alpha1.RollingInBatchesState, v1alpha1.BatchReadyState, 1),
+		newComponentStatus("backend", v1alpha1.RollingInBatchesState, v1alpha1.BatchVerifyingState, 1),
+	}}
+	status.Components[0].UpgradedReplicas = 3
+	status.Components[1].UpgradedReplicas = 2
+	aggregateComponentStatus(status)
+	assert.Equal(t, v1alpha1.RollingInBatchesState, status.RollingState)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	assert.Equal(t, int32(8), status.RolloutTargetSize)
+	assert.Equal(t, int32(5), status.UpgradedReplicas)
+
+	status.Components[1].RollingState = v1alpha1.RolloutFailedState
+	aggregateComponentStatus(status)
+	assert.Equal(t, v1alpha1.RolloutFailingState, status.RollingState)
+
+	status.Components[0].RollingState = v1alpha1.RolloutSucceedState
+	aggregateComponentStatus(status)
+	assert.Equal(t, v1alpha1.RolloutFailedState, status.RollingState)
+
+	status.Components[1].RollingState = v1alpha1.RolloutSucceedState
+	aggregateComponentStatus(status)
+	assert.Equal(t, v1alpha1.RolloutSucceedState, status.RollingState)
+}
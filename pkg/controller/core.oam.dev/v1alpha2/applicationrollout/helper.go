@@ -22,6 +22,7 @@ import (
 	"k8s.io/utils/pointer"
 
 	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 
@@ -35,16 +36,13 @@ import (
 func rolloutWorkloadName() assemble.WorkloadOption {
 	return assemble.WorkloadOptionFn(func(w *unstructured.Unstructured, component *v1alpha2.Component, definition *v1beta1.ComponentDefinition) error {
 		// we hard code the behavior depends on the workload group/kind for now. The only in-place upgradable resources
-		// we support is cloneset/statefulset for now. We can easily add more later.
-		if w.GroupVersionKind().Group == v1alpha1.GroupVersion.Group {
-			if w.GetKind() == reflect.TypeOf(v1alpha1.CloneSet{}).Name() ||
-				w.GetKind() == reflect.TypeOf(v1alpha1.StatefulSet{}).Name() {
-				// we use the component name alone for those resources that do support in-place upgrade
-				klog.InfoS("we reuse the component name for resources that support in-place upgrade",
-					"GVK", w.GroupVersionKind(), "instance name", component.Name)
-				w.SetName(component.Name)
-				return nil
-			}
+		// we support is cloneset/statefulset/daemonset for now. We can easily add more later.
+		if isInplaceUpgradable(w) {
+			// we use the component name alone for those resources that do support in-place upgrade
+			klog.InfoS("we reuse the component name for resources that support in-place upgrade",
+				"GVK", w.GroupVersionKind(), "instance name", component.Name)
+			w.SetName(component.Name)
+			return nil
 		}
 		// we assume that the rest of the resources do not support in-place upgrade
 		compRevName := w.GetLabels()[oam.LabelAppComponentRevision]
@@ -55,6 +53,20 @@ func rolloutWorkloadName() assemble.WorkloadOption {
 	})
 }
 
+func isInplaceUpgradable(w *unstructured.Unstructured) bool {
+	switch w.GroupVersionKind().Group {
+	case v1alpha1.GroupVersion.Group:
+		return w.GetKind() == reflect.TypeOf(v1alpha1.CloneSet{}).Name() ||
+			w.GetKind() == reflect.TypeOf(v1alpha1.StatefulSet{}).Name()
+	case appsv1.GroupName:
+		// the pods of the statefulset and daemonset are bound to their volumes or nodes, they can't be run
+		// side by side with another instance of the workload
+		return w.GetKind() == reflect.TypeOf(appsv1.StatefulSet{}).Name() ||
+			w.GetKind() == reflect.TypeOf(appsv1.DaemonSet{}).Name()
+	}
+	return false
+}
+
 // appRollout should take over updating workload, so disable previous controller owner(resourceTracker)
 func disableControllerOwner(workload *unstructured.Unstructured) {
 	if workload == nil {
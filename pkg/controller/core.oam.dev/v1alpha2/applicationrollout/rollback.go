@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationrollout
+
+import (
+	"fmt"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// the conditions set when the rollout fails in the middle of rolling out the batches
+var batchConditionTypes = map[runtimev1alpha1.ConditionType]bool{
+	v1alpha1.RolloutInProgress:  true,
+	v1alpha1.BatchInitializing:  true,
+	v1alpha1.BatchVerifying:     true,
+	v1alpha1.BatchRolloutFailed: true,
+	v1alpha1.BatchFinalizing:    true,
+}
+
+// isRollingBack returns if the rollout is rolling back to the source app revision
+func isRollingBack(appRollout *v1beta1.AppRollout) bool {
+	return len(appRollout.Status.RollbackReason) != 0
+}
+
+// needRollback checks if the failed rollout needs to be rolled back to the source app revision. It's only rolled
+// back once and only when one of its batches fails, there is nothing to roll back if it fails before that.
+func needRollback(appRollout *v1beta1.AppRollout) (string, bool) {
+	if appRollout.Status.RollingState != v1alpha1.RolloutFailedState || isRollingBack(appRollout) ||
+		len(appRollout.Spec.SourceAppRevisionName) == 0 {
+		return "", false
+	}
+	policy := appRollout.Spec.RolloutPlan.RollbackPolicy
+	if policy == nil || !policy.Automatic {
+		return "", false
+	}
+	return batchFailure(&appRollout.Status)
+}
+
+// batchFailure returns the reason why the batches of the rollout failed
+func batchFailure(status *common.AppRolloutStatus) (string, bool) {
+	if len(status.Components) == 0 {
+		return lastBatchFailure(&status.RolloutStatus)
+	}
+	for i := range status.Components {
+		if reason, failed := lastBatchFailure(&status.Components[i].RolloutStatus); failed {
+			if len(status.Components) > 1 {
+				reason = fmt.Sprintf("component %s: %s", status.Components[i].Name, reason)
+			}
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// lastBatchFailure returns the message of the latest negative condition if it's set by a batch
+func lastBatchFailure(status *v1alpha1.RolloutStatus) (string, bool) {
+	var last *runtimev1alpha1.Condition
+	for i := range status.Conditions {
+		cond := &status.Conditions[i]
+		if cond.Status != corev1.ConditionFalse {
+			continue
+		}
+		if last == nil || last.LastTransitionTime.Before(&cond.LastTransitionTime) {
+			last = cond
+		}
+	}
+	if last == nil || !batchConditionTypes[last.Type] {
+		return "", false
+	}
+	return last.Message, true
+}
+
+// rollbackRollout starts to roll the failed rollout back, all the components start over with the source and target
+// app revision swapped
+func (h *rolloutHandler) rollbackRollout(reason string) {
+	klog.InfoS("rollout failed, roll back to the source app revision", "appRollout", klog.KObj(h.appRollout),
+		"source", h.appRollout.Spec.SourceAppRevisionName, "target", h.appRollout.Spec.TargetAppRevisionName,
+		"reason", reason)
+	h.record.Event(h.appRollout, event.Warning("Rollout Rolling Back", errors.Errorf(
+		"roll back to %s: %s", h.appRollout.Spec.SourceAppRevisionName, reason)))
+	h.appRollout.Status.RollbackReason = reason
+	h.appRollout.Status.ResetStatus()
+	h.appRollout.Status.Components = nil
+}
+
+// finalizeRollingBack marks the rollout as failed once it's rolled back
+func (h *rolloutHandler) finalizeRollingBack() {
+	klog.InfoS("rollout rolled back to the source app revision", "appRollout", klog.KObj(h.appRollout),
+		"source", h.appRollout.Spec.SourceAppRevisionName)
+	h.record.Event(h.appRollout, event.Normal("Rollout Rolled Back",
+		fmt.Sprintf("rolled back to %s", h.appRollout.Spec.SourceAppRevisionName)))
+	h.appRollout.Status.RolloutFailed(fmt.Sprintf("rolled back to %s: %s",
+		h.appRollout.Spec.SourceAppRevisionName, h.appRollout.Status.RollbackReason))
+}
+
+// rolloutPlan returns the rollout plan to reconcile the components. The rollback goes through all the batches
+// without the canary metrics which are meant for the target app revision. It leaves the traffic alone as the
+// failed rollout has routed all of it back to the source app revision, and it doesn't wait for the schedule
+// since a failed rollout is rolled back right away.
+func (h *rolloutHandler) rolloutPlan() *v1alpha1.RolloutPlan {
+	plan := &h.appRollout.Spec.RolloutPlan
+	if !isRollingBack(h.appRollout) {
+		return plan
+	}
+	rollbackPlan := plan.DeepCopy()
+	rollbackPlan.BatchPartition = nil
+	rollbackPlan.CanaryMetric = nil
+	rollbackPlan.RollbackPolicy = nil
+	rollbackPlan.TrafficRouting = nil
+	rollbackPlan.Schedule = nil
+	for i := range rollbackPlan.RolloutBatches {
+		rollbackPlan.RolloutBatches[i].CanaryMetric = nil
+	}
+	return rollbackPlan
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationrollout
+
+import (
+	"testing"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func newFailedCondition(condType runtimev1alpha1.ConditionType, message string, age time.Duration) runtimev1alpha1.Condition {
+	cond := v1alpha1.NewNegativeCondition(condType, message)
+	cond.LastTransitionTime = metav1.NewTime(time.Now().Add(-age))
+	return cond
+}
+
+func TestNeedRollback(t *testing.T) {
+	newAppRollout := func(conditions ...runtimev1alpha1.Condition) *v1beta1.AppRollout {
+		appRollout := &v1beta1.AppRollout{Spec: v1beta1.AppRolloutSpec{
+			SourceAppRevisionName: "app-v1",
+			TargetAppRevisionName: "app-v2",
+			RolloutPlan: v1alpha1.RolloutPlan{
+				RollbackPolicy: &v1alpha1.RollbackPolicy{Automatic: true},
+			},
+		}}
+		appRollout.Status.RollingState = v1alpha1.RolloutFailedState
+		appRollout.Status.Components = []common.ComponentRolloutStatus{{Name: "frontend"}, {Name: "backend"}}
+		appRollout.Status.Components[1].SetConditions(conditions...)
+		return appRollout
+	}
+
+	reason, rollback := needRollback(newAppRollout(newFailedCondition(v1alpha1.BatchVerifying, "pods not ready", 0)))
+	assert.Assert(t, rollback)
+	assert.Equal(t, "component backend: pods not ready", reason)
+
+	// the rollout fails before any of the batches
+	_, rollback = needRollback(newAppRollout(
+		newFailedCondition(v1alpha1.BatchFinalizing, "webhook retried", time.Minute),
+		newFailedCondition(v1alpha1.RolloutSpecVerifying, "invalid spec", 0)))
+	assert.Assert(t, !rollback)
+
+	appRollout := newAppRollout(newFailedCondition(v1alpha1.BatchRolloutFailed, "canary failed", 0))
+	appRollout.Spec.RolloutPlan.RollbackPolicy = nil
+	_, rollback = needRollback(appRollout)
+	assert.Assert(t, !rollback)
+
+	// the rollback itself is not rolled back again
+	appRollout = newAppRollout(newFailedCondition(v1alpha1.BatchRolloutFailed, "canary failed", 0))
+	appRollout.Status.RollbackReason = "canary failed"
+	_, rollback = needRollback(appRollout)
+	assert.Assert(t, !rollback)
+
+	// there is nothing to roll back to for a scale operation
+	appRollout = newAppRollout(newFailedCondition(v1alpha1.BatchRolloutFailed, "canary failed", 0))
+	appRollout.Spec.SourceAppRevisionName = ""
+	_, rollback = needRollback(appRollout)
+	assert.Assert(t, !rollback)
+}
+
+func TestRollbackRolloutPlan(t *testing.T) {
+	appRollout := &v1beta1.AppRollout{Spec: v1beta1.AppRolloutSpec{RolloutPlan: v1alpha1.RolloutPlan{
+		BatchPartition: pointer.Int32Ptr(0),
+		CanaryMetric:   []v1alpha1.CanaryMetric{{Name: "success-rate"}},
+		RollbackPolicy: &v1alpha1.RollbackPolicy{Automatic: true},
+		RolloutBatches: []v1alpha1.RolloutBatch{{CanaryMetric: []v1alpha1.CanaryMetric{{Name: "latency"}}}},
+		TrafficRouting: &v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficProvider},
+		Schedule:       &v1alpha1.RolloutSchedule{SoakSeconds: pointer.Int32Ptr(600)},
+	}}}
+	h := &rolloutHandler{appRollout: appRollout}
+	assert.Equal(t, &appRollout.Spec.RolloutPlan, h.rolloutPlan())
+
+	appRollout.Status.RollbackReason = "canary failed"
+	plan := h.rolloutPlan()
+	assert.Assert(t, plan.BatchPartition == nil)
+	assert.Assert(t, plan.RollbackPolicy == nil)
+	assert.Assert(t, plan.TrafficRouting == nil)
+	assert.Assert(t, plan.Schedule == nil)
+	assert.Equal(t, 0, len(plan.CanaryMetric))
+	assert.Equal(t, 0, len(plan.RolloutBatches[0].CanaryMetric))
+	assert.Equal(t, 1, len(appRollout.Spec.RolloutPlan.RolloutBatches[0].CanaryMetric))
+}
@@ -62,8 +62,8 @@ type rolloutHandler struct {
 	// sourceManifests used by dispatch(template targetRevision) and handleSucceed(GC) phase
 	sourceManifests []*unstructured.Unstructured
 
-	// needRollComponent is find common component between source and target revision
-	needRollComponent string
+	// needRollComponents are the common components between source and target revision that need to rollout
+	needRollComponents []string
 }
 
 // prepareRollout  call assemble func to prepare info needed in whole reconcile loop
@@ -116,8 +116,8 @@ func (h *rolloutHandler) prepareRollout(ctx context.Context) error {
 	return nil
 }
 
-// we only support one workload now, so this func is to determine witch component is need to rollout
-func (h *rolloutHandler) determineRolloutComponent() error {
+// determineRolloutComponents determines which components need to rollout, they are rolled out in the listed order
+func (h *rolloutHandler) determineRolloutComponents() error {
 	componentList := h.appRollout.Spec.ComponentList
 	// if user not set ComponentList in AppRollout we also find a common component between source and target
 	if len(componentList) == 0 {
@@ -126,27 +126,35 @@ func (h *rolloutHandler) determineRolloutComponent() error {
 		if len(commons) != 1 {
 			return fmt.Errorf("cannot find a default component, too many common components: %+v", commons)
 		}
-		h.needRollComponent = commons[0]
-	} else {
-		// assume that the validator webhook has already guaranteed that there is no more than one component for now
-		// and the component exists in both the target and source app
-		h.needRollComponent = componentList[0]
+		h.needRollComponents = commons
+		return nil
+	}
+	// assume that the validator webhook has already guaranteed that the components exist in both the target
+	// and source app, but the target app could be modified after the rollout is created
+	for _, comp := range componentList {
+		if _, exist := h.targetWorkloads[comp]; !exist {
+			return fmt.Errorf("cannot find the component %s in the target app revision %s", comp, h.targetRevName)
+		}
+		if _, exist := h.sourceWorkloads[comp]; len(h.sourceRevName) != 0 && !exist {
+			return fmt.Errorf("cannot find the component %s in the source app revision %s", comp, h.sourceRevName)
+		}
 	}
+	h.needRollComponents = componentList
 	return nil
 }
 
-// fetch source and target workload
-func (h *rolloutHandler) fetchSourceAndTargetWorkload(ctx context.Context) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
+// fetch source and target workload of a component
+func (h *rolloutHandler) fetchSourceAndTargetWorkload(ctx context.Context, comp string) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
 	var sourceWorkload, targetWorkload *unstructured.Unstructured
 	var err error
 	if len(h.sourceRevName) == 0 {
 		klog.Info("source app fields not filled, this is a scale operation")
-	} else if sourceWorkload, err = h.extractWorkload(ctx, *h.sourceWorkloads[h.needRollComponent]); err != nil {
+	} else if sourceWorkload, err = h.extractWorkload(ctx, *h.sourceWorkloads[comp]); err != nil {
 		klog.Errorf("specified sourceRevName but cannot fetch source workload %s: %v",
 			h.appRollout.Spec.SourceAppRevisionName, err)
 		return nil, nil, err
 	}
-	if targetWorkload, err = h.extractWorkload(ctx, *h.targetWorkloads[h.needRollComponent]); err != nil {
+	if targetWorkload, err = h.extractWorkload(ctx, *h.targetWorkloads[comp]); err != nil {
 		klog.Errorf("cannot fetch target workload %s: %v", h.appRollout.Spec.TargetAppRevisionName, err)
 		return nil, nil, err
 	}
@@ -178,6 +186,10 @@ func (h *rolloutHandler) handleRolloutModified() {
 		// continue to handle the previous resources until we are okay to move forward
 		h.targetRevName = h.appRollout.Status.LastUpgradedTargetAppRevision
 		h.sourceRevName = h.appRollout.Status.LastSourceAppRevision
+		if isRollingBack(h.appRollout) {
+			// the previous rollout is rolling back from its target to its source
+			h.targetRevName, h.sourceRevName = h.sourceRevName, h.targetRevName
+		}
 	} else {
 		// previous rollout have finished, go ahead using new source/target revision
 		h.targetRevName = h.appRollout.Spec.TargetAppRevisionName
@@ -185,6 +197,7 @@ func (h *rolloutHandler) handleRolloutModified() {
 		// mark so that we don't think we are modified again
 		h.appRollout.Status.LastUpgradedTargetAppRevision = h.appRollout.Spec.TargetAppRevisionName
 		h.appRollout.Status.LastSourceAppRevision = h.appRollout.Spec.SourceAppRevisionName
+		h.appRollout.Status.RollbackReason = ""
 	}
 	h.appRollout.Status.StateTransition(v1alpha1.RollingModifiedEvent)
 }
@@ -196,10 +209,10 @@ func (h *rolloutHandler) templateTargetManifest(ctx context.Context) error {
 	// revision
 	if h.sourceAppRevision != nil {
 		rt = new(v1beta1.ResourceTracker)
-		err := h.Get(ctx, types.NamespacedName{Name: dispatch.ConstructResourceTrackerName(h.appRollout.Spec.SourceAppRevisionName, h.appRollout.Namespace)}, rt)
+		err := h.Get(ctx, types.NamespacedName{Name: dispatch.ConstructResourceTrackerName(h.sourceRevName, h.appRollout.Namespace)}, rt)
 		if err != nil {
 			klog.Errorf("specified sourceAppRevisionName %s but cannot fetch the sourceResourceTracker %v",
-				h.appRollout.Spec.SourceAppRevisionName, err)
+				h.sourceRevName, err)
 			return err
 		}
 	}
@@ -211,20 +224,7 @@ func (h *rolloutHandler) templateTargetManifest(ctx context.Context) error {
 		klog.Errorf("dispatch targetRevision error %s:%v", h.appRollout.Spec.TargetAppRevisionName, err)
 		return err
 	}
-	workload, err := h.extractWorkload(ctx, *h.targetWorkloads[h.needRollComponent])
-	if err != nil {
-		return err
-	}
-	ref := metav1.GetControllerOfNoCopy(workload)
-	if ref != nil && ref.Kind == v1beta1.ResourceTrackerKind {
-		wlPatch := client.MergeFrom(workload.DeepCopy())
-		// guarantee resourceTracker isn't controller owner of workload
-		disableControllerOwner(workload)
-		if err = h.Client.Patch(ctx, workload, wlPatch, client.FieldOwner(h.appRollout.UID)); err != nil {
-			return err
-		}
-	}
-	return nil
+	return h.disableWorkloadsControllerOwner(ctx, h.targetWorkloads)
 }
 
 // templateTargetManifest call dispatch to template source app revision's manifests to cluster
@@ -242,18 +242,24 @@ func (h *rolloutHandler) templateSourceManifest(ctx context.Context) error {
 		klog.Errorf("dispatch sourceRevision error %s:%v", h.appRollout.Spec.TargetAppRevisionName, err)
 		return err
 	}
-	workload, err := h.extractWorkload(ctx, *h.sourceWorkloads[h.needRollComponent])
-	if err != nil {
-		return err
-	}
-	ref := metav1.GetControllerOfNoCopy(workload)
-	if ref != nil && ref.Kind == v1beta1.ResourceTrackerKind {
-		wlPatch := client.MergeFrom(workload.DeepCopy())
-		// guarantee resourceTracker isn't controller owner of workload
-		disableControllerOwner(workload)
-		if err = h.Client.Patch(ctx, workload, wlPatch, client.FieldOwner(h.appRollout.UID)); err != nil {
+	return h.disableWorkloadsControllerOwner(ctx, h.sourceWorkloads)
+}
+
+// disableWorkloadsControllerOwner guarantees resourceTracker isn't controller owner of the workloads to rollout
+func (h *rolloutHandler) disableWorkloadsControllerOwner(ctx context.Context, workloads map[string]*unstructured.Unstructured) error {
+	for _, comp := range h.needRollComponents {
+		workload, err := h.extractWorkload(ctx, *workloads[comp])
+		if err != nil {
 			return err
 		}
+		ref := metav1.GetControllerOfNoCopy(workload)
+		if ref != nil && ref.Kind == v1beta1.ResourceTrackerKind {
+			wlPatch := client.MergeFrom(workload.DeepCopy())
+			disableControllerOwner(workload)
+			if err = h.Client.Patch(ctx, workload, wlPatch, client.FieldOwner(h.appRollout.UID)); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -261,14 +267,16 @@ func (h *rolloutHandler) templateSourceManifest(ctx context.Context) error {
 // handle rollout succeed work left
 func (h *rolloutHandler) finalizeRollingSucceeded(ctx context.Context) error {
 	// yield controller owner back to resourceTracker
-	workload, err := h.extractWorkload(ctx, *h.targetWorkloads[h.needRollComponent])
-	if err != nil {
-		return err
-	}
-	wlPatch := client.MergeFrom(workload.DeepCopy())
-	enableControllerOwner(workload)
-	if err = h.Client.Patch(ctx, workload, wlPatch, client.FieldOwner(h.appRollout.UID)); err != nil {
-		return err
+	for _, comp := range h.needRollComponents {
+		workload, err := h.extractWorkload(ctx, *h.targetWorkloads[comp])
+		if err != nil {
+			return err
+		}
+		wlPatch := client.MergeFrom(workload.DeepCopy())
+		enableControllerOwner(workload)
+		if err = h.Client.Patch(ctx, workload, wlPatch, client.FieldOwner(h.appRollout.UID)); err != nil {
+			return err
+		}
 	}
 
 	// only when sourceAppRevision is not nil, we need gc old revision resources
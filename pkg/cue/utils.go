@@ -16,6 +16,11 @@ limitations under the License.
 
 package cue
 
+import (
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/parser"
+)
+
 // int data can evaluate with number in CUE, so it's OK if we convert the original float type data to int
 func isIntegral(val float64) bool {
 	return val == float64(int(val))
@@ -55,3 +60,30 @@ func intifyMap(m map[string]interface{}) interface{} {
 	}
 	return m2
 }
+
+// HasTopLevelField checks whether the field is declared at the top level of the CUE template,
+// the template is only parsed so that it doesn't need to be complete.
+func HasTopLevelField(templateStr, field string) bool {
+	f, err := parser.ParseFile("-", templateStr)
+	if err != nil {
+		return false
+	}
+	found := false
+	ast.Walk(f, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.File:
+			return true
+		case *ast.Comprehension:
+			// fields declared conditionally, e.g. `if parameter.enabled { output: {...} }`
+			return true
+		case *ast.StructLit:
+			return true
+		case *ast.Field:
+			if name, _, _ := ast.LabelName(n.Label); name == field {
+				found = true
+			}
+		}
+		return false
+	}, nil)
+	return found
+}
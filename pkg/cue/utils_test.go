@@ -0,0 +1,39 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasTopLevelField(t *testing.T) {
+	tmpl := `
+parameter: enabled: bool
+if parameter.enabled {
+	patch: metadata: labels: a: "b"
+}
+output: spec: violations: []
+`
+	assert.True(t, HasTopLevelField(tmpl, "parameter"))
+	assert.True(t, HasTopLevelField(tmpl, "patch"))
+	assert.True(t, HasTopLevelField(tmpl, "output"))
+	assert.False(t, HasTopLevelField(tmpl, "violations"))
+	assert.False(t, HasTopLevelField(tmpl, "metadata"))
+	assert.False(t, HasTopLevelField("output: {", "output"))
+}
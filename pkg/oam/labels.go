@@ -35,6 +35,8 @@ const (
 	LabelAppRevisionHash = "app.oam.dev/app-revision-hash"
 	// LabelAppNamespace records the namespace of Application
 	LabelAppNamespace = "app.oam.dev/namesapce"
+	// LabelResourceTracker records the name of the ResourceTracker a shard of ResourceTracker belongs to
+	LabelResourceTracker = "app.oam.dev/resource-tracker"
 
 	// WorkloadTypeLabel indicates the type of the workloadDefinition
 	WorkloadTypeLabel = "workload.oam.dev/type"
@@ -92,6 +94,45 @@ const (
 	// AnnotationWorkflowContext is used to pass in the workflow context marshalled in json format.
 	AnnotationWorkflowContext = "app.oam.dev/workflow-context"
 
+	// AnnotationWorkflowControl is the control operation (suspend, resume, terminate or restart) to apply to the
+	// workflow of the application, it will be removed once the operation is applied.
+	AnnotationWorkflowControl = "app.oam.dev/workflow-control"
+
+	// AnnotationWorkflowRestartStep is the step to restart the workflow from, used with the restart operation.
+	AnnotationWorkflowRestartStep = "app.oam.dev/workflow-restart-step"
+
+	// AnnotationRolloutAbort is the target app revision whose rollout is aborted, the rollout of a different target
+	// app revision is not affected.
+	AnnotationRolloutAbort = "app.oam.dev/rollout-abort"
+
+	// AnnotationApplyStrategy is the strategy used to apply the resource, it can be ThreeWayMerge or ServerSideApply.
+	// Set on an Application, it overrides the apply strategy of all the components' ComponentDefinitions.
+	AnnotationApplyStrategy = "app.oam.dev/apply-strategy"
+
+	// AnnotationResourcePolicy decides what garbage collection does to the resource, it can be delete, orphan or
+	// retain. Set on an Application, it applies to all the resources of the application.
+	AnnotationResourcePolicy = "app.oam.dev/resource-policy"
+
+	// AnnotationProtectedResource marks the resource protected if it's "true", the garbage collection never touches
+	// a protected resource, which isn't owned by the resource trackers. Set on an Application, it applies to all the
+	// resources of the application.
+	AnnotationProtectedResource = "app.oam.dev/protected"
+
+	// AnnotationAutoCorrectDrift indicates the resources of the application drifted from their manifests are
+	// applied again, by default the drift is only reported in the status of the application.
+	AnnotationAutoCorrectDrift = "app.oam.dev/auto-correct-drift"
+
 	// AnnotationKubeVelaVersion is used to record current KubeVela version
 	AnnotationKubeVelaVersion = "oam.dev/kubevela-version"
 )
+
+const (
+	// ResourcePolicyDelete deletes the resource once it's no longer rendered or the application is deleted
+	ResourcePolicyDelete = "delete"
+	// ResourcePolicyOrphan keeps the resource once it's no longer rendered by a new revision of the application,
+	// but still deletes it when the application is deleted
+	ResourcePolicyOrphan = "orphan"
+	// ResourcePolicyRetain never deletes the resource, it keeps the resource once it's no longer rendered and when
+	// the application is deleted
+	ResourcePolicyRetain = "retain"
+)
@@ -0,0 +1,265 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	velacue "github.com/oam-dev/kubevela/pkg/cue"
+	"github.com/oam-dev/kubevela/pkg/cue/model"
+	"github.com/oam-dev/kubevela/pkg/cue/packages"
+	"github.com/oam-dev/kubevela/pkg/cue/process"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const (
+	// ContextFieldName is the field of the context describing the component of the manifest
+	ContextFieldName = "context"
+	// ManifestFieldName is the field of the rendered manifest evaluated by the guardrail policy
+	ManifestFieldName = "manifest"
+	// ViolationsFieldName is the field of the guardrail policy listing the reasons why the manifest is rejected
+	ViolationsFieldName = "violations"
+	// PatchFieldName is the field of the guardrail policy patching the manifest by strategic merge
+	PatchFieldName = "patch"
+
+	// ContextManifestType is the type of the manifest in the context, which is workload or trait
+	ContextManifestType = "manifestType"
+	// ContextType is the workload type or trait type of the manifest in the context
+	ContextType = "type"
+
+	// ManifestTypeWorkload is the manifest type of the workload of a component
+	ManifestTypeWorkload = "workload"
+	// ManifestTypeTrait is the manifest type of a trait of a component
+	ManifestTypeTrait = "trait"
+)
+
+// IsGuardrail checks whether the policy validates or mutates the rendered manifests, i.e. the template of its
+// PolicyDefinition declares `violations` or `patch`. A guardrail policy is evaluated against every rendered workload
+// and trait, which is available as `manifest` in the template, with `context` describing the component.
+//
+// For example, the following policy rejects privileged containers:
+//
+//	violations: [ if manifest.kind == "Deployment" for c in manifest.spec.template.spec.containers
+//		if c.securityContext != _|_ if c.securityContext.privileged {
+//		"container \(c.name) of component \(context.name) is privileged"
+//	}]
+func IsGuardrail(p *appfile.Workload) bool {
+	if p.FullTemplate == nil || p.CapabilityCategory != types.CUECategory {
+		return false
+	}
+	return velacue.HasTopLevelField(p.FullTemplate.TemplateStr, ViolationsFieldName) ||
+		velacue.HasTopLevelField(p.FullTemplate.TemplateStr, PatchFieldName)
+}
+
+// Violation is a rule of a guardrail policy violated by a rendered manifest.
+type Violation struct {
+	Policy    string
+	Component string
+	Manifest  string
+	Message   string
+}
+
+// ViolationError is returned if any rendered manifest violates the guardrail policies.
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, fmt.Sprintf("policy %s rejects %s of component %s: %s", v.Policy, v.Manifest, v.Component, v.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// IsViolation checks whether the error is caused by violations of guardrail policies.
+func IsViolation(err error) bool {
+	var violationErr *ViolationError
+	return errors.As(err, &violationErr)
+}
+
+// Enforce evaluates the guardrail policies of the appfile against the rendered workloads and traits before they're
+// dispatched. The manifests are patched in place in the order of policies, and a ViolationError is returned if any
+// manifest is rejected.
+func Enforce(af *appfile.Appfile, pd *packages.PackageDiscover, ac *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) error {
+	var guardrails []*appfile.Workload
+	for _, p := range af.Policies {
+		if IsGuardrail(p) {
+			guardrails = append(guardrails, p)
+		}
+	}
+	if len(guardrails) == 0 {
+		return nil
+	}
+
+	accs := make(map[string]*v1alpha2.ApplicationConfigurationComponent, len(ac.Spec.Components))
+	for i := range ac.Spec.Components {
+		accs[ac.Spec.Components[i].ComponentName] = &ac.Spec.Components[i]
+	}
+	violationErr := &ViolationError{}
+	for _, comp := range comps {
+		acc, ok := accs[comp.Name]
+		if !ok {
+			return errors.Errorf("component %s is not found in the application configuration", comp.Name)
+		}
+		if err := enforceManifest(guardrails, pd, af, &comp.Spec.Workload, comp.Name, ManifestTypeWorkload,
+			oam.WorkloadTypeLabel, violationErr); err != nil {
+			return err
+		}
+		for j := range acc.Traits {
+			if err := enforceManifest(guardrails, pd, af, &acc.Traits[j].Trait, comp.Name,
+				ManifestTypeTrait, oam.TraitTypeLabel, violationErr); err != nil {
+				return err
+			}
+		}
+	}
+	if len(violationErr.Violations) != 0 {
+		return violationErr
+	}
+	return nil
+}
+
+func enforceManifest(guardrails []*appfile.Workload, pd *packages.PackageDiscover, af *appfile.Appfile,
+	raw *runtime.RawExtension, compName, manifestType, typeLabel string, violationErr *ViolationError) error {
+	manifest, err := util.RawExtension2Unstructured(raw)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s of component %s", manifestType, compName)
+	}
+	ctx := map[string]interface{}{
+		process.ContextName:        compName,
+		process.ContextAppName:     af.Name,
+		process.ContextAppRevision: af.RevisionName,
+		process.ContextNamespace:   af.Namespace,
+		ContextManifestType:        manifestType,
+		ContextType:                manifest.GetLabels()[typeLabel],
+	}
+	patched := false
+	for _, p := range guardrails {
+		result, violations, err := Evaluate(p, pd, ctx, manifest.Object)
+		if err != nil {
+			return errors.WithMessagef(err, "cannot evaluate policy %s against %s of component %s", p.Name, manifestType, compName)
+		}
+		for _, msg := range violations {
+			violationErr.Violations = append(violationErr.Violations, Violation{
+				Policy:    p.Name,
+				Component: compName,
+				Manifest:  fmt.Sprintf("%s %s", manifest.GetKind(), manifest.GetName()),
+				Message:   msg,
+			})
+		}
+		if result != nil {
+			manifest.Object = result
+			patched = true
+		}
+	}
+	if patched {
+		*raw = util.Object2RawExtension(manifest.Object)
+	}
+	return nil
+}
+
+// Evaluate evaluates the guardrail policy against the manifest, it returns the patched manifest if the policy
+// patches it, and the violations of the manifest.
+func Evaluate(p *appfile.Workload, pd *packages.PackageDiscover, ctx map[string]interface{},
+	manifest map[string]interface{}) (map[string]interface{}, []string, error) {
+	bi := build.NewContext().NewInstance("", nil)
+	if err := bi.AddFile("-", p.FullTemplate.TemplateStr); err != nil {
+		return nil, nil, errors.WithMessagef(err, "invalid cue template of policy %s", p.Name)
+	}
+	files := map[string]interface{}{
+		velacue.ParameterTag: p.Params,
+		ContextFieldName:     ctx,
+		ManifestFieldName:    manifest,
+	}
+	for name, value := range files {
+		if value == nil {
+			value = map[string]interface{}{}
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := bi.AddFile(name, fmt.Sprintf("%s: %s", name, string(b))); err != nil {
+			return nil, nil, errors.WithMessagef(err, "invalid %s of policy %s", name, p.Name)
+		}
+	}
+	var inst *cue.Instance
+	var err error
+	if pd != nil {
+		inst, err = pd.ImportPackagesAndBuildInstance(bi)
+	} else {
+		var r cue.Runtime
+		inst, err = r.Build(bi)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var violations []string
+	if v := inst.Lookup(ViolationsFieldName); v.Exists() {
+		b, err := v.MarshalJSON()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid %s", ViolationsFieldName)
+		}
+		if err := json.Unmarshal(b, &violations); err != nil {
+			return nil, nil, errors.Wrapf(err, "%s must be a list of strings", ViolationsFieldName)
+		}
+	}
+
+	v := inst.Lookup(PatchFieldName)
+	if !v.Exists() {
+		return nil, violations, nil
+	}
+	patch, err := model.NewOther(v)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid %s", PatchFieldName)
+	}
+	var r cue.Runtime
+	mi, err := r.Compile("-", fmt.Sprintf("%s: %s", ManifestFieldName, mustJSON(manifest)))
+	if err != nil {
+		return nil, nil, err
+	}
+	base, err := model.NewBase(mi.Lookup(ManifestFieldName))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := base.Unify(patch); err != nil {
+		return nil, nil, errors.WithMessagef(err, "cannot patch manifest")
+	}
+	patched, err := base.Unstructured()
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "cannot patch manifest")
+	}
+	return patched.Object, violations, nil
+}
+
+func mustJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const noPrivilegedTemplate = `
+violations: [ if manifest.kind == "Deployment" for c in manifest.spec.template.spec.containers
+	if c.securityContext != _|_ if c.securityContext.privileged {
+	"container \(c.name) of \(context.name) is privileged"
+}]
+`
+
+const registryTemplate = `
+parameter: registry: string
+violations: [ if context.manifestType == "workload" for c in manifest.spec.template.spec.containers if !strings.HasPrefix(c.image, parameter.registry) {
+	"image \(c.image) is not from \(parameter.registry)"
+}]
+`
+
+const labelTemplate = `
+if context.manifestType == "workload" {
+	patch: metadata: labels: team: parameter.team
+}
+parameter: team: string
+`
+
+const noTraitTemplate = `
+violations: [ if context.manifestType == "trait" {
+	"trait \(manifest.kind) is not allowed"
+}]
+`
+
+const outputTemplate = `
+output: {
+	apiVersion: "v1"
+	kind:       "ConfigMap"
+}
+`
+
+func newPolicy(name, tmpl string, params map[string]interface{}) *appfile.Workload {
+	return &appfile.Workload{
+		Name:               name,
+		Type:               name,
+		CapabilityCategory: types.CUECategory,
+		Params:             params,
+		FullTemplate:       &appfile.Template{TemplateStr: tmpl},
+	}
+}
+
+func newDeployment(name string, containers ...map[string]interface{}) *v1alpha2.Component {
+	var cs []interface{}
+	for _, c := range containers {
+		cs = append(cs, c)
+	}
+	comp := &v1alpha2.Component{}
+	comp.Name = name
+	comp.Spec.Workload = util.Object2RawExtension(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":   name,
+			"labels": map[string]interface{}{oam.WorkloadTypeLabel: "webservice"},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"containers": cs},
+			},
+		},
+	})
+	return comp
+}
+
+func TestIsGuardrail(t *testing.T) {
+	assert.True(t, IsGuardrail(newPolicy("a", noPrivilegedTemplate, nil)))
+	assert.True(t, IsGuardrail(newPolicy("b", labelTemplate, nil)))
+	assert.False(t, IsGuardrail(newPolicy("c", outputTemplate, nil)))
+	assert.False(t, IsGuardrail(&appfile.Workload{Name: "d"}))
+}
+
+func TestEnforce(t *testing.T) {
+	comps := []*v1alpha2.Component{
+		newDeployment("frontend", map[string]interface{}{"name": "nginx", "image": "registry.example.com/nginx"}),
+		newDeployment("backend", map[string]interface{}{
+			"name":            "app",
+			"image":           "docker.io/app",
+			"securityContext": map[string]interface{}{"privileged": true},
+		}),
+	}
+	ac := &v1alpha2.ApplicationConfiguration{}
+	ac.Spec.Components = []v1alpha2.ApplicationConfigurationComponent{
+		{
+			ComponentName: "frontend",
+			Traits: []v1alpha2.ComponentTrait{{Trait: util.Object2RawExtension(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "frontend"},
+			})}},
+		},
+		{ComponentName: "backend"},
+	}
+
+	t.Run("violations are collected from all the policies", func(t *testing.T) {
+		af := &appfile.Appfile{Name: "app", Namespace: "default", Policies: []*appfile.Workload{
+			newPolicy("no-privileged", noPrivilegedTemplate, nil),
+			newPolicy("registry", "import \"strings\"\n"+registryTemplate,
+				map[string]interface{}{"registry": "registry.example.com/"}),
+			newPolicy("output", outputTemplate, nil),
+		}}
+		err := Enforce(af, nil, ac.DeepCopy(), []*v1alpha2.Component{comps[0].DeepCopy(), comps[1].DeepCopy()})
+		require.Error(t, err)
+		assert.True(t, IsViolation(err))
+		assert.True(t, IsViolation(errors.WithMessage(err, "wrapped")))
+		assert.True(t, IsViolation(fmt.Errorf("cannot dispatch: %w", err)))
+		violationErr := err.(*ViolationError)
+		assert.Equal(t, []Violation{
+			{Policy: "no-privileged", Component: "backend", Manifest: "Deployment backend", Message: "container app of backend is privileged"},
+			{Policy: "registry", Component: "backend", Manifest: "Deployment backend", Message: "image docker.io/app is not from registry.example.com/"},
+		}, violationErr.Violations)
+	})
+
+	t.Run("manifests are patched", func(t *testing.T) {
+		af := &appfile.Appfile{Name: "app", Namespace: "default", Policies: []*appfile.Workload{
+			newPolicy("label", labelTemplate, map[string]interface{}{"team": "core"}),
+		}}
+		acCopy := ac.DeepCopy()
+		compsCopy := []*v1alpha2.Component{comps[0].DeepCopy(), comps[1].DeepCopy()}
+		require.NoError(t, Enforce(af, nil, acCopy, compsCopy))
+		for _, comp := range compsCopy {
+			u, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{oam.WorkloadTypeLabel: "webservice", "team": "core"}, u.GetLabels())
+		}
+		trait, err := util.RawExtension2Unstructured(&acCopy.Spec.Components[0].Traits[0].Trait)
+		require.NoError(t, err)
+		assert.Empty(t, trait.GetLabels())
+	})
+
+	t.Run("traits are matched by component name", func(t *testing.T) {
+		af := &appfile.Appfile{Name: "app", Policies: []*appfile.Workload{
+			newPolicy("no-trait", noTraitTemplate, nil),
+		}}
+		reversed := ac.DeepCopy()
+		reversed.Spec.Components[0], reversed.Spec.Components[1] = reversed.Spec.Components[1], reversed.Spec.Components[0]
+		err := Enforce(af, nil, reversed, []*v1alpha2.Component{comps[0].DeepCopy(), comps[1].DeepCopy()})
+		require.Error(t, err)
+		assert.Equal(t, []Violation{
+			{Policy: "no-trait", Component: "frontend", Manifest: "Service frontend", Message: "trait Service is not allowed"},
+		}, err.(*ViolationError).Violations)
+
+		missing := ac.DeepCopy()
+		missing.Spec.Components = missing.Spec.Components[:1]
+		err = Enforce(af, nil, missing, []*v1alpha2.Component{comps[0].DeepCopy(), comps[1].DeepCopy()})
+		require.Error(t, err)
+		assert.False(t, IsViolation(err))
+		assert.Contains(t, err.Error(), "component backend is not found")
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		af := &appfile.Appfile{Name: "app", Policies: []*appfile.Workload{
+			newPolicy("invalid", "violations: manifest.kind", nil),
+		}}
+		err := Enforce(af, nil, ac.DeepCopy(), []*v1alpha2.Component{comps[0].DeepCopy()})
+		require.Error(t, err)
+		assert.False(t, IsViolation(err))
+	})
+}
@@ -46,6 +46,21 @@ type Applicator interface {
 // nolint: golint
 type ApplyOption func(ctx context.Context, existing, desired runtime.Object) error
 
+// Strategy is the way an Applicator applies the new state to an existing object.
+type Strategy string
+
+const (
+	// ThreeWayMergeStrategy computes a three-way diff merge in client side based on the
+	// last-applied-state tracked through an annotation, it's the default strategy.
+	ThreeWayMergeStrategy Strategy = "ThreeWayMerge"
+	// ServerSideApplyStrategy sends the new state by server-side apply and lets the API server
+	// merge it with the fields managed by other field managers.
+	ServerSideApplyStrategy Strategy = "ServerSideApply"
+)
+
+// FieldManager is the name of the field manager used by server-side apply
+const FieldManager = "kubevela"
+
 // NewAPIApplicator creates an Applicator that applies state to an
 // object or creates the object if not exist.
 func NewAPIApplicator(c client.Client) *APIApplicator {
@@ -93,8 +108,18 @@ func loggingApply(msg string, desired runtime.Object) {
 	klog.InfoS(msg, "name", d.GetName(), "resource", desired.GetObjectKind().GroupVersionKind().String())
 }
 
-// Apply applies new state to an object or create it if not exist
+// Apply applies new state to an object or create it if not exist.
+// The Strategy is picked from the oam.AnnotationApplyStrategy annotation of the object,
+// it uses ThreeWayMergeStrategy if the annotation is not set.
 func (a *APIApplicator) Apply(ctx context.Context, desired runtime.Object, ao ...ApplyOption) error {
+	strategy, err := getStrategy(desired)
+	if err != nil {
+		return err
+	}
+	if strategy == ServerSideApplyStrategy {
+		return a.serverSideApply(ctx, desired, ao...)
+	}
+
 	existing, err := a.createOrGetExisting(ctx, a.c, desired, ao...)
 	if err != nil {
 		return err
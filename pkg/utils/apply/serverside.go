@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// the message of a field manager conflict looks like `conflict with "kubectl" using apps/v1`
+var conflictManagerRegexp = regexp.MustCompile(`conflict with "([^"]*)"`)
+
+// FieldConflict is a field of the applied object which is managed by another field manager
+type FieldConflict struct {
+	// Field is the path of the field, e.g. .spec.replicas
+	Field string
+	// Manager is the field manager owning the field
+	Manager string
+	// Message is the message returned by the API server
+	Message string
+}
+
+// ConflictError is returned by server-side apply if the applied object changes fields
+// managed by other field managers, such as the replicas of a Deployment scaled by an HPA.
+type ConflictError struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	Conflicts        []FieldConflict
+
+	err error
+}
+
+// Error implements error
+func (e *ConflictError) Error() string {
+	fields := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		fields[i] = fmt.Sprintf("%s (managed by %q)", c.Field, c.Manager)
+	}
+	return fmt.Sprintf("apply %s %s conflicts with other field managers on fields: %s",
+		e.GroupVersionKind.Kind, types.NamespacedName{Namespace: e.Namespace, Name: e.Name}, strings.Join(fields, ", "))
+}
+
+// Unwrap returns the original error returned by the API server
+func (e *ConflictError) Unwrap() error {
+	return e.err
+}
+
+// IsConflict returns true if the error is, or wraps, a ConflictError
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// newConflictError converts the conflict returned by the API server into a ConflictError,
+// it returns nil if the error is not a field manager conflict.
+func newConflictError(obj oam.Object, err error) *ConflictError {
+	status, ok := err.(kerrors.APIStatus)
+	if !ok || !kerrors.IsConflict(err) || status.Status().Details == nil {
+		return nil
+	}
+	conflict := &ConflictError{
+		GroupVersionKind: obj.GetObjectKind().GroupVersionKind(),
+		Namespace:        obj.GetNamespace(),
+		Name:             obj.GetName(),
+		err:              err,
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		fc := FieldConflict{Field: cause.Field, Message: cause.Message}
+		if m := conflictManagerRegexp.FindStringSubmatch(cause.Message); len(m) == 2 {
+			fc.Manager = m[1]
+		}
+		conflict.Conflicts = append(conflict.Conflicts, fc)
+	}
+	if len(conflict.Conflicts) == 0 {
+		return nil
+	}
+	return conflict
+}
+
+// getStrategy returns the apply strategy recorded in the annotation of the object
+func getStrategy(obj runtime.Object) (Strategy, error) {
+	annots, err := metadataAccessor.Annotations(obj)
+	if err != nil {
+		// objects without metadata are left to the three way merge to report
+		return ThreeWayMergeStrategy, nil
+	}
+	switch s := Strategy(annots[oam.AnnotationApplyStrategy]); s {
+	case "", ThreeWayMergeStrategy:
+		return ThreeWayMergeStrategy, nil
+	case ServerSideApplyStrategy:
+		return s, nil
+	default:
+		return "", errors.Errorf("unsupported apply strategy %q, it must be %s or %s", s,
+			ThreeWayMergeStrategy, ServerSideApplyStrategy)
+	}
+}
+
+// serverSideApply applies the object by server-side apply with FieldManager.
+// The fields of an object applied by the three way merge before are taken over forcibly,
+// any other conflict is returned as a ConflictError.
+func (a *APIApplicator) serverSideApply(ctx context.Context, desired runtime.Object, ao ...ApplyOption) error {
+	m, ok := desired.(oam.Object)
+	if !ok {
+		return errors.New("cannot access object metadata")
+	}
+	if desired.GetObjectKind().GroupVersionKind().Empty() {
+		return errors.New("cannot apply an object without apiVersion and kind by server-side apply")
+	}
+
+	// server-side apply requires a name, create the object with only generateName directly
+	if m.GetName() == "" && m.GetGenerateName() != "" {
+		if err := executeApplyOptions(ctx, nil, desired, ao); err != nil {
+			return err
+		}
+		loggingApply("creating object", desired)
+		return errors.Wrap(a.c.Create(ctx, desired, client.FieldOwner(FieldManager)), "cannot create object")
+	}
+
+	existing := &unstructured.Unstructured{}
+	existing.GetObjectKind().SetGroupVersionKind(desired.GetObjectKind().GroupVersionKind())
+	err := a.c.Get(ctx, types.NamespacedName{Name: m.GetName(), Namespace: m.GetNamespace()}, existing)
+	switch {
+	case kerrors.IsNotFound(err):
+		err = executeApplyOptions(ctx, nil, desired, ao)
+		existing = nil
+	case err != nil:
+		return errors.Wrap(err, "cannot get object")
+	default:
+		err = executeApplyOptions(ctx, existing, desired, ao)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the applied configuration must not carry the managed fields or the last-applied-state
+	m.SetManagedFields(nil)
+	if annots := m.GetAnnotations(); annots != nil {
+		delete(annots, oam.AnnotationLastAppliedConfig)
+		m.SetAnnotations(annots)
+	}
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	takeOver := existing != nil && existing.GetAnnotations()[oam.AnnotationLastAppliedConfig] != ""
+	if takeOver {
+		opts = append(opts, client.ForceOwnership)
+	}
+	loggingApply("applying object by server-side apply", desired)
+	if err := a.c.Patch(ctx, desired, client.Apply, opts...); err != nil {
+		if conflict := newConflictError(m, err); conflict != nil {
+			return conflict
+		}
+		return errors.Wrap(err, "cannot apply object by server-side apply")
+	}
+	if takeOver {
+		// the last-applied-state is useless once the object is managed by server-side apply
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, oam.AnnotationLastAppliedConfig)
+		if err := a.c.Patch(ctx, existing, client.RawPatch(types.MergePatchType, []byte(patch))); err != nil {
+			return errors.Wrap(err, "cannot remove the last-applied-state annotation")
+		}
+	}
+	return nil
+}
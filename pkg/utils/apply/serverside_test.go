@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newServerSideDeploy() *unstructured.Unstructured {
+	deploy := &unstructured.Unstructured{}
+	deploy.SetAPIVersion("apps/v1")
+	deploy.SetKind("Deployment")
+	deploy.SetNamespace("default")
+	deploy.SetName("web")
+	deploy.SetAnnotations(map[string]string{oam.AnnotationApplyStrategy: string(ServerSideApplyStrategy)})
+	return deploy
+}
+
+func TestGetStrategy(t *testing.T) {
+	deploy := newServerSideDeploy()
+	s, err := getStrategy(deploy)
+	assert.NoError(t, err)
+	assert.Equal(t, ServerSideApplyStrategy, s)
+
+	deploy.SetAnnotations(nil)
+	s, err = getStrategy(deploy)
+	assert.NoError(t, err)
+	assert.Equal(t, ThreeWayMergeStrategy, s)
+
+	deploy.SetAnnotations(map[string]string{oam.AnnotationApplyStrategy: "Replace"})
+	_, err = getStrategy(deploy)
+	assert.Error(t, err)
+
+	s, err = getStrategy(&testNoMetaObject{})
+	assert.NoError(t, err)
+	assert.Equal(t, ThreeWayMergeStrategy, s)
+}
+
+func TestServerSideApply(t *testing.T) {
+	type patchCall struct {
+		patchType    types.PatchType
+		fieldManager string
+		force        bool
+	}
+	conflict := &kerrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   409,
+		Reason: metav1.StatusReasonConflict,
+		Details: &metav1.StatusDetails{Causes: []metav1.StatusCause{{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "kube-controller-manager" using apps/v1`,
+			Field:   ".spec.replicas",
+		}}},
+	}}
+	notFound := kerrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web")
+
+	cases := map[string]struct {
+		existing *unstructured.Unstructured
+		getErr   error
+		patchErr error
+		want     []patchCall
+		conflict bool
+	}{
+		"CreateByApply": {
+			getErr: notFound,
+			want:   []patchCall{{patchType: types.ApplyPatchType, fieldManager: FieldManager}},
+		},
+		"UpdateByApply": {
+			existing: newServerSideDeploy(),
+			want:     []patchCall{{patchType: types.ApplyPatchType, fieldManager: FieldManager}},
+		},
+		"TakeOverThreeWayMerge": {
+			existing: func() *unstructured.Unstructured {
+				deploy := newServerSideDeploy()
+				deploy.SetAnnotations(map[string]string{oam.AnnotationLastAppliedConfig: "{}"})
+				return deploy
+			}(),
+			want: []patchCall{
+				{patchType: types.ApplyPatchType, fieldManager: FieldManager, force: true},
+				{patchType: types.MergePatchType},
+			},
+		},
+		"Conflict": {
+			existing: newServerSideDeploy(),
+			patchErr: conflict,
+			want:     []patchCall{{patchType: types.ApplyPatchType, fieldManager: FieldManager}},
+			conflict: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var calls []patchCall
+			c := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					if tc.getErr != nil {
+						return tc.getErr
+					}
+					tc.existing.DeepCopyInto(obj.(*unstructured.Unstructured))
+					return nil
+				},
+				MockPatch: func(_ context.Context, _ runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+					po := &client.PatchOptions{}
+					po.ApplyOptions(opts)
+					call := patchCall{patchType: patch.Type(), fieldManager: po.FieldManager}
+					if po.Force != nil {
+						call.force = *po.Force
+					}
+					calls = append(calls, call)
+					return tc.patchErr
+				},
+			}
+			err := NewAPIApplicator(c).Apply(ctx, newServerSideDeploy(), MustBeControllableBy("uid"))
+			assert.Equal(t, tc.want, calls)
+			if !tc.conflict {
+				assert.NoError(t, err)
+				return
+			}
+			require.True(t, IsConflict(errors.Wrap(err, "cannot apply manifest")))
+			conflictErr := &ConflictError{}
+			require.True(t, errors.As(err, &conflictErr))
+			assert.Equal(t, []FieldConflict{{
+				Field:   ".spec.replicas",
+				Manager: "kube-controller-manager",
+				Message: `conflict with "kube-controller-manager" using apps/v1`,
+			}}, conflictErr.Conflicts)
+			assert.Contains(t, err.Error(), `.spec.replicas (managed by "kube-controller-manager")`)
+		})
+	}
+
+	// the three way merge stays the default
+	desired := newServerSideDeploy()
+	desired.SetAnnotations(nil)
+	c := &test.MockClient{
+		MockGet: test.NewMockGetFn(notFound),
+		MockCreate: func(_ context.Context, obj runtime.Object, _ ...client.CreateOption) error {
+			assert.Contains(t, obj.(metav1.Object).GetAnnotations(), oam.AnnotationLastAppliedConfig)
+			return nil
+		},
+	}
+	assert.NoError(t, NewAPIApplicator(c).Apply(ctx, desired))
+}
@@ -19,13 +19,17 @@ package rollout
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/schedule"
 )
 
 // DefaultRolloutBatches set the default values for a rollout batches
@@ -50,6 +54,9 @@ func DefaultRolloutPlan(rollout *v1alpha1.RolloutPlan) {
 	if len(rollout.RolloutStrategy) == 0 {
 		rollout.RolloutStrategy = v1alpha1.IncreaseFirstRolloutStrategyType
 	}
+	if rollout.TrafficRouting != nil && len(rollout.TrafficRouting.Strategy) == 0 {
+		rollout.TrafficRouting.Strategy = v1alpha1.CanaryTrafficRoutingStrategyType
+	}
 }
 
 // FillRolloutBatches fills the replicas in each batch depends on the total size and number of batches
@@ -96,6 +103,19 @@ func ValidateCreate(client client.Client, rollout *v1alpha1.RolloutPlan, rootPat
 	// validate the rollout batches
 	allErrs = append(allErrs, validateRolloutBatches(rollout, rootPath)...)
 
+	// validate the canary metrics
+	allErrs = append(allErrs, validateCanaryMetrics(rollout.CanaryMetric, rootPath.Child("canaryMetric"))...)
+	for i, rb := range rollout.RolloutBatches {
+		allErrs = append(allErrs, validateCanaryMetrics(rb.CanaryMetric,
+			rootPath.Child("rolloutBatches").Index(i).Child("canaryMetric"))...)
+	}
+
+	// validate the traffic routing
+	allErrs = append(allErrs, validateTrafficRouting(rollout, rootPath)...)
+
+	// validate the schedule
+	allErrs = append(allErrs, validateSchedule(rollout.Schedule, rootPath.Child("schedule"))...)
+
 	// TODO: The total number of num in the batches match the current target resource pod size
 	return allErrs
 }
@@ -114,6 +134,7 @@ func validateWebhook(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allEr
 				allErrs = append(allErrs, field.Invalid(webhookPath.Index(i),
 					rw.Method, "the rollout webhook method can only be Get/PUT/POST"))
 			}
+			allErrs = append(allErrs, validateWebhookRequest(rw, webhookPath.Index(i))...)
 		}
 	}
 
@@ -127,6 +148,8 @@ func validateWebhook(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allEr
 					allErrs = append(allErrs, field.Invalid(rolloutBatchPath.Child("batchRolloutWebhooks").Index(j),
 						brw.Type, "the batch webhook type can only be pre or post batch webhook"))
 				}
+				allErrs = append(allErrs, validateWebhookRequest(brw,
+					rolloutBatchPath.Child("batchRolloutWebhooks").Index(j))...)
 				// TODO: check the URL/name uniqueness?
 			}
 		}
@@ -134,6 +157,40 @@ func validateWebhook(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allEr
 	return allErrs
 }
 
+// validateWebhookRequest validates how the requests to a webhook are sent
+func validateWebhookRequest(rw v1alpha1.RolloutWebhook, webhookPath *field.Path) (allErrs field.ErrorList) {
+	if rw.TimeoutSeconds != nil && *rw.TimeoutSeconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("timeoutSeconds"), *rw.TimeoutSeconds,
+			"the timeout has to be positive"))
+	}
+	if rw.Retries != nil && *rw.Retries < 0 {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("retries"), *rw.Retries,
+			"the retries can not be negative"))
+	}
+	if rw.Auth == nil {
+		return allErrs
+	}
+	authPath := webhookPath.Child("auth")
+	secretRefs := []struct {
+		name string
+		ref  *corev1.SecretKeySelector
+	}{
+		{"hmacSecretRef", rw.Auth.HMACSecretRef},
+		{"bearerTokenSecretRef", rw.Auth.BearerTokenSecretRef},
+	}
+	for _, secretRef := range secretRefs {
+		if ref := secretRef.ref; ref != nil && (len(ref.Name) == 0 || len(ref.Key) == 0) {
+			allErrs = append(allErrs, field.Required(authPath.Child(secretRef.name),
+				"the secret reference needs both the name and the key"))
+		}
+	}
+	if rw.Auth.TLSSecretRef != nil && len(rw.Auth.TLSSecretRef.Name) == 0 {
+		allErrs = append(allErrs, field.Required(authPath.Child("tlsSecretRef", "name"),
+			"the secret reference needs the name"))
+	}
+	return allErrs
+}
+
 func validateRolloutBatches(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
 	if rollout.RolloutBatches != nil {
 		batchesPath := rootPath.Child("rolloutBatches")
@@ -153,6 +210,121 @@ func validateRolloutBatches(rollout *v1alpha1.RolloutPlan, rootPath *field.Path)
 	return allErrs
 }
 
+func validateCanaryMetrics(metrics []v1alpha1.CanaryMetric, metricsPath *field.Path) (allErrs field.ErrorList) {
+	for i, metric := range metrics {
+		metricPath := metricsPath.Index(i)
+		if metric.TemplateRef == nil {
+			allErrs = append(allErrs, field.Required(metricPath.Child("templateRef"),
+				"the canary metric has to reference a metric template"))
+		}
+		if metric.Interval != "" {
+			if _, err := time.ParseDuration(metric.Interval); err != nil {
+				allErrs = append(allErrs, field.Invalid(metricPath.Child("interval"), metric.Interval,
+					fmt.Sprintf("invalid interval, err = %s", err)))
+			}
+		}
+		if metric.MetricsRange == nil {
+			continue
+		}
+		min, minErr := validateMetricBound(metric.MetricsRange.Min, metricPath.Child("metricsRange", "min"))
+		max, maxErr := validateMetricBound(metric.MetricsRange.Max, metricPath.Child("metricsRange", "max"))
+		allErrs = append(allErrs, minErr...)
+		allErrs = append(allErrs, maxErr...)
+		if min != nil && max != nil && *min > *max {
+			allErrs = append(allErrs, field.Invalid(metricPath.Child("metricsRange"), metric.MetricsRange,
+				"the min value of the metrics range is larger than the max value"))
+		}
+	}
+	return allErrs
+}
+
+func validateTrafficRouting(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
+	routing := rollout.TrafficRouting
+	if routing == nil {
+		return nil
+	}
+	routingPath := rootPath.Child("trafficRouting")
+	switch routing.Provider {
+	case v1alpha1.IstioTrafficProvider, v1alpha1.SMITrafficProvider, v1alpha1.ServiceTrafficProvider:
+	default:
+		allErrs = append(allErrs, field.NotSupported(routingPath.Child("provider"), routing.Provider,
+			[]string{string(v1alpha1.IstioTrafficProvider), string(v1alpha1.SMITrafficProvider),
+				string(v1alpha1.ServiceTrafficProvider)}))
+	}
+	if len(routing.Strategy) != 0 && routing.Strategy != v1alpha1.CanaryTrafficRoutingStrategyType &&
+		routing.Strategy != v1alpha1.BlueGreenTrafficRoutingStrategyType {
+		allErrs = append(allErrs, field.NotSupported(routingPath.Child("strategy"), routing.Strategy,
+			[]string{string(v1alpha1.CanaryTrafficRoutingStrategyType),
+				string(v1alpha1.BlueGreenTrafficRoutingStrategyType)}))
+	}
+
+	// the traffic of the batches can only grow, the service provider can only switch all the traffic at once
+	var prevWeight int32
+	batchesPath := rootPath.Child("rolloutBatches")
+	for i, rb := range rollout.RolloutBatches {
+		if rb.TrafficWeight == nil {
+			continue
+		}
+		weightPath := batchesPath.Index(i).Child("trafficWeight")
+		weight := *rb.TrafficWeight
+		switch {
+		case weight < 0 || weight > 100:
+			allErrs = append(allErrs, field.Invalid(weightPath, weight,
+				"the traffic weight has to be between 0 and 100"))
+		case weight < prevWeight:
+			allErrs = append(allErrs, field.Invalid(weightPath, weight,
+				"the traffic weight can not be less than the one of the previous batch"))
+		case routing.Provider == v1alpha1.ServiceTrafficProvider && weight != 0 && weight != 100:
+			allErrs = append(allErrs, field.Invalid(weightPath, weight,
+				"the service traffic provider can not split the traffic, the weight can only be 0 or 100"))
+		default:
+			prevWeight = weight
+		}
+	}
+	return allErrs
+}
+
+func validateSchedule(rolloutSchedule *v1alpha1.RolloutSchedule, schedulePath *field.Path) (allErrs field.ErrorList) {
+	if rolloutSchedule == nil {
+		return nil
+	}
+	if rolloutSchedule.SoakSeconds != nil && *rolloutSchedule.SoakSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(schedulePath.Child("soakSeconds"), *rolloutSchedule.SoakSeconds,
+			"the soak time can not be negative"))
+	}
+	if len(rolloutSchedule.TimeZone) != 0 {
+		if _, err := time.LoadLocation(rolloutSchedule.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(schedulePath.Child("timeZone"), rolloutSchedule.TimeZone,
+				"the time zone is not a valid IANA time zone"))
+		}
+	}
+	for i, w := range rolloutSchedule.Windows {
+		windowPath := schedulePath.Child("windows").Index(i)
+		if _, err := schedule.ParseCron(w.Schedule); err != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("schedule"), w.Schedule, err.Error()))
+		}
+		if duration, err := time.ParseDuration(w.Duration); err != nil || duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("duration"), w.Duration,
+				"the duration has to be a positive duration such as 8h"))
+		}
+	}
+	return allErrs
+}
+
+func validateMetricBound(bound *intstr.IntOrString, boundPath *field.Path) (*float64, field.ErrorList) {
+	if bound == nil {
+		return nil, nil
+	}
+	value := float64(bound.IntVal)
+	if bound.Type == intstr.String {
+		var err error
+		if value, err = strconv.ParseFloat(bound.StrVal, 64); err != nil {
+			return nil, field.ErrorList{field.Invalid(boundPath, bound.StrVal, "the bound has to be a number")}
+		}
+	}
+	return &value, nil
+}
+
 // ValidateUpdate validate if one can change the rollout plan from the previous psec
 func ValidateUpdate(client client.Client, new *v1alpha1.RolloutPlan, prev *v1alpha1.RolloutPlan,
 	rootPath *field.Path) field.ErrorList {
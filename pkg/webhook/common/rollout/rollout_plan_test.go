@@ -19,6 +19,8 @@ package rollout
 import (
 	"testing"
 
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/utils/pointer"
@@ -155,3 +157,101 @@ func TestValidateIllegalReplicas(t *testing.T) {
 		t.Error("should invalidate negative replica value")
 	}
 }
+
+func TestValidateCanaryMetrics(t *testing.T) {
+	min, max, illegal := intstr.FromString("0.99"), intstr.FromInt(1), intstr.FromString("high")
+	ref := &runtimev1alpha1.TypedReference{APIVersion: "v1", Kind: "ConfigMap", Name: "success-rate"}
+	validMetric := v1alpha1.CanaryMetric{
+		Name:         "success-rate",
+		Interval:     "1m",
+		MetricsRange: &v1alpha1.MetricsExpectedRange{Min: &min, Max: &max},
+		TemplateRef:  ref,
+	}
+	if errList := validateCanaryMetrics([]v1alpha1.CanaryMetric{validMetric}, field.NewPath("canaryMetric")); len(errList) != 0 {
+		t.Errorf("should validate the canary metric, got %v", errList)
+	}
+
+	illegalMetrics := []v1alpha1.CanaryMetric{
+		{Name: "no-template"},
+		{Name: "illegal-interval", Interval: "1 minute", TemplateRef: ref},
+		{Name: "illegal-bound", MetricsRange: &v1alpha1.MetricsExpectedRange{Max: &illegal}, TemplateRef: ref},
+		{Name: "min-larger-than-max", MetricsRange: &v1alpha1.MetricsExpectedRange{Min: &max, Max: &min}, TemplateRef: ref},
+	}
+	if errList := validateCanaryMetrics(illegalMetrics, field.NewPath("canaryMetric")); len(errList) != len(illegalMetrics) {
+		t.Errorf("should invalidate illegal canary metrics, got %v", errList)
+	}
+}
+
+func TestValidateTrafficRouting(t *testing.T) {
+	rollout := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{TrafficWeight: pointer.Int32Ptr(20)},
+			{},
+			{TrafficWeight: pointer.Int32Ptr(100)},
+		},
+		TrafficRouting: &v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficProvider},
+	}
+	DefaultRolloutPlan(rollout)
+	if rollout.TrafficRouting.Strategy != v1alpha1.CanaryTrafficRoutingStrategyType {
+		t.Errorf("should default the traffic routing strategy to canary, got %s", rollout.TrafficRouting.Strategy)
+	}
+	if errList := validateTrafficRouting(rollout, field.NewPath("spec")); len(errList) != 0 {
+		t.Errorf("should validate the traffic routing, got %v", errList)
+	}
+
+	rollout.TrafficRouting.Provider = v1alpha1.ServiceTrafficProvider
+	if errList := validateTrafficRouting(rollout, field.NewPath("spec")); len(errList) != 1 {
+		t.Errorf("should invalidate the traffic split of the service provider, got %v", errList)
+	}
+
+	rollout.TrafficRouting = &v1alpha1.TrafficRouting{Provider: "nginx", Strategy: "Shadow"}
+	rollout.RolloutBatches[0].TrafficWeight = pointer.Int32Ptr(120)
+	rollout.RolloutBatches[1].TrafficWeight = pointer.Int32Ptr(10)
+	rollout.RolloutBatches[2].TrafficWeight = pointer.Int32Ptr(5)
+	if errList := validateTrafficRouting(rollout, field.NewPath("spec")); len(errList) != 4 {
+		t.Errorf("should invalidate the illegal traffic routing, got %v", errList)
+	}
+}
+
+func TestValidateWebhookRequest(t *testing.T) {
+	rw := v1alpha1.RolloutWebhook{
+		TimeoutSeconds: pointer.Int32Ptr(5),
+		Retries:        pointer.Int32Ptr(0),
+		Auth: &v1alpha1.WebhookAuth{
+			HMACSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "webhook-auth"}, Key: "hmac"},
+			TLSSecretRef: &corev1.LocalObjectReference{Name: "webhook-tls"},
+		},
+	}
+	if errList := validateWebhookRequest(rw, field.NewPath("webhook")); len(errList) != 0 {
+		t.Errorf("should validate the webhook request, got %v", errList)
+	}
+
+	rw.TimeoutSeconds = pointer.Int32Ptr(0)
+	rw.Retries = pointer.Int32Ptr(-1)
+	rw.Auth.HMACSecretRef.Key = ""
+	rw.Auth.BearerTokenSecretRef = &corev1.SecretKeySelector{Key: "token"}
+	rw.Auth.TLSSecretRef.Name = ""
+	if errList := validateWebhookRequest(rw, field.NewPath("webhook")); len(errList) != 5 {
+		t.Errorf("should invalidate the illegal webhook request, got %v", errList)
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	rolloutSchedule := &v1alpha1.RolloutSchedule{
+		TimeZone:    "America/New_York",
+		SoakSeconds: pointer.Int32Ptr(600),
+		Windows:     []v1alpha1.RolloutWindow{{Schedule: "0 9 * * mon-fri", Duration: "8h"}},
+	}
+	if errList := validateSchedule(rolloutSchedule, field.NewPath("schedule")); len(errList) != 0 {
+		t.Errorf("should validate the schedule, got %v", errList)
+	}
+
+	rolloutSchedule.TimeZone = "New York"
+	rolloutSchedule.SoakSeconds = pointer.Int32Ptr(-1)
+	rolloutSchedule.Windows = append(rolloutSchedule.Windows,
+		v1alpha1.RolloutWindow{Schedule: "0 25 * * *", Duration: "-1h"})
+	if errList := validateSchedule(rolloutSchedule, field.NewPath("schedule")); len(errList) != 4 {
+		t.Errorf("should invalidate the illegal schedule, got %v", errList)
+	}
+}
@@ -17,12 +17,17 @@ limitations under the License.
 package application
 
 import (
+	"encoding/json"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 )
 
 var _ = Describe("Test Application Validator", func() {
@@ -129,6 +134,56 @@ var _ = Describe("Test Application Validator", func() {
 		Expect(resp.Allowed).Should(BeFalse())
 	})
 
+	It("Test Application Validator guardrail policy [error]", func() {
+		policyDef := &v1beta1.PolicyDefinition{}
+		pdJSON, _ := yaml.YAMLToJSON([]byte(registryPolicyYaml))
+		Expect(json.Unmarshal(pdJSON, policyDef)).Should(BeNil())
+		Expect(k8sClient.Create(ctx, policyDef)).Should(BeNil())
+
+		req := admission.Request{
+			AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: admissionv1beta1.Create,
+				Resource:  metav1.GroupVersionResource{Group: "core.oam.dev", Version: "v1alpha2", Resource: "applications"},
+				Object: runtime.RawExtension{
+					Raw: []byte(`
+{"apiVersion":"core.oam.dev/v1beta1","kind":"Application",
+"metadata":{"name":"application-sample"},
+"spec":{"components":[{"name":"myweb","type":"worker","properties":{"image":"busybox"}}],
+"policies":[{"name":"registry","type":"allowed-registry","properties":{"registry":"registry.example.com/"}}]}}
+`),
+				},
+			},
+		}
+		resp := handler.Handle(ctx, req)
+		Expect(resp.Allowed).Should(BeFalse())
+		Expect(resp.Result.Message).Should(ContainSubstring("image busybox is not from registry.example.com/"))
+	})
+
+	It("Test Application Validator guardrail policy cannot be evaluated [error]", func() {
+		policyDef := &v1beta1.PolicyDefinition{}
+		pdJSON, _ := yaml.YAMLToJSON([]byte(invalidPolicyYaml))
+		Expect(json.Unmarshal(pdJSON, policyDef)).Should(BeNil())
+		Expect(k8sClient.Create(ctx, policyDef)).Should(BeNil())
+
+		req := admission.Request{
+			AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: admissionv1beta1.Create,
+				Resource:  metav1.GroupVersionResource{Group: "core.oam.dev", Version: "v1alpha2", Resource: "applications"},
+				Object: runtime.RawExtension{
+					Raw: []byte(`
+{"apiVersion":"core.oam.dev/v1beta1","kind":"Application",
+"metadata":{"name":"application-sample"},
+"spec":{"components":[{"name":"myweb","type":"worker","properties":{"image":"busybox"}}],
+"policies":[{"name":"invalid","type":"invalid-guardrail"}]}}
+`),
+				},
+			},
+		}
+		resp := handler.Handle(ctx, req)
+		Expect(resp.Allowed).Should(BeFalse())
+		Expect(resp.Result.Message).Should(ContainSubstring("cannot evaluate the guardrail policies"))
+	})
+
 	It("Test Application Validator rolloutPlan [error]", func() {
 		req := admission.Request{
 			AdmissionRequest: admissionv1beta1.AdmissionRequest{
@@ -149,3 +204,34 @@ var _ = Describe("Test Application Validator", func() {
 		Expect(resp.Allowed).Should(BeFalse())
 	})
 })
+
+const registryPolicyYaml = `
+apiVersion: core.oam.dev/v1beta1
+kind: PolicyDefinition
+metadata:
+  name: allowed-registry
+  namespace: vela-system
+spec:
+  schematic:
+    cue:
+      template: |
+        import "strings"
+
+        parameter: registry: string
+        violations: [ if context.manifestType == "workload" for c in manifest.spec.template.spec.containers if !strings.HasPrefix(c.image, parameter.registry) {
+        	"image \(c.image) is not from \(parameter.registry)"
+        }]
+`
+
+const invalidPolicyYaml = `
+apiVersion: core.oam.dev/v1beta1
+kind: PolicyDefinition
+metadata:
+  name: invalid-guardrail
+  namespace: vela-system
+spec:
+  schematic:
+    cue:
+      template: |
+        violations: manifest.kind
+`
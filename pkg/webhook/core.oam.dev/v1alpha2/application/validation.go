@@ -18,13 +18,20 @@ package application
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/dispatch"
+	"github.com/oam-dev/kubevela/pkg/cue/packages"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/policy"
 	"github.com/oam-dev/kubevela/pkg/webhook/common/rollout"
+	"github.com/oam-dev/kubevela/pkg/workflow"
 )
 
 // ValidateCreate validates the Application on creation
@@ -42,15 +49,60 @@ func (h *ValidatingHandler) ValidateCreate(ctx context.Context, app *v1beta1.App
 	if err := appParser.ValidateCUESchematicAppfile(af); err != nil {
 		componentErrs = append(componentErrs, field.Invalid(field.NewPath("schematic"), app, err.Error()))
 	}
+	componentErrs = append(componentErrs, validatePolicies(af, h.pd)...)
 	if v := app.GetAnnotations()[oam.AnnotationAppRollout]; len(v) != 0 && v != "true" {
 		componentErrs = append(componentErrs, field.Invalid(field.NewPath("annotation:app.oam.dev/rollout-template"), app, "the annotation value of rollout-template must be true"))
 	}
+	if err := dispatch.ValidateComponentDependencies(app.Spec.Components); err != nil {
+		componentErrs = append(componentErrs, field.Invalid(field.NewPath("spec", "components"), app.Spec.Components, err.Error()))
+	}
+	if err := workflow.ValidateWorkflowSteps(app.Spec.Workflow); err != nil {
+		componentErrs = append(componentErrs, field.Invalid(field.NewPath("spec", "workflow"), app.Spec.Workflow, err.Error()))
+	}
 	if app.Spec.RolloutPlan != nil {
 		componentErrs = append(componentErrs, rollout.ValidateCreate(h.Client, app.Spec.RolloutPlan, field.NewPath("rolloutPlan"))...)
 	}
 	return componentErrs
 }
 
+// validatePolicies rejects the application if its rendered manifests violate the guardrail policies, or if the
+// manifests cannot be rendered or evaluated against the guardrail policies, as they cannot be dispatched either.
+func validatePolicies(af *appfile.Appfile, pd *packages.PackageDiscover) field.ErrorList {
+	hasGuardrail := false
+	for _, p := range af.Policies {
+		if policy.IsGuardrail(p) {
+			hasGuardrail = true
+			break
+		}
+	}
+	if !hasGuardrail {
+		return nil
+	}
+	policiesPath := field.NewPath("spec", "policies")
+	ac, comps, err := af.GenerateApplicationConfiguration()
+	if err != nil {
+		klog.InfoS("Reject the application which cannot be rendered to validate its policies", "application", af.Name, "err", err)
+		return field.ErrorList{field.Invalid(policiesPath, af.Name,
+			fmt.Sprintf("cannot render the application to validate the guardrail policies: %v", err))}
+	}
+	err = policy.Enforce(af, pd, ac, comps)
+	if err == nil {
+		return nil
+	}
+	var violationErr *policy.ViolationError
+	if !errors.As(err, &violationErr) {
+		klog.InfoS("Reject the application whose policies cannot be evaluated", "application", af.Name, "err", err)
+		return field.ErrorList{field.Invalid(policiesPath, af.Name,
+			fmt.Sprintf("cannot evaluate the guardrail policies: %v", err))}
+	}
+	var errs field.ErrorList
+	for _, v := range violationErr.Violations {
+		errs = append(errs, field.Forbidden(policiesPath.Key(v.Policy),
+			fmt.Sprintf("%s of component %s: %s", v.Manifest, v.Component, v.Message)))
+	}
+	return errs
+}
+
 // ValidateUpdate validates the Application on update
 func (h *ValidatingHandler) ValidateUpdate(ctx context.Context, newApp, oldApp *v1beta1.Application) field.ErrorList {
 	// check if the newApp is valid
@@ -22,7 +22,9 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 )
@@ -141,3 +143,40 @@ func fillWorkloads(componentNames []string) map[string]*unstructured.Unstructure
 	}
 	return w
 }
+
+var _ = Describe("Test validate component func", func() {
+	var targetApp, sourceApp *v1alpha2.ApplicationConfiguration
+	fldPath := field.NewPath("spec", "componentList")
+
+	BeforeEach(func() {
+		targetApp = &v1alpha2.ApplicationConfiguration{}
+		sourceApp = &v1alpha2.ApplicationConfiguration{}
+		fillApplication(&targetApp.Spec, []string{"frontend", "backend", "worker"})
+		fillApplication(&sourceApp.Spec, []string{"frontend", "backend"})
+	})
+
+	It("Test multiple common components", func() {
+		Expect(validateComponent([]string{"frontend", "backend"}, targetApp, sourceApp, fldPath)).Should(BeEmpty())
+	})
+
+	It("Test no default component", func() {
+		Expect(validateComponent(nil, targetApp, sourceApp, fldPath)).Should(HaveLen(1))
+	})
+
+	It("Test component not in the source app", func() {
+		errs := validateComponent([]string{"frontend", "worker"}, targetApp, sourceApp, fldPath)
+		Expect(errs).Should(HaveLen(1))
+		Expect(errs[0].Field).Should(Equal("spec.componentList[1]"))
+	})
+
+	It("Test duplicated component", func() {
+		errs := validateComponent([]string{"frontend", "frontend"}, targetApp, sourceApp, fldPath)
+		Expect(errs).Should(HaveLen(1))
+		Expect(errs[0].Type).Should(Equal(field.ErrorTypeDuplicate))
+	})
+
+	It("Test multi component strategy", func() {
+		Expect(validateMultiComponentStrategy(common.OrderedMultiComponentStrategy, fldPath)).Should(BeEmpty())
+		Expect(validateMultiComponentStrategy("Random", fldPath)).Should(HaveLen(1))
+	})
+})
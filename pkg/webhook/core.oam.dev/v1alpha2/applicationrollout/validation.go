@@ -27,6 +27,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/util/slice"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
@@ -89,6 +90,8 @@ func (h *ValidatingHandler) ValidateCreate(appRollout *v1beta1.AppRollout) field
 		allErrs = append(allErrs, validateComponent(appRollout.Spec.ComponentList, targetApp, sourceApp,
 			fldPath.Child("componentList"))...)
 	}
+	allErrs = append(allErrs, validateMultiComponentStrategy(appRollout.Spec.MultiComponentStrategy,
+		fldPath.Child("multiComponentStrategy"))...)
 
 	// validate the rollout plan spec
 	allErrs = append(allErrs, rollout.ValidateCreate(h, &appRollout.Spec.RolloutPlan, fldPath.Child("rolloutPlan"))...)
@@ -96,18 +99,12 @@ func (h *ValidatingHandler) ValidateCreate(appRollout *v1beta1.AppRollout) field
 }
 
 // validateComponent validate the ComponentList
-// 1. there can only be one component or less
-// 2. if there are no components, make sure the applications has only one common component so that's the default
-// 3. it is contained in both source and target application
-// 4. the common component has the same type
+// 1. if there are no components, make sure the applications has only one common component so that's the default
+// 2. each component is contained in both source and target application with the same type
+// 3. each component is listed only once
 func validateComponent(componentList []string, targetApp, sourceApp *v1alpha2.ApplicationConfiguration,
 	fldPath *field.Path) field.ErrorList {
 	var componentErrs field.ErrorList
-	var commonComponentName string
-	if len(componentList) > 1 {
-		componentErrs = append(componentErrs, field.TooLong(fldPath, componentList, 1))
-		return componentErrs
-	}
 	commons := FindCommonComponent(targetApp, sourceApp)
 	if len(componentList) == 0 {
 		// we need to find the default
@@ -115,31 +112,36 @@ func validateComponent(componentList []string, targetApp, sourceApp *v1alpha2.Ap
 			// we cannot find a default component if there are multiple
 			klog.Error("there are more than one common component", "common component", commons)
 			componentErrs = append(componentErrs, field.TooMany(fldPath, len(commons), 1))
-			return componentErrs
 		}
-		commonComponentName = commons[0]
-	} else {
+		return componentErrs
+	}
+	for i, comp := range componentList {
+		if slice.ContainsString(componentList[:i], comp, nil) {
+			componentErrs = append(componentErrs, field.Duplicate(fldPath.Index(i), comp))
+			continue
+		}
 		// the component need to be one of the common components
-		if !slice.ContainsString(commons, componentList[0], nil) {
+		if !slice.ContainsString(commons, comp, nil) {
 			klog.Error("The component does not belong to the application",
-				"common components", commons, "component to upgrade", componentList[0])
-			componentErrs = append(componentErrs, field.Invalid(fldPath, componentList[0],
+				"common components", commons, "component to upgrade", comp)
+			componentErrs = append(componentErrs, field.Invalid(fldPath.Index(i), comp,
 				"it is not a common component in the application"))
-			return componentErrs
 		}
-		commonComponentName = componentList[0]
 	}
-	// check if the workload type are the same in the source and target application
-	if len(commonComponentName) == 0 {
-		klog.Error("the common component have different types in the application",
-			"common component", commonComponentName)
-		componentErrs = append(componentErrs, field.Invalid(fldPath, componentList[0],
-			"the common component have different types in the application"))
-	}
-
 	return componentErrs
 }
 
+// validateMultiComponentStrategy validate the strategy to rollout multiple components
+func validateMultiComponentStrategy(strategy common.MultiComponentStrategy, fldPath *field.Path) field.ErrorList {
+	switch strategy {
+	case "", common.LockstepMultiComponentStrategy, common.OrderedMultiComponentStrategy:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(fldPath, strategy, []string{
+			string(common.LockstepMultiComponentStrategy), string(common.OrderedMultiComponentStrategy)})}
+	}
+}
+
 // ValidateUpdate validates the AppRollout on update
 func (h *ValidatingHandler) ValidateUpdate(new, old *v1beta1.AppRollout) field.ErrorList {
 	klog.InfoS("validate update", "name", new.Name)
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/cue/process"
+)
+
+const (
+	// ConditionContextFieldName is the field of the app context in the condition of step
+	ConditionContextFieldName = "context"
+	// ConditionOutputsFieldName is the field of the variables exported by the outputs of steps in the condition of step
+	ConditionOutputsFieldName = "outputs"
+	// ConditionComponentsFieldName is the field of the health status of components in the condition of step
+	ConditionComponentsFieldName = "components"
+
+	conditionResultFieldName = "condition"
+)
+
+// validateCondition checks the `if` condition of the step is a valid CUE expression.
+func validateCondition(step oamcore.WorkflowStep) error {
+	if step.If == "" {
+		return nil
+	}
+	if _, err := parser.ParseExpr("if", step.If); err != nil {
+		return errors.Wrapf(err, "invalid if condition of workflow step %s", step.Name)
+	}
+	return nil
+}
+
+// evaluateCondition evaluates the `if` condition of the step, which can refer to
+//  - context: the name, namespace and revision of the application
+//  - outputs: the variables exported by the outputs of the previous steps
+//  - components: the health status of the components observed by the last reconciliation, e.g. `components.frontend.healthy`
+func evaluateCondition(step oamcore.WorkflowStep, app *oamcore.Application, rev string, vars *Variables) (bool, error) {
+	appCtx := map[string]interface{}{
+		process.ContextName:        app.Name,
+		process.ContextAppName:     app.Name,
+		process.ContextNamespace:   app.Namespace,
+		process.ContextAppRevision: rev,
+	}
+	components := map[string]interface{}{}
+	for _, svc := range app.Status.Services {
+		components[svc.Name] = map[string]interface{}{
+			"healthy": svc.Healthy,
+			"message": svc.Message,
+		}
+	}
+	vars.mu.RLock()
+	outputs, err := json.Marshal(vars.data)
+	vars.mu.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	ctxJSON, err := json.Marshal(appCtx)
+	if err != nil {
+		return false, err
+	}
+	compJSON, err := json.Marshal(components)
+	if err != nil {
+		return false, err
+	}
+
+	src := fmt.Sprintf("%s: %s\n%s: %s\n%s: %s\n%s: %s\n",
+		ConditionContextFieldName, ctxJSON,
+		ConditionOutputsFieldName, outputs,
+		ConditionComponentsFieldName, compJSON,
+		conditionResultFieldName, step.If)
+	var r cue.Runtime
+	inst, err := r.Compile("-", src)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid if condition %q", step.If)
+	}
+	result, err := inst.Lookup(conditionResultFieldName).Bool()
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot evaluate if condition %q to a bool", step.If)
+	}
+	return result, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	app := &oamcore.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "prod"},
+		Status: common.AppStatus{
+			Services: []common.ApplicationComponentStatus{
+				{Name: "frontend", Healthy: true},
+				{Name: "backend", Healthy: false, Message: "not ready"},
+			},
+		},
+	}
+	vars := NewVariables(map[string]interface{}{"replicas": 3})
+
+	testcases := map[string]struct {
+		cond    string
+		want    bool
+		wantErr bool
+	}{
+		"context": {
+			cond: `context.namespace == "prod" && context.appRevision == "test-v1"`,
+			want: true,
+		},
+		"context not matched": {
+			cond: `context.namespace == "staging"`,
+			want: false,
+		},
+		"outputs": {
+			cond: `outputs.replicas > 2`,
+			want: true,
+		},
+		"components": {
+			cond: `components.frontend.healthy && !components.backend.healthy`,
+			want: true,
+		},
+		"not bool": {
+			cond:    `context.namespace`,
+			wantErr: true,
+		},
+		"missing output": {
+			cond:    `outputs.endpoint != ""`,
+			wantErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			got, err := evaluateCondition(oamcore.WorkflowStep{Name: "s1", If: tc.cond}, app, "test-v1", vars)
+			assert.Equal(t, tc.wantErr, err != nil, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
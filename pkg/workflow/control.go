@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+const (
+	// ControlSuspend suspends the running workflow, steps won't be executed until it's resumed.
+	ControlSuspend = "suspend"
+	// ControlResume resumes the suspended workflow, a running `suspend` step is regarded as approved.
+	ControlResume = "resume"
+	// ControlTerminate terminates the workflow, steps won't be executed anymore in the current app revision.
+	ControlTerminate = "terminate"
+	// ControlRestart restarts the workflow from the beginning, or from the given step.
+	ControlRestart = "restart"
+)
+
+// Control applies the control operation to the workflow status of the application.
+// step is only used by ControlRestart, the status of the step and all the steps depending on it is reset so that
+// they're executed from scratch again.
+func Control(app *oamcore.Application, operation string, step string) error {
+	if len(app.Spec.Workflow) == 0 {
+		return errors.Errorf("application %s has no workflow", app.Name)
+	}
+	if step != "" && operation != ControlRestart {
+		return errors.Errorf("step can only be specified when restarting workflow")
+	}
+	wfStatus := app.Status.Workflow
+	if wfStatus == nil {
+		if operation == ControlRestart {
+			return nil
+		}
+		return errors.Errorf("workflow of application %s is not started yet", app.Name)
+	}
+
+	switch operation {
+	case ControlSuspend:
+		if wfStatus.Terminated {
+			return errors.Errorf("workflow of application %s is terminated", app.Name)
+		}
+		wfStatus.Suspend = true
+	case ControlResume:
+		if wfStatus.Terminated {
+			return errors.Errorf("workflow of application %s is terminated", app.Name)
+		}
+		wfStatus.Suspend = false
+	case ControlTerminate:
+		wfStatus.Suspend = false
+		wfStatus.Terminated = true
+	case ControlRestart:
+		if step == "" {
+			app.Status.Workflow = &common.WorkflowStatus{AppRevision: wfStatus.AppRevision}
+			return nil
+		}
+		reset, err := stepAndDependents(app.Spec.Workflow, step)
+		if err != nil {
+			return err
+		}
+		var steps []common.WorkflowStepStatus
+		for _, ss := range wfStatus.Steps {
+			if !reset[ss.Name] {
+				steps = append(steps, ss)
+			}
+		}
+		wfStatus.Steps = steps
+		wfStatus.Suspend = false
+		wfStatus.Terminated = false
+	default:
+		return errors.Errorf("unknown workflow control operation %q", operation)
+	}
+	return nil
+}
+
+// stepAndDependents returns the step and all the steps depending on it directly or indirectly.
+func stepAndDependents(steps []oamcore.WorkflowStep, step string) (map[string]bool, error) {
+	specs := make(map[string]oamcore.WorkflowStep, len(steps))
+	for _, spec := range steps {
+		specs[spec.Name] = spec
+	}
+	if _, ok := specs[step]; !ok {
+		return nil, errors.Errorf("workflow step %s is not found", step)
+	}
+	dag := false
+	for _, spec := range steps {
+		if len(spec.DependsOn) != 0 {
+			dag = true
+			break
+		}
+	}
+	result := map[string]bool{step: true}
+	if !dag {
+		// steps are executed in order, so all the steps after the given one depend on it
+		for i, spec := range steps {
+			if i > 0 && result[steps[i-1].Name] {
+				result[spec.Name] = true
+			}
+		}
+		return result, nil
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, spec := range steps {
+			if result[spec.Name] {
+				continue
+			}
+			for _, dep := range spec.DependsOn {
+				if result[dep] {
+					result[spec.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return result, nil
+}
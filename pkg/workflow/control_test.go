@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestControl(t *testing.T) {
+	newApp := func(steps []oamcore.WorkflowStep, status *common.WorkflowStatus) *oamcore.Application {
+		return &oamcore.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec:       oamcore.ApplicationSpec{Workflow: steps},
+			Status:     common.AppStatus{Workflow: status},
+		}
+	}
+	sequential := []oamcore.WorkflowStep{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	dag := []oamcore.WorkflowStep{
+		{Name: "a"},
+		{Name: "b"},
+		{Name: "c", DependsOn: []string{"a"}},
+		{Name: "d", DependsOn: []string{"c"}},
+	}
+	stepStatus := func(names ...string) []common.WorkflowStepStatus {
+		var steps []common.WorkflowStepStatus
+		for _, name := range names {
+			steps = append(steps, common.WorkflowStepStatus{Name: name, Phase: common.WorkflowStepPhaseSucceeded})
+		}
+		return steps
+	}
+
+	testcases := map[string]struct {
+		app       *oamcore.Application
+		operation string
+		step      string
+		want      *common.WorkflowStatus
+		wantErr   bool
+	}{
+		"suspend": {
+			app:       newApp(sequential, &common.WorkflowStatus{AppRevision: "app-v1", Steps: stepStatus("a")}),
+			operation: ControlSuspend,
+			want:      &common.WorkflowStatus{AppRevision: "app-v1", Suspend: true, Steps: stepStatus("a")},
+		},
+		"resume": {
+			app:       newApp(sequential, &common.WorkflowStatus{AppRevision: "app-v1", Suspend: true}),
+			operation: ControlResume,
+			want:      &common.WorkflowStatus{AppRevision: "app-v1"},
+		},
+		"resume terminated workflow": {
+			app:       newApp(sequential, &common.WorkflowStatus{AppRevision: "app-v1", Terminated: true}),
+			operation: ControlResume,
+			wantErr:   true,
+		},
+		"terminate": {
+			app:       newApp(sequential, &common.WorkflowStatus{AppRevision: "app-v1", Suspend: true}),
+			operation: ControlTerminate,
+			want:      &common.WorkflowStatus{AppRevision: "app-v1", Terminated: true},
+		},
+		"restart": {
+			app:       newApp(sequential, &common.WorkflowStatus{AppRevision: "app-v1", Terminated: true, Steps: stepStatus("a", "b")}),
+			operation: ControlRestart,
+			want:      &common.WorkflowStatus{AppRevision: "app-v1"},
+		},
+		"restart from step of sequential workflow": {
+			app:       newApp(sequential, &common.WorkflowStatus{AppRevision: "app-v1", Terminated: true, Steps: stepStatus("a", "b", "c")}),
+			operation: ControlRestart,
+			step:      "b",
+			want:      &common.WorkflowStatus{AppRevision: "app-v1", Steps: stepStatus("a")},
+		},
+		"restart from step of dag workflow": {
+			app:       newApp(dag, &common.WorkflowStatus{AppRevision: "app-v1", Steps: stepStatus("a", "b", "c", "d")}),
+			operation: ControlRestart,
+			step:      "a",
+			want:      &common.WorkflowStatus{AppRevision: "app-v1", Steps: stepStatus("b")},
+		},
+		"restart from non-existent step": {
+			app:       newApp(dag, &common.WorkflowStatus{AppRevision: "app-v1"}),
+			operation: ControlRestart,
+			step:      "x",
+			wantErr:   true,
+		},
+		"step with other operation": {
+			app:       newApp(dag, &common.WorkflowStatus{AppRevision: "app-v1"}),
+			operation: ControlSuspend,
+			step:      "a",
+			wantErr:   true,
+		},
+		"workflow not started": {
+			app:       newApp(sequential, nil),
+			operation: ControlSuspend,
+			wantErr:   true,
+		},
+		"no workflow": {
+			app:       newApp(nil, nil),
+			operation: ControlRestart,
+			wantErr:   true,
+		},
+		"unknown operation": {
+			app:       newApp(sequential, &common.WorkflowStatus{AppRevision: "app-v1"}),
+			operation: "pause",
+			wantErr:   true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			err := Control(tc.app, tc.operation, tc.step)
+			assert.Equal(t, tc.wantErr, err != nil, err)
+			if tc.wantErr {
+				return
+			}
+			assert.Equal(t, tc.want, tc.app.Status.Workflow)
+		})
+	}
+}
+
+func TestExecuteTerminatedSteps(t *testing.T) {
+	app := &oamcore.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       oamcore.ApplicationSpec{Workflow: []oamcore.WorkflowStep{{Name: "a"}}},
+		Status:     common.AppStatus{Workflow: &common.WorkflowStatus{AppRevision: "app-v1", Terminated: true}},
+	}
+	done, err := NewWorkflow(app, newFakeClient()).ExecuteSteps(context.Background(), "app-v1",
+		[]TaskRunner{mockRunner("a", common.WorkflowStepPhaseSucceeded, nil, nil)})
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, common.ApplicationWorkflowTerminated, app.Status.Phase)
+	assert.Empty(t, app.Status.Workflow.Steps)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// ValidateWorkflowSteps checks the step names are unique, the inputs of steps are exported by some steps,
+// the conditions and failure policies of steps are valid, and the dependencies of steps form a DAG.
+func ValidateWorkflowSteps(steps []oamcore.WorkflowStep) error {
+	deps := make(map[string][]string, len(steps))
+	vars := map[string]bool{}
+	for _, step := range steps {
+		if step.Name == "" {
+			return errors.New("name of workflow step must be specified")
+		}
+		if _, ok := deps[step.Name]; ok {
+			return errors.Errorf("duplicated workflow step %s", step.Name)
+		}
+		deps[step.Name] = step.DependsOn
+		if err := validateStepPolicy(step); err != nil {
+			return err
+		}
+		if err := validateCondition(step); err != nil {
+			return err
+		}
+		for _, output := range step.Outputs {
+			if output.Name == "" || output.ExportKey == "" {
+				return errors.Errorf("name and exportKey of outputs of workflow step %s must be specified", step.Name)
+			}
+			vars[output.Name] = true
+		}
+	}
+	rollbackSteps := map[string]bool{}
+	for _, step := range steps {
+		if step.OnFailure != oamcore.WorkflowStepOnFailureRollback {
+			continue
+		}
+		if step.RollbackStep == step.Name {
+			return errors.Errorf("workflow step %s cannot roll back by itself", step.Name)
+		}
+		if _, ok := deps[step.RollbackStep]; !ok {
+			return errors.Errorf("rollback step %s of workflow step %s is not found", step.RollbackStep, step.Name)
+		}
+		if len(deps[step.RollbackStep]) != 0 {
+			return errors.Errorf("rollback step %s of workflow step %s cannot depend on other steps", step.RollbackStep, step.Name)
+		}
+		rollbackSteps[step.RollbackStep] = true
+	}
+	for _, step := range steps {
+		if rollbackSteps[step.Name] && step.OnFailure == oamcore.WorkflowStepOnFailureRollback {
+			return errors.Errorf("rollback step %s cannot roll back by other steps", step.Name)
+		}
+		for _, input := range step.Inputs {
+			if !vars[input.From] {
+				return errors.Errorf("input %s of workflow step %s is not exported by any step", input.From, step.Name)
+			}
+		}
+		for _, dep := range step.DependsOn {
+			if rollbackSteps[dep] {
+				return errors.Errorf("workflow step %s cannot depend on rollback step %s", step.Name, dep)
+			}
+			if dep == step.Name {
+				return errors.Errorf("workflow step %s cannot depend on itself", step.Name)
+			}
+			if _, ok := deps[dep]; !ok {
+				return errors.Errorf("workflow step %s depends on non-existent step %s", step.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(steps))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return errors.Errorf("workflow steps have circular dependencies: %v", append(path, name))
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, step := range steps {
+		if err := visit(step.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stepDependencies returns the names of the steps each step depends on.
+// If no step specifies dependsOn, every step depends on its previous one so that steps are executed in order,
+// rollback steps are skipped as they're only executed on failure.
+func stepDependencies(specs map[string]oamcore.WorkflowStep, taskRunners []TaskRunner, rollbackSteps map[string]bool) map[string][]string {
+	dag := false
+	for _, spec := range specs {
+		if len(spec.DependsOn) != 0 {
+			dag = true
+			break
+		}
+	}
+	deps := make(map[string][]string, len(taskRunners))
+	prev := ""
+	for _, runner := range taskRunners {
+		switch {
+		case dag:
+			deps[runner.Name()] = specs[runner.Name()].DependsOn
+		case rollbackSteps[runner.Name()]:
+			continue
+		case prev != "":
+			deps[runner.Name()] = []string{prev}
+		}
+		prev = runner.Name()
+	}
+	return deps
+}
+
+// validateStepPolicy checks the timeout, retries and failure policy of the step.
+func validateStepPolicy(step oamcore.WorkflowStep) error {
+	for field, value := range map[string]string{"timeout": step.Timeout, "backoff": step.Backoff} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return errors.Errorf("%s %q of workflow step %s must be a positive duration", field, value, step.Name)
+		}
+	}
+	if step.Retries != nil && *step.Retries < 0 {
+		return errors.Errorf("retries of workflow step %s cannot be negative", step.Name)
+	}
+	switch step.OnFailure {
+	case "", oamcore.WorkflowStepOnFailureAbort, oamcore.WorkflowStepOnFailureContinue:
+		if step.RollbackStep != "" {
+			return errors.Errorf("rollbackStep of workflow step %s can only be specified with onFailure rollback", step.Name)
+		}
+	case oamcore.WorkflowStepOnFailureRollback:
+		if step.RollbackStep == "" {
+			return errors.Errorf("rollbackStep of workflow step %s must be specified with onFailure rollback", step.Name)
+		}
+	default:
+		return errors.Errorf("unknown onFailure policy %q of workflow step %s", step.OnFailure, step.Name)
+	}
+	return nil
+}
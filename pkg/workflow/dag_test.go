@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestValidateWorkflowSteps(t *testing.T) {
+	testcases := map[string]struct {
+		steps   []oamcore.WorkflowStep
+		wantErr bool
+	}{
+		"no steps": {},
+		"steps without dependencies": {
+			steps: []oamcore.WorkflowStep{{Name: "a"}, {Name: "b"}},
+		},
+		"valid dag": {
+			steps: []oamcore.WorkflowStep{
+				{Name: "a"},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"a"}},
+				{Name: "d", DependsOn: []string{"b", "c"}},
+			},
+		},
+		"empty name": {
+			steps:   []oamcore.WorkflowStep{{Name: ""}},
+			wantErr: true,
+		},
+		"duplicated name": {
+			steps:   []oamcore.WorkflowStep{{Name: "a"}, {Name: "a"}},
+			wantErr: true,
+		},
+		"depends on itself": {
+			steps:   []oamcore.WorkflowStep{{Name: "a", DependsOn: []string{"a"}}},
+			wantErr: true,
+		},
+		"depends on non-existent step": {
+			steps:   []oamcore.WorkflowStep{{Name: "a", DependsOn: []string{"b"}}},
+			wantErr: true,
+		},
+		"input exported by other step": {
+			steps: []oamcore.WorkflowStep{
+				{Name: "a", Outputs: []oamcore.WorkflowStepOutput{{Name: "endpoint", ExportKey: "status.endpoint"}}},
+				{Name: "b", Inputs: []oamcore.WorkflowStepInput{{From: "endpoint", ParameterKey: "url"}}},
+			},
+		},
+		"input not exported by any step": {
+			steps: []oamcore.WorkflowStep{
+				{Name: "a", Inputs: []oamcore.WorkflowStepInput{{From: "endpoint"}}},
+			},
+			wantErr: true,
+		},
+		"output without export key": {
+			steps: []oamcore.WorkflowStep{
+				{Name: "a", Outputs: []oamcore.WorkflowStepOutput{{Name: "endpoint"}}},
+			},
+			wantErr: true,
+		},
+		"valid failure policies": {
+			steps: []oamcore.WorkflowStep{
+				{Name: "a", Timeout: "5m", Retries: intPtr(3), Backoff: "30s", OnFailure: oamcore.WorkflowStepOnFailureRollback, RollbackStep: "b"},
+				{Name: "b"},
+			},
+		},
+		"invalid timeout": {
+			steps:   []oamcore.WorkflowStep{{Name: "a", Timeout: "5"}},
+			wantErr: true,
+		},
+		"negative retries": {
+			steps:   []oamcore.WorkflowStep{{Name: "a", Retries: intPtr(-1)}},
+			wantErr: true,
+		},
+		"rollback without rollback step": {
+			steps:   []oamcore.WorkflowStep{{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureRollback}},
+			wantErr: true,
+		},
+		"rollback step not found": {
+			steps:   []oamcore.WorkflowStep{{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureRollback, RollbackStep: "b"}},
+			wantErr: true,
+		},
+		"depends on rollback step": {
+			steps: []oamcore.WorkflowStep{
+				{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureRollback, RollbackStep: "b"},
+				{Name: "b"},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			wantErr: true,
+		},
+		"invalid if condition": {
+			steps:   []oamcore.WorkflowStep{{Name: "a", If: `context.namespace ==`}},
+			wantErr: true,
+		},
+		"circular dependencies": {
+			steps: []oamcore.WorkflowStep{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateWorkflowSteps(tc.steps)
+			assert.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// MaxStepEvents is the maximum number of events kept for each step in WorkflowRun
+const MaxStepEvents = 20
+
+// WorkflowRunName returns the name of the WorkflowRun recording the workflow executed for the app revision.
+func WorkflowRunName(appRevision string) string {
+	return appRevision
+}
+
+// recordWorkflowRun records the current workflow status of the app revision into its WorkflowRun,
+// the phase transitions of steps are appended as events.
+func recordWorkflowRun(ctx context.Context, cli client.Client, app *oamcore.Application, done bool, vars *Variables) error {
+	wfStatus := app.Status.Workflow
+	now := metav1.Now()
+	run := &oamcore.WorkflowRun{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: WorkflowRunName(wfStatus.AppRevision)}, run)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, "cannot get workflow run")
+		}
+		run = &oamcore.WorkflowRun{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      WorkflowRunName(wfStatus.AppRevision),
+				Namespace: app.Namespace,
+				Labels: map[string]string{
+					oam.LabelAppName:     app.Name,
+					oam.LabelAppRevision: wfStatus.AppRevision,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(app, oamcore.ApplicationKindVersionKind),
+				},
+			},
+			Spec: oamcore.WorkflowRunSpec{
+				AppName:     app.Name,
+				AppRevision: wfStatus.AppRevision,
+			},
+			Status: oamcore.WorkflowRunStatus{StartTime: &now},
+		}
+	}
+	origin := run.Status.DeepCopy()
+
+	specs := make(map[string]oamcore.WorkflowStep, len(app.Spec.Workflow))
+	for _, spec := range app.Spec.Workflow {
+		specs[spec.Name] = spec
+	}
+	records := make(map[string]int, len(run.Status.Steps))
+	for i, record := range run.Status.Steps {
+		records[record.Name] = i
+	}
+	failed := false
+	for _, ss := range wfStatus.Steps {
+		i, ok := records[ss.Name]
+		if !ok {
+			run.Status.Steps = append(run.Status.Steps, oamcore.WorkflowStepRecord{Name: ss.Name})
+			i = len(run.Status.Steps) - 1
+			records[ss.Name] = i
+		}
+		recordStep(&run.Status.Steps[i], ss, specs[ss.Name], vars, now)
+		if isFailed(ss.Phase) && specs[ss.Name].OnFailure != oamcore.WorkflowStepOnFailureContinue {
+			failed = true
+		}
+	}
+
+	switch {
+	case wfStatus.Terminated:
+		run.Status.Phase = oamcore.WorkflowRunTerminated
+	case wfStatus.Suspend:
+		run.Status.Phase = oamcore.WorkflowRunSuspending
+	case done && failed:
+		run.Status.Phase = oamcore.WorkflowRunFailed
+	case done:
+		run.Status.Phase = oamcore.WorkflowRunSucceeded
+	default:
+		run.Status.Phase = oamcore.WorkflowRunRunning
+	}
+	switch {
+	case done || wfStatus.Terminated:
+		if run.Status.EndTime == nil {
+			run.Status.EndTime = &now
+		}
+	default:
+		// the workflow may be restarted
+		run.Status.EndTime = nil
+	}
+
+	if run.ResourceVersion == "" {
+		return errors.Wrap(cli.Create(ctx, run), "cannot create workflow run")
+	}
+	if equality.Semantic.DeepEqual(origin, &run.Status) {
+		return nil
+	}
+	return errors.Wrap(cli.Update(ctx, run), "cannot update workflow run")
+}
+
+// recordStep updates the record of the step with its current status.
+func recordStep(record *oamcore.WorkflowStepRecord, ss common.WorkflowStepStatus, spec oamcore.WorkflowStep,
+	vars *Variables, now metav1.Time) {
+	changed := record.Phase != ss.Phase || record.Message != ss.Message
+	record.Type = ss.Type
+	record.Phase = ss.Phase
+	record.Message = ss.Message
+	record.Attempts = ss.Attempts
+	switch {
+	case ss.StartTime != nil:
+		record.StartTime = ss.StartTime.DeepCopy()
+	case record.StartTime == nil:
+		record.StartTime = &now
+	}
+	switch {
+	case ss.Phase == common.WorkflowStepPhaseRunning:
+		record.EndTime = nil
+	case record.EndTime == nil:
+		record.EndTime = &now
+	}
+	if changed {
+		record.Events = append(record.Events, oamcore.WorkflowStepEvent{Time: now, Phase: ss.Phase, Message: ss.Message})
+		if len(record.Events) > MaxStepEvents {
+			record.Events = record.Events[len(record.Events)-MaxStepEvents:]
+		}
+	}
+
+	if ss.Phase != common.WorkflowStepPhaseSucceeded || len(spec.Outputs) == 0 || vars == nil {
+		return
+	}
+	outputs := map[string]interface{}{}
+	for _, output := range spec.Outputs {
+		if value, ok := vars.Get(output.Name); ok {
+			outputs[output.Name] = value
+		}
+	}
+	if b, err := json.Marshal(outputs); err == nil {
+		record.Outputs = &runtime.RawExtension{Raw: b}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestRecordWorkflowRun(t *testing.T) {
+	app := &oamcore.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: oamcore.ApplicationSpec{Workflow: []oamcore.WorkflowStep{
+			{Name: "create-db", Outputs: []oamcore.WorkflowStepOutput{{Name: "endpoint", ExportKey: "status.endpoint"}}},
+			{Name: "deploy"},
+		}},
+	}
+	cli := newFakeClient()
+	deployPhase := common.WorkflowStepPhaseRunning
+	runners := []TaskRunner{
+		&testRunner{name: "create-db", runWithVars: func(vars *Variables) (common.WorkflowStepStatus, *Operation, error) {
+			vars.Set("endpoint", "db.test:3306")
+			return common.WorkflowStepStatus{Name: "create-db", Type: "apply-object", Phase: common.WorkflowStepPhaseSucceeded}, nil, nil
+		}},
+		&testRunner{name: "deploy", run: func(*types.WorkflowContext, *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+			return common.WorkflowStepStatus{Name: "deploy", Type: "apply-component", Phase: deployPhase}, nil, nil
+		}},
+	}
+
+	done, err := NewWorkflow(app, cli).ExecuteSteps(context.Background(), "test-v1", runners)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	run := &oamcore.WorkflowRun{}
+	assert.NoError(t, cli.Get(context.Background(), client.ObjectKey{Namespace: "test", Name: WorkflowRunName("test-v1")}, run))
+	assert.Equal(t, "test", run.Labels[oam.LabelAppName])
+	assert.Equal(t, "test-v1", run.Spec.AppRevision)
+	assert.Equal(t, oamcore.WorkflowRunRunning, run.Status.Phase)
+	assert.NotNil(t, run.Status.StartTime)
+	assert.Nil(t, run.Status.EndTime)
+	assert.Equal(t, 2, len(run.Status.Steps))
+	assert.JSONEq(t, `{"endpoint":"db.test:3306"}`, string(run.Status.Steps[0].Outputs.Raw))
+	assert.NotNil(t, run.Status.Steps[0].EndTime)
+	assert.Nil(t, run.Status.Steps[1].EndTime)
+	assert.Equal(t, 1, len(run.Status.Steps[1].Events))
+
+	deployPhase = common.WorkflowStepPhaseFailed
+	done, err = NewWorkflow(app, cli).ExecuteSteps(context.Background(), "test-v1", runners)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	run = &oamcore.WorkflowRun{}
+	assert.NoError(t, cli.Get(context.Background(), client.ObjectKey{Namespace: "test", Name: WorkflowRunName("test-v1")}, run))
+	assert.Equal(t, oamcore.WorkflowRunFailed, run.Status.Phase)
+	assert.NotNil(t, run.Status.EndTime)
+	assert.Equal(t, 1, len(run.Status.Steps[0].Events))
+	assert.Equal(t, []common.WorkflowStepPhase{common.WorkflowStepPhaseRunning, common.WorkflowStepPhaseFailed},
+		[]common.WorkflowStepPhase{run.Status.Steps[1].Events[0].Phase, run.Status.Steps[1].Events[1].Phase})
+
+	// the workflow of a new revision is recorded separately
+	deployPhase = common.WorkflowStepPhaseSucceeded
+	done, err = NewWorkflow(app, cli).ExecuteSteps(context.Background(), "test-v2", runners)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	runs := &oamcore.WorkflowRunList{}
+	assert.NoError(t, cli.List(context.Background(), runs, client.MatchingLabels{oam.LabelAppName: "test"}))
+	assert.Equal(t, 2, len(runs.Items))
+}
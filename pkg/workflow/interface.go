@@ -19,14 +19,33 @@ package workflow
 import (
 	"context"
 
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/types"
 )
 
 // Workflow is used to execute the workflow steps of Application.
 type Workflow interface {
-	// ExecuteSteps executes the steps of an Application with given steps of rendered resources.
-	// It returns done=true only if all steps are executed and succeeded.
-	ExecuteSteps(ctx context.Context, appRevName string, steps []*unstructured.Unstructured) (done bool, err error)
+	// ExecuteSteps executes the steps of an Application with given runners of steps.
+	// Steps whose dependencies are done are executed in parallel.
+	// It returns done=true if all steps are finished, or the workflow is aborted by a failed step.
+	ExecuteSteps(ctx context.Context, appRevName string, taskRunners []TaskRunner) (done bool, err error)
+}
+
+// TaskRunner is used to execute a workflow step.
+type TaskRunner interface {
+	// Name returns the name of the workflow step.
+	Name() string
+	// Run executes the workflow step and returns its latest status.
+	// vars are the variables exported by the steps, which are read by the inputs and written by the outputs of the step.
+	// prev is the status of the step recorded in last reconciliation of the same app revision, it's nil if the
+	// step has never been executed.
+	Run(ctx context.Context, wctx *types.WorkflowContext, vars *Variables, prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error)
+}
+
+// Operation is the control instruction returned by a TaskRunner to the workflow.
+type Operation struct {
+	// Suspend makes the workflow suspend after the step, until it's resumed.
+	Suspend bool
 }
 
 // SucceededMessage is the data json-marshalled into the message of `workflow-progress` condition
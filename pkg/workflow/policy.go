@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+const (
+	// DefaultStepBackoff is the duration to wait before the first retry of a failed step if backoff is not specified
+	DefaultStepBackoff = 10 * time.Second
+	// MaxStepBackoff is the maximum duration to wait between two retries of a failed step
+	MaxStepBackoff = 10 * time.Minute
+)
+
+// defaultStepRetries are the retries of the step types retried by default, e.g. the steps calling the external
+// services which may fail temporarily
+var defaultStepRetries = map[string]int{}
+
+// RegisterDefaultStepRetries sets the retries of the steps of the type which don't specify their retries.
+func RegisterDefaultStepRetries(typ string, retries int) {
+	defaultStepRetries[typ] = retries
+}
+
+// runStep executes the step with its timeout and retry policy applied, the attempts and elapsed time of the step
+// are recorded in the returned status.
+// A step that is failed after all the retries or timed out is not executed anymore in the same app revision.
+func runStep(ctx context.Context, runner TaskRunner, spec oamcore.WorkflowStep, wctx *types.WorkflowContext,
+	vars *Variables, prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+	now := metav1.Now()
+	if prev != nil && isFailed(prev.Phase) && prev.NextRetryTime == nil {
+		return *prev, nil, nil
+	}
+
+	startTime, attempts := now, 1
+	if prev != nil {
+		if prev.StartTime != nil {
+			startTime = *prev.StartTime
+		}
+		if prev.Attempts > 0 {
+			attempts = prev.Attempts
+		}
+	}
+	elapsed := &metav1.Duration{Duration: now.Sub(startTime.Time)}
+
+	if timeout := stepTimeout(spec); timeout > 0 && elapsed.Duration > timeout &&
+		(prev == nil || prev.Phase != common.WorkflowStepPhaseSucceeded) {
+		status := common.WorkflowStepStatus{Name: runner.Name(), Type: spec.Type}
+		if prev != nil {
+			status = *prev
+		}
+		status.Phase = common.WorkflowStepPhaseFailed
+		status.Message = fmt.Sprintf("step is timed out after %s", spec.Timeout)
+		status.NextRetryTime = nil
+		status.Attempts = attempts
+		status.StartTime = &startTime
+		status.Elapsed = elapsed
+		return status, nil, nil
+	}
+
+	runPrev := prev
+	if prev != nil && prev.NextRetryTime != nil {
+		if now.Before(prev.NextRetryTime) {
+			status := *prev
+			status.Elapsed = elapsed
+			return status, nil, nil
+		}
+		// the retry is executed from scratch
+		attempts++
+		runPrev = nil
+	}
+
+	status, operation, err := runner.Run(ctx, wctx, vars, runPrev)
+	if err != nil {
+		if prev == nil {
+			return common.WorkflowStepStatus{}, nil, err
+		}
+		status = *prev
+		status.Phase = common.WorkflowStepPhaseRunning
+		status.Message = err.Error()
+		status.NextRetryTime = nil
+		status.Attempts = attempts
+		status.StartTime = &startTime
+		status.Elapsed = elapsed
+		return status, nil, err
+	}
+	status.Attempts = attempts
+	status.StartTime = &startTime
+	status.Elapsed = elapsed
+	if prev != nil && prev.Phase == common.WorkflowStepPhaseSucceeded && prev.Elapsed != nil {
+		// the step is finished already, it's only re-executed to keep the resources in sync
+		status.Elapsed = prev.Elapsed
+	}
+	if isFailed(status.Phase) && attempts <= stepRetries(spec) {
+		next := metav1.NewTime(now.Add(stepBackoff(spec, attempts)))
+		status.Message = fmt.Sprintf("attempt %d is %s and will be retried at %s: %s",
+			attempts, status.Phase, next.Format(time.RFC3339), status.Message)
+		status.Phase = common.WorkflowStepPhaseRunning
+		status.NextRetryTime = &next
+	}
+	return status, operation, nil
+}
+
+// stepRetries returns the retries of the step, the default retries of its type are used if it doesn't specify.
+func stepRetries(spec oamcore.WorkflowStep) int {
+	if spec.Retries != nil {
+		return *spec.Retries
+	}
+	return defaultStepRetries[spec.Type]
+}
+
+// stepTimeout returns the timeout of the step, zero means the step never times out.
+func stepTimeout(spec oamcore.WorkflowStep) time.Duration {
+	if spec.Timeout == "" {
+		return 0
+	}
+	// the duration is validated by ValidateWorkflowSteps
+	timeout, _ := time.ParseDuration(spec.Timeout)
+	return timeout
+}
+
+// stepBackoff returns the duration to wait before the next attempt once the given attempt is failed,
+// the duration is doubled for each attempt and capped by MaxStepBackoff.
+func stepBackoff(spec oamcore.WorkflowStep, attempt int) time.Duration {
+	backoff := DefaultStepBackoff
+	if spec.Backoff != "" {
+		backoff, _ = time.ParseDuration(spec.Backoff)
+	}
+	for i := 1; i < attempt && backoff < MaxStepBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxStepBackoff {
+		backoff = MaxStepBackoff
+	}
+	return backoff
+}
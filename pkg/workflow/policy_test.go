@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+func TestRunStepWithRetries(t *testing.T) {
+	spec := oamcore.WorkflowStep{Name: "s1", Retries: intPtr(1), Backoff: "1m"}
+	runs := 0
+	var runPrev *common.WorkflowStepStatus
+	runner := &testRunner{name: "s1", run: func(_ *types.WorkflowContext, p *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+		runs++
+		runPrev = p
+		return common.WorkflowStepStatus{Name: "s1", Phase: common.WorkflowStepPhaseFailed, Message: "boom"}, nil, nil
+	}}
+
+	// the first failure is retried after backoff
+	status, _, err := runStep(context.Background(), runner, spec, &types.WorkflowContext{}, NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+	assert.Equal(t, 1, status.Attempts)
+	assert.NotNil(t, status.StartTime)
+	assert.NotNil(t, status.Elapsed)
+	assert.NotNil(t, status.NextRetryTime)
+	assert.Contains(t, status.Message, "boom")
+
+	// the step is not executed before the next retry time
+	status2, _, err := runStep(context.Background(), runner, spec, &types.WorkflowContext{}, NewVariables(nil), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, status.NextRetryTime, status2.NextRetryTime)
+
+	// the retry is executed from scratch and fails the step finally
+	past := metav1.NewTime(time.Now().Add(-time.Second))
+	status.NextRetryTime = &past
+	status3, _, err := runStep(context.Background(), runner, spec, &types.WorkflowContext{}, NewVariables(nil), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Nil(t, runPrev)
+	assert.Equal(t, common.WorkflowStepPhaseFailed, status3.Phase)
+	assert.Equal(t, 2, status3.Attempts)
+	assert.Nil(t, status3.NextRetryTime)
+	assert.Equal(t, status.StartTime, status3.StartTime)
+
+	// the failed step is not executed anymore
+	status4, _, err := runStep(context.Background(), runner, spec, &types.WorkflowContext{}, NewVariables(nil), &status3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, status3, status4)
+}
+
+func TestRunStepWithDefaultRetries(t *testing.T) {
+	RegisterDefaultStepRetries("flaky", 1)
+	defer delete(defaultStepRetries, "flaky")
+	runner := &testRunner{name: "s1", run: func(*types.WorkflowContext, *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+		return common.WorkflowStepStatus{Name: "s1", Phase: common.WorkflowStepPhaseFailed}, nil, nil
+	}}
+
+	// the step is retried by the default retries of its type
+	status, _, err := runStep(context.Background(), runner, oamcore.WorkflowStep{Name: "s1", Type: "flaky"},
+		&types.WorkflowContext{}, NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+	assert.NotNil(t, status.NextRetryTime)
+
+	// the retries of the step override the default ones
+	status, _, err = runStep(context.Background(), runner, oamcore.WorkflowStep{Name: "s1", Type: "flaky", Retries: intPtr(0)},
+		&types.WorkflowContext{}, NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseFailed, status.Phase)
+	assert.Nil(t, status.NextRetryTime)
+}
+
+func TestRunStepWithTimeout(t *testing.T) {
+	spec := oamcore.WorkflowStep{Name: "s1", Type: "test", Timeout: "1m"}
+	runs := 0
+	runner := &testRunner{name: "s1", run: func(*types.WorkflowContext, *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+		runs++
+		return common.WorkflowStepStatus{Name: "s1", Phase: common.WorkflowStepPhaseRunning}, nil, nil
+	}}
+	status, _, err := runStep(context.Background(), runner, spec, &types.WorkflowContext{}, NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+
+	start := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	status.StartTime = &start
+	status, _, err = runStep(context.Background(), runner, spec, &types.WorkflowContext{}, NewVariables(nil), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, common.WorkflowStepPhaseFailed, status.Phase)
+	assert.Equal(t, "step is timed out after 1m", status.Message)
+	assert.True(t, status.Elapsed.Duration >= 2*time.Minute)
+
+	// succeeded step doesn't time out when it's re-executed
+	status.Phase = common.WorkflowStepPhaseSucceeded
+	status, _, err = runStep(context.Background(), runner, spec, &types.WorkflowContext{}, NewVariables(nil), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, runs)
+}
+
+func TestStepBackoff(t *testing.T) {
+	assert.Equal(t, DefaultStepBackoff, stepBackoff(oamcore.WorkflowStep{}, 1))
+	assert.Equal(t, 2*DefaultStepBackoff, stepBackoff(oamcore.WorkflowStep{}, 2))
+	assert.Equal(t, 4*time.Second, stepBackoff(oamcore.WorkflowStep{Backoff: "1s"}, 3))
+	assert.Equal(t, MaxStepBackoff, stepBackoff(oamcore.WorkflowStep{Backoff: "1m"}, 20))
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/dispatch"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+const (
+	// StepApplyComponent applies the resources of a component of the application.
+	StepApplyComponent = "apply-component"
+	// StepApplyObject applies an arbitrary K8s object.
+	StepApplyObject = "apply-object"
+	// StepSuspend suspends the workflow until it's resumed.
+	StepSuspend = "suspend"
+	// StepWaitForCondition waits until an object has a condition with expected status.
+	StepWaitForCondition = "wait-for-condition"
+)
+
+func init() {
+	registerBuiltinStep(StepApplyComponent, applyComponent)
+	registerBuiltinStep(StepApplyObject, applyObject)
+	registerBuiltinStep(StepSuspend, suspend)
+	registerBuiltinStep(StepWaitForCondition, waitForCondition)
+}
+
+type applyComponentParams struct {
+	Component string `json:"component"`
+}
+
+func applyComponent(ctx context.Context, td *TaskDiscover, _ *types.WorkflowContext,
+	params map[string]interface{}, _ *common.WorkflowStepStatus) (*stepResult, error) {
+	p := &applyComponentParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+	if p.Component == "" {
+		return nil, errors.New("component must be specified")
+	}
+	if td.applyComponent == nil {
+		return nil, errors.New("applying component is not supported")
+	}
+	if err := td.applyComponent(ctx, p.Component); err != nil {
+		if dispatch.IsPendingDependencies(err) {
+			return &stepResult{phase: common.WorkflowStepPhaseRunning, message: err.Error()}, nil
+		}
+		return nil, errors.WithMessagef(err, "cannot apply component %s", p.Component)
+	}
+	return &stepResult{phase: common.WorkflowStepPhaseSucceeded}, nil
+}
+
+type applyObjectParams struct {
+	Value map[string]interface{} `json:"value"`
+}
+
+func applyObject(ctx context.Context, td *TaskDiscover, wctx *types.WorkflowContext,
+	params map[string]interface{}, _ *common.WorkflowStepStatus) (*stepResult, error) {
+	p := &applyObjectParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+	if len(p.Value) == 0 {
+		return nil, errors.New("value must be specified")
+	}
+	obj := &unstructured.Unstructured{Object: p.Value}
+	// only set app's namespace when namespace is unspecified
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(td.app.Namespace)
+	}
+	obj.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(td.app, v1beta1.ApplicationKindVersionKind),
+	})
+	oamutil.AddLabels(obj, map[string]string{
+		oam.LabelAppName:     wctx.AppName,
+		oam.LabelAppRevision: wctx.AppRevision,
+	})
+	if err := td.applicator.Apply(ctx, obj); err != nil {
+		return nil, errors.WithMessagef(err, "cannot apply object %s %s", obj.GetKind(), obj.GetName())
+	}
+	return &stepResult{phase: common.WorkflowStepPhaseSucceeded, object: obj.Object}, nil
+}
+
+func suspend(_ context.Context, _ *TaskDiscover, _ *types.WorkflowContext,
+	_ map[string]interface{}, prev *common.WorkflowStepStatus) (*stepResult, error) {
+	// a suspended workflow is not executed at all, so being executed again means it has been resumed
+	if prev != nil && (prev.Phase == common.WorkflowStepPhaseRunning || prev.Phase == common.WorkflowStepPhaseSucceeded) {
+		return &stepResult{phase: common.WorkflowStepPhaseSucceeded, message: "resumed"}, nil
+	}
+	return &stepResult{
+		phase:     common.WorkflowStepPhaseRunning,
+		message:   "suspended, waiting for resume",
+		operation: &workflow.Operation{Suspend: true},
+	}, nil
+}
+
+type waitForConditionParams struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Type is the type of the condition to wait for, default to Ready
+	Type string `json:"type,omitempty"`
+	// Status is the expected status of the condition, default to True
+	Status string `json:"status,omitempty"`
+}
+
+func waitForCondition(ctx context.Context, td *TaskDiscover, _ *types.WorkflowContext,
+	params map[string]interface{}, _ *common.WorkflowStepStatus) (*stepResult, error) {
+	p := &waitForConditionParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+	if p.APIVersion == "" || p.Kind == "" || p.Name == "" {
+		return nil, errors.New("apiVersion, kind and name must be specified")
+	}
+	if p.Namespace == "" {
+		p.Namespace = td.app.Namespace
+	}
+	if p.Type == "" {
+		p.Type = "Ready"
+	}
+	if p.Status == "" {
+		p.Status = string(corev1.ConditionTrue)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(p.APIVersion)
+	obj.SetKind(p.Kind)
+	if err := td.cli.Get(ctx, client.ObjectKey{Name: p.Name, Namespace: p.Namespace}, obj); err != nil {
+		if kerrors.IsNotFound(err) {
+			return &stepResult{
+				phase:   common.WorkflowStepPhaseRunning,
+				message: fmt.Sprintf("waiting for %s %s to be created", p.Kind, p.Name),
+			}, nil
+		}
+		return nil, errors.Wrapf(err, "cannot get %s %s", p.Kind, p.Name)
+	}
+	cond, found, err := utils.GetUnstructuredObjectStatusCondition(obj, p.Type)
+	if err != nil {
+		return nil, err
+	}
+	if !found || string(cond.Status) != p.Status {
+		return &stepResult{
+			phase:   common.WorkflowStepPhaseRunning,
+			message: fmt.Sprintf("waiting for condition %s of %s %s to be %s", p.Type, p.Kind, p.Name, p.Status),
+		}, nil
+	}
+	return &stepResult{phase: common.WorkflowStepPhaseSucceeded, object: obj.Object}, nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/dispatch"
+	"github.com/oam-dev/kubevela/pkg/cue/packages"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+var testApp = &v1beta1.Application{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "app",
+		Namespace: "default",
+		UID:       "app-uid",
+	},
+}
+
+var testWorkflowContext = &types.WorkflowContext{
+	AppName:     "app",
+	AppRevision: "app-v1",
+}
+
+type mockApplicator struct {
+	applied []*unstructured.Unstructured
+	// status is set to the applied object, just like the server response
+	status map[string]interface{}
+}
+
+func (m *mockApplicator) Apply(_ context.Context, obj runtime.Object, _ ...apply.ApplyOption) error {
+	u := obj.(*unstructured.Unstructured)
+	if m.status != nil {
+		u.Object["status"] = runtime.DeepCopyJSON(m.status)
+	}
+	m.applied = append(m.applied, u)
+	return nil
+}
+
+func TestGenerateTaskRunners(t *testing.T) {
+	td := NewTaskDiscover(testApp, nil, &mockApplicator{}, &packages.PackageDiscover{}, nil)
+	runners, err := td.GenerateTaskRunners([]*appfile.Workload{
+		{Name: "s1", Type: StepSuspend, FullTemplate: &appfile.Template{}},
+		{Name: "s2", Type: "custom", FullTemplate: &appfile.Template{TemplateStr: `output: {}`}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(runners))
+	assert.Equal(t, "s1", runners[0].Name())
+	assert.IsType(t, &builtinTask{}, runners[0])
+	assert.Equal(t, "s2", runners[1].Name())
+	assert.IsType(t, &customTask{}, runners[1])
+
+	_, err = td.GenerateTaskRunners([]*appfile.Workload{{Name: "s1", Type: "unknown", FullTemplate: &appfile.Template{}}})
+	assert.Error(t, err)
+	assert.True(t, IsBuiltinStep(StepApplyComponent))
+	assert.False(t, IsBuiltinStep("unknown"))
+}
+
+func TestApplyComponentStep(t *testing.T) {
+	var applied string
+	td := NewTaskDiscover(testApp, nil, &mockApplicator{}, nil, func(_ context.Context, compName string) error {
+		applied = compName
+		return nil
+	})
+	status, op, err := td.runBuiltinStep(context.Background(), "s1", StepApplyComponent, StepApplyComponent,
+		testWorkflowContext, map[string]interface{}{"component": "web"}, nil, workflow.NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, op)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Equal(t, "web", applied)
+
+	_, _, err = td.runBuiltinStep(context.Background(), "s1", StepApplyComponent, StepApplyComponent,
+		testWorkflowContext, map[string]interface{}{}, nil, workflow.NewVariables(nil), nil)
+	assert.Error(t, err)
+
+	// the step keeps running until the dependencies of the component are healthy
+	td = NewTaskDiscover(testApp, nil, &mockApplicator{}, nil, func(_ context.Context, compName string) error {
+		return &dispatch.PendingDependenciesError{Components: map[string][]string{compName: {"db"}}}
+	})
+	status, _, err = td.runBuiltinStep(context.Background(), "s1", StepApplyComponent, StepApplyComponent,
+		testWorkflowContext, map[string]interface{}{"component": "web"}, nil, workflow.NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+	assert.Contains(t, status.Message, "web (waiting for db)")
+}
+
+func TestApplyObjectStep(t *testing.T) {
+	applicator := &mockApplicator{}
+	td := NewTaskDiscover(testApp, nil, applicator, nil, nil)
+	status, _, err := td.runBuiltinStep(context.Background(), "s1", StepApplyObject, StepApplyObject,
+		testWorkflowContext, map[string]interface{}{
+			"value": map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "cm"},
+			},
+		}, nil, workflow.NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Equal(t, 1, len(applicator.applied))
+	obj := applicator.applied[0]
+	assert.Equal(t, "default", obj.GetNamespace())
+	assert.Equal(t, "app", obj.GetLabels()[oam.LabelAppName])
+	assert.Equal(t, "app-v1", obj.GetLabels()[oam.LabelAppRevision])
+	assert.Equal(t, "app", metav1.GetControllerOf(obj).Name)
+}
+
+func TestSuspendStep(t *testing.T) {
+	td := NewTaskDiscover(testApp, nil, &mockApplicator{}, nil, nil)
+	status, op, err := td.runBuiltinStep(context.Background(), "s1", StepSuspend, StepSuspend, testWorkflowContext, nil, nil, workflow.NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+	assert.True(t, op.Suspend)
+
+	status, op, err = td.runBuiltinStep(context.Background(), "s1", StepSuspend, StepSuspend, testWorkflowContext, nil, nil, workflow.NewVariables(nil), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Nil(t, op)
+}
+
+func TestWaitForConditionStep(t *testing.T) {
+	params := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"name":       "web",
+	}
+	testcases := map[string]struct {
+		get   test.MockGetFn
+		phase common.WorkflowStepPhase
+	}{
+		"not found": {
+			get:   test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "deployments"}, "web")),
+			phase: common.WorkflowStepPhaseRunning,
+		},
+		"condition not ready": {
+			get: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+				obj.(*unstructured.Unstructured).Object["status"] = map[string]interface{}{
+					"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False"}},
+				}
+				return nil
+			},
+			phase: common.WorkflowStepPhaseRunning,
+		},
+		"condition ready": {
+			get: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+				if key.Namespace != "default" || key.Name != "web" {
+					return kerrors.NewNotFound(schema.GroupResource{Resource: "deployments"}, key.Name)
+				}
+				obj.(*unstructured.Unstructured).Object["status"] = map[string]interface{}{
+					"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+				}
+				return nil
+			},
+			phase: common.WorkflowStepPhaseSucceeded,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			td := NewTaskDiscover(testApp, &test.MockClient{MockGet: tc.get}, &mockApplicator{}, nil, nil)
+			status, _, err := td.runBuiltinStep(context.Background(), "s1", StepWaitForCondition, StepWaitForCondition,
+				testWorkflowContext, params, nil, workflow.NewVariables(nil), nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.phase, status.Phase)
+		})
+	}
+}
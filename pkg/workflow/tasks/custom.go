@@ -0,0 +1,260 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	velacue "github.com/oam-dev/kubevela/pkg/cue"
+	"github.com/oam-dev/kubevela/pkg/cue/model"
+	"github.com/oam-dev/kubevela/pkg/cue/process"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+const (
+	// DoFieldName is the field in the template of WorkflowStepDefinition naming the built-in step to execute,
+	// all the other top-level fields except `parameter` and `context` are passed to the built-in step as parameters.
+	DoFieldName = "do"
+	// OutputFieldName is the field in the template of WorkflowStepDefinition containing the CR of a CR based step.
+	OutputFieldName = process.OutputFieldName
+	// ContextFieldName is the field in the template of WorkflowStepDefinition containing the context
+	ContextFieldName = "context"
+	// InputsFieldName is the field in the context containing the values of the inputs of the step
+	InputsFieldName = "inputs"
+)
+
+// customTask runs a step defined by a WorkflowStepDefinition.
+// The template is evaluated every time the step is executed, and it's either
+// - a built-in step if `do` is specified, or
+// - a CR based step which applies the `output` and waits for its `workflow-progress` condition.
+type customTask struct {
+	td       *TaskDiscover
+	name     string
+	typ      string
+	template string
+	params   map[string]interface{}
+	inputs   []v1beta1.WorkflowStepInput
+	outputs  []v1beta1.WorkflowStepOutput
+}
+
+func (t *customTask) Name() string {
+	return t.name
+}
+
+func (t *customTask) Run(ctx context.Context, wctx *types.WorkflowContext, vars *workflow.Variables,
+	prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, *workflow.Operation, error) {
+	status := common.WorkflowStepStatus{
+		Name: t.name,
+		Type: t.typ,
+	}
+	params, inputs, err := resolveInputs(t.inputs, t.params, vars)
+	if err != nil {
+		return status, nil, errors.WithMessagef(err, "workflow step %s", t.name)
+	}
+	inst, err := t.render(wctx, params, inputs)
+	if err != nil {
+		return status, nil, err
+	}
+
+	if do := inst.Lookup(DoFieldName); do.Exists() {
+		builtinType, err := do.String()
+		if err != nil {
+			return status, nil, errors.WithMessagef(err, "invalid %s of workflow step %s", DoFieldName, t.name)
+		}
+		params, err := builtinParams(inst)
+		if err != nil {
+			return status, nil, errors.WithMessagef(err, "invalid parameters of workflow step %s", t.name)
+		}
+		return t.td.runBuiltinStep(ctx, t.name, t.typ, builtinType, wctx, params, t.outputs, vars, prev)
+	}
+
+	output := inst.Lookup(OutputFieldName)
+	if !output.Exists() {
+		return status, nil, errors.Errorf("template of workflow step %s has neither %s nor %s", t.name, DoFieldName, OutputFieldName)
+	}
+	base, err := model.NewBase(output)
+	if err != nil {
+		return status, nil, errors.WithMessagef(err, "invalid output of workflow step %s", t.name)
+	}
+	obj, err := base.Unstructured()
+	if err != nil {
+		return status, nil, errors.WithMessagef(err, "evaluate output of workflow step %s", t.name)
+	}
+	if err := t.applyCR(ctx, obj, wctx); err != nil {
+		return status, nil, err
+	}
+	status, op, err := syncCRStatus(status, obj)
+	if err != nil {
+		return status, nil, err
+	}
+	if status.Phase == common.WorkflowStepPhaseSucceeded {
+		if err := exportOutputs(t.outputs, obj.Object, vars); err != nil {
+			return status, nil, errors.WithMessagef(err, "workflow step %s", t.name)
+		}
+	}
+	return status, op, nil
+}
+
+func (t *customTask) render(wctx *types.WorkflowContext, params, inputs map[string]interface{}) (*cue.Instance, error) {
+	bi := build.NewContext().NewInstance("", nil)
+	if err := bi.AddFile("-", t.template); err != nil {
+		return nil, errors.WithMessagef(err, "invalid cue template of workflow step %s", t.name)
+	}
+	var paramFile = velacue.ParameterTag + ": {}"
+	if params != nil {
+		bt, err := json.Marshal(params)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "marshal parameter of workflow step %s", t.name)
+		}
+		if string(bt) != "null" {
+			paramFile = fmt.Sprintf("%s: %s", velacue.ParameterTag, string(bt))
+		}
+	}
+	if err := bi.AddFile("parameter", paramFile); err != nil {
+		return nil, errors.WithMessagef(err, "invalid parameter of workflow step %s", t.name)
+	}
+	pCtx := process.NewContext(t.td.app.Namespace, t.name, wctx.AppName, wctx.AppRevision)
+	if err := bi.AddFile("context", pCtx.BaseContextFile()); err != nil {
+		return nil, errors.WithMessagef(err, "invalid context of workflow step %s", t.name)
+	}
+	if len(inputs) != 0 {
+		bt, err := json.Marshal(inputs)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "marshal inputs of workflow step %s", t.name)
+		}
+		if err := bi.AddFile("inputs", fmt.Sprintf("%s: %s: %s", ContextFieldName, InputsFieldName, string(bt))); err != nil {
+			return nil, errors.WithMessagef(err, "invalid inputs of workflow step %s", t.name)
+		}
+	}
+	inst, err := t.td.pd.ImportPackagesAndBuildInstance(bi)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid cue template of workflow step %s", t.name)
+	}
+	if err := inst.Value().Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid cue template of workflow step %s after merge parameter and context", t.name)
+	}
+	return inst, nil
+}
+
+// builtinParams collects the parameters of a built-in step from the evaluated template.
+func builtinParams(inst *cue.Instance) (map[string]interface{}, error) {
+	b, err := inst.Value().MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(b, &params); err != nil {
+		return nil, err
+	}
+	delete(params, DoFieldName)
+	delete(params, velacue.ParameterTag)
+	delete(params, ContextFieldName)
+	return params, nil
+}
+
+func (t *customTask) applyCR(ctx context.Context, obj *unstructured.Unstructured, wctx *types.WorkflowContext) error {
+	obj.SetName(t.name)
+	obj.SetNamespace(t.td.app.Namespace)
+	obj.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(t.td.app, v1beta1.ApplicationKindVersionKind),
+	})
+	oamutil.AddLabels(obj, map[string]string{
+		oam.LabelAppName:      wctx.AppName,
+		oam.LabelAppRevision:  wctx.AppRevision,
+		oam.LabelAppComponent: t.name,
+		oam.WorkloadTypeLabel: t.typ,
+	})
+	if err := addWorkflowContextToAnnotation(obj, wctx); err != nil {
+		return err
+	}
+	return errors.WithMessagef(t.td.applicator.Apply(ctx, obj), "cannot apply workflow step %s", t.name)
+}
+
+func addWorkflowContextToAnnotation(obj *unstructured.Unstructured, wc *types.WorkflowContext) error {
+	b, err := json.Marshal(wc)
+	if err != nil {
+		return err
+	}
+	m := map[string]string{
+		oam.AnnotationWorkflowContext: string(b),
+	}
+	obj.SetAnnotations(oamutil.MergeMapOverrideWithDst(m, obj.GetAnnotations()))
+	return nil
+}
+
+// syncCRStatus computes the phase of a CR based step from the `workflow-progress` condition of the applied object.
+func syncCRStatus(status common.WorkflowStepStatus, obj *unstructured.Unstructured) (common.WorkflowStepStatus, *workflow.Operation, error) {
+	status.ResourceRef = runtimev1alpha1.TypedReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+	}
+
+	cond, found, err := utils.GetUnstructuredObjectStatusCondition(obj, workflow.CondTypeWorkflowFinish)
+	if err != nil {
+		return status, nil, err
+	}
+
+	if !found || cond.Status != workflow.CondStatusTrue {
+		status.Phase = common.WorkflowStepPhaseRunning
+		return status, nil, nil
+	}
+
+	switch cond.Reason {
+	case workflow.CondReasonSucceeded:
+		observedG, err := parseGeneration(cond.Message)
+		if err != nil {
+			return status, nil, err
+		}
+		if observedG != obj.GetGeneration() {
+			status.Phase = common.WorkflowStepPhaseRunning
+		} else {
+			status.Phase = common.WorkflowStepPhaseSucceeded
+		}
+	case workflow.CondReasonFailed:
+		status.Phase = common.WorkflowStepPhaseFailed
+		status.Message = cond.Message
+	case workflow.CondReasonStopped:
+		status.Phase = common.WorkflowStepPhaseStopped
+		status.Message = cond.Message
+	default:
+		status.Phase = common.WorkflowStepPhaseRunning
+	}
+	return status, nil, nil
+}
+
+func parseGeneration(message string) (int64, error) {
+	m := &workflow.SucceededMessage{}
+	err := json.Unmarshal([]byte(message), m)
+	return m.ObservedGeneration, err
+}
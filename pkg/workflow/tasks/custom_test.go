@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/cue/packages"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+const crStepTemplate = `
+output: {
+	apiVersion: "example.com/v1"
+	kind:       "Deployer"
+	metadata: generation: 1
+	spec: image: parameter.image
+}
+parameter: image: string
+`
+
+func TestCustomTaskCR(t *testing.T) {
+	succeededMessage, err := json.Marshal(&workflow.SucceededMessage{ObservedGeneration: 1})
+	assert.NoError(t, err)
+	unmatchedMessage, err := json.Marshal(&workflow.SucceededMessage{ObservedGeneration: 0})
+	assert.NoError(t, err)
+
+	cond := func(reason, message string) map[string]interface{} {
+		return map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{
+				"type":    workflow.CondTypeWorkflowFinish,
+				"reason":  reason,
+				"message": message,
+				"status":  workflow.CondStatusTrue,
+			}},
+		}
+	}
+
+	testcases := map[string]struct {
+		status map[string]interface{}
+		phase  common.WorkflowStepPhase
+	}{
+		"no condition should be running": {
+			phase: common.WorkflowStepPhaseRunning,
+		},
+		"succeeded": {
+			status: cond(workflow.CondReasonSucceeded, string(succeededMessage)),
+			phase:  common.WorkflowStepPhaseSucceeded,
+		},
+		"succeeded with unmatched generation should be running": {
+			status: cond(workflow.CondReasonSucceeded, string(unmatchedMessage)),
+			phase:  common.WorkflowStepPhaseRunning,
+		},
+		"condition without reason should be running": {
+			status: cond("", ""),
+			phase:  common.WorkflowStepPhaseRunning,
+		},
+		"stopped": {
+			status: cond(workflow.CondReasonStopped, "stop"),
+			phase:  common.WorkflowStepPhaseStopped,
+		},
+		"failed": {
+			status: cond(workflow.CondReasonFailed, "fail"),
+			phase:  common.WorkflowStepPhaseFailed,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			applicator := &mockApplicator{status: tc.status}
+			td := NewTaskDiscover(testApp, nil, applicator, &packages.PackageDiscover{}, nil)
+			runners, err := td.GenerateTaskRunners([]*appfile.Workload{{
+				Name:         "deploy",
+				Type:         "deployer",
+				FullTemplate: &appfile.Template{TemplateStr: crStepTemplate},
+				Params:       map[string]interface{}{"image": "nginx"},
+			}})
+			assert.NoError(t, err)
+			status, op, err := runners[0].Run(context.Background(), testWorkflowContext, workflow.NewVariables(nil), nil)
+			assert.NoError(t, err)
+			assert.Nil(t, op)
+			assert.Equal(t, tc.phase, status.Phase)
+			assert.Equal(t, "deploy", status.ResourceRef.Name)
+
+			assert.Equal(t, 1, len(applicator.applied))
+			obj := applicator.applied[0]
+			assert.Equal(t, "deploy", obj.GetName())
+			assert.Equal(t, "default", obj.GetNamespace())
+			assert.Equal(t, "deploy", obj.GetLabels()[oam.LabelAppComponent])
+			assert.Equal(t, "deployer", obj.GetLabels()[oam.WorkloadTypeLabel])
+			image, _, _ := unstructured.NestedString(obj.Object, "spec", "image")
+			assert.Equal(t, "nginx", image)
+			wctx := &types.WorkflowContext{}
+			assert.NoError(t, json.Unmarshal([]byte(obj.GetAnnotations()[oam.AnnotationWorkflowContext]), wctx))
+			assert.Equal(t, "app-v1", wctx.AppRevision)
+		})
+	}
+}
+
+func TestCustomTaskBuiltin(t *testing.T) {
+	applicator := &mockApplicator{}
+	td := NewTaskDiscover(testApp, nil, applicator, &packages.PackageDiscover{}, nil)
+	runners, err := td.GenerateTaskRunners([]*appfile.Workload{{
+		Name: "apply-cm",
+		Type: "apply-configmap",
+		FullTemplate: &appfile.Template{TemplateStr: `
+do: "apply-object"
+value: {
+	apiVersion: "v1"
+	kind:       "ConfigMap"
+	metadata: name: parameter.name
+	data: key: context.appRevision
+}
+parameter: name: string
+`},
+		Params: map[string]interface{}{"name": "cm"},
+	}})
+	assert.NoError(t, err)
+	status, _, err := runners[0].Run(context.Background(), testWorkflowContext, workflow.NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Equal(t, "apply-configmap", status.Type)
+	assert.Equal(t, 1, len(applicator.applied))
+	assert.Equal(t, "cm", applicator.applied[0].GetName())
+	data, _, _ := unstructured.NestedString(applicator.applied[0].Object, "data", "key")
+	assert.Equal(t, "app-v1", data)
+
+	runners, err = td.GenerateTaskRunners([]*appfile.Workload{{
+		Name:         "invalid",
+		Type:         "invalid",
+		FullTemplate: &appfile.Template{TemplateStr: `do: "unknown"`},
+	}})
+	assert.NoError(t, err)
+	_, _, err = runners[0].Run(context.Background(), testWorkflowContext, workflow.NewVariables(nil), nil)
+	assert.Error(t, err)
+}
+
+func TestCustomTaskInputsAndOutputs(t *testing.T) {
+	app := testApp.DeepCopy()
+	app.Spec.Workflow = []v1beta1.WorkflowStep{{
+		Name:    "deploy",
+		Type:    "deployer",
+		Inputs:  []v1beta1.WorkflowStepInput{{From: "digest", ParameterKey: "image"}, {From: "endpoint"}},
+		Outputs: []v1beta1.WorkflowStepOutput{{Name: "url", ExportKey: "status.url"}},
+	}}
+	succeededMessage, err := json.Marshal(&workflow.SucceededMessage{ObservedGeneration: 1})
+	assert.NoError(t, err)
+	applicator := &mockApplicator{status: map[string]interface{}{
+		"url": "http://deploy.test",
+		"conditions": []interface{}{map[string]interface{}{
+			"type":    workflow.CondTypeWorkflowFinish,
+			"reason":  workflow.CondReasonSucceeded,
+			"message": string(succeededMessage),
+			"status":  workflow.CondStatusTrue,
+		}},
+	}}
+	td := NewTaskDiscover(app, nil, applicator, &packages.PackageDiscover{}, nil)
+	runners, err := td.GenerateTaskRunners([]*appfile.Workload{{
+		Name: "deploy",
+		Type: "deployer",
+		FullTemplate: &appfile.Template{TemplateStr: crStepTemplate + `
+output: spec: db: context.inputs.endpoint
+`},
+		Params: map[string]interface{}{},
+	}})
+	assert.NoError(t, err)
+
+	vars := workflow.NewVariables(map[string]interface{}{"digest": "nginx@sha256:abc", "endpoint": "db.test:3306"})
+	status, _, err := runners[0].Run(context.Background(), testWorkflowContext, vars, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	image, _, _ := unstructured.NestedString(applicator.applied[0].Object, "spec", "image")
+	assert.Equal(t, "nginx@sha256:abc", image)
+	db, _, _ := unstructured.NestedString(applicator.applied[0].Object, "spec", "db")
+	assert.Equal(t, "db.test:3306", db)
+	url, ok := vars.Get("url")
+	assert.True(t, ok)
+	assert.Equal(t, "http://deploy.test", url)
+
+	// missing input should fail the step
+	_, _, err = runners[0].Run(context.Background(), testWorkflowContext, workflow.NewVariables(nil), nil)
+	assert.Error(t, err)
+}
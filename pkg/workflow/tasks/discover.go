@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/cue/packages"
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// ComponentApplier applies the rendered resources of the named component of the application.
+type ComponentApplier func(ctx context.Context, compName string) error
+
+// TaskDiscover generates the runners of workflow steps.
+// A step whose type has a WorkflowStepDefinition is evaluated from the CUE template of the definition, otherwise
+// the type must be one of the built-in steps which are executed inside the application controller.
+type TaskDiscover struct {
+	app            *v1beta1.Application
+	cli            client.Client
+	applicator     apply.Applicator
+	pd             *packages.PackageDiscover
+	applyComponent ComponentApplier
+}
+
+// NewTaskDiscover creates a TaskDiscover for the given application.
+func NewTaskDiscover(app *v1beta1.Application, cli client.Client, applicator apply.Applicator,
+	pd *packages.PackageDiscover, applyComponent ComponentApplier) *TaskDiscover {
+	return &TaskDiscover{
+		app:            app,
+		cli:            cli,
+		applicator:     applicator,
+		pd:             pd,
+		applyComponent: applyComponent,
+	}
+}
+
+// GenerateTaskRunners generates a runner for each parsed workflow step, in the same order as the steps.
+func (td *TaskDiscover) GenerateTaskRunners(steps []*appfile.Workload) ([]workflow.TaskRunner, error) {
+	specs := make(map[string]v1beta1.WorkflowStep, len(td.app.Spec.Workflow))
+	for _, spec := range td.app.Spec.Workflow {
+		specs[spec.Name] = spec
+	}
+	runners := make([]workflow.TaskRunner, 0, len(steps))
+	for _, step := range steps {
+		spec := specs[step.Name]
+		if step.FullTemplate == nil || step.FullTemplate.TemplateStr == "" {
+			if _, ok := builtinSteps[step.Type]; !ok {
+				return nil, errors.Errorf("type %q of workflow step %s is neither a WorkflowStepDefinition nor a built-in step",
+					step.Type, step.Name)
+			}
+			runners = append(runners, &builtinTask{
+				td:      td,
+				name:    step.Name,
+				typ:     step.Type,
+				params:  step.Params,
+				inputs:  spec.Inputs,
+				outputs: spec.Outputs,
+			})
+			continue
+		}
+		runners = append(runners, &customTask{
+			td:       td,
+			name:     step.Name,
+			typ:      step.Type,
+			template: step.FullTemplate.TemplateStr,
+			params:   step.Params,
+			inputs:   spec.Inputs,
+			outputs:  spec.Outputs,
+		})
+	}
+	return runners, nil
+}
+
+// stepResult is the result of executing a built-in step once.
+type stepResult struct {
+	phase     common.WorkflowStepPhase
+	message   string
+	operation *workflow.Operation
+	// object is the object applied or watched by the step, which the outputs of the step are exported from
+	object map[string]interface{}
+}
+
+// builtinStep executes a built-in step with the given parameters.
+type builtinStep func(ctx context.Context, td *TaskDiscover, wctx *types.WorkflowContext,
+	params map[string]interface{}, prev *common.WorkflowStepStatus) (*stepResult, error)
+
+var builtinSteps = map[string]builtinStep{}
+
+func registerBuiltinStep(typ string, step builtinStep) {
+	builtinSteps[typ] = step
+}
+
+// IsBuiltinStep checks whether the given step type is a built-in step.
+func IsBuiltinStep(typ string) bool {
+	_, ok := builtinSteps[typ]
+	return ok
+}
+
+// builtinTask runs a built-in step with the properties of the step as parameters.
+type builtinTask struct {
+	td      *TaskDiscover
+	name    string
+	typ     string
+	params  map[string]interface{}
+	inputs  []v1beta1.WorkflowStepInput
+	outputs []v1beta1.WorkflowStepOutput
+}
+
+func (t *builtinTask) Name() string {
+	return t.name
+}
+
+func (t *builtinTask) Run(ctx context.Context, wctx *types.WorkflowContext, vars *workflow.Variables,
+	prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, *workflow.Operation, error) {
+	status := common.WorkflowStepStatus{
+		Name: t.name,
+		Type: t.typ,
+	}
+	params, _, err := resolveInputs(t.inputs, t.params, vars)
+	if err != nil {
+		return status, nil, errors.WithMessagef(err, "workflow step %s", t.name)
+	}
+	return t.td.runBuiltinStep(ctx, t.name, t.typ, t.typ, wctx, params, t.outputs, vars, prev)
+}
+
+func (td *TaskDiscover) runBuiltinStep(ctx context.Context, name, typ, builtinType string, wctx *types.WorkflowContext,
+	params map[string]interface{}, outputs []v1beta1.WorkflowStepOutput, vars *workflow.Variables,
+	prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, *workflow.Operation, error) {
+	status := common.WorkflowStepStatus{
+		Name: name,
+		Type: typ,
+	}
+	step, ok := builtinSteps[builtinType]
+	if !ok {
+		return status, nil, errors.Errorf("workflow step %s: unknown built-in step %q", name, builtinType)
+	}
+	res, err := step(ctx, td, wctx, params, prev)
+	if err != nil {
+		return status, nil, errors.WithMessagef(err, "workflow step %s", name)
+	}
+	status.Phase = res.phase
+	status.Message = res.message
+	if status.Phase == common.WorkflowStepPhaseSucceeded {
+		if err := exportOutputs(outputs, res.object, vars); err != nil {
+			return status, nil, errors.WithMessagef(err, "workflow step %s", name)
+		}
+	}
+	return status, res.operation, nil
+}
+
+func decodeParams(params map[string]interface{}, v interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(json.Unmarshal(b, v), "invalid parameters")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// resolveInputs returns the parameters with the inputs of the step injected, and the values of all inputs.
+// The given parameters are not modified.
+func resolveInputs(inputs []v1beta1.WorkflowStepInput, params map[string]interface{},
+	vars *workflow.Variables) (map[string]interface{}, map[string]interface{}, error) {
+	if len(inputs) == 0 {
+		return params, nil, nil
+	}
+	resolved := map[string]interface{}{}
+	if err := decodeParams(params, &resolved); err != nil {
+		return nil, nil, err
+	}
+	values := make(map[string]interface{}, len(inputs))
+	for _, input := range inputs {
+		value, ok := vars.Get(input.From)
+		if !ok {
+			return nil, nil, errors.Errorf("variable %s of input is not found, it must be exported by a previous step", input.From)
+		}
+		values[input.From] = value
+		if input.ParameterKey == "" {
+			continue
+		}
+		if err := setParameter(resolved, input.ParameterKey, value); err != nil {
+			return nil, nil, errors.WithMessagef(err, "cannot set input %s to parameter %s", input.From, input.ParameterKey)
+		}
+	}
+	return resolved, values, nil
+}
+
+// setParameter sets the value into the dot separated path of the parameters, the missing fields are created.
+func setParameter(params map[string]interface{}, path string, value interface{}) error {
+	fields := strings.Split(path, ".")
+	cur := params
+	for i, f := range fields {
+		if f == "" {
+			return errors.Errorf("invalid parameter key %q", path)
+		}
+		if i == len(fields)-1 {
+			cur[f] = value
+			return nil
+		}
+		next, ok := cur[f]
+		if !ok || next == nil {
+			next = map[string]interface{}{}
+			cur[f] = next
+		}
+		m, ok := next.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("field %s of parameter key %q is not an object", f, path)
+		}
+		cur = m
+	}
+	return nil
+}
+
+// exportOutputs evaluates the outputs of the step against the object produced by the step and sets them as variables.
+func exportOutputs(outputs []v1beta1.WorkflowStepOutput, obj map[string]interface{}, vars *workflow.Variables) error {
+	if len(outputs) == 0 {
+		return nil
+	}
+	if obj == nil {
+		return errors.New("the step doesn't produce any object to export outputs from")
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	for _, output := range outputs {
+		value, err := lookupValue(string(b), output.ExportKey)
+		if err != nil {
+			return errors.WithMessagef(err, "cannot export output %s", output.Name)
+		}
+		vars.Set(output.Name, value)
+	}
+	return nil
+}
+
+// lookupValue evaluates the CUE path, e.g. `status.endpoint` or `status.addresses[0].ip`, against the JSON object.
+func lookupValue(object string, path string) (interface{}, error) {
+	var r cue.Runtime
+	inst, err := r.Compile("-", fmt.Sprintf("object: %s\nvalue: object.%s\n", object, path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid export key %q", path)
+	}
+	b, err := inst.Lookup("value").MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot find %q", path)
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+func TestResolveInputs(t *testing.T) {
+	vars := workflow.NewVariables(map[string]interface{}{
+		"endpoint": "db.test:3306",
+		"digest":   "sha256:abc",
+	})
+	params := map[string]interface{}{
+		"image": "nginx",
+		"env":   map[string]interface{}{"mode": "prod"},
+	}
+	resolved, inputs, err := resolveInputs([]v1beta1.WorkflowStepInput{
+		{From: "endpoint", ParameterKey: "env.db"},
+		{From: "digest"},
+	}, params, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"image": "nginx",
+		"env":   map[string]interface{}{"mode": "prod", "db": "db.test:3306"},
+	}, resolved)
+	assert.Equal(t, map[string]interface{}{"endpoint": "db.test:3306", "digest": "sha256:abc"}, inputs)
+	// the original parameters are not modified
+	assert.Equal(t, map[string]interface{}{"mode": "prod"}, params["env"])
+
+	_, _, err = resolveInputs([]v1beta1.WorkflowStepInput{{From: "unknown", ParameterKey: "x"}}, params, vars)
+	assert.Error(t, err)
+
+	_, _, err = resolveInputs([]v1beta1.WorkflowStepInput{{From: "endpoint", ParameterKey: "image.name"}}, params, vars)
+	assert.Error(t, err)
+}
+
+func TestExportOutputs(t *testing.T) {
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "db"},
+		"status": map[string]interface{}{
+			"endpoint":  "db.test:3306",
+			"addresses": []interface{}{map[string]interface{}{"ip": "10.0.0.1"}},
+			"replicas":  int64(3),
+		},
+	}
+	vars := workflow.NewVariables(nil)
+	assert.NoError(t, exportOutputs([]v1beta1.WorkflowStepOutput{
+		{Name: "endpoint", ExportKey: "status.endpoint"},
+		{Name: "ip", ExportKey: "status.addresses[0].ip"},
+		{Name: "replicas", ExportKey: "status.replicas"},
+		{Name: "status", ExportKey: "status"},
+	}, obj, vars))
+	endpoint, _ := vars.Get("endpoint")
+	assert.Equal(t, "db.test:3306", endpoint)
+	ip, _ := vars.Get("ip")
+	assert.Equal(t, "10.0.0.1", ip)
+	replicas, _ := vars.Get("replicas")
+	assert.Equal(t, float64(3), replicas)
+	status, _ := vars.Get("status")
+	assert.Equal(t, "db.test:3306", status.(map[string]interface{})["endpoint"])
+
+	assert.Error(t, exportOutputs([]v1beta1.WorkflowStepOutput{{Name: "x", ExportKey: "status.notExist"}}, obj, vars))
+	assert.Error(t, exportOutputs([]v1beta1.WorkflowStepOutput{{Name: "x", ExportKey: "status.endpoint"}}, nil, vars))
+	assert.NoError(t, exportOutputs(nil, nil, vars))
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/builtin"
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+	"github.com/oam-dev/kubevela/pkg/cue/process"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// StepNotification posts a message rendered from the workflow context to a webhook, Slack or DingTalk.
+const StepNotification = "notification"
+
+const (
+	// NotificationWebhook posts the payload to a generic webhook
+	NotificationWebhook = "webhook"
+	// NotificationSlack posts the message to a Slack-compatible incoming webhook
+	NotificationSlack = "slack"
+	// NotificationDingTalk posts the message to a DingTalk-compatible robot webhook
+	NotificationDingTalk = "dingtalk"
+
+	defaultNotificationMessage = "Workflow of application {{ .context.appName }} revision {{ .context.appRevision }} " +
+		"in namespace {{ .context.namespace }} reached step {{ .context.stepIndex }}"
+	defaultNotificationTimeout = 10 * time.Second
+	// defaultNotificationRetries is the retries of a notification step which doesn't specify its retries, as the
+	// endpoints may be unavailable temporarily
+	defaultNotificationRetries = 3
+)
+
+func init() {
+	registerBuiltinStep(StepNotification, notification)
+	workflow.RegisterDefaultStepRetries(StepNotification, defaultNotificationRetries)
+}
+
+type notificationParams struct {
+	// Type is the type of the endpoint, one of webhook, slack and dingtalk, default to webhook
+	Type string `json:"type,omitempty"`
+	// URL is the address of the endpoint
+	URL string `json:"url,omitempty"`
+	// URLSecretRef refers to the key of a Secret in the namespace of the application storing the URL,
+	// as the URL of Slack or DingTalk webhook contains the credential
+	URLSecretRef *corev1.SecretKeySelector `json:"urlSecretRef,omitempty"`
+	// Message is the Go template of the message, which can refer to the workflow context by `.context`,
+	// e.g. `{{ .context.appName }} is deployed`
+	Message string `json:"message,omitempty"`
+	// Payload is the Go template of the request body posted to a generic webhook, which can refer to the
+	// rendered message by `.message` and the workflow context by `.context`. The `json` function quotes a value as JSON.
+	// Default to a JSON object with the message and the workflow context.
+	Payload string `json:"payload,omitempty"`
+	// Headers are the extra headers of the request
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timeout is the timeout of each request, default to 10s
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func notification(ctx context.Context, td *TaskDiscover, wctx *types.WorkflowContext,
+	params map[string]interface{}, prev *common.WorkflowStepStatus) (*stepResult, error) {
+	// the notification is only sent once for an app revision even if the step is executed again
+	if prev != nil && prev.Phase == common.WorkflowStepPhaseSucceeded {
+		return &stepResult{phase: common.WorkflowStepPhaseSucceeded, message: prev.Message}, nil
+	}
+	p := &notificationParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+	url, err := notificationURL(ctx, td, p)
+	if err != nil {
+		return nil, err
+	}
+	timeout := defaultNotificationTimeout
+	if p.Timeout != "" {
+		if timeout, err = time.ParseDuration(p.Timeout); err != nil {
+			return nil, errors.Wrapf(err, "invalid timeout %q", p.Timeout)
+		}
+	}
+	body, err := renderNotification(p, td.app.Namespace, wctx)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range p.Headers {
+		headers[k] = v
+	}
+
+	// the notification is posted once per reconcile, a failed one is retried by the retries and backoff of the step
+	resp, err := postNotification(ctx, p.Type, url, body, headers, timeout)
+	if err != nil {
+		return &stepResult{
+			phase:   common.WorkflowStepPhaseFailed,
+			message: fmt.Sprintf("cannot send notification: %v", err),
+		}, nil
+	}
+	return &stepResult{
+		phase:   common.WorkflowStepPhaseSucceeded,
+		message: "notification is sent",
+		object: map[string]interface{}{
+			"statusCode": resp.StatusCode,
+			"body":       resp.Body,
+		},
+	}, nil
+}
+
+func notificationURL(ctx context.Context, td *TaskDiscover, p *notificationParams) (string, error) {
+	if p.URLSecretRef == nil {
+		if p.URL == "" {
+			return "", errors.New("url or urlSecretRef must be specified")
+		}
+		return p.URL, nil
+	}
+	secret := &corev1.Secret{}
+	if err := td.cli.Get(ctx, client.ObjectKey{Namespace: td.app.Namespace, Name: p.URLSecretRef.Name}, secret); err != nil {
+		return "", errors.Wrapf(err, "cannot get secret %s", p.URLSecretRef.Name)
+	}
+	url := string(secret.Data[p.URLSecretRef.Key])
+	if url == "" {
+		return "", errors.Errorf("key %s is not found in secret %s", p.URLSecretRef.Key, p.URLSecretRef.Name)
+	}
+	return url, nil
+}
+
+// renderNotification renders the request body of the notification according to the type of the endpoint.
+func renderNotification(p *notificationParams, namespace string, wctx *types.WorkflowContext) (string, error) {
+	data := map[string]interface{}{
+		"context": map[string]interface{}{
+			process.ContextAppName:     wctx.AppName,
+			process.ContextAppRevision: wctx.AppRevision,
+			process.ContextNamespace:   namespace,
+			"stepIndex":                wctx.WorkflowIndex,
+		},
+	}
+	message := p.Message
+	if message == "" {
+		message = defaultNotificationMessage
+	}
+	msg, err := renderTemplate("message", message, data)
+	if err != nil {
+		return "", err
+	}
+	data["message"] = msg
+
+	var payload interface{}
+	switch p.Type {
+	case "", NotificationWebhook:
+		if p.Payload != "" {
+			return renderTemplate("payload", p.Payload, data)
+		}
+		payload = data
+	case NotificationSlack:
+		payload = map[string]interface{}{"text": msg}
+	case NotificationDingTalk:
+		payload = map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]interface{}{"content": msg},
+		}
+	default:
+		return "", errors.Errorf("unknown notification type %q", p.Type)
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func renderTemplate(name, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid %s template", name)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", errors.Wrapf(err, "cannot render %s template", name)
+	}
+	return buf.String(), nil
+}
+
+type notificationResponse struct {
+	StatusCode int
+	Body       string
+}
+
+// postNotification posts the body by the built-in http task and checks the response.
+func postNotification(ctx context.Context, typ, url, body string, headers map[string]string,
+	timeout time.Duration) (*notificationResponse, error) {
+	req, err := json.Marshal(map[string]interface{}{
+		"method":  "POST",
+		"url":     url,
+		"timeout": timeout.String(),
+		"request": map[string]interface{}{
+			"body":   body,
+			"header": headers,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", req)
+	if err != nil {
+		return nil, err
+	}
+	got, err := builtin.RunTaskByKey("http", cue.Value{}, &registry.Meta{Context: ctx, Obj: inst.Value()})
+	if err != nil {
+		return nil, err
+	}
+	result, ok := got.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid response of http task")
+	}
+	resp := &notificationResponse{}
+	resp.StatusCode, _ = result["statusCode"].(int)
+	resp.Body, _ = result["body"].(string)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, errors.Errorf("unexpected status code %d: %s", resp.StatusCode, resp.Body)
+	}
+	if typ == NotificationDingTalk {
+		// DingTalk reports errors by errcode in the response with status code 200
+		dt := struct {
+			ErrCode int    `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}{}
+		if err := json.Unmarshal([]byte(resp.Body), &dt); err == nil && dt.ErrCode != 0 {
+			return resp, errors.Errorf("errcode %d: %s", dt.ErrCode, dt.ErrMsg)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+type notificationServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+	headers  []http.Header
+	// failures is the number of requests to fail before succeeding
+	failures int
+	response string
+}
+
+func newNotificationServer(failures int, response string) *notificationServer {
+	s := &notificationServer{failures: failures, response: response}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, string(b))
+		s.headers = append(s.headers, r.Header)
+		if len(s.requests) <= s.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(s.response))
+	}))
+	return s
+}
+
+func TestNotification(t *testing.T) {
+	testcases := map[string]struct {
+		params       notificationParams
+		failures     int
+		response     string
+		wantPhase    common.WorkflowStepPhase
+		wantRequests int
+		wantBody     string
+	}{
+		"webhook with default payload": {
+			params:       notificationParams{Message: "{{ .context.appName }} is deployed"},
+			wantPhase:    common.WorkflowStepPhaseSucceeded,
+			wantRequests: 1,
+			wantBody:     `{"context":{"appName":"app","appRevision":"app-v1","namespace":"default","stepIndex":0},"message":"app is deployed"}`,
+		},
+		"webhook with payload template": {
+			params: notificationParams{
+				Type:    NotificationWebhook,
+				Message: "revision {{ .context.appRevision }}",
+				Payload: `{"title": {{ json .message }}, "app": "{{ .context.appName }}"}`,
+			},
+			wantPhase:    common.WorkflowStepPhaseSucceeded,
+			wantRequests: 1,
+			wantBody:     `{"title":"revision app-v1","app":"app"}`,
+		},
+		"slack": {
+			params:       notificationParams{Type: NotificationSlack, Message: "{{ .context.appName }} is deployed"},
+			response:     "ok",
+			wantPhase:    common.WorkflowStepPhaseSucceeded,
+			wantRequests: 1,
+			wantBody:     `{"text":"app is deployed"}`,
+		},
+		"dingtalk": {
+			params:       notificationParams{Type: NotificationDingTalk, Message: "{{ .context.appName }} is deployed"},
+			response:     `{"errcode":0,"errmsg":"ok"}`,
+			wantPhase:    common.WorkflowStepPhaseSucceeded,
+			wantRequests: 1,
+			wantBody:     `{"msgtype":"text","text":{"content":"app is deployed"}}`,
+		},
+		"dingtalk with error code": {
+			params:       notificationParams{Type: NotificationDingTalk},
+			response:     `{"errcode":310000,"errmsg":"keywords not in content"}`,
+			wantPhase:    common.WorkflowStepPhaseFailed,
+			wantRequests: 1,
+		},
+		"failed without retrying in the step": {
+			params:       notificationParams{Type: NotificationSlack},
+			failures:     1,
+			wantPhase:    common.WorkflowStepPhaseFailed,
+			wantRequests: 1,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			s := newNotificationServer(tc.failures, tc.response)
+			defer s.Close()
+			tc.params.URL = s.URL
+			b, err := json.Marshal(tc.params)
+			assert.NoError(t, err)
+			params := map[string]interface{}{}
+			assert.NoError(t, json.Unmarshal(b, &params))
+
+			td := NewTaskDiscover(testApp, nil, &mockApplicator{}, nil, nil)
+			status, _, err := td.runBuiltinStep(context.Background(), "notify", StepNotification, StepNotification,
+				testWorkflowContext, params, nil, workflow.NewVariables(nil), nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantPhase, status.Phase, status.Message)
+			assert.Equal(t, tc.wantRequests, len(s.requests))
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, s.requests[0])
+			}
+			assert.Equal(t, "application/json", s.headers[0].Get("Content-Type"))
+		})
+	}
+}
+
+func TestNotificationURLFromSecret(t *testing.T) {
+	s := newNotificationServer(0, "ok")
+	defer s.Close()
+	cli := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+		secret := obj.(*corev1.Secret)
+		secret.Data = map[string][]byte{"url": []byte(s.URL)}
+		return nil
+	}}
+	td := NewTaskDiscover(testApp, cli, &mockApplicator{}, nil, nil)
+	params := map[string]interface{}{
+		"type":         NotificationSlack,
+		"urlSecretRef": map[string]interface{}{"name": "slack", "key": "url"},
+	}
+	status, _, err := td.runBuiltinStep(context.Background(), "notify", StepNotification, StepNotification,
+		testWorkflowContext, params, nil, workflow.NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Equal(t, 1, len(s.requests))
+
+	// the notification is not sent again once succeeded
+	status, _, err = td.runBuiltinStep(context.Background(), "notify", StepNotification, StepNotification,
+		testWorkflowContext, params, nil, workflow.NewVariables(nil), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Equal(t, 1, len(s.requests))
+}
+
+func TestNotificationRetriedByStep(t *testing.T) {
+	s := newNotificationServer(1, "ok")
+	defer s.Close()
+	td := NewTaskDiscover(testApp, nil, &mockApplicator{}, nil, nil)
+	params := map[string]interface{}{"type": NotificationSlack, "url": s.URL}
+	status, _, err := td.runBuiltinStep(context.Background(), "notify", StepNotification, StepNotification,
+		testWorkflowContext, params, nil, workflow.NewVariables(nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseFailed, status.Phase)
+	assert.Equal(t, 1, len(s.requests))
+
+	// the failed notification is posted again once the step is retried
+	status, _, err = td.runBuiltinStep(context.Background(), "notify", StepNotification, StepNotification,
+		testWorkflowContext, params, nil, workflow.NewVariables(nil), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Equal(t, 2, len(s.requests))
+}
+
+func TestNotificationRetriedByDefault(t *testing.T) {
+	s := newNotificationServer(1, "ok")
+	defer s.Close()
+	app := testApp.DeepCopy()
+	// the step specifies no retries
+	app.Spec.Workflow = []v1beta1.WorkflowStep{{Name: "notify", Type: StepNotification}}
+	cli := fake.NewFakeClientWithScheme(velacommon.Scheme, app)
+	td := NewTaskDiscover(app, cli, &mockApplicator{}, nil, nil)
+	runners, err := td.GenerateTaskRunners([]*appfile.Workload{{Name: "notify", Type: StepNotification,
+		Params: map[string]interface{}{"type": NotificationSlack, "url": s.URL}}})
+	assert.NoError(t, err)
+
+	// the endpoint returns 5xx, the step is retried after the default backoff instead of being failed
+	done, err := workflow.NewWorkflow(app, cli).ExecuteSteps(context.Background(), "app-v1", runners)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, 1, len(s.requests))
+	status := app.Status.Workflow.Steps[0]
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+	assert.NotNil(t, status.NextRetryTime)
+	assert.True(t, status.NextRetryTime.Sub(time.Now()) > workflow.DefaultStepBackoff/2)
+
+	// the endpoint returns 2xx once the step is retried
+	past := metav1.NewTime(time.Now().Add(-time.Second))
+	app.Status.Workflow.Steps[0].NextRetryTime = &past
+	done, err = workflow.NewWorkflow(app, cli).ExecuteSteps(context.Background(), "app-v1", runners)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 2, len(s.requests))
+	status = app.Status.Workflow.Steps[0]
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+	assert.Equal(t, 2, status.Attempts)
+}
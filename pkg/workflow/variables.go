@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const (
+	// ContextKeyAppRevision is the key in the data of the workflow context ConfigMap for the app revision
+	// which the variables are exported by
+	ContextKeyAppRevision = "appRevision"
+	// ContextKeyVariables is the key in the data of the workflow context ConfigMap for the variables
+	ContextKeyVariables = "variables"
+)
+
+// ContextConfigMapName returns the name of the ConfigMap storing the workflow context of the application.
+func ContextConfigMapName(appName string) string {
+	return fmt.Sprintf("workflow-%s-context", appName)
+}
+
+// Variables are the values exported by the outputs of workflow steps, which can be consumed by the inputs of
+// later steps. They're shared by all the steps of the application, which may be executed in parallel.
+type Variables struct {
+	mu      sync.RWMutex
+	data    map[string]interface{}
+	changed bool
+}
+
+// NewVariables creates Variables with the given values.
+func NewVariables(data map[string]interface{}) *Variables {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return &Variables{data: data}
+}
+
+// Get returns the value of the variable.
+func (v *Variables) Get(name string) (interface{}, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.data[name]
+	return value, ok
+}
+
+// Set sets the value of the variable.
+func (v *Variables) Set(name string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.data[name] = value
+	v.changed = true
+}
+
+// loadVariables loads the variables exported for the app revision from the workflow context ConfigMap,
+// variables exported for other revisions are discarded.
+func loadVariables(ctx context.Context, cli client.Client, app *oamcore.Application, rev string) (*Variables, error) {
+	cm := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: ContextConfigMapName(app.Name)}, cm); err != nil {
+		if kerrors.IsNotFound(err) {
+			return NewVariables(nil), nil
+		}
+		return nil, errors.Wrap(err, "cannot get workflow context")
+	}
+	if cm.Data[ContextKeyAppRevision] != rev || cm.Data[ContextKeyVariables] == "" {
+		vars := NewVariables(nil)
+		vars.changed = true
+		return vars, nil
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(cm.Data[ContextKeyVariables]), &data); err != nil {
+		return nil, errors.Wrap(err, "invalid variables in workflow context")
+	}
+	return NewVariables(data), nil
+}
+
+// saveVariables saves the variables into the workflow context ConfigMap if they're changed.
+func saveVariables(ctx context.Context, cli client.Client, app *oamcore.Application, rev string, vars *Variables) error {
+	vars.mu.RLock()
+	defer vars.mu.RUnlock()
+	if !vars.changed {
+		return nil
+	}
+	b, err := json.Marshal(vars.data)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		ContextKeyAppRevision: rev,
+		ContextKeyVariables:   string(b),
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: ContextConfigMapName(app.Name)}, cm); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, "cannot get workflow context")
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ContextConfigMapName(app.Name),
+				Namespace: app.Namespace,
+				Labels: map[string]string{
+					oam.LabelAppName: app.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(app, oamcore.ApplicationKindVersionKind),
+				},
+			},
+			Data: data,
+		}
+		return errors.Wrap(cli.Create(ctx, cm), "cannot create workflow context")
+	}
+	cm.Data = data
+	return errors.Wrap(cli.Update(ctx, cm), "cannot update workflow context")
+}
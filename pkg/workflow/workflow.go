@@ -18,99 +18,269 @@ package workflow
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"sync"
 
-	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
-	"github.com/oam-dev/kubevela/pkg/controller/utils"
-	"github.com/oam-dev/kubevela/pkg/oam"
-	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
-	"github.com/oam-dev/kubevela/pkg/utils/apply"
 )
 
 type workflow struct {
-	app        *oamcore.Application
-	applicator apply.Applicator
+	app  *oamcore.Application
+	cli  client.Client
+	vars *Variables
 }
 
 // NewWorkflow returns a Workflow implementation.
-func NewWorkflow(app *oamcore.Application, applicator apply.Applicator) Workflow {
+func NewWorkflow(app *oamcore.Application, cli client.Client) Workflow {
 	return &workflow{
-		app:        app,
-		applicator: applicator,
+		app: app,
+		cli: cli,
 	}
 }
 
-func (w *workflow) ExecuteSteps(ctx context.Context, rev string, objects []*unstructured.Unstructured) (bool, error) {
-	steps := w.app.Spec.Workflow
-
-	if len(steps) == 0 {
+func (w *workflow) ExecuteSteps(ctx context.Context, rev string, taskRunners []TaskRunner) (bool, error) {
+	if len(taskRunners) == 0 {
 		return true, nil
 	}
+	done, err := w.executeSteps(ctx, rev, taskRunners)
+	if w.app.Status.Workflow == nil || w.app.Status.Workflow.AppRevision != rev {
+		return done, err
+	}
+	if recordErr := recordWorkflowRun(ctx, w.cli, w.app, done, w.vars); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	return done, err
+}
+
+func (w *workflow) executeSteps(ctx context.Context, rev string, taskRunners []TaskRunner) (bool, error) {
+	if err := ValidateWorkflowSteps(w.app.Spec.Workflow); err != nil {
+		return false, err
+	}
 
+	// the status of steps only makes sense for the app revision they're executed for
+	if w.app.Status.Workflow == nil || w.app.Status.Workflow.AppRevision != rev {
+		w.app.Status.Workflow = &common.WorkflowStatus{
+			AppRevision: rev,
+		}
+	}
+	wfStatus := w.app.Status.Workflow
+
+	if wfStatus.Terminated {
+		w.app.Status.Phase = common.ApplicationWorkflowTerminated
+		return false, nil
+	}
+	if wfStatus.Suspend {
+		w.app.Status.Phase = common.ApplicationWorkflowSuspending
+		return false, nil
+	}
 	w.app.Status.Phase = common.ApplicationRunningWorkflow
 
-	w.app.Status.Workflow = []common.WorkflowStepStatus{}
-	for i, step := range steps {
-		obj := objects[i].DeepCopy()
-		obj.SetName(step.Name)
-		obj.SetNamespace(w.app.Namespace)
-		obj.SetOwnerReferences([]metav1.OwnerReference{
-			*metav1.NewControllerRef(w.app, oamcore.ApplicationKindVersionKind),
-		})
-		err := w.applyWorkflowStep(ctx, obj, &types.WorkflowContext{
-			AppName:       w.app.Name,
-			AppRevision:   rev,
-			WorkflowIndex: i,
-			ResourceConfigMap: corev1.LocalObjectReference{
-				Name: rev,
-			},
-		})
-		if err != nil {
-			return false, err
+	specs := make(map[string]oamcore.WorkflowStep, len(w.app.Spec.Workflow))
+	for _, spec := range w.app.Spec.Workflow {
+		specs[spec.Name] = spec
+	}
+	rollbackSteps := map[string]bool{}
+	for _, spec := range w.app.Spec.Workflow {
+		if spec.OnFailure == oamcore.WorkflowStepOnFailureRollback {
+			rollbackSteps[spec.RollbackStep] = true
+		}
+	}
+	deps := stepDependencies(specs, taskRunners, rollbackSteps)
+	vars, err := loadVariables(ctx, w.cli, w.app, rev)
+	if err != nil {
+		return false, err
+	}
+	w.vars = vars
+	prevSteps := make(map[string]common.WorkflowStepStatus, len(wfStatus.Steps))
+	for _, ss := range wfStatus.Steps {
+		prevSteps[ss.Name] = ss
+	}
+
+	results := make(map[string]common.WorkflowStepStatus, len(taskRunners))
+	// rollback steps are only executed once the steps they're responsible for are failed
+	rollbacks := map[string]bool{}
+	var (
+		suspend bool
+		aborted bool
+		runErr  error
+	)
+	// every round executes all the steps whose dependencies are done in parallel,
+	// until no more step can be executed in this reconcile
+	for !suspend && runErr == nil {
+		var ready []int
+		for i, runner := range taskRunners {
+			if _, ok := results[runner.Name()]; ok {
+				continue
+			}
+			if rollbackSteps[runner.Name()] {
+				if rollbacks[runner.Name()] {
+					ready = append(ready, i)
+				}
+				continue
+			}
+			if !aborted && dependenciesDone(deps[runner.Name()], results, specs) {
+				ready = append(ready, i)
+			}
+		}
+		if len(ready) == 0 {
+			break
+		}
+
+		outcomes := make([]stepOutcome, len(ready))
+		var wg sync.WaitGroup
+		for j, i := range ready {
+			wg.Add(1)
+			go func(j, i int) {
+				defer wg.Done()
+				runner := taskRunners[i]
+				var prev *common.WorkflowStepStatus
+				if ss, ok := prevSteps[runner.Name()]; ok {
+					prev = &ss
+				}
+				spec := specs[runner.Name()]
+				if spec.If != "" && (prev == nil || prev.Phase == common.WorkflowStepPhaseSkipped) {
+					if status, skipped := w.checkCondition(spec, rev, vars, prev); skipped {
+						outcomes[j].status = status
+						return
+					}
+				}
+				outcomes[j].status, outcomes[j].operation, outcomes[j].err = runStep(ctx, runner, spec, &types.WorkflowContext{
+					AppName:       w.app.Name,
+					AppRevision:   rev,
+					WorkflowIndex: i,
+					ResourceConfigMap: corev1.LocalObjectReference{
+						Name: rev,
+					},
+					ContextConfigMap: corev1.LocalObjectReference{
+						Name: ContextConfigMapName(w.app.Name),
+					},
+				}, vars, prev)
+			}(j, i)
 		}
+		wg.Wait()
 
-		status, err := w.syncWorkflowStatus(step, obj)
-		if err != nil {
-			return false, err
+		for j, i := range ready {
+			o, spec := outcomes[j], specs[taskRunners[i].Name()]
+			if o.err != nil {
+				if runErr == nil {
+					runErr = o.err
+				}
+				if o.status.Name != "" {
+					// keep the status of the step started before so that its attempts and elapsed time are not lost
+					results[taskRunners[i].Name()] = o.status
+				}
+				continue
+			}
+			results[taskRunners[i].Name()] = o.status
+			if o.operation != nil && o.operation.Suspend {
+				suspend = true
+			}
+			if !isFailed(o.status.Phase) {
+				continue
+			}
+			switch spec.OnFailure {
+			case oamcore.WorkflowStepOnFailureContinue:
+			case oamcore.WorkflowStepOnFailureRollback:
+				aborted = true
+				rollbacks[spec.RollbackStep] = true
+			default:
+				aborted = true
+			}
 		}
+	}
 
-		w.app.Status.Workflow = append(w.app.Status.Workflow, *status)
-		switch status.Phase {
-		case common.WorkflowStepPhaseSucceeded: // This one is done. Continue
-		case common.WorkflowStepPhaseRunning: // Need to retry shortly.
+	wfStatus.Steps = []common.WorkflowStepStatus{}
+	for _, runner := range taskRunners {
+		if status, ok := results[runner.Name()]; ok {
+			wfStatus.Steps = append(wfStatus.Steps, status)
+		}
+	}
+	if err := saveVariables(ctx, w.cli, w.app, rev, vars); err != nil {
+		return false, err
+	}
+	if runErr != nil {
+		return false, runErr
+	}
+	if suspend {
+		wfStatus.Suspend = true
+		w.app.Status.Phase = common.ApplicationWorkflowSuspending
+		return false, nil
+	}
+	if aborted {
+		// a failed or stopped step terminates the workflow once the rollback steps are finished
+		for name := range rollbacks {
+			if status, ok := results[name]; !ok || status.Phase == common.WorkflowStepPhaseRunning {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	for _, runner := range taskRunners {
+		if rollbackSteps[runner.Name()] {
+			continue
+		}
+		status, ok := results[runner.Name()]
+		if !ok || status.Phase == common.WorkflowStepPhaseRunning {
+			// Need to retry shortly.
 			return false, nil
-		default:
-			return true, nil
 		}
 	}
 	return true, nil // all steps done
 }
 
-func (w *workflow) applyWorkflowStep(ctx context.Context, obj *unstructured.Unstructured, wctx *types.WorkflowContext) error {
-	if err := addWorkflowContextToAnnotation(obj, wctx); err != nil {
-		return err
-	}
+type stepOutcome struct {
+	status    common.WorkflowStepStatus
+	operation *Operation
+	err       error
+}
 
-	return w.applicator.Apply(ctx, obj)
+func isFailed(phase common.WorkflowStepPhase) bool {
+	return phase == common.WorkflowStepPhaseFailed || phase == common.WorkflowStepPhaseStopped
 }
 
-func addWorkflowContextToAnnotation(obj *unstructured.Unstructured, wc *types.WorkflowContext) error {
-	b, err := json.Marshal(wc)
-	if err != nil {
-		return err
+// checkCondition evaluates the `if` condition of the step which is not started yet, it returns the status of the step
+// and true if the step shouldn't be executed. The condition is only evaluated once, a skipped step is kept skipped.
+// The step is failed if the condition cannot be evaluated.
+func (w *workflow) checkCondition(spec oamcore.WorkflowStep, rev string, vars *Variables,
+	prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, bool) {
+	if prev != nil {
+		return *prev, true
 	}
-	m := map[string]string{
-		oam.AnnotationWorkflowContext: string(b),
+	status := common.WorkflowStepStatus{Name: spec.Name, Type: spec.Type}
+	ok, err := evaluateCondition(spec, w.app, rev, vars)
+	switch {
+	case err != nil:
+		status.Phase = common.WorkflowStepPhaseFailed
+		status.Message = err.Error()
+	case !ok:
+		status.Phase = common.WorkflowStepPhaseSkipped
+		status.Message = fmt.Sprintf("if condition %q is false", spec.If)
+	default:
+		return status, false
 	}
-	obj.SetAnnotations(oamutil.MergeMapOverrideWithDst(m, obj.GetAnnotations()))
-	return nil
+	return status, true
+}
+
+// dependenciesDone checks whether all the dependencies are succeeded or skipped, or failed but allowed to continue.
+func dependenciesDone(deps []string, results map[string]common.WorkflowStepStatus, specs map[string]oamcore.WorkflowStep) bool {
+	for _, dep := range deps {
+		status, ok := results[dep]
+		if !ok {
+			return false
+		}
+		switch {
+		case status.Phase == common.WorkflowStepPhaseSucceeded || status.Phase == common.WorkflowStepPhaseSkipped:
+		case isFailed(status.Phase) && specs[dep].OnFailure == oamcore.WorkflowStepOnFailureContinue:
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 const (
@@ -127,52 +297,3 @@ const (
 	// CondStatusTrue is the status of the workflow progress condition which is True
 	CondStatusTrue = "True"
 )
-
-func (w *workflow) syncWorkflowStatus(step oamcore.WorkflowStep, obj *unstructured.Unstructured) (*common.WorkflowStepStatus, error) {
-	status := &common.WorkflowStepStatus{
-		Name: step.Name,
-		Type: step.Type,
-		ResourceRef: runtimev1alpha1.TypedReference{
-			APIVersion: obj.GetAPIVersion(),
-			Kind:       obj.GetKind(),
-			Name:       obj.GetName(),
-			UID:        obj.GetUID(),
-		},
-	}
-
-	cond, found, err := utils.GetUnstructuredObjectStatusCondition(obj, CondTypeWorkflowFinish)
-	if err != nil {
-		return nil, err
-	}
-
-	if !found || cond.Status != CondStatusTrue {
-		status.Phase = common.WorkflowStepPhaseRunning
-		return status, nil
-	}
-
-	switch cond.Reason {
-	case CondReasonSucceeded:
-		observedG, err := parseGeneration(cond.Message)
-		if err != nil {
-			return nil, err
-		}
-		if observedG != obj.GetGeneration() {
-			status.Phase = common.WorkflowStepPhaseRunning
-		} else {
-			status.Phase = common.WorkflowStepPhaseSucceeded
-		}
-	case CondReasonFailed:
-		status.Phase = common.WorkflowStepPhaseFailed
-	case CondReasonStopped:
-		status.Phase = common.WorkflowStepPhaseStopped
-	default:
-		status.Phase = common.WorkflowStepPhaseRunning
-	}
-	return status, nil
-}
-
-func parseGeneration(message string) (int64, error) {
-	m := &SucceededMessage{}
-	err := json.Unmarshal([]byte(message), m)
-	return m.ObservedGeneration, err
-}
@@ -18,170 +18,530 @@ package workflow
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
-	"github.com/oam-dev/kubevela/pkg/utils/apply"
+	"github.com/oam-dev/kubevela/apis/types"
 )
 
 func TestExecuteSteps(t *testing.T) {
 
-	zerostepApp := &oamcore.Application{
+	app := &oamcore.Application{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "test",
 			Namespace: "test",
 		},
-		Spec: oamcore.ApplicationSpec{
-			Workflow: []oamcore.WorkflowStep{},
-		},
 	}
 
-	onestepApp := zerostepApp.DeepCopy()
-	onestepApp.Spec.Workflow = []oamcore.WorkflowStep{{
-		Name: "test",
-		Type: "test",
-	}}
-
-	twostepsApp := onestepApp.DeepCopy()
-	twostepsApp.Spec.Workflow = append(twostepsApp.Spec.Workflow, oamcore.WorkflowStep{
-		Name: "test2",
-		Type: "test2",
-	})
-
-	succeededMessage, err := json.Marshal(&SucceededMessage{ObservedGeneration: 1})
-	if err != nil {
-		panic(err)
+	type want struct {
+		done    bool
+		err     error
+		phase   common.ApplicationPhase
+		suspend bool
+		steps   []common.WorkflowStepPhase
 	}
 
-	succeededStep := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"metadata": map[string]interface{}{
-				"generation": int64(1),
-			},
-			"status": map[string]interface{}{
-				"conditions": []interface{}{map[string]interface{}{
-					"type":    CondTypeWorkflowFinish,
-					"reason":  CondReasonSucceeded,
-					"message": string(succeededMessage),
-					"status":  CondStatusTrue,
-				}},
-			},
+	testcases := []struct {
+		desc   string
+		status *common.WorkflowStatus
+		steps  []TaskRunner
+		want   want
+	}{{
+		desc: "zero steps should return true",
+		want: want{
+			done: true,
 		},
-	}
-	succeededStepUnmatchedGen := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"metadata": map[string]interface{}{
-				"generation": int64(2),
-			},
-			"status": map[string]interface{}{
-				"conditions": []interface{}{map[string]interface{}{
-					"type":    CondTypeWorkflowFinish,
-					"reason":  CondReasonSucceeded,
-					"message": string(succeededMessage),
-					"status":  CondStatusTrue,
-				}},
-			},
+	}, {
+		desc:  "one succeeded step should return true",
+		steps: []TaskRunner{mockRunner("s1", common.WorkflowStepPhaseSucceeded, nil, nil)},
+		want: want{
+			done:  true,
+			phase: common.ApplicationRunningWorkflow,
+			steps: []common.WorkflowStepPhase{common.WorkflowStepPhaseSucceeded},
+		},
+	}, {
+		desc:  "one running step should return false",
+		steps: []TaskRunner{mockRunner("s1", common.WorkflowStepPhaseRunning, nil, nil)},
+		want: want{
+			done:  false,
+			phase: common.ApplicationRunningWorkflow,
+			steps: []common.WorkflowStepPhase{common.WorkflowStepPhaseRunning},
+		},
+	}, {
+		desc: "one stopped step should return true and skip the following steps",
+		steps: []TaskRunner{
+			mockRunner("s1", common.WorkflowStepPhaseStopped, nil, nil),
+			mockRunner("s2", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
+		want: want{
+			done:  true,
+			phase: common.ApplicationRunningWorkflow,
+			steps: []common.WorkflowStepPhase{common.WorkflowStepPhaseStopped},
+		},
+	}, {
+		desc: "one succeeded step and one running step should return false",
+		steps: []TaskRunner{
+			mockRunner("s1", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("s2", common.WorkflowStepPhaseRunning, nil, nil),
+		},
+		want: want{
+			done:  false,
+			phase: common.ApplicationRunningWorkflow,
+			steps: []common.WorkflowStepPhase{common.WorkflowStepPhaseSucceeded, common.WorkflowStepPhaseRunning},
+		},
+	}, {
+		desc: "suspend operation should suspend the workflow",
+		steps: []TaskRunner{
+			mockRunner("s1", common.WorkflowStepPhaseRunning, &Operation{Suspend: true}, nil),
+			mockRunner("s2", common.WorkflowStepPhaseSucceeded, nil, nil),
 		},
+		want: want{
+			done:    false,
+			phase:   common.ApplicationWorkflowSuspending,
+			suspend: true,
+			steps:   []common.WorkflowStepPhase{common.WorkflowStepPhaseRunning},
+		},
+	}, {
+		desc:   "suspended workflow should not execute any step",
+		status: &common.WorkflowStatus{AppRevision: "app-v1", Suspend: true},
+		steps:  []TaskRunner{mockRunner("s1", common.WorkflowStepPhaseSucceeded, nil, nil)},
+		want: want{
+			done:    false,
+			phase:   common.ApplicationWorkflowSuspending,
+			suspend: true,
+		},
+	}, {
+		desc:   "workflow status of a previous revision should be reset",
+		status: &common.WorkflowStatus{AppRevision: "app-v0", Suspend: true},
+		steps:  []TaskRunner{mockRunner("s1", common.WorkflowStepPhaseSucceeded, nil, nil)},
+		want: want{
+			done:  true,
+			phase: common.ApplicationRunningWorkflow,
+			steps: []common.WorkflowStepPhase{common.WorkflowStepPhaseSucceeded},
+		},
+	}, {
+		desc:  "error of step should be returned",
+		steps: []TaskRunner{mockRunner("s1", "", nil, errors.New("boom"))},
+		want: want{
+			err:   errors.New("boom"),
+			phase: common.ApplicationRunningWorkflow,
+		},
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			a := app.DeepCopy()
+			a.Status.Workflow = tc.status
+			done, err := NewWorkflow(a, newFakeClient()).ExecuteSteps(context.Background(), "app-v1", tc.steps)
+			assert.Equal(t, tc.want.err, err)
+			assert.Equal(t, tc.want.done, done)
+			assert.Equal(t, tc.want.phase, a.Status.Phase)
+			if len(tc.steps) == 0 {
+				return
+			}
+			assert.Equal(t, "app-v1", a.Status.Workflow.AppRevision)
+			assert.Equal(t, tc.want.suspend, a.Status.Workflow.Suspend)
+			var phases []common.WorkflowStepPhase
+			for _, ss := range a.Status.Workflow.Steps {
+				phases = append(phases, ss.Phase)
+			}
+			assert.Equal(t, tc.want.steps, phases)
+		})
 	}
+}
 
-	runningStep := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"status": map[string]interface{}{
-				"conditions": []interface{}{map[string]interface{}{
-					"type":   CondTypeWorkflowFinish,
-					"status": CondStatusTrue,
-				}},
-			},
+func TestExecuteStepsWithPreviousStatus(t *testing.T) {
+	app := &oamcore.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
 		},
 	}
-	stoppedStep := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"status": map[string]interface{}{
-				"conditions": []interface{}{map[string]interface{}{
-					"type":   CondTypeWorkflowFinish,
-					"reason": CondReasonStopped,
-					"status": CondStatusTrue,
-				}},
-			},
-		},
+	app.Status.Workflow = &common.WorkflowStatus{
+		AppRevision: "app-v1",
+		Steps: []common.WorkflowStepStatus{{
+			Name:  "s1",
+			Phase: common.WorkflowStepPhaseRunning,
+		}},
 	}
+	var prev *common.WorkflowStepStatus
+	var wctx *types.WorkflowContext
+	runner := &testRunner{name: "s1", run: func(c *types.WorkflowContext, p *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+		prev, wctx = p, c
+		return common.WorkflowStepStatus{Name: "s1", Phase: common.WorkflowStepPhaseSucceeded}, nil, nil
+	}}
+	done, err := NewWorkflow(app, newFakeClient()).ExecuteSteps(context.Background(), "app-v1", []TaskRunner{runner})
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, prev.Phase)
+	assert.Equal(t, "test", wctx.AppName)
+	assert.Equal(t, "app-v1", wctx.AppRevision)
+	assert.Equal(t, "app-v1", wctx.ResourceConfigMap.Name)
+}
 
-	type want struct {
-		done bool
-		err  error
+func TestExecuteDAGSteps(t *testing.T) {
+	newApp := func(steps ...oamcore.WorkflowStep) *oamcore.Application {
+		return &oamcore.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec:       oamcore.ApplicationSpec{Workflow: steps},
+		}
 	}
 
+	type want struct {
+		done  bool
+		err   bool
+		steps map[string]common.WorkflowStepPhase
+	}
 	testcases := []struct {
 		desc  string
 		app   *oamcore.Application
-		steps []*unstructured.Unstructured
+		steps []TaskRunner
 		want  want
 	}{{
-		desc: "zero steps should return true",
-		app:  zerostepApp.DeepCopy(),
+		desc: "step should wait for all its dependencies",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a"},
+			oamcore.WorkflowStep{Name: "b"},
+			oamcore.WorkflowStep{Name: "c", DependsOn: []string{"a", "b"}},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseRunning, nil, nil),
+			mockRunner("c", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
+		want: want{
+			done: false,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseSucceeded,
+				"b": common.WorkflowStepPhaseRunning,
+			},
+		},
+	}, {
+		desc: "steps should be executed in the same reconcile once dependencies are done",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a"},
+			oamcore.WorkflowStep{Name: "b", DependsOn: []string{"a"}},
+			oamcore.WorkflowStep{Name: "c", DependsOn: []string{"b"}},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("c", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
 		want: want{
 			done: true,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseSucceeded,
+				"b": common.WorkflowStepPhaseSucceeded,
+				"c": common.WorkflowStepPhaseSucceeded,
+			},
 		},
 	}, {
-		desc:  "one succeeded step should return true",
-		app:   onestepApp.DeepCopy(),
-		steps: []*unstructured.Unstructured{succeededStep.DeepCopy()},
+		desc: "failed step should abort the workflow by default",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a"},
+			oamcore.WorkflowStep{Name: "b"},
+			oamcore.WorkflowStep{Name: "c", DependsOn: []string{"a"}},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseFailed, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("c", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
 		want: want{
 			done: true,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseFailed,
+				"b": common.WorkflowStepPhaseSucceeded,
+			},
 		},
 	}, {
-		desc:  "one succeeded step with unmatched generation should return false",
-		app:   onestepApp.DeepCopy(),
-		steps: []*unstructured.Unstructured{succeededStepUnmatchedGen.DeepCopy()},
+		desc: "failed step with continue policy should not block dependent steps",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureContinue},
+			oamcore.WorkflowStep{Name: "b", DependsOn: []string{"a"}},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseStopped, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
+		want: want{
+			done: true,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseStopped,
+				"b": common.WorkflowStepPhaseSucceeded,
+			},
+		},
+	}, {
+		desc: "failed step with continue policy should not stop sequential steps",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureContinue},
+			oamcore.WorkflowStep{Name: "b"},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseFailed, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseRunning, nil, nil),
+		},
 		want: want{
 			done: false,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseFailed,
+				"b": common.WorkflowStepPhaseRunning,
+			},
 		},
 	}, {
-		desc:  "one running step should return false",
-		app:   onestepApp.DeepCopy(),
-		steps: []*unstructured.Unstructured{runningStep.DeepCopy()},
+		desc: "failed step with rollback policy should execute the rollback step",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureRollback, RollbackStep: "c"},
+			oamcore.WorkflowStep{Name: "b"},
+			oamcore.WorkflowStep{Name: "c"},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseFailed, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("c", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
+		want: want{
+			done: true,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseFailed,
+				"c": common.WorkflowStepPhaseSucceeded,
+			},
+		},
+	}, {
+		desc: "workflow should wait for the running rollback step",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureRollback, RollbackStep: "b"},
+			oamcore.WorkflowStep{Name: "b"},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseStopped, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseRunning, nil, nil),
+		},
 		want: want{
 			done: false,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseStopped,
+				"b": common.WorkflowStepPhaseRunning,
+			},
 		},
 	}, {
-		desc:  "one stopped step should return true",
-		app:   onestepApp.DeepCopy(),
-		steps: []*unstructured.Unstructured{stoppedStep.DeepCopy()},
+		desc: "rollback step should be skipped if no step is failed",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", OnFailure: oamcore.WorkflowStepOnFailureRollback, RollbackStep: "b"},
+			oamcore.WorkflowStep{Name: "b"},
+			oamcore.WorkflowStep{Name: "c"},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("c", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
 		want: want{
 			done: true,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseSucceeded,
+				"c": common.WorkflowStepPhaseSucceeded,
+			},
 		},
 	}, {
-		desc:  "one succeeded step and one running step should return false",
-		app:   twostepsApp.DeepCopy(),
-		steps: []*unstructured.Unstructured{succeededStep.DeepCopy(), runningStep.DeepCopy()},
+		desc: "failed step with retries should be retried later",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", Retries: intPtr(2)},
+			oamcore.WorkflowStep{Name: "b"},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseFailed, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
 		want: want{
 			done: false,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseRunning,
+			},
+		},
+	}, {
+		desc: "step should be skipped if its condition is false",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", If: `context.namespace == "prod"`},
+			oamcore.WorkflowStep{Name: "b", If: `context.namespace == "test"`},
+			oamcore.WorkflowStep{Name: "c", DependsOn: []string{"a"}},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("c", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
+		want: want{
+			done: true,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseSkipped,
+				"b": common.WorkflowStepPhaseSucceeded,
+				"c": common.WorkflowStepPhaseSucceeded,
+			},
+		},
+	}, {
+		desc: "step should be failed if its condition cannot be evaluated",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", If: `outputs.endpoint != ""`},
+			oamcore.WorkflowStep{Name: "b"},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
+		want: want{
+			done: true,
+			steps: map[string]common.WorkflowStepPhase{
+				"a": common.WorkflowStepPhaseFailed,
+			},
+		},
+	}, {
+		desc: "circular dependencies should return error",
+		app: newApp(
+			oamcore.WorkflowStep{Name: "a", DependsOn: []string{"b"}},
+			oamcore.WorkflowStep{Name: "b", DependsOn: []string{"a"}},
+		),
+		steps: []TaskRunner{
+			mockRunner("a", common.WorkflowStepPhaseSucceeded, nil, nil),
+			mockRunner("b", common.WorkflowStepPhaseSucceeded, nil, nil),
+		},
+		want: want{
+			err: true,
 		},
 	}}
 	for _, tc := range testcases {
-		t.Logf("%s", tc.desc)
-		done, err := NewWorkflow(tc.app, mockApplicator()).ExecuteSteps(context.Background(), "app-v1", tc.steps)
-		if err != nil {
-			assert.Equal(t, tc.want.err, err)
-			continue
-		}
-		assert.Equal(t, tc.want.done, done)
+		t.Run(tc.desc, func(t *testing.T) {
+			done, err := NewWorkflow(tc.app, newFakeClient()).ExecuteSteps(context.Background(), "app-v1", tc.steps)
+			assert.Equal(t, tc.want.err, err != nil, err)
+			if tc.want.err {
+				return
+			}
+			assert.Equal(t, tc.want.done, done)
+			phases := map[string]common.WorkflowStepPhase{}
+			for _, ss := range tc.app.Status.Workflow.Steps {
+				phases[ss.Name] = ss.Phase
+			}
+			assert.Equal(t, tc.want.steps, phases)
+		})
+	}
+}
+
+func TestExecuteIndependentStepsInParallel(t *testing.T) {
+	app := &oamcore.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: oamcore.ApplicationSpec{Workflow: []oamcore.WorkflowStep{
+			{Name: "migrate-db"},
+			{Name: "warmup-cache"},
+			{Name: "deploy", DependsOn: []string{"migrate-db", "warmup-cache"}},
+		}},
 	}
+	// each of the independent steps can only finish when the other one is running at the same time
+	var started sync.WaitGroup
+	started.Add(2)
+	parallelRunner := func(name string) TaskRunner {
+		return &testRunner{name: name, run: func(*types.WorkflowContext, *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+			started.Done()
+			ch := make(chan struct{})
+			go func() {
+				started.Wait()
+				close(ch)
+			}()
+			select {
+			case <-ch:
+				return common.WorkflowStepStatus{Name: name, Phase: common.WorkflowStepPhaseSucceeded}, nil, nil
+			case <-time.After(10 * time.Second):
+				return common.WorkflowStepStatus{}, nil, errors.New("steps are not executed in parallel")
+			}
+		}}
+	}
+	done, err := NewWorkflow(app, newFakeClient()).ExecuteSteps(context.Background(), "app-v1", []TaskRunner{
+		parallelRunner("migrate-db"),
+		parallelRunner("warmup-cache"),
+		mockRunner("deploy", common.WorkflowStepPhaseSucceeded, nil, nil),
+	})
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 3, len(app.Status.Workflow.Steps))
+}
+
+func TestExecuteStepsWithVariables(t *testing.T) {
+	app := &oamcore.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+	}
+	cli := newFakeClient()
+	producer := &testRunner{name: "create-db", runWithVars: func(vars *Variables) (common.WorkflowStepStatus, *Operation, error) {
+		vars.Set("endpoint", "db.test:3306")
+		return common.WorkflowStepStatus{Name: "create-db", Phase: common.WorkflowStepPhaseSucceeded}, nil, nil
+	}}
+	var consumed interface{}
+	consumer := &testRunner{name: "deploy", runWithVars: func(vars *Variables) (common.WorkflowStepStatus, *Operation, error) {
+		consumed, _ = vars.Get("endpoint")
+		return common.WorkflowStepStatus{Name: "deploy", Phase: common.WorkflowStepPhaseRunning}, nil, nil
+	}}
+	done, err := NewWorkflow(app, cli).ExecuteSteps(context.Background(), "app-v1", []TaskRunner{producer, consumer})
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, "db.test:3306", consumed)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, cli.Get(context.Background(), client.ObjectKey{Namespace: "test", Name: ContextConfigMapName("test")}, cm))
+	assert.Equal(t, "app-v1", cm.Data[ContextKeyAppRevision])
+	assert.JSONEq(t, `{"endpoint":"db.test:3306"}`, cm.Data[ContextKeyVariables])
+
+	// variables are kept across reconciliations of the same revision
+	consumed = nil
+	_, err = NewWorkflow(app, cli).ExecuteSteps(context.Background(), "app-v1", []TaskRunner{consumer})
+	assert.NoError(t, err)
+	assert.Equal(t, "db.test:3306", consumed)
+
+	// variables of previous revision are discarded
+	consumed = nil
+	_, err = NewWorkflow(app, cli).ExecuteSteps(context.Background(), "app-v2", []TaskRunner{consumer})
+	assert.NoError(t, err)
+	assert.Nil(t, consumed)
+	assert.NoError(t, cli.Get(context.Background(), client.ObjectKey{Namespace: "test", Name: ContextConfigMapName("test")}, cm))
+	assert.Equal(t, "app-v2", cm.Data[ContextKeyAppRevision])
+	assert.JSONEq(t, `{}`, cm.Data[ContextKeyVariables])
+}
+
+type testRunner struct {
+	name        string
+	run         func(wctx *types.WorkflowContext, prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error)
+	runWithVars func(vars *Variables) (common.WorkflowStepStatus, *Operation, error)
 }
 
-type testmockApplicator struct {
+func (r *testRunner) Name() string {
+	return r.name
 }
 
-func (t *testmockApplicator) Apply(ctx context.Context, object runtime.Object, option ...apply.ApplyOption) error {
-	return nil
+func (r *testRunner) Run(_ context.Context, wctx *types.WorkflowContext, vars *Variables, prev *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+	if r.runWithVars != nil {
+		return r.runWithVars(vars)
+	}
+	return r.run(wctx, prev)
+}
+
+func mockRunner(name string, phase common.WorkflowStepPhase, op *Operation, err error) TaskRunner {
+	return &testRunner{name: name, run: func(*types.WorkflowContext, *common.WorkflowStepStatus) (common.WorkflowStepStatus, *Operation, error) {
+		return common.WorkflowStepStatus{Name: name, Type: "test", Phase: phase}, op, err
+	}}
 }
 
-func mockApplicator() apply.Applicator {
-	return &testmockApplicator{}
+func newFakeClient(objs ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = oamcore.SchemeBuilder.AddToScheme(scheme)
+	return fake.NewFakeClientWithScheme(scheme, objs...)
 }
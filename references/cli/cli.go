@@ -82,6 +82,8 @@ func NewCommand() *cobra.Command {
 		NewExecCommand(commandArgs, ioStream),
 		NewPortForwardCommand(commandArgs, ioStream),
 		NewLogsCommand(commandArgs, ioStream),
+		NewWorkflowCommand(commandArgs, ioStream),
+		NewRolloutCommand(commandArgs, ioStream),
 		NewEnvCommand(commandArgs, ioStream),
 		NewConfigCommand(ioStream),
 
@@ -0,0 +1,451 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+const (
+	// FlagWatch is the flag to keep showing the rollout status until the rollout finishes
+	FlagWatch = "watch"
+	// FlagBatch is the flag of the batch to promote the rollout to
+	FlagBatch = "batch"
+	// FlagAll is the flag to promote the rollout to all the batches
+	FlagAll = "all"
+
+	// RolloutPause pauses the rollout
+	RolloutPause = "pause"
+	// RolloutResume resumes the paused rollout
+	RolloutResume = "resume"
+	// RolloutAbort aborts the rollout
+	RolloutAbort = "abort"
+
+	rolloutWatchInterval = 3 * time.Second
+)
+
+// NewRolloutCommand creates `rollout` command and its nested children commands
+func NewRolloutCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollout",
+		Short: "Operate the rollout of an application",
+		Long:  "Show the status of an AppRollout batch by batch, pause, resume, promote, abort it or show its history.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+	}
+	cmd.SetOut(ioStreams.Out)
+	cmd.AddCommand(
+		newRolloutStatusCommand(c, ioStreams),
+		newRolloutControlCommand(c, ioStreams, RolloutPause,
+			"Pause the rollout, the current batch is finished but the next one won't start until it's resumed"),
+		newRolloutControlCommand(c, ioStreams, RolloutResume, "Resume the paused rollout"),
+		newRolloutPromoteCommand(c, ioStreams),
+		newRolloutControlCommand(c, ioStreams, RolloutAbort,
+			"Abort the rollout, all the traffic goes back to the source and it's rolled back if it has an automatic rollback policy"),
+		newRolloutHistoryCommand(c, ioStreams),
+	)
+	return cmd
+}
+
+func newRolloutStatusCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "status ROLLOUT_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show the status of a rollout batch by batch",
+		Long:                  "Show the status of a rollout batch by batch, and of each of its components.",
+		Example:               fmt.Sprintf("vela rollout status frontend-rollout --%s", FlagWatch),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must specify name for the rollout")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			watch, err := cmd.Flags().GetBool(FlagWatch)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			for {
+				finished, err := printRolloutStatus(context.Background(), newClient, ioStreams, env.Namespace, args[0])
+				if err != nil || !watch || finished {
+					return err
+				}
+				time.Sleep(rolloutWatchInterval)
+				ioStreams.Info("\n")
+			}
+		},
+	}
+	cmd.Flags().BoolP(FlagWatch, "w", false, "keep showing the status until the rollout finishes")
+	return cmd
+}
+
+func newRolloutControlCommand(c common.Args, ioStreams cmdutil.IOStreams, operation, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:                   operation + " ROLLOUT_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 short,
+		Long:                  short,
+		Example:               fmt.Sprintf("vela rollout %s frontend-rollout", operation),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must specify name for the rollout")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			var control func(*v1beta1.AppRollout) error
+			switch operation {
+			case RolloutPause:
+				control = pauseRollout
+			case RolloutResume:
+				control = resumeRollout
+			case RolloutAbort:
+				control = abortRollout
+			}
+			if err := controlRollout(context.Background(), newClient, env.Namespace, args[0], control); err != nil {
+				return err
+			}
+			ioStreams.Infof("Successfully requested to %s the rollout %s\n", operation, args[0])
+			return nil
+		},
+	}
+}
+
+func newRolloutPromoteCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "promote ROLLOUT_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 "Promote the rollout held by its batch partition to the next batch",
+		Long: "Promote the rollout held by its batch partition to the next batch, to the given batch, " +
+			"or to all the batches.",
+		Example: fmt.Sprintf("vela rollout promote frontend-rollout --%s 2", FlagBatch),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must specify name for the rollout")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			batch, err := cmd.Flags().GetInt32(FlagBatch)
+			if err != nil {
+				return err
+			}
+			all, err := cmd.Flags().GetBool(FlagAll)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			var partition *int32
+			err = controlRollout(context.Background(), newClient, env.Namespace, args[0], func(rollout *v1beta1.AppRollout) error {
+				if err := promoteRollout(rollout, batch, all); err != nil {
+					return err
+				}
+				partition = rollout.Spec.RolloutPlan.BatchPartition
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if partition == nil {
+				ioStreams.Infof("Successfully promoted the rollout %s to all the batches\n", args[0])
+			} else {
+				ioStreams.Infof("Successfully promoted the rollout %s to batch %d\n", args[0], *partition)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Int32P(FlagBatch, "b", -1, "promote the rollout up to the given batch (included), the batch starts from 0")
+	cmd.Flags().BoolP(FlagAll, "", false, "promote the rollout to all the batches")
+	return cmd
+}
+
+func newRolloutHistoryCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:                   "history ROLLOUT_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show the history of a rollout",
+		Long:                  "Show the app revisions a rollout rolled between and the events recorded along the way.",
+		Example:               "vela rollout history frontend-rollout",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must specify name for the rollout")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			return printRolloutHistory(context.Background(), newClient, ioStreams, env.Namespace, args[0])
+		},
+	}
+}
+
+// controlRollout applies the control to the rollout spec and updates it. The control is applied again to the latest
+// rollout if the rollout is changed by others in the meantime, so it's always validated against the current status.
+func controlRollout(ctx context.Context, c client.Client, namespace, name string,
+	control func(*v1beta1.AppRollout) error) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		rollout := &v1beta1.AppRollout{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, rollout); err != nil {
+			return errors.Wrapf(err, "cannot get rollout %s", name)
+		}
+		if rolloutFinished(&rollout.Status.RolloutStatus) {
+			return errors.Errorf("the rollout %s is already in the %s state", name, rollout.Status.RollingState)
+		}
+		if err := control(rollout); err != nil {
+			return err
+		}
+		return c.Update(ctx, rollout)
+	})
+}
+
+func pauseRollout(rollout *v1beta1.AppRollout) error {
+	if rollout.Spec.RolloutPlan.Paused {
+		return errors.Errorf("the rollout %s is already paused", rollout.Name)
+	}
+	rollout.Spec.RolloutPlan.Paused = true
+	return nil
+}
+
+func resumeRollout(rollout *v1beta1.AppRollout) error {
+	if !rollout.Spec.RolloutPlan.Paused {
+		return errors.Errorf("the rollout %s is not paused", rollout.Name)
+	}
+	rollout.Spec.RolloutPlan.Paused = false
+	return nil
+}
+
+// abortRollout requests the rollout controller to abort the rollout to the current target app revision
+func abortRollout(rollout *v1beta1.AppRollout) error {
+	oamutil.AddAnnotations(rollout, map[string]string{oam.AnnotationRolloutAbort: rollout.Spec.TargetAppRevisionName})
+	return nil
+}
+
+// promoteRollout moves the batch partition of the rollout forward, to the next batch if the batch is negative
+func promoteRollout(rollout *v1beta1.AppRollout, batch int32, all bool) error {
+	plan := &rollout.Spec.RolloutPlan
+	if plan.BatchPartition == nil {
+		return errors.Errorf("the rollout %s is not held by a batch partition", rollout.Name)
+	}
+	if all {
+		plan.BatchPartition = nil
+		return nil
+	}
+	if batch < 0 {
+		batch = *plan.BatchPartition + 1
+	}
+	numBatches := int32(len(plan.RolloutBatches))
+	if batch >= numBatches {
+		return errors.Errorf("the rollout %s only has %d batches", rollout.Name, numBatches)
+	}
+	if batch <= *plan.BatchPartition {
+		return errors.Errorf("the rollout %s is already promoted to batch %d", rollout.Name, *plan.BatchPartition)
+	}
+	plan.BatchPartition = &batch
+	return nil
+}
+
+// printRolloutStatus prints the status of the rollout and each of its batches, it returns if the rollout is finished
+func printRolloutStatus(ctx context.Context, c client.Client, ioStreams cmdutil.IOStreams, namespace, name string) (bool, error) {
+	rollout := &v1beta1.AppRollout{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, rollout); err != nil {
+		return false, errors.Wrapf(err, "cannot get rollout %s", name)
+	}
+	plan := &rollout.Spec.RolloutPlan
+	status := &rollout.Status
+	state := string(status.RollingState)
+	if status.RollingState == v1alpha1.RollingInBatchesState {
+		state = fmt.Sprintf("%s (%s)", status.RollingState, status.BatchRollingState)
+	}
+	ioStreams.Infof("Rollout: %s\nTarget: %s\nSource: %s\nState: %s\n", rollout.Name,
+		rollout.Spec.TargetAppRevisionName, rollout.Spec.SourceAppRevisionName, state)
+	batch := fmt.Sprintf("%d/%d", status.CurrentBatch+1, len(plan.RolloutBatches))
+	if plan.BatchPartition != nil {
+		batch += fmt.Sprintf(" (partition: %d)", *plan.BatchPartition)
+	}
+	ioStreams.Infof("Batch: %s\nReplicas: %d upgraded, %d ready, %d target\n", batch, status.UpgradedReplicas,
+		status.UpgradedReadyReplicas, status.RolloutTargetSize)
+	if plan.TrafficRouting != nil {
+		ioStreams.Infof("Traffic: %d%% to the target\n", status.TrafficWeight)
+	}
+	if plan.Paused {
+		ioStreams.Info("Paused: true\n")
+	}
+	if status.RollbackReason != "" {
+		ioStreams.Infof("Rollback: %s\n", status.RollbackReason)
+	}
+
+	// show a column for each component if there are more than one
+	header := []interface{}{"BATCH", "REPLICAS"}
+	statuses := []*v1alpha1.RolloutStatus{&status.RolloutStatus}
+	if len(status.Components) > 1 {
+		statuses = nil
+		for i := range status.Components {
+			header = append(header, strings.ToUpper(status.Components[i].Name))
+			statuses = append(statuses, &status.Components[i].RolloutStatus)
+		}
+	} else {
+		header = append(header, "STATE")
+	}
+	table := newUITable()
+	table.AddRow(header...)
+	for i, rb := range plan.RolloutBatches {
+		replicas := rb.Replicas.String()
+		if len(rb.PodList) != 0 {
+			replicas = fmt.Sprintf("%d pods", len(rb.PodList))
+		}
+		row := []interface{}{i, replicas}
+		for _, compStatus := range statuses {
+			row = append(row, batchState(compStatus, plan.BatchPartition, int32(i)))
+		}
+		table.AddRow(row...)
+	}
+	ioStreams.Infof("\n%s\n", table.String())
+
+	var messages []string
+	for _, cond := range status.Conditions {
+		if cond.Message != "" {
+			messages = append(messages, fmt.Sprintf("  %s: %s", cond.Type, cond.Message))
+		}
+	}
+	if len(messages) != 0 {
+		sort.Strings(messages)
+		ioStreams.Infof("\nConditions:\n%s\n", strings.Join(messages, "\n"))
+	}
+	return rolloutFinished(&status.RolloutStatus), nil
+}
+
+// batchState returns the state of a batch of the rollout
+func batchState(status *v1alpha1.RolloutStatus, partition *int32, batch int32) string {
+	switch status.RollingState {
+	case v1alpha1.FinalisingState, v1alpha1.RolloutSucceedState:
+		return "done"
+	case v1alpha1.RollingInBatchesState:
+		switch {
+		case batch < status.CurrentBatch:
+			return "done"
+		case batch == status.CurrentBatch:
+			return string(status.BatchRollingState)
+		case partition != nil && batch > *partition:
+			return "held"
+		default:
+			return "pending"
+		}
+	case v1alpha1.RolloutFailingState, v1alpha1.RolloutFailedState, v1alpha1.RolloutAbandoningState,
+		v1alpha1.RolloutDeletingState:
+		switch {
+		case batch < status.CurrentBatch:
+			return "done"
+		case batch == status.CurrentBatch:
+			return string(status.RollingState)
+		default:
+			return "-"
+		}
+	default:
+		return "pending"
+	}
+}
+
+func rolloutFinished(status *v1alpha1.RolloutStatus) bool {
+	return status.RollingState == v1alpha1.RolloutSucceedState || status.RollingState == v1alpha1.RolloutFailedState
+}
+
+// printRolloutHistory prints the app revisions the rollout rolled between and its events from the oldest one
+func printRolloutHistory(ctx context.Context, c client.Client, ioStreams cmdutil.IOStreams, namespace, name string) error {
+	rollout := &v1beta1.AppRollout{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, rollout); err != nil {
+		return errors.Wrapf(err, "cannot get rollout %s", name)
+	}
+	ioStreams.Infof("Rollout: %s\nTarget: %s\nSource: %s\nLast Target: %s\nLast Source: %s\n", rollout.Name,
+		rollout.Spec.TargetAppRevisionName, rollout.Spec.SourceAppRevisionName,
+		rollout.Status.LastUpgradedTargetAppRevision, rollout.Status.LastSourceAppRevision)
+
+	events := &corev1.EventList{}
+	if err := c.List(ctx, events, client.InNamespace(namespace)); err != nil {
+		return errors.Wrapf(err, "cannot list events of rollout %s", name)
+	}
+	var rolloutEvents []corev1.Event
+	for _, e := range events.Items {
+		if e.InvolvedObject.Kind == v1beta1.AppRolloutKind && e.InvolvedObject.Name == name &&
+			(e.InvolvedObject.UID == "" || e.InvolvedObject.UID == rollout.UID) {
+			rolloutEvents = append(rolloutEvents, e)
+		}
+	}
+	if len(rolloutEvents) == 0 {
+		ioStreams.Infof("\nNo events of rollout %s\n", name)
+		return nil
+	}
+	sort.SliceStable(rolloutEvents, func(i, j int) bool {
+		return eventTime(rolloutEvents[i]).Before(eventTime(rolloutEvents[j]))
+	})
+	table := newUITable()
+	table.AddRow("TIME", "TYPE", "REASON", "MESSAGE")
+	for _, e := range rolloutEvents {
+		table.AddRow(eventTime(e).Format(time.RFC3339), e.Type, e.Reason, e.Message)
+	}
+	ioStreams.Infof("\n%s\n", table.String())
+	return nil
+}
+
+func eventTime(e corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return e.FirstTimestamp.Time
+}
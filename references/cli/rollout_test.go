@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+func newTestAppRollout() *v1beta1.AppRollout {
+	return &v1beta1.AppRollout{
+		ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: "default", UID: "rollout-uid"},
+		Spec: v1beta1.AppRolloutSpec{
+			TargetAppRevisionName: "app-v2",
+			SourceAppRevisionName: "app-v1",
+			RolloutPlan: v1alpha1.RolloutPlan{
+				RolloutBatches: []v1alpha1.RolloutBatch{{}, {}, {}},
+				BatchPartition: pointer.Int32Ptr(0),
+			},
+		},
+		Status: commontypes.AppRolloutStatus{RolloutStatus: v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: v1alpha1.BatchReadyState,
+		}},
+	}
+}
+
+func TestControlRollout(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(common.Scheme, newTestAppRollout())
+	get := func() *v1beta1.AppRollout {
+		rollout := &v1beta1.AppRollout{}
+		assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "rollout"}, rollout))
+		return rollout
+	}
+
+	assert.NoError(t, controlRollout(ctx, c, "default", "rollout", pauseRollout))
+	assert.True(t, get().Spec.RolloutPlan.Paused)
+	assert.Error(t, controlRollout(ctx, c, "default", "rollout", pauseRollout))
+	assert.NoError(t, controlRollout(ctx, c, "default", "rollout", resumeRollout))
+	assert.False(t, get().Spec.RolloutPlan.Paused)
+	assert.Error(t, controlRollout(ctx, c, "default", "rollout", resumeRollout))
+
+	assert.NoError(t, controlRollout(ctx, c, "default", "rollout", abortRollout))
+	assert.Equal(t, "app-v2", get().Annotations[oam.AnnotationRolloutAbort])
+
+	assert.Error(t, controlRollout(ctx, c, "default", "not-exist", pauseRollout))
+
+	rollout := get()
+	rollout.Status.RollingState = v1alpha1.RolloutSucceedState
+	assert.NoError(t, c.Update(ctx, rollout))
+	assert.Error(t, controlRollout(ctx, c, "default", "rollout", pauseRollout))
+}
+
+func TestPromoteRollout(t *testing.T) {
+	rollout := newTestAppRollout()
+	assert.NoError(t, promoteRollout(rollout, -1, false))
+	assert.Equal(t, int32(1), *rollout.Spec.RolloutPlan.BatchPartition)
+
+	// the partition can't go back or beyond the last batch
+	assert.Error(t, promoteRollout(rollout, 1, false))
+	assert.Error(t, promoteRollout(rollout, 3, false))
+	assert.NoError(t, promoteRollout(rollout, 2, false))
+	assert.Equal(t, int32(2), *rollout.Spec.RolloutPlan.BatchPartition)
+
+	rollout = newTestAppRollout()
+	assert.NoError(t, promoteRollout(rollout, -1, true))
+	assert.Nil(t, rollout.Spec.RolloutPlan.BatchPartition)
+	assert.Error(t, promoteRollout(rollout, -1, false))
+}
+
+func TestPrintRolloutStatus(t *testing.T) {
+	ctx := context.Background()
+	rollout := newTestAppRollout()
+	rollout.Spec.RolloutPlan.TrafficRouting = &v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficProvider}
+	rollout.Status.CurrentBatch = 1
+	rollout.Status.BatchRollingState = v1alpha1.BatchVerifyingState
+	rollout.Status.TrafficWeight = 40
+	rollout.Status.Conditions = []runtimev1alpha1.Condition{v1alpha1.NewNegativeCondition(v1alpha1.BatchVerifying,
+		"the pods are not ready")}
+	rollout.Status.Components = []commontypes.ComponentRolloutStatus{
+		{Name: "frontend", RolloutStatus: rollout.Status.RolloutStatus},
+		{Name: "backend", RolloutStatus: v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: v1alpha1.BatchReadyState,
+			CurrentBatch:      1,
+		}},
+	}
+	c := fake.NewFakeClientWithScheme(common.Scheme, rollout)
+
+	buffer := bytes.NewBuffer(nil)
+	ioStreams := cmdutil.IOStreams{In: nil, Out: buffer, ErrOut: buffer}
+	finished, err := printRolloutStatus(ctx, c, ioStreams, "default", "rollout")
+	assert.NoError(t, err)
+	assert.False(t, finished)
+	assert.Contains(t, buffer.String(), "rollingInBatches (batchVerifying)")
+	assert.Contains(t, buffer.String(), "2/3 (partition: 0)")
+	assert.Contains(t, buffer.String(), "40% to the target")
+	assert.Contains(t, buffer.String(), "FRONTEND")
+	assert.Contains(t, buffer.String(), "batchVerifying")
+	assert.Contains(t, buffer.String(), "held")
+	assert.Contains(t, buffer.String(), "BatchVerifying: the pods are not ready")
+
+	_, err = printRolloutStatus(ctx, c, ioStreams, "default", "not-exist")
+	assert.Error(t, err)
+}
+
+func TestPrintRolloutHistory(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	newEvent := func(name, kind, reason string, t time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: "rollout", UID: "rollout-uid"},
+			Type:           corev1.EventTypeNormal,
+			Reason:         reason,
+			LastTimestamp:  metav1.NewTime(t),
+		}
+	}
+	c := fake.NewFakeClientWithScheme(common.Scheme, newTestAppRollout(),
+		newEvent("rolled-back", v1beta1.AppRolloutKind, "Rollout Rolled Back", start.Add(time.Minute)),
+		newEvent("traffic", v1beta1.AppRolloutKind, "Traffic Shifted", start),
+		newEvent("other", v1beta1.ApplicationKind, "Deployed", start))
+
+	buffer := bytes.NewBuffer(nil)
+	ioStreams := cmdutil.IOStreams{In: nil, Out: buffer, ErrOut: buffer}
+	assert.NoError(t, printRolloutHistory(ctx, c, ioStreams, "default", "rollout"))
+	out := buffer.String()
+	assert.Contains(t, out, "Target: app-v2")
+	assert.NotContains(t, out, "Deployed")
+	assert.Less(t, bytes.Index(buffer.Bytes(), []byte("Traffic Shifted")),
+		bytes.Index(buffer.Bytes(), []byte("Rollout Rolled Back")))
+
+	assert.Error(t, printRolloutHistory(ctx, c, ioStreams, "default", "not-exist"))
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+const (
+	// FlagStep is the flag of the workflow step to restart from
+	FlagStep = "step"
+	// FlagRevision is the flag of the app revision to show the workflow history of
+	FlagRevision = "revision"
+)
+
+// NewWorkflowCommand creates `workflow` command and its nested children commands
+func NewWorkflowCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workflow",
+		Short: "Operate the workflow of an application",
+		Long:  "Suspend, resume, terminate, restart the workflow of an application or show its history.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+	}
+	cmd.SetOut(ioStreams.Out)
+	cmd.AddCommand(
+		newWorkflowControlCommand(c, ioStreams, workflow.ControlSuspend,
+			"Suspend the workflow of an application, steps won't be executed until it's resumed"),
+		newWorkflowControlCommand(c, ioStreams, workflow.ControlResume,
+			"Resume the suspended workflow of an application, a running suspend step is approved"),
+		newWorkflowControlCommand(c, ioStreams, workflow.ControlTerminate,
+			"Terminate the workflow of an application, steps won't be executed until it's restarted"),
+		newWorkflowControlCommand(c, ioStreams, workflow.ControlRestart,
+			"Restart the workflow of an application from the beginning, or from the given step"),
+		newWorkflowHistoryCommand(c, ioStreams),
+	)
+	return cmd
+}
+
+func newWorkflowControlCommand(c common.Args, ioStreams cmdutil.IOStreams, operation, short string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   operation + " APP_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 short,
+		Long:                  short,
+		Example:               fmt.Sprintf("vela workflow %s frontend", operation),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must specify name for the app")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			var step string
+			if operation == workflow.ControlRestart {
+				if step, err = cmd.Flags().GetString(FlagStep); err != nil {
+					return err
+				}
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			if err := controlWorkflow(context.Background(), newClient, env.Namespace, args[0], operation, step); err != nil {
+				return err
+			}
+			ioStreams.Infof("Successfully requested to %s the workflow of application %s\n", operation, args[0])
+			return nil
+		},
+	}
+	if operation == workflow.ControlRestart {
+		cmd.Example = fmt.Sprintf("vela workflow %s frontend --%s deploy-prod", operation, FlagStep)
+		cmd.Flags().StringP(FlagStep, "", "", "restart the workflow from the given step")
+	}
+	return cmd
+}
+
+// controlWorkflow requests the application controller to apply the control operation to the workflow by annotation.
+// The operation is validated against the current workflow status first to fail fast.
+func controlWorkflow(ctx context.Context, c client.Client, namespace, appName, operation, step string) error {
+	app := &v1beta1.Application{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: appName}, app); err != nil {
+		return errors.Wrapf(err, "cannot get application %s", appName)
+	}
+	if err := workflow.Control(app.DeepCopy(), operation, step); err != nil {
+		return err
+	}
+	oamutil.AddAnnotations(app, map[string]string{oam.AnnotationWorkflowControl: operation})
+	if step != "" {
+		oamutil.AddAnnotations(app, map[string]string{oam.AnnotationWorkflowRestartStep: step})
+	} else {
+		oamutil.RemoveAnnotations(app, []string{oam.AnnotationWorkflowRestartStep})
+	}
+	return errors.Wrapf(c.Update(ctx, app), "cannot update application %s", appName)
+}
+
+func newWorkflowHistoryCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "history APP_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show the workflow history of an application",
+		Long:                  "Show the workflows executed for the revisions of an application, or the steps of the given revision.",
+		Example:               fmt.Sprintf("vela workflow history frontend --%s frontend-v2", FlagRevision),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must specify name for the app")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			revision, err := cmd.Flags().GetString(FlagRevision)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			return printWorkflowHistory(context.Background(), newClient, ioStreams, env.Namespace, args[0], revision)
+		},
+	}
+	cmd.Flags().StringP(FlagRevision, "r", "", "show the steps of the workflow executed for the given app revision")
+	return cmd
+}
+
+// printWorkflowHistory prints the workflow runs of the application from the latest one,
+// or the steps and their events of the workflow run of the given revision.
+func printWorkflowHistory(ctx context.Context, c client.Client, ioStreams cmdutil.IOStreams, namespace, appName, revision string) error {
+	if revision != "" {
+		run := &v1beta1.WorkflowRun{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: workflow.WorkflowRunName(revision)}, run); err != nil {
+			return errors.Wrapf(err, "cannot get workflow history of revision %s", revision)
+		}
+		if run.Spec.AppName != appName {
+			return errors.Errorf("revision %s doesn't belong to application %s", revision, appName)
+		}
+		printWorkflowRunSteps(ioStreams, run)
+		return nil
+	}
+
+	runs := &v1beta1.WorkflowRunList{}
+	if err := c.List(ctx, runs, client.InNamespace(namespace), client.MatchingLabels{oam.LabelAppName: appName}); err != nil {
+		return errors.Wrapf(err, "cannot list workflow history of application %s", appName)
+	}
+	if len(runs.Items) == 0 {
+		ioStreams.Infof("No workflow history of application %s\n", appName)
+		return nil
+	}
+	sort.Slice(runs.Items, func(i, j int) bool {
+		return workflowRunStartTime(runs.Items[i]).After(workflowRunStartTime(runs.Items[j]))
+	})
+	table := newUITable()
+	table.AddRow("REVISION", "PHASE", "STARTED", "DURATION", "STEPS")
+	for _, run := range runs.Items {
+		finished := 0
+		for _, step := range run.Status.Steps {
+			if step.EndTime != nil {
+				finished++
+			}
+		}
+		table.AddRow(run.Spec.AppRevision, run.Status.Phase, formatWorkflowTime(run.Status.StartTime),
+			formatWorkflowDuration(run.Status.StartTime, run.Status.EndTime), fmt.Sprintf("%d/%d", finished, len(run.Status.Steps)))
+	}
+	ioStreams.Info(table.String())
+	return nil
+}
+
+func printWorkflowRunSteps(ioStreams cmdutil.IOStreams, run *v1beta1.WorkflowRun) {
+	ioStreams.Infof("Revision: %s\nPhase: %s\nStarted: %s\nDuration: %s\n\n", run.Spec.AppRevision, run.Status.Phase,
+		formatWorkflowTime(run.Status.StartTime), formatWorkflowDuration(run.Status.StartTime, run.Status.EndTime))
+	table := newUITable()
+	table.AddRow("STEP", "TYPE", "PHASE", "ATTEMPTS", "STARTED", "DURATION", "MESSAGE")
+	for _, step := range run.Status.Steps {
+		table.AddRow(step.Name, step.Type, step.Phase, step.Attempts, formatWorkflowTime(step.StartTime),
+			formatWorkflowDuration(step.StartTime, step.EndTime), step.Message)
+	}
+	ioStreams.Info(table.String())
+
+	for _, step := range run.Status.Steps {
+		if len(step.Events) == 0 && step.Outputs == nil {
+			continue
+		}
+		ioStreams.Infof("\nStep %s:\n", step.Name)
+		if step.Outputs != nil {
+			ioStreams.Infof("  Outputs: %s\n", string(step.Outputs.Raw))
+		}
+		for _, event := range step.Events {
+			ioStreams.Infof("  %s  %-10s %s\n", event.Time.Format(time.RFC3339), event.Phase, event.Message)
+		}
+	}
+}
+
+func workflowRunStartTime(run v1beta1.WorkflowRun) time.Time {
+	if run.Status.StartTime != nil {
+		return run.Status.StartTime.Time
+	}
+	return run.CreationTimestamp.Time
+}
+
+func formatWorkflowTime(t *metav1.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatWorkflowDuration(start, end *metav1.Time) string {
+	if start == nil {
+		return "-"
+	}
+	if end == nil {
+		return duration.HumanDuration(time.Since(start.Time)) + " (running)"
+	}
+	return duration.HumanDuration(end.Sub(start.Time))
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+func TestControlWorkflow(t *testing.T) {
+	ctx := context.Background()
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1beta1.ApplicationSpec{
+			Workflow: []v1beta1.WorkflowStep{{Name: "deploy-staging"}, {Name: "deploy-prod"}},
+		},
+		Status: commontypes.AppStatus{
+			Workflow: &commontypes.WorkflowStatus{AppRevision: "app-v1"},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(common.Scheme, app)
+
+	assert.NoError(t, controlWorkflow(ctx, c, "default", "app", workflow.ControlRestart, "deploy-prod"))
+	got := &v1beta1.Application{}
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app"}, got))
+	assert.Equal(t, workflow.ControlRestart, got.Annotations[oam.AnnotationWorkflowControl])
+	assert.Equal(t, "deploy-prod", got.Annotations[oam.AnnotationWorkflowRestartStep])
+
+	assert.NoError(t, controlWorkflow(ctx, c, "default", "app", workflow.ControlSuspend, ""))
+	got = &v1beta1.Application{}
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app"}, got))
+	assert.Equal(t, workflow.ControlSuspend, got.Annotations[oam.AnnotationWorkflowControl])
+	assert.Empty(t, got.Annotations[oam.AnnotationWorkflowRestartStep])
+
+	assert.Error(t, controlWorkflow(ctx, c, "default", "app", workflow.ControlRestart, "not-exist"))
+	assert.Error(t, controlWorkflow(ctx, c, "default", "not-exist", workflow.ControlResume, ""))
+}
+
+func TestPrintWorkflowHistory(t *testing.T) {
+	ctx := context.Background()
+	start := metav1.NewTime(time.Now().Add(-time.Hour))
+	end := metav1.NewTime(start.Add(time.Minute))
+	newRun := func(rev string, phase v1beta1.WorkflowRunPhase) *v1beta1.WorkflowRun {
+		return &v1beta1.WorkflowRun{
+			ObjectMeta: metav1.ObjectMeta{Name: rev, Namespace: "default", Labels: map[string]string{oam.LabelAppName: "app"}},
+			Spec:       v1beta1.WorkflowRunSpec{AppName: "app", AppRevision: rev},
+			Status: v1beta1.WorkflowRunStatus{
+				Phase:     phase,
+				StartTime: &start,
+				EndTime:   &end,
+				Steps: []v1beta1.WorkflowStepRecord{{
+					Name:      "deploy",
+					Type:      "apply-component",
+					Phase:     commontypes.WorkflowStepPhaseFailed,
+					Message:   "image not found",
+					StartTime: &start,
+					EndTime:   &end,
+					Events: []v1beta1.WorkflowStepEvent{
+						{Time: start, Phase: commontypes.WorkflowStepPhaseRunning},
+						{Time: end, Phase: commontypes.WorkflowStepPhaseFailed, Message: "image not found"},
+					},
+				}},
+			},
+		}
+	}
+	c := fake.NewFakeClientWithScheme(common.Scheme, newRun("app-v1", v1beta1.WorkflowRunSucceeded), newRun("app-v2", v1beta1.WorkflowRunFailed))
+
+	buffer := bytes.NewBuffer(nil)
+	ioStreams := cmdutil.IOStreams{In: nil, Out: buffer, ErrOut: buffer}
+	assert.NoError(t, printWorkflowHistory(ctx, c, ioStreams, "default", "app", ""))
+	assert.Contains(t, buffer.String(), "app-v1")
+	assert.Contains(t, buffer.String(), "app-v2")
+	assert.Contains(t, buffer.String(), "1/1")
+
+	buffer.Reset()
+	assert.NoError(t, printWorkflowHistory(ctx, c, ioStreams, "default", "app", "app-v2"))
+	assert.Contains(t, buffer.String(), "Phase: failed")
+	assert.Contains(t, buffer.String(), "image not found")
+	assert.Contains(t, buffer.String(), "Step deploy:")
+
+	assert.Error(t, printWorkflowHistory(ctx, c, ioStreams, "default", "other", "app-v2"))
+	assert.Error(t, printWorkflowHistory(ctx, c, ioStreams, "default", "app", "app-v3"))
+}
@@ -325,11 +325,11 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if len(workload.OwnerReferences) != 1 || workload.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("wrokload ownerreference error")
 			}
-			if len(checkRt.Status.TrackedResources) != 1 {
+			if len(checkRt.Spec.TrackedResources) != 1 {
 				return fmt.Errorf("resourceTracker status recode trackedResource length missmatch")
 			}
-			if checkRt.Status.TrackedResources[0].Name != workload.Name {
-				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", checkRt.Status.TrackedResources[0].Name, workload.Name)
+			if checkRt.Spec.TrackedResources[0].Name != workload.Name {
+				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", checkRt.Spec.TrackedResources[0].Name, workload.Name)
 			}
 			return nil
 		}, time.Second*5, time.Millisecond*300).Should(BeNil())
@@ -464,8 +464,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if len(trait.OwnerReferences) != 1 || trait.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("trait owner reference missmatch")
 			}
-			if len(resourceTracker.Status.TrackedResources) != 2 {
-				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 2 {
+				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Spec.TrackedResources))
 			}
 			return nil
 		}, time.Second*5, time.Millisecond*500).Should(BeNil())
@@ -540,8 +540,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if len(trait.OwnerReferences) != 1 || trait.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("trait owner reference missmatch")
 			}
-			if len(resourceTracker.Status.TrackedResources) != 2 {
-				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 2 {
+				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Spec.TrackedResources))
 			}
 			return nil
 		}, time.Second*5, time.Millisecond*300).Should(BeNil())
@@ -665,8 +665,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if len(sameDeplpoy.OwnerReferences) != 1 || crossDeplpoy.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("same ns deploy have error ownerReference")
 			}
-			if len(resourceTracker.Status.TrackedResources) != 2 {
-				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 2 {
+				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Spec.TrackedResources))
 			}
 			return nil
 		}, time.Second*5, time.Millisecond*500).Should(BeNil())
@@ -791,8 +791,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if workload.Spec.Template.Spec.Containers[0].Image != "busybox" {
 				return fmt.Errorf("container image not match")
 			}
-			if len(resourceTracker.Status.TrackedResources) != 1 {
-				return fmt.Errorf("expect track %q resources, but got %q", 1, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 1 {
+				return fmt.Errorf("expect track %q resources, but got %q", 1, len(resourceTracker.Spec.TrackedResources))
 			}
 			return nil
 		}, time.Second*50, time.Millisecond*300).Should(BeNil())
@@ -823,8 +823,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if err := k8sClient.Get(ctx, generateResourceTrackerKey(app.Namespace, app.Name, 2), resourceTracker); err != nil {
 				return err
 			}
-			if len(resourceTracker.Status.TrackedResources) != 1 {
-				return fmt.Errorf("expect track %q resources, but got %q", 1, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 1 {
+				return fmt.Errorf("expect track %q resources, but got %q", 1, len(resourceTracker.Spec.TrackedResources))
 			}
 			depolys := new(appsv1.DeploymentList)
 			opts := []client.ListOption{
@@ -941,17 +941,17 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if len(deploy2.OwnerReferences) != 1 || deploy2.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("deploy2 have error ownerReference")
 			}
-			if len(resourceTracker.Status.TrackedResources) != 2 {
-				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 2 {
+				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Spec.TrackedResources))
 			}
-			if resourceTracker.Status.TrackedResources[0].Namespace != crossNamespace || resourceTracker.Status.TrackedResources[1].Namespace != crossNamespace {
+			if resourceTracker.Spec.TrackedResources[0].Namespace != crossNamespace || resourceTracker.Spec.TrackedResources[1].Namespace != crossNamespace {
 				return fmt.Errorf("resourceTracker recorde namespace mismatch")
 			}
-			if resourceTracker.Status.TrackedResources[0].Name != deploy1.Name && resourceTracker.Status.TrackedResources[1].Name != deploy1.Name {
-				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", resourceTracker.Status.TrackedResources[0].Name, deploy1.Name)
+			if resourceTracker.Spec.TrackedResources[0].Name != deploy1.Name && resourceTracker.Spec.TrackedResources[1].Name != deploy1.Name {
+				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", resourceTracker.Spec.TrackedResources[0].Name, deploy1.Name)
 			}
-			if resourceTracker.Status.TrackedResources[0].Name != deploy2.Name && resourceTracker.Status.TrackedResources[1].Name != deploy2.Name {
-				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", resourceTracker.Status.TrackedResources[0].Name, deploy2.Name)
+			if resourceTracker.Spec.TrackedResources[0].Name != deploy2.Name && resourceTracker.Spec.TrackedResources[1].Name != deploy2.Name {
+				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", resourceTracker.Spec.TrackedResources[0].Name, deploy2.Name)
 			}
 			return nil
 		}, time.Second*5, time.Millisecond*300).Should(BeNil())
@@ -994,8 +994,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if err != nil {
 				return fmt.Errorf("error get resourceTracker")
 			}
-			if len(checkRt.Status.TrackedResources) != 1 {
-				return fmt.Errorf("expect track %q resources, but got %q", 1, len(checkRt.Status.TrackedResources))
+			if len(checkRt.Spec.TrackedResources) != 1 {
+				return fmt.Errorf("expect track %q resources, but got %q", 1, len(checkRt.Spec.TrackedResources))
 			}
 			return nil
 		}, time.Second*5, time.Millisecond*500).Should(BeNil())
@@ -1084,8 +1084,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if err != nil || len(mts.Items) != 1 {
 				return fmt.Errorf("failed generate cross namespace trait")
 			}
-			if len(resourceTracker.Status.TrackedResources) != 2 {
-				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 2 {
+				return fmt.Errorf("expect track %q resources, but got %q", 2, len(resourceTracker.Spec.TrackedResources))
 			}
 			trait := mts.Items[0]
 			if len(trait.OwnerReferences) != 1 || trait.OwnerReferences[0].UID != resourceTracker.UID {
@@ -1100,7 +1100,7 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if deploy.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("deploy owner reference missmatch")
 			}
-			for _, resource := range resourceTracker.Status.TrackedResources {
+			for _, resource := range resourceTracker.Spec.TrackedResources {
 				if resource.Kind == deploy.Kind && resource.Name != deploy.Name {
 					return fmt.Errorf("deploy name mismatch ")
 				}
@@ -1142,8 +1142,8 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if err != nil || len(mts.Items) != 0 {
 				return fmt.Errorf("cross namespace trait still exist")
 			}
-			if len(resourceTracker.Status.TrackedResources) != 1 {
-				return fmt.Errorf("expect track %d resources, but got %d", 1, len(resourceTracker.Status.TrackedResources))
+			if len(resourceTracker.Spec.TrackedResources) != 1 {
+				return fmt.Errorf("expect track %d resources, but got %d", 1, len(resourceTracker.Spec.TrackedResources))
 			}
 			deploys := new(appsv1.DeploymentList)
 			err = k8sClient.List(ctx, deploys, opts...)
@@ -1154,7 +1154,7 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if len(deploy.OwnerReferences) != 1 || deploy.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("deploy owner reference missmatch")
 			}
-			if resourceTracker.Status.TrackedResources[0].Name != deploy.Name {
+			if resourceTracker.Spec.TrackedResources[0].Name != deploy.Name {
 				return fmt.Errorf("error to record deploy name in app status")
 			}
 			return nil
@@ -1246,11 +1246,11 @@ var _ = Describe("Test application cross namespace resource", func() {
 			if len(workload.OwnerReferences) != 1 || workload.OwnerReferences[0].UID != resourceTracker.UID {
 				return fmt.Errorf("wrokload ownerreference error")
 			}
-			if len(checkRt.Status.TrackedResources) != 1 {
+			if len(checkRt.Spec.TrackedResources) != 1 {
 				return fmt.Errorf("resourceTracker status recode trackedResource length missmatch")
 			}
-			if checkRt.Status.TrackedResources[0].Name != workload.Name {
-				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", checkRt.Status.TrackedResources[0].Name, workload.Name)
+			if checkRt.Spec.TrackedResources[0].Name != workload.Name {
+				return fmt.Errorf("resourceTracker status recode trackedResource name mismatch recorded %s, actually %s", checkRt.Spec.TrackedResources[0].Name, workload.Name)
 			}
 			return nil
 		}, time.Second*5, time.Millisecond*500).Should(BeNil())